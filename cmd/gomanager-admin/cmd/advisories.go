@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/spf13/cobra"
+)
+
+var (
+	advisoriesDatabase string
+	advisoriesOSV      string
+)
+
+func init() {
+	advisoriesCmd.Flags().StringVarP(&advisoriesDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	advisoriesCmd.Flags().StringVar(&advisoriesOSV, "osv-url", osvAPI, "Base URL of the OSV API")
+	rootCmd.AddCommand(advisoriesCmd)
+}
+
+// osvAPI is the OSV vulnerability database API.
+const osvAPI = "https://api.osv.dev"
+
+var advisoriesCmd = &cobra.Command{
+	Use:   "advisories",
+	Short: "Record known vulnerabilities in each package's module",
+	Long: `Queries the OSV database for advisories affecting the module of every
+published package and records their affected version ranges and severity.
+The advisories are kept in the slim database, and clients refuse to install
+a version with a critical advisory unless given --accept-vulnerable.
+
+Severities come from the GitHub advisory database; Go vulnerability
+database entries don't rate severity, so they are recorded without one.
+Advisories for a module's dependencies are not collected.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(advisoriesDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+		seen := make(map[string]bool)
+		var modules []string
+		for _, b := range binaries {
+			module := pkgbuild.ResolvePaths(b.Package).Module
+			if b.BuildStatus == db.StatusQuarantined || seen[module] {
+				continue
+			}
+			seen[module] = true
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		affected, critical, failed := 0, 0, 0
+		for i, module := range modules {
+			advisories, err := queryOSV(client, advisoriesOSV, module)
+			if err != nil {
+				fmt.Printf("[%d/%d] %s: %v\n", i+1, len(modules), module, err)
+				failed++
+				continue
+			}
+			if err := db.ReplaceAdvisories(conn, module, advisories); err != nil {
+				return fmt.Errorf("failed to record advisories for %s: %w", module, err)
+			}
+			if len(advisories) == 0 {
+				continue
+			}
+			affected++
+			n := 0
+			for _, a := range advisories {
+				if a.Critical() {
+					n++
+				}
+			}
+			if n > 0 {
+				critical++
+			}
+			fmt.Printf("[%d/%d] %s: %d advisories (%d critical)\n", i+1, len(modules), module, len(advisories), n)
+		}
+
+		fmt.Printf("\nDone. %d of %d modules have advisories, %d of them critical; %d queries failed.\n",
+			affected, len(modules), critical, failed)
+		return nil
+	},
+}
+
+// osvVuln is the part of an OSV vulnerability record queryOSV reads.
+type osvVuln struct {
+	ID               string `json:"id"`
+	Summary          string `json:"summary"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Affected []struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// queryOSV returns the advisories OSV lists for a Go module, one per
+// affected version range.
+func queryOSV(client *http.Client, apiBase, module string) ([]db.Advisory, error) {
+	body, err := json.Marshal(map[string]any{
+		"package": map[string]string{"name": module, "ecosystem": "Go"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(apiBase+"/v1/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var result struct {
+		Vulns []osvVuln `json:"vulns"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	var advisories []db.Advisory
+	for _, v := range result.Vulns {
+		for _, aff := range v.Affected {
+			if aff.Package.Ecosystem != "Go" || aff.Package.Name != module {
+				continue
+			}
+			for _, r := range aff.Ranges {
+				if r.Type != "SEMVER" {
+					continue
+				}
+				// Events alternate between introduced and fixed versions.
+				var cur *db.Advisory
+				for _, e := range r.Events {
+					switch {
+					case e.Introduced != "":
+						if cur != nil {
+							advisories = append(advisories, *cur)
+						}
+						cur = &db.Advisory{
+							ID:         v.ID,
+							Module:     module,
+							Severity:   strings.ToUpper(v.DatabaseSpecific.Severity),
+							Introduced: osvVersion(e.Introduced),
+							Summary:    v.Summary,
+						}
+					case e.Fixed != "" && cur != nil:
+						cur.Fixed = osvVersion(e.Fixed)
+						advisories = append(advisories, *cur)
+						cur = nil
+					}
+				}
+				if cur != nil {
+					advisories = append(advisories, *cur)
+				}
+			}
+		}
+	}
+	return advisories, nil
+}
+
+// osvVersion converts an OSV SEMVER version, which has no "v" prefix, to a
+// Go module version. The "0" OSV uses for "every version" becomes "".
+func osvVersion(v string) string {
+	if v == "0" {
+		return ""
+	}
+	return "v" + v
+}
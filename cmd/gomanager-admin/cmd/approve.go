@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	approveDatabase string
+	approveAll      bool
+	approveReview   bool
+	approveDryRun   bool
+	approveStatus   string
+	approveFilter   db.QuarantineFilter
+)
+
+func init() {
+	approveCmd.Flags().StringVarP(&approveDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	approveCmd.Flags().BoolVar(&approveAll, "all", false, "Approve every quarantined package matching the filters")
+	approveCmd.Flags().BoolVar(&approveReview, "review", false, "Review quarantined packages one at a time, approving or rejecting each")
+	approveCmd.Flags().BoolVar(&approveDryRun, "dry-run", false, "Only show what would be approved, don't modify the database")
+	approveCmd.Flags().StringVar(&approveStatus, "status", "unknown", "Status to give approved packages: unknown or pending")
+	approveCmd.Flags().IntVar(&approveFilter.MinStars, "min-stars", 0, "Only packages with at least this many stars")
+	approveCmd.Flags().StringVar(&approveFilter.Match, "match", "", "Only packages whose name, path, or description contains this text")
+	approveCmd.Flags().StringVar(&approveFilter.Query, "query", "", "Only packages discovered by this scanner search query")
+	rootCmd.AddCommand(approveCmd)
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve [package...]",
+	Short: "Promote quarantined packages so they are verified and published",
+	Long: `The scanner adds new packages as quarantined: they are not verified and
+are left out of the slim database clients download until a maintainer
+approves them. This command moves them to the unknown (or, with --status,
+pending) status.
+
+Packages can be approved by path or binary name, in bulk with --all and
+the --min-stars, --match, and --query filters, or interactively with
+--review, which shows each matching package and asks whether to approve it,
+reject (delete) it, or leave it quarantined. With no arguments or flags,
+the quarantined packages are listed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if approveStatus != "unknown" && approveStatus != "pending" {
+			return fmt.Errorf("invalid --status %q (want unknown or pending)", approveStatus)
+		}
+		if len(args) > 0 && (approveAll || approveReview) {
+			return fmt.Errorf("packages cannot be combined with --all or --review")
+		}
+		if approveAll && approveReview {
+			return fmt.Errorf("--all and --review are mutually exclusive")
+		}
+
+		conn, err := openAdminDB(approveDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		var selected []db.Binary
+		if len(args) > 0 {
+			for _, arg := range args {
+				b, err := db.GetAnyByPackage(conn, arg)
+				if errors.Is(err, db.ErrNotFound) {
+					b, err = db.GetAnyByName(conn, arg)
+				}
+				if err != nil {
+					return err
+				}
+				if b.BuildStatus != db.StatusQuarantined {
+					fmt.Printf("%s is not quarantined (%s), skipping\n", b.Package, b.BuildStatus)
+					continue
+				}
+				selected = append(selected, *b)
+			}
+		} else {
+			selected, err = db.ListQuarantined(conn, approveFilter)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(args) == 0 && !approveAll && !approveReview {
+			if len(selected) == 0 {
+				fmt.Println("No quarantined packages.")
+				return nil
+			}
+			for _, b := range selected {
+				fmt.Printf("%-24s %-50s %6d stars\n", b.Name, b.Package, b.Stars)
+			}
+			fmt.Printf("\n%d quarantined packages. Approve with --all, --review, or by name.\n", len(selected))
+			return nil
+		}
+
+		if approveReview {
+			return reviewQuarantined(conn, selected)
+		}
+
+		approved := 0
+		for _, b := range selected {
+			if approveDryRun {
+				fmt.Printf("Would approve %s\n", b.Package)
+				approved++
+				continue
+			}
+			ok, err := db.Approve(conn, b.ID, approveStatus)
+			if err != nil {
+				fmt.Printf("Warning: failed to approve %s: %v\n", b.Package, err)
+				continue
+			}
+			if ok {
+				recordEvent(conn, b.Package, db.EventApproved, approveStatus)
+				enqueueJob(conn, b.Package, db.JobApproved)
+				fmt.Printf("Approved %s\n", b.Package)
+				approved++
+			}
+		}
+
+		if approveDryRun {
+			fmt.Printf("\nDry run complete. Would approve %d packages.\n", approved)
+		} else {
+			fmt.Printf("\nApproved %d packages as %s.\n", approved, approveStatus)
+		}
+		return nil
+	},
+}
+
+// reviewQuarantined walks through packages one at a time, prompting on
+// stdin to approve, reject, or skip each.
+func reviewQuarantined(conn *sql.DB, binaries []db.Binary) error {
+	in := bufio.NewReader(os.Stdin)
+	approved, rejected := 0, 0
+	for i, b := range binaries {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(binaries), b.Name)
+		fmt.Printf("  Package:     %s@%s\n", b.Package, b.Version)
+		fmt.Printf("  Repository:  %s (%d stars)\n", b.RepoURL, b.Stars)
+		if b.Description != "" {
+			fmt.Printf("  Description: %s\n", truncate(b.Description, 200))
+		}
+		if b.License != "" {
+			fmt.Printf("  License:     %s\n", b.License)
+		}
+		fmt.Print("Approve? [y]es, [r]eject, [N]o, [q]uit: ")
+
+		line, err := in.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if err != nil && answer == "" {
+			break
+		}
+		switch answer {
+		case "y", "yes":
+			if approveDryRun {
+				fmt.Printf("Would approve %s\n", b.Package)
+			} else if _, err := db.Approve(conn, b.ID, approveStatus); err != nil {
+				fmt.Printf("Warning: failed to approve %s: %v\n", b.Package, err)
+				continue
+			} else {
+				recordEvent(conn, b.Package, db.EventApproved, approveStatus)
+				enqueueJob(conn, b.Package, db.JobApproved)
+			}
+			approved++
+		case "r", "reject":
+			if approveDryRun {
+				fmt.Printf("Would delete %s\n", b.Package)
+			} else if err := db.DeleteBinary(conn, b.ID); err != nil {
+				fmt.Printf("Warning: failed to delete %s: %v\n", b.Package, err)
+				continue
+			} else {
+				recordEvent(conn, b.Package, db.EventRejected, "")
+			}
+			rejected++
+		case "q", "quit":
+			fmt.Printf("\nApproved %d, rejected %d.\n", approved, rejected)
+			return nil
+		}
+	}
+	fmt.Printf("\nApproved %d, rejected %d.\n", approved, rejected)
+	return nil
+}
+
+// recordEvent logs a curation event for why, warning if it can't be saved.
+func recordEvent(conn *sql.DB, pkg, event, detail string) {
+	if err := db.RecordEvent(conn, pkg, event, detail); err != nil {
+		fmt.Printf("Warning: failed to record %s event for %s: %v\n", event, pkg, err)
+	}
+}
+
+// enqueueJob queues pkg for verification, warning rather than failing the
+// run if the queue can't be written.
+func enqueueJob(conn *sql.DB, pkg, reason string) {
+	if err := db.EnqueueJob(conn, pkg, reason); err != nil {
+		fmt.Printf("  Warning: failed to queue %s for verification: %v\n", pkg, err)
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/bincache"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheServerAddr          string
+	cacheServerDir           string
+	cacheServerToken         string
+	cacheServerPublicRead    bool
+	cacheServerMaxAge        string
+	cacheServerMaxSize       string
+	cacheServerMaxObjectSize string
+	cacheServerGCInterval    time.Duration
+)
+
+func init() {
+	cacheServerCmd.Flags().StringVar(&cacheServerAddr, "addr", ":8080", "Address to listen on")
+	cacheServerCmd.Flags().StringVar(&cacheServerDir, "dir", "", "Directory holding the cached binaries")
+	cacheServerCmd.Flags().StringVar(&cacheServerToken, "token", "", "Bearer token clients must send (default: $GOMANAGER_CACHE_TOKEN)")
+	cacheServerCmd.Flags().BoolVar(&cacheServerPublicRead, "public-read", false, "Allow downloads without the token (uploads still need it)")
+	cacheServerCmd.Flags().StringVar(&cacheServerMaxAge, "max-age", "30d", "Remove binaries not fetched or uploaded within this long (e.g. 30d, 12h; 0 = keep)")
+	cacheServerCmd.Flags().StringVar(&cacheServerMaxSize, "max-size", "0", "Remove the least recently used binaries beyond this total size (e.g. 20GiB; 0 = no limit)")
+	cacheServerCmd.Flags().StringVar(&cacheServerMaxObjectSize, "max-object-size", "1GiB", "Largest upload accepted")
+	cacheServerCmd.Flags().DurationVar(&cacheServerGCInterval, "gc-interval", time.Hour, "How often to collect garbage")
+	cacheServerCmd.MarkFlagRequired("dir")
+	rootCmd.AddCommand(cacheServerCmd)
+}
+
+var cacheServerCmd = &cobra.Command{
+	Use:   "cache-server",
+	Short: "Serve a shared binary cache over HTTP",
+	Long: `Serves a directory as the binary cache clients configure with
+
+  [cache]
+  url = "https://cache.example.com"
+
+Clients download with GET /<key> and upload with PUT /<key>, sending the
+token as "Authorization: Bearer <token>" (GOMANAGER_CACHE_TOKEN on the
+client). With --public-read, downloads don't need the token.
+
+Garbage collection runs at startup and every --gc-interval: binaries not
+fetched or uploaded within --max-age are removed, then the least recently
+used ones until the cache fits in --max-size. The server shuts down
+gracefully on SIGINT or SIGTERM.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cacheServerToken
+		if token == "" {
+			token = os.Getenv("GOMANAGER_CACHE_TOKEN")
+		}
+		if token == "" {
+			return fmt.Errorf("a token is required: set --token or GOMANAGER_CACHE_TOKEN")
+		}
+		maxAge := time.Duration(0)
+		if cacheServerMaxAge != "0" {
+			var err error
+			if maxAge, err = parseAge(cacheServerMaxAge); err != nil {
+				return fmt.Errorf("invalid --max-age: %w", err)
+			}
+		}
+		maxSize, err := parseByteSize(cacheServerMaxSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+		maxObject, err := parseByteSize(cacheServerMaxObjectSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-object-size: %w", err)
+		}
+		if err := os.MkdirAll(cacheServerDir, 0o755); err != nil {
+			return fmt.Errorf("cannot create cache directory: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		collect := func() {
+			stats, err := bincache.GC(cacheServerDir, maxAge, maxSize)
+			if err != nil {
+				fmt.Printf("Garbage collection failed: %v\n", err)
+				return
+			}
+			fmt.Printf("Garbage collection: removed %d entries (%s), kept %d (%s)\n",
+				stats.Removed, formatBytes(stats.Freed), stats.Kept, formatBytes(stats.Size))
+		}
+		collect()
+		go func() {
+			ticker := time.NewTicker(cacheServerGCInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					collect()
+				}
+			}
+		}()
+
+		srv := &http.Server{
+			Addr: cacheServerAddr,
+			Handler: &bincache.Server{
+				Dir:           cacheServerDir,
+				Token:         token,
+				PublicRead:    cacheServerPublicRead,
+				MaxObjectSize: maxObject,
+			},
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		fmt.Printf("Serving binary cache %s on %s\n", cacheServerDir, cacheServerAddr)
+
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		fmt.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// parseByteSize parses a size in bytes with an optional binary (KiB, MiB,
+// GiB, TiB) or decimal (KB, MB, GB, TB) unit.
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"B", 1},
+	}
+	s = strings.TrimSpace(s)
+	mult := int64(1)
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, mult = strings.TrimSpace(n), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ciConfigPath string
+	ciDatabase   string
+	ciRestart    bool
+)
+
+func init() {
+	ciCmd.Flags().StringVarP(&ciConfigPath, "config", "c", "", "TOML file configuring the stages (default: built-in defaults)")
+	ciCmd.Flags().StringVarP(&ciDatabase, "database", "d", "", "Path to database.db (overrides the config file)")
+	ciCmd.Flags().BoolVar(&ciRestart, "restart", false, "Run every stage, ignoring progress saved by a previous failed run")
+	rootCmd.AddCommand(ciCmd)
+}
+
+// ciConfig configures the ci command's stages.
+type ciConfig struct {
+	// Database is the full database to update.
+	Database string `toml:"database"`
+	// Slim is where the release stage writes the slim client database.
+	Slim string `toml:"slim"`
+	// State is the file recording completed stages, so a failed run can be
+	// resumed. It defaults to the database path with ".ci-state.json"
+	// appended.
+	State string `toml:"state"`
+	// Skip lists stages not to run.
+	Skip []string `toml:"skip"`
+
+	UpdateVersions struct {
+		BatchSize int `toml:"batch_size"`
+	} `toml:"update_versions"`
+	Verify struct {
+		BatchSize int    `toml:"batch_size"`
+		Jobs      int    `toml:"jobs"`
+		MaxAge    string `toml:"max_age"`
+		// MaxDuration stops verify from starting builds after this long, so
+		// it finishes inside the CI job's timeout.
+		MaxDuration string   `toml:"max_duration"`
+		Platforms   []string `toml:"platforms"`
+		// Queue verifies from the job queue (verify --queue) instead of
+		// querying for updated packages.
+		Queue bool `toml:"queue"`
+	} `toml:"verify"`
+	Scan struct {
+		ScannedRepos string `toml:"scanned_repos"`
+	} `toml:"scan"`
+	// Daemon configures the daemon command, which runs some of the stages
+	// on a schedule.
+	Daemon struct {
+		// Health is the address of the health and status endpoint.
+		Health string `toml:"health"`
+		// Intervals maps a daemon loop to how often it runs (e.g. "6h");
+		// "0" disables it.
+		Intervals map[string]string `toml:"intervals"`
+	} `toml:"daemon"`
+}
+
+// defaultCIConfig returns the configuration used for settings the config
+// file leaves unset; it matches the scheduled workflows.
+func defaultCIConfig() ciConfig {
+	var c ciConfig
+	c.Database = "./database.db"
+	c.Slim = "./database-slim.db"
+	c.UpdateVersions.BatchSize = 100
+	c.Verify.BatchSize = 50
+	c.Verify.Jobs = 1
+	c.Scan.ScannedRepos = "./scanned_repos.json"
+	c.Daemon.Health = ":8081"
+	return c
+}
+
+// ciStage is one step of the ci pipeline: a gomanager-admin invocation and
+// the exit code reported if it fails.
+type ciStage struct {
+	name     string
+	exitCode int
+	args     func(c ciConfig) []string
+}
+
+var ciStages = []ciStage{
+	{"update-versions", ExitUpdateVersions, func(c ciConfig) []string {
+		return []string{"update-versions", "-d", c.Database, "-n", strconv.Itoa(c.UpdateVersions.BatchSize)}
+	}},
+	{"verify", ExitVerify, func(c ciConfig) []string {
+		mode := "--recheck"
+		if c.Verify.Queue {
+			mode = "--queue"
+		}
+		args := []string{"verify", "-d", c.Database, mode,
+			"-n", strconv.Itoa(c.Verify.BatchSize), "-j", strconv.Itoa(c.Verify.Jobs)}
+		if c.Verify.MaxAge != "" {
+			args = append(args, "--max-age", c.Verify.MaxAge)
+		}
+		if c.Verify.MaxDuration != "" {
+			args = append(args, "--max-duration", c.Verify.MaxDuration)
+		}
+		if len(c.Verify.Platforms) > 0 {
+			args = append(args, "--platforms="+strings.Join(c.Verify.Platforms, ","))
+		}
+		return args
+	}},
+	{"score", ExitScore, func(c ciConfig) []string {
+		return []string{"confidence", "-d", c.Database}
+	}},
+	{"prune", ExitPrune, func(c ciConfig) []string {
+		return []string{"db", "optimize", "-d", c.Database}
+	}},
+	{"check", ExitCheck, func(c ciConfig) []string {
+		return []string{"db", "check", "-d", c.Database}
+	}},
+	{"release", ExitRelease, func(c ciConfig) []string {
+		return []string{"db", "slim", "-d", c.Database, "-o", c.Slim}
+	}},
+}
+
+// ciState is the progress of a ci run, saved after each stage.
+type ciState struct {
+	Started   time.Time `json:"started"`
+	Completed []string  `json:"completed"`
+}
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Run the update, verify, and publish pipeline",
+	Long: `Runs the maintenance pipeline the scheduled workflows need, in order:
+
+  update-versions  check for new releases (update-versions)
+  verify           re-verify updated and unverified packages (verify --recheck)
+  score            re-score confidence from the new results (confidence)
+  prune            prune history and compact the database (db optimize)
+  check            check the database is fit to publish (db check)
+  release          write the slim client database (db slim)
+
+Settings come from a single TOML file (--config); see the README for its
+format. Each completed stage is recorded in a state file, so re-running
+after a failure resumes at the failed stage; --restart runs every stage
+again. The state file is removed once all stages succeed.
+
+If a stage fails, ci stops and exits with that stage's code:
+  10 update-versions, 11 verify, 15 score, 12 prune, 13 check, 14 release.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadCIConfig(ciConfigPath)
+		if err != nil {
+			return err
+		}
+		if ciDatabase != "" {
+			cfg.Database = ciDatabase
+		}
+		if cfg.State == "" {
+			cfg.State = cfg.Database + ".ci-state.json"
+		}
+		for _, name := range cfg.Skip {
+			if !knownCIStage(name) {
+				return fmt.Errorf("unknown stage %q in skip", name)
+			}
+		}
+
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot locate gomanager-admin: %w", err)
+		}
+
+		state := ciState{Started: time.Now().UTC()}
+		if !ciRestart {
+			saved, err := loadCIState(cfg.State)
+			if err != nil {
+				return err
+			}
+			if saved != nil {
+				state = *saved
+				fmt.Printf("Resuming run started %s; completed: %s\n",
+					state.Started.Format(time.RFC3339), strings.Join(state.Completed, ", "))
+			}
+		}
+
+		for _, stage := range ciStages {
+			if containsString(cfg.Skip, stage.name) {
+				fmt.Printf("\n=== %s: skipped\n", stage.name)
+				continue
+			}
+			if containsString(state.Completed, stage.name) {
+				fmt.Printf("\n=== %s: already completed\n", stage.name)
+				continue
+			}
+
+			stageArgs := append([]string{"--github-api", githubAPI}, stage.args(cfg)...)
+			fmt.Printf("\n=== %s: gomanager-admin %s\n", stage.name, strings.Join(stageArgs[2:], " "))
+			started := time.Now()
+			run := exec.Command(self, stageArgs...)
+			run.Stdout = os.Stdout
+			run.Stderr = os.Stderr
+			if err := run.Run(); err != nil {
+				if serr := saveCIState(cfg.State, state); serr != nil {
+					fmt.Printf("Warning: cannot save progress: %v\n", serr)
+				}
+				return withExitCode(stage.exitCode,
+					fmt.Errorf("stage %s failed after %s: %w (re-run to resume)", stage.name, time.Since(started).Round(time.Second), err))
+			}
+			fmt.Printf("=== %s: done in %s\n", stage.name, time.Since(started).Round(time.Second))
+
+			state.Completed = append(state.Completed, stage.name)
+			if err := saveCIState(cfg.State, state); err != nil {
+				fmt.Printf("Warning: cannot save progress: %v\n", err)
+			}
+		}
+
+		if err := os.Remove(cfg.State); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: cannot remove %s: %v\n", cfg.State, err)
+		}
+		fmt.Printf("\nAll stages completed in %s.\n", time.Since(state.Started).Round(time.Second))
+		return nil
+	},
+}
+
+// loadCIConfig reads the ci configuration at path over the defaults. An
+// empty path yields the defaults.
+func loadCIConfig(path string) (ciConfig, error) {
+	cfg := defaultCIConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	md, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid ci config %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("invalid ci config %s: unknown key %s", path, undecoded[0])
+	}
+	return cfg, nil
+}
+
+func knownCIStage(name string) bool {
+	for _, s := range ciStages {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCIState reads saved ci progress, or returns nil if there is none.
+func loadCIState(path string) (*ciState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s ciState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid ci state %s (use --restart): %w", path, err)
+	}
+	return &s, nil
+}
+
+func saveCIState(path string, s ciState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
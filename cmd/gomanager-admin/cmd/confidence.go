@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	confidenceDatabase string
+	confidenceDryRun   bool
+)
+
+func init() {
+	confidenceCmd.Flags().StringVarP(&confidenceDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	confidenceCmd.Flags().BoolVar(&confidenceDryRun, "dry-run", false, "Only list low-confidence packages, don't modify the database")
+	rootCmd.AddCommand(confidenceCmd)
+}
+
+// sourcePoints is what each entrypoint source contributes to the confidence
+// score. Packages discovered before sources were recorded get
+// unrecordedSourcePoints.
+var sourcePoints = map[string]int{
+	sourceRootMain:   35,
+	sourceCmdDir:     35,
+	sourceGoreleaser: 25,
+	sourceRootProbe:  25,
+	sourceHomebrew:   10,
+}
+
+const unrecordedSourcePoints = 20
+
+var confidenceCmd = &cobra.Command{
+	Use:   "confidence",
+	Short: "Score how confident the database is in each entry",
+	Long: `Combines each package's discovery provenance, verification results, and
+curation events into a 0-100 confidence score that clients show in 'gomanager
+info'. Installing a package scoring below 50 asks for --low-confidence-ok.
+
+Signals, with the maximum points each contributes:
+  entrypoint  35  root main.go or cmd/ directory 35, goreleaser config or
+                  module root probe 25, Homebrew formula 10, not recorded 20
+  builds      45  current version confirmed 35, an earlier version 25,
+                  unverified 10, failed 0; +10 for two or more confirmed
+                  builds with no failures, -10 if a version both passed
+                  and failed
+  curation    20  approved by a maintainer 20, added before quarantine 10
+
+Scores only use the local database, so every package is re-scored on each
+run.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(confidenceDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+
+		scored, low := 0, 0
+		for _, b := range binaries {
+			prov, err := db.GetProvenance(conn, b.Package)
+			if err != nil {
+				return err
+			}
+			events, err := db.GetEvents(conn, b.Package)
+			if err != nil {
+				return err
+			}
+			history, err := db.GetBuildHistory(conn, b.Package, historyLimit)
+			if err != nil {
+				return err
+			}
+
+			score := confidenceScore(b, prov.Source, events, history)
+			if score < db.LowConfidence && b.BuildStatus != db.StatusQuarantined {
+				fmt.Printf("%-24s %-50s %3d\n", b.Name, b.Package, score)
+				low++
+			}
+			if confidenceDryRun || score == b.Confidence {
+				continue
+			}
+			if err := db.UpdateConfidence(conn, b.ID, score); err != nil {
+				fmt.Printf("Warning: failed to update %s: %v\n", b.Package, err)
+				continue
+			}
+			scored++
+		}
+
+		if confidenceDryRun {
+			fmt.Printf("\nDry run complete. %d published packages score below %d.\n", low, db.LowConfidence)
+		} else {
+			fmt.Printf("\nDone. Updated %d of %d scores; %d published packages score below %d.\n",
+				scored, len(binaries), low, db.LowConfidence)
+		}
+		return nil
+	},
+}
+
+// confidenceScore combines a package's entrypoint source, curation events,
+// and verification history into a 0-100 score. The weights are documented
+// in the confidence command's help.
+func confidenceScore(b db.Binary, source string, events []db.Event, history []db.BuildRecord) int {
+	score := unrecordedSourcePoints
+	if source != "" {
+		score = sourcePoints[source]
+	}
+
+	switch b.BuildStatus {
+	case "confirmed":
+		if b.VersionConfirmed() {
+			score += 35
+		} else {
+			score += 25
+		}
+	case "failed", "regressed":
+	default:
+		score += 10
+	}
+
+	confirmed, failures := 0, 0
+	passed := make(map[string]bool)
+	flaky := false
+	for _, r := range history {
+		if r.Status == "confirmed" {
+			confirmed++
+			passed[r.Version] = true
+		} else {
+			failures++
+		}
+	}
+	for _, r := range history {
+		flaky = flaky || (r.Status != "confirmed" && passed[r.Version])
+	}
+	if confirmed >= 2 && failures == 0 {
+		score += 10
+	} else if flaky {
+		score -= 10
+	}
+
+	approved := false
+	for _, e := range events {
+		approved = approved || e.Event == db.EventApproved
+	}
+	switch {
+	case approved:
+		score += 20
+	case b.BuildStatus != db.StatusQuarantined:
+		score += 10
+	}
+
+	return max(0, min(100, score))
+}
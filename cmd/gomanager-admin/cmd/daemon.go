@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonConfigPath      string
+	daemonDatabase        string
+	daemonHealth          string
+	daemonShutdownTimeout time.Duration
+)
+
+func init() {
+	daemonCmd.Flags().StringVarP(&daemonConfigPath, "config", "c", "", "TOML file configuring the loops, in the ci format (default: built-in defaults)")
+	daemonCmd.Flags().StringVarP(&daemonDatabase, "database", "d", "", "Path to database.db (overrides the config file)")
+	daemonCmd.Flags().StringVar(&daemonHealth, "health", "", "Address of the health endpoint (overrides the config file; default :8081)")
+	daemonCmd.Flags().DurationVar(&daemonShutdownTimeout, "shutdown-timeout", 5*time.Minute, "How long to let a running loop finish after SIGINT or SIGTERM")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// daemonLoop is a gomanager-admin invocation the daemon runs repeatedly.
+type daemonLoop struct {
+	name     string
+	interval time.Duration
+	args     func(c ciConfig) []string
+}
+
+// daemonLoops are the loops the daemon runs, with their default intervals.
+// The update-versions, verify, and prune loops run the ci stages of the
+// same name, verify always in queue mode.
+var daemonLoops = []daemonLoop{
+	{"scan", 24 * time.Hour, func(c ciConfig) []string {
+		return []string{"scan", "-d", c.Database, "--scanned-repos", c.Scan.ScannedRepos}
+	}},
+	{"update-versions", 6 * time.Hour, ciStageArgs("update-versions")},
+	{"verify", time.Hour, func(c ciConfig) []string {
+		// Hourly runs would re-query the whole table; take work from the
+		// queue the other loops fill instead
+		c.Verify.Queue = true
+		return ciStageArgs("verify")(c)
+	}},
+	{"prune", 24 * time.Hour, ciStageArgs("prune")},
+}
+
+// ciStageArgs returns the arguments function of the named ci stage.
+func ciStageArgs(name string) func(c ciConfig) []string {
+	return func(c ciConfig) []string {
+		for _, s := range ciStages {
+			if s.name == name {
+				return s.args(c)
+			}
+		}
+		panic("unknown ci stage " + name)
+	}
+}
+
+// loopStatus is a loop's state as reported by the status endpoint.
+type loopStatus struct {
+	Interval     string    `json:"interval"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+	Running      bool      `json:"running"`
+	LastStart    time.Time `json:"last_start,omitzero"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	Next         time.Time `json:"next"`
+}
+
+// daemonStatus is shared between the scheduler and the status endpoint.
+type daemonStatus struct {
+	mu      sync.Mutex
+	Started time.Time              `json:"started"`
+	Loops   map[string]*loopStatus `json:"loops"`
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the scan, update, verify, and prune loops on a schedule",
+	Long: `Runs the maintenance loops continuously, for maintainers who operate the
+pipeline on a server instead of CI cron:
+
+  scan             discover new repositories (scan), every 24h
+  update-versions  check for new releases (update-versions), every 6h
+  verify           verify packages from the job queue (verify --queue), every 1h
+  prune            prune history and compact the database, every 24h
+
+Loops run one at a time, since they share the database, each when its
+interval has passed since it last started. Settings come from the ci
+config file, with a [daemon] section for the schedule:
+
+  [daemon]
+  health = ":8081"
+  intervals = { verify = "30m", scan = "0" }   # "0" disables a loop
+
+The scan, update-versions, and approve commands add to the job queue; run
+'gomanager-admin queue seed' once to queue packages added before it existed.
+
+GET /healthz on the health address answers "ok" while the daemon runs, and
+GET /status reports each loop's last run, failures, and next run as JSON.
+On SIGINT or SIGTERM no new loop starts; a running loop is interrupted and
+given --shutdown-timeout to finish before it is killed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadCIConfig(daemonConfigPath)
+		if err != nil {
+			return err
+		}
+		if daemonDatabase != "" {
+			cfg.Database = daemonDatabase
+		}
+		if daemonHealth != "" {
+			cfg.Daemon.Health = daemonHealth
+		}
+		loops, err := scheduledLoops(cfg)
+		if err != nil {
+			return err
+		}
+		if len(loops) == 0 {
+			return fmt.Errorf("every loop is disabled")
+		}
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot locate gomanager-admin: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		status := &daemonStatus{Started: time.Now().UTC(), Loops: make(map[string]*loopStatus)}
+		for _, l := range loops {
+			status.Loops[l.name] = &loopStatus{Interval: l.interval.String(), Next: status.Started}
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+		mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+			status.mu.Lock()
+			defer status.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(status)
+		})
+		srv := &http.Server{Addr: cfg.Daemon.Health, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		fmt.Printf("Daemon started; health endpoint on %s\n", cfg.Daemon.Health)
+
+		runErr := runDaemonLoops(ctx, self, cfg, loops, status, errc)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+		if runErr != nil && !errors.Is(runErr, http.ErrServerClosed) {
+			return runErr
+		}
+		fmt.Println("Daemon stopped.")
+		return nil
+	},
+}
+
+// scheduledLoops returns the loops with their configured intervals,
+// leaving out disabled ones.
+func scheduledLoops(cfg ciConfig) ([]daemonLoop, error) {
+	var loops []daemonLoop
+	for name := range cfg.Daemon.Intervals {
+		if !knownDaemonLoop(name) {
+			return nil, fmt.Errorf("unknown loop %q in daemon intervals", name)
+		}
+	}
+	for _, l := range daemonLoops {
+		if s, ok := cfg.Daemon.Intervals[l.name]; ok {
+			if s == "0" {
+				continue
+			}
+			d, err := parseAge(s)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid interval %q for %s", s, l.name)
+			}
+			l.interval = d
+		}
+		loops = append(loops, l)
+	}
+	return loops, nil
+}
+
+func knownDaemonLoop(name string) bool {
+	for _, l := range daemonLoops {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runDaemonLoops runs the due loop, one at a time, until ctx is cancelled
+// or the health server fails.
+func runDaemonLoops(ctx context.Context, self string, cfg ciConfig, loops []daemonLoop, status *daemonStatus, errc <-chan error) error {
+	for {
+		// Pick the loop that has been due longest; ties go to the earlier
+		// loop in daemonLoops order.
+		status.mu.Lock()
+		next := loops[0]
+		for _, l := range loops[1:] {
+			if status.Loops[l.name].Next.Before(status.Loops[next.name].Next) {
+				next = l
+			}
+		}
+		wait := time.Until(status.Loops[next.name].Next)
+		status.mu.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case err := <-errc:
+				timer.Stop()
+				return fmt.Errorf("health endpoint: %w", err)
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		runDaemonLoop(ctx, self, cfg, next, status)
+	}
+}
+
+// runDaemonLoop runs one iteration of l and records the outcome.
+func runDaemonLoop(ctx context.Context, self string, cfg ciConfig, l daemonLoop, status *daemonStatus) {
+	started := time.Now()
+	st := status.Loops[l.name]
+	status.mu.Lock()
+	st.Running = true
+	st.LastStart = started.UTC()
+	st.Next = started.Add(l.interval).UTC()
+	status.mu.Unlock()
+
+	loopArgs := append([]string{"--github-api", githubAPI}, l.args(cfg)...)
+	fmt.Printf("\n=== %s %s: gomanager-admin %s\n", started.Format(time.RFC3339), l.name, strings.Join(loopArgs[2:], " "))
+	run := exec.Command(self, loopArgs...)
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	err := run.Start()
+	if err == nil {
+		done := make(chan error, 1)
+		go func() { done <- run.Wait() }()
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			fmt.Printf("=== %s: interrupting, waiting up to %s\n", l.name, daemonShutdownTimeout)
+			run.Process.Signal(os.Interrupt)
+			select {
+			case err = <-done:
+			case <-time.After(daemonShutdownTimeout):
+				run.Process.Kill()
+				err = <-done
+			}
+		}
+	}
+
+	elapsed := time.Since(started).Round(time.Second)
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	st.Running = false
+	st.Runs++
+	st.LastDuration = elapsed.String()
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+		fmt.Printf("=== %s: failed after %s: %v; next run %s\n", l.name, elapsed, err, st.Next.Local().Format(time.RFC3339))
+		return
+	}
+	fmt.Printf("=== %s: done in %s; next run %s\n", l.name, elapsed, st.Next.Local().Format(time.RFC3339))
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbDatabase    string
+	dbSplitErrors string
+	dbSlimOutput  string
+)
+
+func init() {
+	dbCmd.PersistentFlags().StringVarP(&dbDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	dbOptimizeCmd.Flags().StringVar(&dbSplitErrors, "split-errors", "", "Move build errors and history into this SQLite file instead of keeping them in the database")
+	dbSlimCmd.Flags().StringVarP(&dbSlimOutput, "output", "o", "./database-slim.db", "Path to write the slim database to (replaced if it exists)")
+	dbCmd.AddCommand(dbOptimizeCmd)
+	dbCmd.AddCommand(dbSlimCmd)
+	dbCmd.AddCommand(dbCheckCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance commands",
+}
+
+var dbOptimizeCmd = &cobra.Command{
+	Use:   "optimize",
+	Short: "Shrink the database before publishing it",
+	Long: `Prepares the database for publishing: removes build history for packages
+that are no longer tracked, refreshes query planner statistics (ANALYZE), and
+rebuilds the file to reclaim free pages (VACUUM).
+
+With --split-errors, build errors and build history are moved into a separate
+SQLite file, keeping the bulkiest text out of the database clients download.
+Repeated runs merge into the same file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, path, err := openDBCommand()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+		before := fileSize(path)
+
+		pruned, err := db.PruneHistory(conn)
+		if err != nil {
+			return fmt.Errorf("prune history: %w", err)
+		}
+		fmt.Printf("Removed %d orphaned history rows\n", pruned)
+
+		prunedJobs, err := db.PruneJobs(conn)
+		if err != nil {
+			return fmt.Errorf("prune jobs: %w", err)
+		}
+		fmt.Printf("Removed %d orphaned queue jobs\n", prunedJobs)
+
+		if dbSplitErrors != "" {
+			moved, err := db.SplitErrors(conn, dbSplitErrors)
+			if err != nil {
+				return fmt.Errorf("split errors: %w", err)
+			}
+			fmt.Printf("Moved %d build errors and the build history to %s\n", moved, dbSplitErrors)
+		}
+
+		if err := db.Compact(conn); err != nil {
+			return err
+		}
+
+		after := fileSize(path)
+		fmt.Printf("Size: %s → %s\n", formatBytes(before), formatBytes(after))
+		return nil
+	},
+}
+
+var dbSlimCmd = &cobra.Command{
+	Use:   "slim",
+	Short: "Write the slim client database from the full database",
+	Long: `Writes a copy of the full admin database for clients to download with
+'gomanager update-db'. The copy drops quarantined packages, build errors,
+build history, and scan bookkeeping (owner account age, scorecard, and
+funding signals), and is marked as slim so admin commands refuse to run
+against it.
+
+The conversion is one-way: the full database remains the source of truth.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, _, err := openDBCommand()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		// VACUUM INTO refuses to overwrite an existing file
+		if err := os.Remove(dbSlimOutput); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot replace %s: %w", dbSlimOutput, err)
+		}
+		if err := db.WriteSlim(conn, dbSlimOutput); err != nil {
+			return fmt.Errorf("write slim database: %w", err)
+		}
+		fmt.Printf("Wrote %s (%s)\n", dbSlimOutput, formatBytes(fileSize(dbSlimOutput)))
+		return nil
+	},
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the database is fit to publish",
+	Long: `Runs SQLite's integrity check and sanity checks on the data (the binaries
+table is not empty, some binaries are confirmed, and every build status is
+known). Exits non-zero if any problem is found.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, _, err := openDBCommand()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		problems, err := db.Check(conn)
+		if err != nil {
+			return err
+		}
+		for _, p := range problems {
+			fmt.Printf("✗ %s\n", p)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("database check found %d problems", len(problems))
+		}
+		fmt.Println("✓ database OK")
+		return nil
+	},
+}
+
+// openDBCommand opens the database named by the db command's --database
+// flag, or the default database. It also returns the resolved path.
+func openDBCommand() (*sql.DB, string, error) {
+	path := dbDatabase
+	if path == "" {
+		var err error
+		if path, err = db.DBPath(); err != nil {
+			return nil, "", err
+		}
+	}
+	conn, err := openAdminDB(path)
+	return conn, path, err
+}
+
+// fileSize returns the size of the file at path, or 0 if it can't be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "3.2 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
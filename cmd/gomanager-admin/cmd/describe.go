@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	describeBatchSize int
+	describeDatabase  string
+	describeRefresh   bool
+)
+
+func init() {
+	describeCmd.Flags().IntVarP(&describeBatchSize, "batch-size", "n", 100, "Max repositories to describe")
+	describeCmd.Flags().StringVarP(&describeDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	describeCmd.Flags().BoolVar(&describeRefresh, "refresh", false, "Re-describe repositories that already have a description")
+	rootCmd.AddCommand(describeCmd)
+}
+
+// maxLongDescription caps the README summary stored for a package, in runes.
+const maxLongDescription = 500
+
+// errNoReadme is returned by readme for repositories without one.
+var errNoReadme = errors.New("no README")
+
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Record project homepages, README summaries, tags, and licenses",
+	Long: `Fetches each repository's homepage (the website set in its GitHub
+metadata) and the first paragraph of its README, which 'gomanager info'
+shows alongside the one-line description, and its GitHub topics, which
+are recorded as the package's tags for 'gomanager search --tag'. The
+SPDX identifier of the repository's license is recorded too, if GitHub
+could detect it.
+
+scan records all of them for the packages it adds. By default only packages
+without them are processed, e.g. those added before scan recorded them;
+use --refresh to update the rest as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(describeDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		var pkgs []string
+		if describeRefresh {
+			binaries, err := db.ListAll(conn)
+			if err != nil {
+				return fmt.Errorf("failed to load packages: %w", err)
+			}
+			for _, b := range binaries {
+				pkgs = append(pkgs, b.Package)
+			}
+		} else if pkgs, err = db.GetUndescribed(conn); err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+
+		// Group packages by owner/repo to avoid duplicate API calls
+		repoPkgs := make(map[string][]string)
+		var repoOrder []string
+		for _, pkg := range pkgs {
+			owner, repo, ok := parseGitHubOwnerRepo(pkg)
+			if !ok {
+				continue
+			}
+			key := owner + "/" + repo
+			if _, exists := repoPkgs[key]; !exists {
+				repoOrder = append(repoOrder, key)
+			}
+			repoPkgs[key] = append(repoPkgs[key], pkg)
+		}
+
+		limit := min(describeBatchSize, len(repoOrder))
+		fmt.Printf("Describing %d/%d repositories...\n\n", limit, len(repoOrder))
+
+		s := &scanner{
+			ctx:    context.Background(),
+			client: &http.Client{Timeout: 15 * time.Second},
+			token:  os.Getenv("GITHUB_TOKEN"),
+		}
+		described, failed := 0, 0
+
+		for i, key := range repoOrder[:limit] {
+			owner, repo, _ := strings.Cut(key, "/")
+			info, err := s.projectInfo(owner, repo)
+			if err != nil {
+				fmt.Printf("[%d/%d] %s: %v\n", i+1, limit, key, err)
+				failed++
+				continue
+			}
+			for _, pkg := range repoPkgs[key] {
+				if err := db.SetProjectInfo(conn, pkg, info.homepage, info.summary); err != nil {
+					fmt.Printf("  Warning: failed to update %s: %v\n", pkg, err)
+				}
+				if err := db.SetTags(conn, pkg, info.topics); err != nil {
+					fmt.Printf("  Warning: failed to record tags for %s: %v\n", pkg, err)
+				}
+				if info.license != "" {
+					if err := db.SetLicense(conn, pkg, info.license); err != nil {
+						fmt.Printf("  Warning: failed to record license for %s: %v\n", pkg, err)
+					}
+				}
+			}
+			summary := info.summary
+			if summary == "" {
+				summary = "(no README summary)"
+			}
+			fmt.Printf("[%d/%d] %s: %s\n", i+1, limit, key, truncate(summary, 60))
+			described++
+		}
+
+		fmt.Printf("\nDone. Described %d repos, %d failed.\n", described, failed)
+		return nil
+	},
+}
+
+// projectDetails is what describe records about a repository.
+type projectDetails struct {
+	homepage string
+	// summary is the first paragraph of the README, or empty.
+	summary string
+	topics  []string
+	// license is the SPDX identifier of the license, or empty if GitHub
+	// couldn't identify one.
+	license string
+}
+
+// projectInfo returns the details describe records about owner/repo. A
+// missing README gives an empty summary rather than an error.
+func (s *scanner) projectInfo(owner, repo string) (*projectDetails, error) {
+	resp, err := s.apiGet(fmt.Sprintf(githubAPI+"/repos/%s/%s", owner, repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var info githubRepo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	text, err := s.readme(owner, repo)
+	if err != nil && !errors.Is(err, errNoReadme) {
+		return nil, fmt.Errorf("README: %w", err)
+	}
+	return &projectDetails{
+		homepage: strings.TrimSpace(info.Homepage),
+		summary:  readmeSummary(text),
+		topics:   info.Topics,
+		license:  info.spdxID(),
+	}, nil
+}
+
+// readme fetches the raw contents of the repository's README, whatever its
+// filename. It returns errNoReadme if the repository has none.
+func (s *scanner) readme(owner, repo string) (string, error) {
+	resp, err := s.apiGetAccept(fmt.Sprintf(githubAPI+"/repos/%s/%s/readme", owner, repo), "application/vnd.github.v3.raw")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case 200:
+	case 404:
+		return "", errNoReadme
+	default:
+		io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return string(data), err
+}
+
+var (
+	mdImage    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdLinkDef  = regexp.MustCompile(`^\[[^\]]+\]:\s`)
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	mdListItem = regexp.MustCompile(`^([-*+]|\d+[.)])\s`)
+	underline  = regexp.MustCompile(`^(=+|-+|~+|\^+)$`)
+)
+
+// readmeSummary returns the first prose paragraph of a Markdown or
+// reStructuredText README as plain text, capped at maxLongDescription
+// runes. Headings, badges, images, HTML, code blocks, lists, and tables
+// are skipped.
+func readmeSummary(text string) string {
+	var para []string
+	fence := ""
+	inComment := false
+
+	// flush returns the paragraph collected so far as plain text, or ""
+	// if it has no prose, and starts a new one.
+	flush := func() string {
+		s := strings.Join(para, " ")
+		para = nil
+		s = mdImage.ReplaceAllString(s, "")
+		s = mdLink.ReplaceAllString(s, "$1")
+		s = htmlTag.ReplaceAllString(s, "")
+		s = strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+		s = strings.Join(strings.Fields(s), " ")
+		if len(strings.Fields(s)) < 3 {
+			return ""
+		}
+		return s
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(line, fence) {
+				fence = ""
+			}
+			continue
+		case inComment:
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case strings.HasPrefix(line, "```"), strings.HasPrefix(line, "~~~"):
+			fence = line[:3]
+			continue
+		case strings.HasPrefix(line, "<!--"):
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case underline.MatchString(line) && len(para) > 0:
+			// A setext or reStructuredText heading underline: the
+			// paragraph so far was the heading.
+			para = nil
+			continue
+		}
+
+		if line == "" {
+			if s := flush(); s != "" {
+				return clipSummary(s)
+			}
+			continue
+		}
+		if len(para) == 0 && skipLine(line) {
+			continue
+		}
+		para = append(para, line)
+	}
+	return clipSummary(flush())
+}
+
+// skipLine reports whether a line starting a paragraph isn't prose: a
+// heading, badge or image, HTML, table, list item, quote, link definition,
+// or reStructuredText directive.
+func skipLine(line string) bool {
+	for _, prefix := range []string{"#", "![", "[![", "<", "|", ">", ".. ", "==", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	if mdListItem.MatchString(line) || mdLinkDef.MatchString(line) {
+		return true
+	}
+	// A line of only badges and links
+	return strings.TrimSpace(mdLink.ReplaceAllString(mdImage.ReplaceAllString(line, ""), "")) == ""
+}
+
+// clipSummary truncates s to maxLongDescription runes at a word boundary.
+func clipSummary(s string) string {
+	if utf8.RuneCountInString(s) <= maxLongDescription {
+		return s
+	}
+	s = string([]rune(s)[:maxLongDescription-1])
+	if i := strings.LastIndex(s, " "); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, " ,;:") + "…"
+}
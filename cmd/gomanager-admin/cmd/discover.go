@@ -1,15 +1,24 @@
 package cmd
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/dirs"
 	"github.com/jmelahman/gomanager/internal/pkgbuild"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +29,13 @@ var (
 	discoverNvchecker string
 	discoverLimit     int
 	discoverMaxAge    int
+	discoverVerifyAge int
+	discoverProgress  string
+	discoverLookupTTL string
+	discoverMirror    string
+	discoverAUR       string
+	discoverDistros   []string
+	discoverFormat    string
 )
 
 func init() {
@@ -28,6 +44,13 @@ func init() {
 	discoverCmd.Flags().StringVar(&discoverNvchecker, "nvchecker", "", "Path to nvchecker.toml to append entries to")
 	discoverCmd.Flags().IntVarP(&discoverLimit, "limit", "n", 0, "Maximum number of candidates to output (0 = all)")
 	discoverCmd.Flags().IntVar(&discoverMaxAge, "max-age", 3, "Skip repos with no activity in this many years (0 = no filter)")
+	discoverCmd.Flags().IntVar(&discoverVerifyAge, "max-verify-age", 0, "Skip packages last verified more than this many days ago (0 = no limit)")
+	discoverCmd.Flags().StringVar(&discoverLookupTTL, "lookup-ttl", "7d", "Reuse AUR and official repo lookups made within this long (e.g. 7d, 12h; 0 = always re-check)")
+	discoverCmd.Flags().StringVar(&discoverAUR, "aur-url", "https://aur.archlinux.org", "AUR instance to look packages up in")
+	discoverCmd.Flags().StringVar(&discoverMirror, "arch-mirror", "https://geo.mirror.pkgbuild.com", "Arch Linux mirror to download the official repo package lists from")
+	discoverCmd.Flags().StringSliceVar(&discoverDistros, "distros", nil, "Also check which of these distributions package each candidate: brew, nix, debian")
+	discoverCmd.Flags().StringVar(&discoverFormat, "format", discoverFormatText, "Output format: text, json, csv, or markdown")
+	discoverCmd.Flags().StringVar(&discoverProgress, "progress-file", "", "File saving lookup progress for resuming an interrupted run (default: in the user cache directory)")
 	rootCmd.AddCommand(discoverCmd)
 }
 
@@ -39,21 +62,31 @@ type aurInfoResponse struct {
 	} `json:"results"`
 }
 
-// archPkgResponse represents the official Arch package search response.
-type archPkgResponse struct {
-	Results []struct {
-		PkgName string `json:"pkgname"`
-	} `json:"results"`
+// uncheckedNames returns the names that have no entry in checked.
+func uncheckedNames(names []string, checked map[string]bool) []string {
+	var pending []string
+	for _, n := range names {
+		if _, ok := checked[n]; !ok {
+			pending = append(pending, n)
+		}
+	}
+	return pending
 }
 
-// batchCheckAUR checks multiple package names against the AUR in one request.
-// Returns a set of names that exist in the AUR.
-func batchCheckAUR(client *http.Client, names []string) map[string]bool {
-	exists := make(map[string]bool)
+// batchCheckAUR checks multiple package names against the AUR at aurURL in
+// one request, recording in checked whether each exists. Names already in
+// checked are skipped, and failed lookups are left out so a later run
+// retries them. Each batch's results are passed to record as they arrive.
+// It stops early if ctx is cancelled.
+func batchCheckAUR(ctx context.Context, client *http.Client, aurURL string, names []string, checked map[string]bool, record func(map[string]bool)) {
+	names = uncheckedNames(names, checked)
 
 	// AUR info endpoint supports batching with arg[]=name1&arg[]=name2...
 	// Process in batches of 100 to avoid URL length limits
 	for i := 0; i < len(names); i += 100 {
+		if ctx.Err() != nil {
+			return
+		}
 		end := i + 100
 		if end > len(names) {
 			end = len(names)
@@ -64,7 +97,7 @@ func batchCheckAUR(client *http.Client, names []string) map[string]bool {
 		for _, n := range batch {
 			params = append(params, "arg[]="+n)
 		}
-		url := "https://aur.archlinux.org/rpc/v5/info?" + strings.Join(params, "&")
+		url := strings.TrimSuffix(aurURL, "/") + "/rpc/v5/info?" + strings.Join(params, "&")
 
 		resp, err := client.Get(url)
 		if err != nil {
@@ -79,77 +112,188 @@ func batchCheckAUR(client *http.Client, names []string) map[string]bool {
 			continue
 		}
 
+		results := make(map[string]bool, len(batch))
+		for _, n := range batch {
+			results[n] = false
+		}
 		for _, r := range result.Results {
-			exists[strings.ToLower(r.Name)] = true
+			results[strings.ToLower(r.Name)] = true
 		}
+		maps.Copy(checked, results)
+		record(results)
 
 		// Be polite to AUR API
 		if end < len(names) {
 			time.Sleep(500 * time.Millisecond)
 		}
 	}
-
-	return exists
 }
 
-// checkOfficialRepos checks multiple package names against the official Arch repos.
-// Returns a set of names that exist in official repos.
-func checkOfficialRepos(client *http.Client, names []string) map[string]bool {
-	exists := make(map[string]bool)
-
-	// Official repos API supports exact name match; batch by checking multiple
-	// names per request using repeated &name= params doesn't work, so we check
-	// in batches using q= search and then filter exact matches.
-	// Actually the API supports name=exact, so we check one at a time but can
-	// be smart about it by only checking unique names.
-	// To avoid excessive requests, batch names into groups and use q= search.
-	for i := 0; i < len(names); i += 50 {
-		end := i + 50
-		if end > len(names) {
-			end = len(names)
+// officialRepos are the Arch Linux repositories checked for existing
+// packages.
+var officialRepos = []string{"core", "extra", "multilib"}
+
+// checkOfficialRepos checks package names against the official Arch repos,
+// recording in checked whether each exists. Rather than querying names one
+// at a time, it downloads each repository's package database from mirror
+// once, so the check takes one request per repository. Names already in
+// checked are skipped; if a repository can't be fetched nothing is recorded,
+// so a later run retries.
+func checkOfficialRepos(ctx context.Context, client *http.Client, mirror string, names []string, checked map[string]bool, record func(map[string]bool)) {
+	names = uncheckedNames(names, checked)
+	if len(names) == 0 {
+		return
+	}
+
+	packages := make(map[string]bool)
+	for _, repo := range officialRepos {
+		if ctx.Err() != nil {
+			return
 		}
-		batch := names[i:end]
+		url := fmt.Sprintf("%s/%s/os/x86_64/%s.db", strings.TrimSuffix(mirror, "/"), repo, repo)
+		n, err := fetchRepoPackages(ctx, client, url, packages)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: official repo lookup failed: %v\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %d packages\n", repo, n)
+	}
 
-		for _, name := range batch {
-			url := fmt.Sprintf("https://archlinux.org/packages/search/json/?name=%s", name)
-			resp, err := client.Get(url)
-			if err != nil {
-				continue
-			}
+	results := make(map[string]bool, len(names))
+	for _, name := range names {
+		results[name] = packages[name]
+	}
+	maps.Copy(checked, results)
+	record(results)
+}
 
-			var result archPkgResponse
-			err = json.NewDecoder(resp.Body).Decode(&result)
-			resp.Body.Close()
-			if err != nil {
-				continue
-			}
+// fetchRepoPackages downloads the pacman sync database at url (a gzipped tar
+// of <name>-<version>/desc entries) and adds the lowercased name of each
+// package in it to packages, returning how many it holds.
+func fetchRepoPackages(ctx context.Context, client *http.Client, url string, packages map[string]bool) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
 
-			for _, r := range result.Results {
-				if strings.EqualFold(r.PkgName, name) {
-					exists[strings.ToLower(name)] = true
-				}
-			}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", url, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	count := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", url, err)
+		}
+		if path.Base(hdr.Name) != "desc" {
+			continue
+		}
+		desc, err := io.ReadAll(tr)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", url, err)
+		}
+		if name := descField(string(desc), "NAME"); name != "" {
+			packages[strings.ToLower(name)] = true
+			count++
 		}
-
-		fmt.Fprintf(os.Stderr, "Checked official repos: %d/%d\n", end, len(names))
-		// Rate limit: the Arch API can be slow, be polite
-		time.Sleep(1 * time.Second)
 	}
+	return count, nil
+}
 
-	return exists
+// descField returns the first value of a %FIELD% section in a pacman desc
+// file.
+func descField(desc, field string) string {
+	_, rest, ok := strings.Cut(desc, "%"+field+"%\n")
+	if !ok {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, "\n")
+	return strings.TrimSpace(value)
 }
 
 // repoStatus holds freshness metadata for a GitHub repository.
 type repoStatus struct {
-	Archived bool
-	PushedAt time.Time
+	Archived bool      `json:"archived"`
+	PushedAt time.Time `json:"pushed_at"`
+}
+
+// discoverProgressTTL is how long saved GitHub lookups are reused by a
+// later run.
+const discoverProgressTTL = 24 * time.Hour
+
+// discoverState is the GitHub lookup work of a discover run, saved as it
+// goes so an interrupted run can be resumed without repeating it. (Package
+// repository lookups are cached in the database instead.)
+type discoverState struct {
+	Started time.Time `json:"started"`
+	// Repos maps owner/repo to its GitHub status. Failed lookups aren't
+	// recorded.
+	Repos map[string]*repoStatus `json:"repos"`
+}
+
+// defaultDiscoverProgress returns where discover saves its progress by
+// default.
+func defaultDiscoverProgress() (string, error) {
+	return dirs.CacheFile("discover-progress.json")
+}
+
+// loadDiscoverState reads the progress saved at path, or returns a fresh
+// state if there is none or it is older than discoverProgressTTL.
+func loadDiscoverState(path string) *discoverState {
+	fresh := &discoverState{
+		Started: time.Now().UTC(),
+		Repos:   make(map[string]*repoStatus),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fresh
+	}
+	var st discoverState
+	if err := json.Unmarshal(data, &st); err != nil || time.Since(st.Started) > discoverProgressTTL {
+		return fresh
+	}
+	if st.Repos == nil {
+		st.Repos = make(map[string]*repoStatus)
+	}
+	fmt.Fprintf(os.Stderr, "Resuming from %s (%d GitHub lookups saved)\n", path, len(st.Repos))
+	return &st
+}
+
+// save writes the state to path, replacing it atomically.
+func (st *discoverState) save(path string) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
 // fetchRepoStatus fetches repo metadata from the GitHub API to check if
 // the repo is archived or stale. Returns nil on API failure (caller should
 // keep the candidate in that case).
 func fetchRepoStatus(client *http.Client, owner, repo, token string) *repoStatus {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s", owner, repo)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s", owner, repo)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil
@@ -191,14 +335,59 @@ a star threshold, then checks the AUR and official Arch Linux repositories
 to find packages that don't have Arch packages yet.
 
 Optionally generates PKGBUILDs and nvchecker.toml entries for the discovered
-candidates.`,
+candidates.
+
+With --distros, candidates are also looked up in Homebrew (formulae.brew.sh),
+nixpkgs (search.nixos.org), and Debian (sources.debian.org) under their
+binary and repository names. The output becomes a matrix of where each tool
+is already packaged, listing tools packaged nowhere first.
+
+--format json, csv, or markdown writes the candidates as a structured report
+instead (name, package, version, stars, last push, license, and the
+distributions already packaging them) for dashboards and other tooling.
+Progress is logged to stderr, so stdout holds only the report.
+
+AUR and official repository lookups are cached in the database's
+distro_lookups table for --lookup-ttl, so reruns only check names that are
+new or whose result has expired. GitHub lookups are saved to
+--progress-file as they complete. On SIGINT or SIGTERM discover stops and
+keeps the file, and a run within 24 hours reuses the saved lookups instead
+of repeating them. The file is removed once a run gets through every
+lookup.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		conn, err := db.Open()
+		lookupTTL := time.Duration(0)
+		if discoverLookupTTL != "0" {
+			var err error
+			if lookupTTL, err = parseAge(discoverLookupTTL); err != nil {
+				return fmt.Errorf("invalid --lookup-ttl: %w", err)
+			}
+		}
+
+		switch discoverFormat {
+		case discoverFormatText, discoverFormatJSON, discoverFormatCSV, discoverFormatMarkdown:
+		default:
+			return fmt.Errorf("unknown format %q (want text, json, csv, or markdown)", discoverFormat)
+		}
+
+		var checks []distroCheck
+		for _, name := range discoverDistros {
+			d, ok := findDistroCheck(name)
+			if !ok {
+				return fmt.Errorf("unknown distribution %q in --distros (want brew, nix, or debian)", name)
+			}
+			checks = append(checks, d)
+		}
+
+		conn, err := openAdminDB("")
 		if err != nil {
 			return err
 		}
 		defer conn.Close()
 
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
 		// Query candidates: confirmed builds, primary, with a real version, above star threshold
 		binaries, err := db.ListAll(conn)
 		if err != nil {
@@ -219,6 +408,9 @@ candidates.`,
 			if b.Stars < discoverMinStars {
 				continue
 			}
+			if checkVerifiedAge(&b, discoverVerifyAge) != nil {
+				continue
+			}
 			candidates = append(candidates, b)
 		}
 
@@ -258,19 +450,50 @@ candidates.`,
 		}
 
 		// Deduplicate into a flat list for batch lookups
-		var names []string
-		for v := range allVariants {
-			names = append(names, v)
-		}
+		names := slices.Sorted(maps.Keys(allVariants))
 
 		client := &http.Client{Timeout: 15 * time.Second}
 
+		progressPath := discoverProgress
+		if progressPath == "" {
+			if progressPath, err = defaultDiscoverProgress(); err != nil {
+				return err
+			}
+		}
+		state := loadDiscoverState(progressPath)
+		save := func() {
+			if err := state.save(progressPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save progress: %v\n", err)
+			}
+		}
+		ctx, stop := notifyInterrupt()
+		defer stop()
+		interrupted := func() error {
+			save()
+			fmt.Fprintf(os.Stderr, "\nInterrupted. Lookups so far were saved to the database and %s.\n", progressPath)
+			return interruptedError("run discover again within 24 hours to resume")
+		}
+
 		// Check AUR (fast, batched)
-		fmt.Fprintf(os.Stderr, "Checking AUR for %d name variants...\n", len(names))
-		aurExists := batchCheckAUR(client, names)
+		aurChecked, err := cachedLookups(conn, db.LookupAUR, lookupTTL)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Checking AUR for %d name variants (%d cached)...\n",
+			len(names), len(names)-len(uncheckedNames(names, aurChecked)))
+		batchCheckAUR(ctx, client, discoverAUR, names, aurChecked, recordLookups(conn, db.LookupAUR))
+		if ctx.Err() != nil {
+			return interrupted()
+		}
+		aurExists := make(map[string]bool)
+		for _, n := range names {
+			if aurChecked[n] {
+				aurExists[n] = true
+			}
+		}
 		fmt.Fprintf(os.Stderr, "  Found %d in AUR\n", len(aurExists))
 
-		// Check official repos (slower, one-by-one)
+		// Check official repos
 		// Only check names not already found in AUR
 		var toCheckOfficial []string
 		for _, n := range names {
@@ -279,8 +502,25 @@ candidates.`,
 			}
 		}
 
-		fmt.Fprintf(os.Stderr, "Checking official repos for %d names...\n", len(toCheckOfficial))
-		officialExists := checkOfficialRepos(client, toCheckOfficial)
+		officialChecked, err := cachedLookups(conn, db.LookupArch, lookupTTL)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Checking official repos for %d names (%d cached)...\n",
+			len(toCheckOfficial), len(toCheckOfficial)-len(uncheckedNames(toCheckOfficial, officialChecked)))
+		// The package databases are several megabytes, so allow longer than
+		// for API requests
+		repoClient := &http.Client{Timeout: 5 * time.Minute}
+		checkOfficialRepos(ctx, repoClient, discoverMirror, toCheckOfficial, officialChecked, recordLookups(conn, db.LookupArch))
+		if ctx.Err() != nil {
+			return interrupted()
+		}
+		officialExists := make(map[string]bool)
+		for _, n := range toCheckOfficial {
+			if officialChecked[n] {
+				officialExists[n] = true
+			}
+		}
 		fmt.Fprintf(os.Stderr, "  Found %d in official repos\n", len(officialExists))
 
 		// Filter to packages where none of the variants exist in AUR or official repos
@@ -310,23 +550,33 @@ candidates.`,
 				cutoff.Format("2006-01-02"))
 
 			// Group by owner/repo to avoid duplicate API calls for packages
-			// from the same repository
-			type repoKey struct{ owner, repo string }
-			repoCache := make(map[repoKey]*repoStatus)
+			// from the same repository; state.Repos keeps the successful
+			// lookups across runs
+			failedRepos := make(map[string]bool)
 			archived, stale := 0, 0
 
 			for i, b := range afterArch {
+				if ctx.Err() != nil {
+					return interrupted()
+				}
 				owner, repo, ok := parseGitHubOwnerRepo(b.Package)
 				if !ok {
 					available = append(available, b)
 					continue
 				}
 
-				key := repoKey{owner, repo}
-				status, cached := repoCache[key]
-				if !cached {
+				key := owner + "/" + repo
+				status, cached := state.Repos[key]
+				if !cached && !failedRepos[key] {
 					status = fetchRepoStatus(client, owner, repo, token)
-					repoCache[key] = status
+					if status == nil {
+						failedRepos[key] = true
+					} else {
+						state.Repos[key] = status
+						if len(state.Repos)%50 == 0 {
+							save()
+						}
+					}
 					// Rate limit GitHub API
 					time.Sleep(100 * time.Millisecond)
 				}
@@ -337,6 +587,13 @@ candidates.`,
 					continue
 				}
 
+				// Persist the archive state so clients can warn users
+				if status.Archived != b.Archived {
+					if err := db.SetArchived(conn, b.ID, status.Archived); err != nil {
+						fmt.Fprintf(os.Stderr, "  Warning: failed to record archive state for %s: %v\n", b.Name, err)
+					}
+				}
+
 				if status.Archived {
 					archived++
 					continue
@@ -359,6 +616,47 @@ candidates.`,
 			available = afterArch
 		}
 
+		// Every lookup is done; the next run starts afresh
+		if err := os.Remove(progressPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", progressPath, err)
+		}
+
+		// Check other distributions, putting tools packaged nowhere first
+		matrix := make(distroMatrix)
+		for _, d := range checks {
+			var lookupNames []string
+			for _, b := range available {
+				lookupNames = append(lookupNames, distroNames(b)...)
+			}
+			checked, err := cachedLookups(conn, d.name, lookupTTL)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Checking %s for %d names (%d cached)...\n",
+				d.label, len(lookupNames), len(lookupNames)-len(uncheckedNames(lookupNames, checked)))
+			d.lookup(ctx, repoClient, lookupNames, checked, recordLookups(conn, d.name))
+			if ctx.Err() != nil {
+				return interrupted()
+			}
+			found := 0
+			for _, b := range available {
+				for _, n := range distroNames(b) {
+					if checked[n] {
+						if matrix[b.ID] == nil {
+							matrix[b.ID] = make(map[string]bool)
+						}
+						matrix[b.ID][d.name] = true
+						found++
+						break
+					}
+				}
+			}
+			fmt.Fprintf(os.Stderr, "  %d packaged in %s\n", found, d.label)
+		}
+		if len(checks) > 0 {
+			matrix.sortByPackaging(available)
+		}
+
 		if discoverLimit > 0 && len(available) > discoverLimit {
 			available = available[:discoverLimit]
 		}
@@ -366,8 +664,18 @@ candidates.`,
 		fmt.Fprintf(os.Stderr, "\n%d packages not yet in Arch Linux:\n\n", len(available))
 
 		// Print results
-		for _, b := range available {
-			fmt.Printf("%-30s %6d stars  %s\n", b.Name, b.Stars, b.Package)
+		switch {
+		case discoverFormat != discoverFormatText:
+			rows := discoverCandidates(available, state.Repos, checks, matrix)
+			if err := writeDiscoverReport(os.Stdout, discoverFormat, rows); err != nil {
+				return err
+			}
+		case len(checks) > 0:
+			printDistroMatrix(available, checks, matrix)
+		default:
+			for _, b := range available {
+				fmt.Printf("%-30s %6d stars  %s\n", b.Name, b.Stars, b.Package)
+			}
 		}
 
 		// Generate PKGBUILDs if requested
@@ -434,6 +742,29 @@ candidates.`,
 	},
 }
 
+// cachedLookups returns the lookups from source made within ttl, as a map
+// the lookup functions add to. A zero ttl ignores the cache.
+func cachedLookups(conn *sql.DB, source string, ttl time.Duration) (map[string]bool, error) {
+	if ttl == 0 {
+		return make(map[string]bool), nil
+	}
+	lookups, err := db.GetLookups(conn, source, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("read cached %s lookups: %w", source, err)
+	}
+	return lookups, nil
+}
+
+// recordLookups returns a function caching lookup results from source,
+// warning rather than failing the run if they can't be written.
+func recordLookups(conn *sql.DB, source string) func(map[string]bool) {
+	return func(results map[string]bool) {
+		if err := db.RecordLookups(conn, source, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to cache %s lookups: %v\n", source, err)
+		}
+	}
+}
+
 // detectRepoFilesWithToken fetches repo file listing at the tagged version.
 func detectRepoFilesWithToken(b *db.Binary, token string) *pkgbuild.Options {
 	owner, repo, ok := parseGitHubOwnerRepo(b.Package)
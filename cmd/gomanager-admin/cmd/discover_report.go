@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// Discover report formats.
+const (
+	discoverFormatText     = "text"
+	discoverFormatJSON     = "json"
+	discoverFormatCSV      = "csv"
+	discoverFormatMarkdown = "markdown"
+)
+
+// discoverCandidate is a row of the structured discover report.
+type discoverCandidate struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Stars   int    `json:"stars"`
+	// LastPush is when the repository was last pushed to, if discover
+	// looked it up (i.e. without --max-age 0).
+	LastPush time.Time `json:"last_push,omitzero"`
+	License  string    `json:"license,omitempty"`
+	// PackagedIn lists the --distros that already package the tool.
+	PackagedIn []string `json:"packaged_in"`
+}
+
+// discoverCandidates builds the report rows for binaries from the GitHub
+// lookups in repos and the distribution matrix.
+func discoverCandidates(binaries []db.Binary, repos map[string]*repoStatus, checks []distroCheck, matrix distroMatrix) []discoverCandidate {
+	rows := make([]discoverCandidate, len(binaries))
+	for i, b := range binaries {
+		c := discoverCandidate{
+			Name: b.Name, Package: b.Package, Version: b.Version, Stars: b.Stars,
+			License: b.License, PackagedIn: []string{},
+		}
+		if owner, repo, ok := parseGitHubOwnerRepo(b.Package); ok {
+			if st := repos[owner+"/"+repo]; st != nil {
+				c.LastPush = st.PushedAt
+			}
+		}
+		for _, d := range checks {
+			if matrix[b.ID][d.name] {
+				c.PackagedIn = append(c.PackagedIn, d.name)
+			}
+		}
+		rows[i] = c
+	}
+	return rows
+}
+
+// writeDiscoverReport writes the candidates to w in format (json, csv, or
+// markdown).
+func writeDiscoverReport(w io.Writer, format string, rows []discoverCandidate) error {
+	header := []string{"name", "package", "version", "stars", "last_push", "license", "packaged_in"}
+	cells := func(c discoverCandidate) []string {
+		lastPush := ""
+		if !c.LastPush.IsZero() {
+			lastPush = c.LastPush.UTC().Format("2006-01-02")
+		}
+		return []string{c.Name, c.Package, c.Version, strconv.Itoa(c.Stars), lastPush, c.License, strings.Join(c.PackagedIn, " ")}
+	}
+
+	switch format {
+	case discoverFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case discoverFormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, c := range rows {
+			cw.Write(cells(c))
+		}
+		cw.Flush()
+		return cw.Error()
+	case discoverFormatMarkdown:
+		fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(header)))
+		for _, c := range rows {
+			row := cells(c)
+			for i, cell := range row {
+				row[i] = strings.ReplaceAll(cell, "|", `\|`)
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
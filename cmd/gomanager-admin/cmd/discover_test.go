@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/githubtest"
+)
+
+// fakeArch serves the AUR RPC info endpoint, knowing the packages in aur,
+// and an Arch mirror whose extra repository holds the packages in extra.
+func fakeArch(t *testing.T, aur, extra []string) *httptest.Server {
+	t.Helper()
+	repoDB := func(names []string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, name := range names {
+			desc := []byte("%NAME%\n" + name + "\n\n%VERSION%\n1.0-1\n")
+			tw.WriteHeader(&tar.Header{Name: name + "-1.0-1/desc", Mode: 0o644, Size: int64(len(desc))})
+			tw.Write(desc)
+		}
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/rpc/v5/info":
+			var results []map[string]string
+			for _, name := range r.URL.Query()["arg[]"] {
+				if slices.Contains(aur, name) {
+					results = append(results, map[string]string{"Name": name})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"resultcount": len(results), "results": results})
+		case r.URL.Path == "/extra/os/x86_64/extra.db":
+			w.Write(repoDB(extra))
+		case strings.HasSuffix(r.URL.Path, ".db"):
+			w.Write(repoDB(nil))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestDiscover(t *testing.T) {
+	isolate(t)
+	now := time.Now()
+	gh := githubtest.NewServer(
+		&githubtest.Repo{Owner: "acme", Name: "widget", PushedAt: now},
+		&githubtest.Repo{Owner: "acme", Name: "gizmo", PushedAt: now},
+		&githubtest.Repo{Owner: "acme", Name: "oldtool", PushedAt: now, Archived: true},
+		&githubtest.Repo{Owner: "acme", Name: "stale", PushedAt: now.AddDate(-5, 0, 0)},
+		&githubtest.Repo{Owner: "acme", Name: "fresh", PushedAt: now},
+		&githubtest.Repo{Owner: "acme", Name: "fresh2", PushedAt: now},
+	)
+	defer gh.Close()
+	arch := fakeArch(t, []string{"widget"}, []string{"gizmo"})
+	defer arch.Close()
+
+	dbPath, err := db.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmed := func(name string, stars int) db.Binary {
+		return db.Binary{Name: name, Package: "github.com/acme/" + name, Version: "v1.0.0", Stars: stars, IsPrimary: true, BuildStatus: "confirmed"}
+	}
+	newTestDB(t, dbPath,
+		confirmed("widget", 900),
+		confirmed("gizmo", 800),
+		confirmed("oldtool", 700),
+		confirmed("stale", 600),
+		confirmed("fresh", 500),
+		confirmed("fresh2", 5),
+		db.Binary{Name: "pending", Package: "github.com/acme/pending", Version: "v1.0.0", Stars: 400, IsPrimary: true},
+	)
+
+	out := runAdmin(t, gh.URL, "discover", "--min-stars", "10", "--max-age", "3",
+		"--aur-url", arch.URL, "--arch-mirror", arch.URL, "--format", "json",
+		"--progress-file", filepath.Join(t.TempDir(), "progress.json"))
+	t.Cleanup(func() { discoverFormat = discoverFormatText })
+
+	var rows []discoverCandidate
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("discover output isn't a JSON report: %v\n%s", err, out)
+	}
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.Name)
+	}
+	if want := []string{"fresh"}; !slices.Equal(names, want) {
+		t.Errorf("discover candidates = %q, want %q", names, want)
+	}
+
+	conn := openTestDB(t, dbPath)
+	got := queryStrings(t, conn, `SELECT name FROM binaries WHERE archived = 1`)
+	if want := []string{"oldtool"}; !slices.Equal(got, want) {
+		t.Errorf("archived binaries = %q, want %q", got, want)
+	}
+	got = queryStrings(t, conn, `SELECT source, name FROM distro_lookups WHERE found = 1 ORDER BY source, name`)
+	if want := []string{"arch gizmo", "aur widget"}; !slices.Equal(got, want) {
+		t.Errorf("cached lookups found = %q, want %q", got, want)
+	}
+
+	// A second run answers the package repository lookups from the
+	// database.
+	arch.Close()
+	out = runAdmin(t, gh.URL, "discover", "--min-stars", "10", "--max-age", "3",
+		"--aur-url", arch.URL, "--arch-mirror", arch.URL, "--format", "json",
+		"--progress-file", filepath.Join(t.TempDir(), "progress.json"))
+	rows = nil
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("discover output isn't a JSON report: %v\n%s", err, out)
+	}
+	if len(rows) != 1 || rows[0].Name != "fresh" {
+		t.Errorf("cached discover candidates = %+v, want fresh", rows)
+	}
+}
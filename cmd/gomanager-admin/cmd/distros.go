@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// distroCheck looks up package names in a distribution other than Arch
+// Linux, for discover's --distros matrix.
+type distroCheck struct {
+	// name is the --distros value and the lookup source in the cache.
+	name  string
+	label string
+	// lookup records in checked whether each name not already in it is
+	// packaged, passing each batch of results to record. Failed lookups
+	// are left out, and it stops early if ctx is cancelled.
+	lookup func(ctx context.Context, client *http.Client, names []string, checked map[string]bool, record func(map[string]bool))
+}
+
+var distroChecks = []distroCheck{
+	{db.LookupBrew, "Homebrew", checkHomebrew},
+	{db.LookupNix, "nixpkgs", checkNixpkgs},
+	{db.LookupDebian, "Debian", checkDebian},
+}
+
+// findDistroCheck returns the check named name.
+func findDistroCheck(name string) (distroCheck, bool) {
+	for _, d := range distroChecks {
+		if d.name == name {
+			return d, true
+		}
+	}
+	return distroCheck{}, false
+}
+
+// distroNames returns the lowercase names a binary could be packaged under
+// in other distributions: its binary name and its repository name.
+func distroNames(b db.Binary) []string {
+	names := []string{strings.ToLower(b.Name)}
+	if _, repo, ok := parseGitHubOwnerRepo(b.Package); ok && !strings.EqualFold(repo, b.Name) {
+		names = append(names, strings.ToLower(repo))
+	}
+	return names
+}
+
+// homebrewFormulaURL lists every Homebrew formula in one document.
+const homebrewFormulaURL = "https://formulae.brew.sh/api/formula.json"
+
+// checkHomebrew looks names up among Homebrew formulae and their aliases,
+// downloading the formula list once.
+func checkHomebrew(ctx context.Context, client *http.Client, names []string, checked map[string]bool, record func(map[string]bool)) {
+	names = uncheckedNames(names, checked)
+	if len(names) == 0 {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, homebrewFormulaURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Homebrew lookup failed: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Warning: Homebrew lookup failed: HTTP %d\n", resp.StatusCode)
+		return
+	}
+	var formulae []struct {
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&formulae); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Homebrew lookup failed: %v\n", err)
+		return
+	}
+	packaged := make(map[string]bool)
+	for _, f := range formulae {
+		packaged[strings.ToLower(f.Name)] = true
+		for _, a := range f.Aliases {
+			packaged[strings.ToLower(a)] = true
+		}
+	}
+
+	results := make(map[string]bool, len(names))
+	for _, n := range names {
+		results[n] = packaged[n]
+	}
+	maps.Copy(checked, results)
+	record(results)
+}
+
+// nixSearchURL is the search.nixos.org Elasticsearch endpoint. The index
+// name changes with the search schema version, so it can be overridden with
+// $GOMANAGER_NIX_SEARCH_URL.
+const nixSearchURL = "https://search.nixos.org/backend/latest-44-nixos-unstable/_search"
+
+// nixSearchAuth are the read-only credentials the search.nixos.org frontend
+// publishes for its backend.
+const nixSearchAuth = "aWVSALXpZv:X8gPHnzL52wFEekuxsfQ9cSh"
+
+// checkNixpkgs looks names up among nixpkgs package names and attribute
+// names, 100 names per search request.
+func checkNixpkgs(ctx context.Context, client *http.Client, names []string, checked map[string]bool, record func(map[string]bool)) {
+	names = uncheckedNames(names, checked)
+	endpoint := nixSearchURL
+	if u := os.Getenv("GOMANAGER_NIX_SEARCH_URL"); u != "" {
+		endpoint = u
+	}
+	user, pass, _ := strings.Cut(nixSearchAuth, ":")
+
+	for i := 0; i < len(names); i += 100 {
+		if ctx.Err() != nil {
+			return
+		}
+		batch := names[i:min(i+100, len(names))]
+		query := map[string]any{
+			"size":    1000,
+			"_source": []string{"package_pname", "package_attr_name"},
+			"query": map[string]any{
+				"bool": map[string]any{
+					"filter": []any{map[string]any{"term": map[string]any{"type": "package"}}},
+					"should": []any{
+						map[string]any{"terms": map[string]any{"package_pname": batch}},
+						map[string]any{"terms": map[string]any{"package_attr_name": batch}},
+					},
+					"minimum_should_match": 1,
+				},
+			},
+		}
+		body, _ := json.Marshal(query)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(user, pass)
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: nixpkgs lookup failed: %v\n", err)
+			continue
+		}
+		var result struct {
+			Hits struct {
+				Hits []struct {
+					Source struct {
+						PName    string `json:"package_pname"`
+						AttrName string `json:"package_attr_name"`
+					} `json:"_source"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			fmt.Fprintf(os.Stderr, "Warning: nixpkgs lookup failed: HTTP %d\n", resp.StatusCode)
+			continue
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		results := make(map[string]bool, len(batch))
+		for _, n := range batch {
+			results[n] = false
+		}
+		for _, h := range result.Hits.Hits {
+			for _, n := range []string{h.Source.PName, h.Source.AttrName} {
+				if _, ok := results[strings.ToLower(n)]; ok {
+					results[strings.ToLower(n)] = true
+				}
+			}
+		}
+		maps.Copy(checked, results)
+		record(results)
+	}
+}
+
+// debianSourcesURL is the sources.debian.org API, answering 404 for source
+// packages Debian doesn't have.
+const debianSourcesURL = "https://sources.debian.org/api/src/"
+
+// checkDebian looks names up as Debian source packages, one request per
+// name, recording results in batches of 50.
+func checkDebian(ctx context.Context, client *http.Client, names []string, checked map[string]bool, record func(map[string]bool)) {
+	names = uncheckedNames(names, checked)
+	results := make(map[string]bool)
+	flush := func() {
+		if len(results) > 0 {
+			maps.Copy(checked, results)
+			record(results)
+			results = make(map[string]bool)
+		}
+	}
+	defer flush()
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, debianSourcesURL+url.PathEscape(name)+"/", nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		var result struct {
+			Versions []json.RawMessage `json:"versions"`
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			if json.NewDecoder(resp.Body).Decode(&result) == nil {
+				results[name] = len(result.Versions) > 0
+			}
+		case http.StatusNotFound:
+			results[name] = false
+		}
+		resp.Body.Close()
+		if len(results) >= 50 {
+			flush()
+		}
+		// Be polite to the sources API
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// distroMatrix maps a binary's ID to the --distros checks (by name) that
+// found it packaged.
+type distroMatrix map[int]map[string]bool
+
+// packagedCount returns how many distributions package b.
+func (m distroMatrix) packagedCount(b db.Binary) int {
+	n := 0
+	for _, found := range m[b.ID] {
+		if found {
+			n++
+		}
+	}
+	return n
+}
+
+// sortByPackaging orders binaries by how few distributions package them,
+// then by stars, so tools packaged nowhere come first.
+func (m distroMatrix) sortByPackaging(binaries []db.Binary) {
+	sort.SliceStable(binaries, func(i, j int) bool {
+		ci, cj := m.packagedCount(binaries[i]), m.packagedCount(binaries[j])
+		if ci != cj {
+			return ci < cj
+		}
+		return binaries[i].Stars > binaries[j].Stars
+	})
+}
+
+// printDistroMatrix prints each binary with a column per checked
+// distribution marking where it is already packaged.
+func printDistroMatrix(binaries []db.Binary, checks []distroCheck, m distroMatrix) {
+	fmt.Printf("%-30s %12s", "NAME", "STARS")
+	for _, d := range checks {
+		fmt.Printf("  %-8s", d.name)
+	}
+	fmt.Println("  PACKAGE")
+	for _, b := range binaries {
+		fmt.Printf("%-30s %6d stars", b.Name, b.Stars)
+		for _, d := range checks {
+			mark := "-"
+			if m[b.ID][d.name] {
+				mark = "✓"
+			}
+			fmt.Printf("  %-8s", mark)
+		}
+		fmt.Printf("  %s\n", b.Package)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/dockerfile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dockerfileOutput       string
+	dockerfileAllConfirmed bool
+	dockerfileBase         string
+	dockerfileBuilder      string
+)
+
+func init() {
+	exportDockerfileCmd.Flags().StringVarP(&dockerfileOutput, "output", "o", "", "Directory to write <name>/Dockerfile to (default: stdout)")
+	exportDockerfileCmd.Flags().BoolVar(&dockerfileAllConfirmed, "all-confirmed", false, "Export every primary binary whose current version is confirmed to build (requires --output)")
+	exportDockerfileCmd.Flags().StringVar(&dockerfileBase, "base", dockerfile.BaseDistroless, "Final stage base: distroless or scratch (scratch needs CGO_ENABLED=0)")
+	exportDockerfileCmd.Flags().StringVar(&dockerfileBuilder, "builder", dockerfile.DefaultBuilder, "Image for the builder stage")
+	exportDockerfileCmd.Flags().IntVar(&exportMaxVerifyAge, "max-verify-age", 0, "Refuse to export packages last verified more than this many days ago (0 = no limit)")
+	exportCmd.AddCommand(exportDockerfileCmd)
+}
+
+var exportDockerfileCmd = &cobra.Command{
+	Use:   "dockerfile [<name>]",
+	Short: "Generate a multi-stage Dockerfile for a Go binary",
+	Long: `Generates a minimal multi-stage Dockerfile for a binary confirmed to build.
+The builder stage go installs the binary at its recorded version with its
+recorded build environment and ldflags; the final stage copies it onto a
+distroless image (static when built with CGO_ENABLED=0) or, with
+--base scratch, an empty one.
+
+With --all-confirmed, a Dockerfile is written for every primary binary
+whose current version is confirmed, to <output>/<name>/Dockerfile.
+Binaries that can't be exported are reported and skipped.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if dockerfileAllConfirmed {
+			if len(args) > 0 {
+				return fmt.Errorf("--all-confirmed takes no binary name")
+			}
+			if dockerfileOutput == "" {
+				return fmt.Errorf("--all-confirmed requires --output")
+			}
+		} else if len(args) != 1 {
+			return fmt.Errorf("expected a binary name or --all-confirmed")
+		}
+		opts := dockerfile.Options{Builder: dockerfileBuilder, Base: dockerfileBase}
+
+		conn, err := openAdminDB("")
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if !dockerfileAllConfirmed {
+			b, err := db.GetAnyByName(conn, args[0])
+			if err != nil {
+				return err
+			}
+			if err := checkDockerfileExport(b); err != nil {
+				return fmt.Errorf("refusing to export: %w", err)
+			}
+			if dockerfileOutput == "" {
+				return dockerfile.Generate(os.Stdout, b, opts)
+			}
+			path, err := writeDockerfile(b, opts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Dockerfile written to %s\n", path)
+			return nil
+		}
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return err
+		}
+		// Binaries are ordered by stars, so the most popular of several
+		// with the same name gets <output>/<name>.
+		written, skipped := 0, 0
+		seen := make(map[string]bool)
+		for i := range binaries {
+			b := &binaries[i]
+			if !b.IsPrimary || !b.VersionConfirmed() {
+				continue
+			}
+			if seen[b.Name] {
+				fmt.Printf("Skipping %s (%s): another package already exported that name\n", b.Name, b.Package)
+				skipped++
+				continue
+			}
+			seen[b.Name] = true
+			if err := checkDockerfileExport(b); err != nil {
+				fmt.Printf("Skipping %s: %v\n", b.Name, err)
+				skipped++
+				continue
+			}
+			if _, err := writeDockerfile(b, opts); err != nil {
+				fmt.Printf("Skipping %s: %v\n", b.Name, err)
+				skipped++
+				continue
+			}
+			written++
+		}
+		fmt.Printf("Wrote %d Dockerfiles to %s (%d skipped)\n", written, dockerfileOutput, skipped)
+		return nil
+	},
+}
+
+// checkDockerfileExport fails if b's current version isn't confirmed to
+// build or its verification is older than --max-verify-age.
+func checkDockerfileExport(b *db.Binary) error {
+	if !b.VersionConfirmed() {
+		return fmt.Errorf("%s %s is not confirmed to build (status %s)", b.Name, b.Version, b.BuildStatus)
+	}
+	return checkVerifiedAge(b, exportMaxVerifyAge)
+}
+
+// writeDockerfile generates b's Dockerfile into <output>/<name>/Dockerfile
+// and returns its path. Nothing is written if generation fails.
+func writeDockerfile(b *db.Binary, opts dockerfile.Options) (string, error) {
+	// Render first so a failure doesn't leave an empty file behind.
+	var buf bytes.Buffer
+	if err := dockerfile.Generate(&buf, b, opts); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dockerfileOutput, b.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create output directory: %w", err)
+	}
+	path := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Exit codes returned by gomanager-admin. The ci command exits with the code
+// of the stage that failed so workflows can tell stages apart. Never
+// renumber an existing code.
+const (
+	ExitOK             = 0   // success
+	ExitError          = 1   // unclassified error
+	ExitUpdateVersions = 10  // ci: the update-versions stage failed
+	ExitVerify         = 11  // ci: the verify stage failed
+	ExitPrune          = 12  // ci: the prune (db optimize) stage failed
+	ExitCheck          = 13  // ci: the db check stage failed
+	ExitRelease        = 14  // ci: the release (db slim) stage failed
+	ExitScore          = 15  // ci: the score (confidence) stage failed
+	ExitInterrupted    = 130 // stopped by SIGINT or SIGTERM after saving progress
+)
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that the process exits with the given code.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return ExitError
+}
+
+// notifyInterrupt returns a context cancelled on SIGINT or SIGTERM, so a
+// long-running loop can stop at a safe point and save its progress. Once
+// the context is cancelled, a second signal terminates the process as
+// usual.
+func notifyInterrupt() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// interruptedError reports that a command stopped early on a signal after
+// saving its progress, with a hint on resuming it.
+func interruptedError(hint string) error {
+	return withExitCode(ExitInterrupted, fmt.Errorf("interrupted; %s", hint))
+}
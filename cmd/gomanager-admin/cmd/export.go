@@ -3,9 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,10 +17,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var outputDir string
+var (
+	outputDir          string
+	exportMaxVerifyAge int
+	exportBin          bool
+)
 
 func init() {
 	exportPkgbuildCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Directory to write PKGBUILD to (default: stdout)")
+	exportPkgbuildCmd.Flags().IntVar(&exportMaxVerifyAge, "max-verify-age", 0, "Refuse to export packages last verified more than this many days ago (0 = no limit)")
+	exportPkgbuildCmd.Flags().BoolVar(&exportBin, "bin", false, "Generate a <name>-bin PKGBUILD installing the GitHub release's prebuilt Linux archives")
 	exportCmd.AddCommand(exportPkgbuildCmd)
 	rootCmd.AddCommand(exportCmd)
 }
@@ -38,7 +47,7 @@ var readmeNames = []string{"README.md", "README", "README.txt", "README.rst"}
 // Returns nil (no error) if the API call fails, so the caller can gracefully
 // degrade to no license/readme lines.
 func fetchRepoFiles(owner, repo, token, ref string) map[string]bool {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/", owner, repo)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/contents/", owner, repo)
 	if ref != "" {
 		url += "?ref=" + ref
 	}
@@ -83,7 +92,7 @@ func fetchRepoFiles(owner, repo, token, ref string) map[string]bool {
 // repository at the specified ref and returns the SPDX license identifier
 // (e.g. "MIT", "Apache-2.0"). Returns an empty string on failure.
 func fetchLicenseID(owner, repo, token, ref string) string {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/license", owner, repo)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/license", owner, repo)
 	if ref != "" {
 		url += "?ref=" + ref
 	}
@@ -170,7 +179,7 @@ func buildPkgbuildOpts(files map[string]bool) *pkgbuild.Options {
 
 	// Also check case-insensitive matches for common variants
 	if opts.LicenseFile == "" || opts.ReadmeFile == "" {
-		for f := range files {
+		for _, f := range slices.Sorted(maps.Keys(files)) {
 			upper := strings.ToUpper(f)
 			if opts.LicenseFile == "" && (strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || upper == "COPYING") {
 				opts.LicenseFile = f
@@ -187,24 +196,43 @@ func buildPkgbuildOpts(files map[string]bool) *pkgbuild.Options {
 var exportPkgbuildCmd = &cobra.Command{
 	Use:   "pkgbuild <name>",
 	Short: "Generate an AUR PKGBUILD for a Go binary",
-	Args:  cobra.ExactArgs(1),
+	Long: `Generates an Arch Linux PKGBUILD that builds the binary from source at its
+recorded version.
+
+With --bin, generates a <name>-bin PKGBUILD instead, installing the prebuilt
+Linux archives attached to the version's GitHub release (as published by
+goreleaser). Each archive is downloaded to compute its sha256sum, which is
+checked against the release's checksums file when there is one, and to find
+the binary inside it.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		conn, err := db.Open()
+		conn, err := openAdminDB("")
 		if err != nil {
 			return err
 		}
 		defer conn.Close()
 
-		b, err := db.GetByName(conn, args[0])
+		b, err := db.GetAnyByName(conn, args[0])
 		if err != nil {
 			return err
 		}
+		if err := checkVerifiedAge(b, exportMaxVerifyAge); err != nil {
+			return fmt.Errorf("refusing to export: %w", err)
+		}
 
-		// Fetch repo file listing to detect LICENSE and README
-		opts := detectRepoFiles(b)
+		generate := func(w io.Writer) error { return pkgbuild.Generate(w, b, detectRepoFiles(b)) }
+		pkgName := b.Name
+		if exportBin {
+			opts, err := binPkgbuildOpts(b)
+			if err != nil {
+				return fmt.Errorf("cannot export -bin PKGBUILD: %w", err)
+			}
+			generate = func(w io.Writer) error { return pkgbuild.GenerateBin(w, b, opts) }
+			pkgName += "-bin"
+		}
 
 		if outputDir != "" {
-			dir := filepath.Join(outputDir, b.Name)
+			dir := filepath.Join(outputDir, pkgName)
 			if err := os.MkdirAll(dir, 0o755); err != nil {
 				return fmt.Errorf("cannot create output directory: %w", err)
 			}
@@ -213,13 +241,13 @@ var exportPkgbuildCmd = &cobra.Command{
 				return err
 			}
 			defer f.Close()
-			if err := pkgbuild.Generate(f, b, opts); err != nil {
+			if err := generate(f); err != nil {
 				return err
 			}
 			fmt.Printf("PKGBUILD written to %s/PKGBUILD\n", dir)
 			return nil
 		}
 
-		return pkgbuild.Generate(os.Stdout, b, opts)
+		return generate(os.Stdout)
 	},
 }
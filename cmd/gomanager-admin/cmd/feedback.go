@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/telemetry"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	feedbackDatabase   string
+	feedbackMinReports int
+	feedbackDryRun     bool
+)
+
+func init() {
+	importFeedbackCmd.Flags().StringVarP(&feedbackDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	importFeedbackCmd.Flags().IntVar(&feedbackMinReports, "min-reports", 1, "Queue a package version once this many distinct failures are reported")
+	importFeedbackCmd.Flags().BoolVar(&feedbackDryRun, "dry-run", false, "Show what would be queued without recording anything")
+	rootCmd.AddCommand(importFeedbackCmd)
+}
+
+var importFeedbackCmd = &cobra.Command{
+	Use:   "import-feedback [file...]",
+	Short: "Queue packages users report failing to install for re-verification",
+	Long: `Reads reports of failed installs from the given files (or stdin) and
+queues the packages they are about for re-verification, so the verify
+fallback strategies can find flags that build them or their build status
+can be corrected. Two formats are accepted:
+
+  - install reports posted by client telemetry, as newline-delimited JSON
+    (successful installs are ignored)
+  - GitHub issues filed with the install failure template, as a JSON array
+    from 'gh issue list --label install-failure --json number,url,body'
+
+Reports are deduplicated against everything imported before: an issue by
+its URL, and telemetry by package, version, platform, Go version, failure
+class, and day. Each is then correlated with the database entry for its
+package. A package version is queued (and a feedback event recorded) once
+at least --min-reports distinct failures are on record for it; reports of
+versions older than the database's, or of packages not in the database,
+are only listed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var reports []db.Feedback
+		skipped := 0
+		read := func(name string, r io.Reader) error {
+			fs, n, err := readFeedback(r)
+			if err != nil {
+				return fmt.Errorf("cannot read %s: %w", name, err)
+			}
+			reports = append(reports, fs...)
+			skipped += n
+			return nil
+		}
+		if len(args) == 0 {
+			if err := read("stdin", os.Stdin); err != nil {
+				return err
+			}
+		}
+		for _, path := range args {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			err = read(path, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		conn, err := openAdminDB(feedbackDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		// Group the new reports by package version.
+		type target struct{ pkg, version string }
+		groups := make(map[target][]db.Feedback)
+		var order []target
+		duplicates := 0
+		for _, f := range reports {
+			isNew, err := isNewFeedback(conn, f)
+			if err != nil {
+				return err
+			}
+			if !isNew {
+				duplicates++
+				continue
+			}
+			t := target{f.Package, f.Version}
+			if _, ok := groups[t]; !ok {
+				order = append(order, t)
+			}
+			groups[t] = append(groups[t], f)
+		}
+		fmt.Printf("Read %d reports: %d new, %d already imported, %d unusable.\n",
+			len(reports), len(reports)-duplicates, duplicates, skipped)
+		if len(order) == 0 {
+			return nil
+		}
+		slices.SortFunc(order, func(a, b target) int {
+			if c := strings.Compare(a.pkg, b.pkg); c != 0 {
+				return c
+			}
+			return semver.Compare(a.version, b.version)
+		})
+
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PACKAGE\tVERSION\tNEW\tCLASSES\tACTION")
+		queued := 0
+		for _, t := range order {
+			fs := groups[t]
+			action, ok, err := feedbackAction(conn, t.pkg, t.version, fs)
+			if err != nil {
+				return err
+			}
+			if ok {
+				queued++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", t.pkg, t.version, len(fs), feedbackClasses(fs), action)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if feedbackDryRun {
+			fmt.Printf("\nDry run: %d packages would be queued for re-verification.\n", queued)
+		} else {
+			fmt.Printf("\nQueued %d packages for re-verification.\n", queued)
+		}
+		return nil
+	},
+}
+
+// isNewFeedback records f, unless this is a dry run, and reports whether it
+// wasn't imported before.
+func isNewFeedback(conn *sql.DB, f db.Feedback) (bool, error) {
+	if !feedbackDryRun {
+		return db.RecordFeedback(conn, f)
+	}
+	var n int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM feedback WHERE key = ?`, f.Key).Scan(&n)
+	return n == 0, err
+}
+
+// feedbackAction correlates the new reports fs of pkg at version with the
+// database and queues pkg for re-verification if they warrant it. It
+// returns what was done, for the summary table, and whether pkg was (or with
+// --dry-run, would be) queued.
+func feedbackAction(conn *sql.DB, pkg, version string, fs []db.Feedback) (string, bool, error) {
+	b, err := db.GetAnyByPackage(conn, pkg)
+	if errors.Is(err, db.ErrNotFound) {
+		return "not in database", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if semver.IsValid(version) && semver.IsValid(b.Version) && semver.Compare(version, b.Version) < 0 {
+		return fmt.Sprintf("older than the database's %s", b.Version), false, nil
+	}
+	total, err := db.FeedbackCount(conn, pkg, version)
+	if err != nil {
+		return "", false, err
+	}
+	if feedbackDryRun {
+		total += len(fs)
+	}
+	if total < feedbackMinReports {
+		return fmt.Sprintf("%d of %d reports needed", total, feedbackMinReports), false, nil
+	}
+
+	action := "queued"
+	if feedbackDryRun {
+		action = "would queue"
+	}
+	if b.BuildStatus == "confirmed" {
+		action += " (confirmed build fails for users)"
+	}
+	if feedbackDryRun {
+		return action, true, nil
+	}
+	detail := fmt.Sprintf("%d reports of %s failing: %s", total, version, feedbackClasses(fs))
+	for _, f := range fs {
+		if f.Source == db.FeedbackIssue {
+			detail += " " + f.Detail
+		}
+	}
+	recordEvent(conn, pkg, db.EventFeedback, detail)
+	enqueueJob(conn, pkg, db.JobFeedback)
+	return action, true, nil
+}
+
+// feedbackClasses formats the failure classes of fs with their platforms,
+// most frequent first, e.g. "cgo:2 (darwin/arm64) network:1 (linux/amd64)".
+func feedbackClasses(fs []db.Feedback) string {
+	counts := make(map[string]int)
+	platforms := make(map[string][]string)
+	for _, f := range fs {
+		counts[f.Class]++
+		if f.Platform != "" && !slices.Contains(platforms[f.Class], f.Platform) {
+			platforms[f.Class] = append(platforms[f.Class], f.Platform)
+		}
+	}
+	classes := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	parts := make([]string, len(classes))
+	for i, c := range classes {
+		parts[i] = fmt.Sprintf("%s:%d", c, counts[c])
+		if ps := platforms[c]; len(ps) > 0 {
+			slices.Sort(ps)
+			parts[i] += " (" + strings.Join(ps, ", ") + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// readFeedback reads failed install reports from r: a JSON array of GitHub
+// issues, or telemetry reports as newline-delimited JSON. It returns the
+// reports and the number of entries that weren't usable reports.
+func readFeedback(r io.Reader) ([]db.Feedback, int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return readIssues(data)
+	}
+	reports, skipped, err := telemetry.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	var fs []db.Feedback
+	for _, rep := range reports {
+		if rep.Status != telemetry.StatusFailed {
+			continue
+		}
+		platform := rep.GOOS + "/" + rep.GOARCH
+		fs = append(fs, db.Feedback{
+			Key: strings.Join([]string{"telemetry", rep.Package, rep.Version, platform,
+				rep.GoVersion, rep.Class, rep.Date}, "|"),
+			Source:   db.FeedbackTelemetry,
+			Package:  rep.Package,
+			Version:  rep.Version,
+			Class:    rep.Class,
+			Platform: platform,
+		})
+	}
+	return fs, skipped, nil
+}
+
+// issue is a GitHub issue as 'gh issue list --json number,url,body' prints
+// it.
+type issue struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+// issueSection matches a "### Heading" of an issue form and its value.
+var issueSection = regexp.MustCompile(`(?m)^###\s+(.+?)\s*$`)
+
+// issueFields returns the fields of an issue filed with an issue form,
+// keyed by lowercased heading. "_No response_" values are left out.
+func issueFields(body string) map[string]string {
+	fields := make(map[string]string)
+	locs := issueSection.FindAllStringSubmatchIndex(body, -1)
+	for i, loc := range locs {
+		end := len(body)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		value := strings.TrimSpace(body[loc[1]:end])
+		if value == "_No response_" {
+			continue
+		}
+		fields[strings.ToLower(body[loc[2]:loc[3]])] = value
+	}
+	return fields
+}
+
+// readIssues parses GitHub issues filed with the install failure template.
+func readIssues(data []byte) ([]db.Feedback, int, error) {
+	var issues []issue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		return nil, 0, fmt.Errorf("invalid issue list: %w", err)
+	}
+	var fs []db.Feedback
+	skipped := 0
+	for _, is := range issues {
+		fields := issueFields(is.Body)
+		pkg := strings.Trim(fields["package"], "` ")
+		if pkg == "" || strings.ContainsAny(pkg, " \n") {
+			skipped++
+			continue
+		}
+		key := is.URL
+		if key == "" {
+			key = fmt.Sprintf("#%d", is.Number)
+		}
+		fs = append(fs, db.Feedback{
+			Key:      "issue|" + key,
+			Source:   db.FeedbackIssue,
+			Package:  pkg,
+			Version:  strings.Trim(fields["version"], "` "),
+			Class:    telemetry.Classify(fields["error output"]),
+			Platform: strings.Trim(fields["platform"], "` "),
+			Detail:   is.URL,
+		})
+	}
+	return fs, skipped, nil
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,7 +17,7 @@ var (
 )
 
 func init() {
-	fixModulePathsCmd.Flags().StringVarP(&fixPathsDatabase, "database", "d", "", "Path to database.db (default: ~/.config/gomanager/database.db)")
+	fixModulePathsCmd.Flags().StringVarP(&fixPathsDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
 	fixModulePathsCmd.Flags().BoolVar(&fixPathsDryRun, "dry-run", false, "Only show what would be changed, don't modify the database")
 	rootCmd.AddCommand(fixModulePathsCmd)
 }
@@ -33,14 +32,7 @@ This command checks each package in the database, fetches the go.mod from
 the repository, and corrects the package path if the module declaration
 shows a versioned path.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var conn *sql.DB
-		var err error
-
-		if fixPathsDatabase != "" {
-			conn, err = db.OpenPath(fixPathsDatabase)
-		} else {
-			conn, err = db.Open()
-		}
+		conn, err := openAdminDB(fixPathsDatabase)
 		if err != nil {
 			return err
 		}
@@ -108,6 +100,8 @@ shows a versioned path.`,
 					if !fixPathsDryRun {
 						if err := db.DeleteBinary(conn, b.ID); err != nil {
 							fmt.Printf("    Warning: failed to delete: %v\n", err)
+						} else {
+							recordEvent(conn, b.Package, db.EventDuplicate, newPkg)
 						}
 					}
 					fixed++
@@ -123,6 +117,7 @@ shows a versioned path.`,
 					if err := db.UpdateBuildResult(conn, b.ID, "unknown", b.BuildFlags, ""); err != nil {
 						fmt.Printf("    Warning: failed to reset build status: %v\n", err)
 					}
+					recordEvent(conn, newPkg, db.EventRenamed, b.Package)
 				}
 				fixed++
 			}
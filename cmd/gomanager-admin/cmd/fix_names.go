@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixNamesDatabase string
+	fixNamesDryRun   bool
+)
+
+func init() {
+	fixNamesCmd.Flags().StringVarP(&fixNamesDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	fixNamesCmd.Flags().BoolVar(&fixNamesDryRun, "dry-run", false, "Only show what would be changed, don't modify the database")
+	rootCmd.AddCommand(fixNamesCmd)
+}
+
+// genericNames are binary names that say nothing about the tool, as seeded
+// from cmd/ directories such as cmd/cli.
+var genericNames = map[string]bool{
+	"agent": true, "api": true, "app": true, "bin": true, "cli": true,
+	"client": true, "cmd": true, "command": true, "daemon": true, "demo": true,
+	"example": true, "go": true, "main": true, "run": true, "server": true,
+	"service": true, "src": true, "test": true, "tool": true, "tools": true,
+	"web": true,
+}
+
+// validBinaryName matches names fix-names may give a binary.
+var validBinaryName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// nameSource is a binary name suggested by one source of evidence.
+type nameSource struct {
+	name   string
+	source string
+}
+
+// nameTriage is a binary fix-names couldn't rename on its own, or, while
+// it runs, one it is about to rename to its only candidate.
+type nameTriage struct {
+	binary     db.Binary
+	candidates []nameSource
+	reason     string
+}
+
+var fixNamesCmd = &cobra.Command{
+	Use:   "fix-names",
+	Short: "Replace generic binary names using release configs and entrypoints",
+	Long: `Binary names were seeded from repository and cmd/ directory names, which
+leaves many entries with generic names such as cli, app, or main. For each
+such entry, this command collects the name from three sources:
+
+  goreleaser config  the binary name of the build whose main package is the
+                     entry's (defaulting to the project name, as goreleaser
+                     does)
+  entrypoint         the name entrypoint detection gives the package today:
+                     the repository name for a root main.go, the directory
+                     name under cmd/, or a Homebrew formula's binary
+  go install         the name go install writes, the package path's last
+                     element
+
+Generic names are discarded. If exactly one name remains and no other entry
+has it or is about to get it, the binary is renamed and a curation event is
+recorded; the client installs a go install build under the entry's name.
+Entries with no remaining name, with disagreeing sources, or whose new name
+is taken are listed at the end for manual triage.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(fixNamesDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+
+		// Packages by lowercased name, for spotting names already taken.
+		taken := make(map[string][]string)
+		for _, b := range binaries {
+			key := strings.ToLower(b.Name)
+			taken[key] = append(taken[key], b.Package)
+		}
+
+		// Group generic names by owner/repo to fetch each repository once.
+		repoBinaries := make(map[string][]db.Binary)
+		var repoOrder []string
+		for _, b := range binaries {
+			if !genericNames[strings.ToLower(b.Name)] {
+				continue
+			}
+			key := b.Package
+			if owner, repo, ok := parseGitHubOwnerRepo(b.Package); ok {
+				key = owner + "/" + repo
+			}
+			if _, exists := repoBinaries[key]; !exists {
+				repoOrder = append(repoOrder, key)
+			}
+			repoBinaries[key] = append(repoBinaries[key], b)
+		}
+		fmt.Printf("Checking %d repositories with generic binary names...\n\n", len(repoOrder))
+
+		s := &scanner{
+			ctx:    context.Background(),
+			client: &http.Client{Timeout: 15 * time.Second},
+			token:  os.Getenv("GITHUB_TOKEN"),
+		}
+		var renames []nameTriage
+		var triage []nameTriage
+
+		for _, key := range repoOrder {
+			var goreleaser *goreleaserConfig
+			var entrypoints []entrypoint
+			owner, repo, onGitHub := parseGitHubOwnerRepo(repoBinaries[key][0].Package)
+			if onGitHub {
+				rootFiles := s.listFiles(owner, repo, "")
+				goreleaser = s.goreleaserConfig(owner, repo, rootFiles)
+				entrypoints = s.findEntrypoints(owner, repo, rootFiles, goreleaser != nil)
+			}
+
+			for _, b := range repoBinaries[key] {
+				suffix := repoSuffix(b.Package)
+				var sources []nameSource
+				if goreleaser != nil {
+					if name := goreleaser.binaryFor(suffix, repo); name != "" {
+						sources = append(sources, nameSource{name, "goreleaser config"})
+					}
+				}
+				for _, ep := range entrypoints {
+					if ep.pathSuffix == suffix {
+						sources = append(sources, nameSource{ep.binaryName, ep.source})
+						break
+					}
+				}
+				sources = append(sources, nameSource{db.InstallName(b.Package), "go install"})
+
+				candidates := nameCandidates(sources)
+				switch {
+				case len(candidates) == 0:
+					triage = append(triage, nameTriage{b, sources, "no specific name found"})
+					continue
+				case len(candidates) > 1:
+					triage = append(triage, nameTriage{b, candidates, "sources disagree"})
+					continue
+				}
+				renames = append(renames, nameTriage{binary: b, candidates: candidates})
+			}
+		}
+
+		// A name is only given to a binary if no other entry has it or is
+		// about to get it.
+		claims := make(map[string][]string)
+		for _, r := range renames {
+			key := strings.ToLower(r.candidates[0].name)
+			claims[key] = append(claims[key], r.binary.Package)
+		}
+		fixed := 0
+		for _, r := range renames {
+			b, name := r.binary, r.candidates[0].name
+			key := strings.ToLower(name)
+			if others := taken[key]; len(others) > 0 {
+				triage = append(triage, nameTriage{b, r.candidates, "name taken by " + strings.Join(others, ", ")})
+				continue
+			}
+			if len(claims[key]) > 1 {
+				triage = append(triage, nameTriage{b, r.candidates, "also suggested for " + strings.Join(otherPackages(claims[key], b.Package), ", ")})
+				continue
+			}
+
+			fmt.Printf("  %s: %s → %s (%s)\n", b.Package, b.Name, name, r.candidates[0].source)
+			if !fixNamesDryRun {
+				if err := db.RenameBinary(conn, b.ID, name); err != nil {
+					fmt.Printf("    Warning: failed to rename: %v\n", err)
+					continue
+				}
+				recordEvent(conn, b.Package, db.EventNameFixed, b.Name)
+			}
+			fixed++
+		}
+
+		if len(triage) > 0 {
+			fmt.Printf("\nNeeds manual triage (%d):\n\n", len(triage))
+			printNameTriage(triage)
+		}
+		if fixNamesDryRun {
+			fmt.Printf("\nDry run complete. Would rename %d binaries; %d need manual triage.\n", fixed, len(triage))
+		} else {
+			fmt.Printf("\nDone. Renamed %d binaries; %d need manual triage.\n", fixed, len(triage))
+		}
+		return nil
+	},
+}
+
+// repoSuffix returns the directory of pkg's main package relative to its
+// repository root, "" for the root itself, in the form entrypoint detection
+// and goreleaser configs use.
+func repoSuffix(pkg string) string {
+	paths := pkgbuild.ResolvePaths(pkg)
+	dir := path.Join(paths.ModuleDir, paths.Build)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// nameCandidates returns the distinct valid, non-generic names among
+// sources, in order, each with every source that suggested it.
+func nameCandidates(sources []nameSource) []nameSource {
+	var candidates []nameSource
+	index := make(map[string]int)
+	for _, s := range sources {
+		if !validBinaryName.MatchString(s.name) || genericNames[strings.ToLower(s.name)] {
+			continue
+		}
+		if i, ok := index[s.name]; ok {
+			candidates[i].source += ", " + s.source
+			continue
+		}
+		index[s.name] = len(candidates)
+		candidates = append(candidates, s)
+	}
+	return candidates
+}
+
+// otherPackages returns pkgs without pkg.
+func otherPackages(pkgs []string, pkg string) []string {
+	var others []string
+	for _, p := range pkgs {
+		if p != pkg {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+// printNameTriage prints the binaries fix-names left for manual triage.
+func printNameTriage(triage []nameTriage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tNAME\tCANDIDATES\tREASON")
+	for _, t := range triage {
+		candidates := make([]string, len(t.candidates))
+		for i, c := range t.candidates {
+			candidates[i] = fmt.Sprintf("%s (%s)", c.name, c.source)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.binary.Package, t.binary.Name, strings.Join(candidates, "; "), t.reason)
+	}
+	w.Flush()
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// goreleaserFiles are the config filenames goreleaser looks for, in order.
+var goreleaserFiles = []string{
+	".goreleaser.yml",
+	".goreleaser.yaml",
+	"goreleaser.yml",
+	"goreleaser.yaml",
+}
+
+// goreleaserConfig is the subset of a goreleaser configuration needed to
+// derive build flags and binary names.
+type goreleaserConfig struct {
+	ProjectName string            `yaml:"project_name"`
+	Env         []string          `yaml:"env"`
+	Builds      []goreleaserBuild `yaml:"builds"`
+}
+
+// goreleaserBuild is a single entry of the builds list.
+type goreleaserBuild struct {
+	ID      string     `yaml:"id"`
+	Main    string     `yaml:"main"`
+	Dir     string     `yaml:"dir"`
+	Binary  string     `yaml:"binary"`
+	Env     []string   `yaml:"env"`
+	LDFlags stringList `yaml:"ldflags"`
+}
+
+// stringList accepts either a single string or a list of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// parseGoreleaser parses a goreleaser config. It returns nil if the content
+// isn't valid YAML.
+func parseGoreleaser(data []byte) *goreleaserConfig {
+	var cfg goreleaserConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil
+	}
+	return &cfg
+}
+
+// buildFor returns the build whose main package is at pathSuffix ("" for the
+// repository root). If no build matches, the first build is returned, since
+// most configs have a single build whose main is left at its default.
+func (c *goreleaserConfig) buildFor(pathSuffix string) *goreleaserBuild {
+	if len(c.Builds) == 0 {
+		return nil
+	}
+	for i, b := range c.Builds {
+		if b.mainDir() == pathSuffix {
+			return &c.Builds[i]
+		}
+	}
+	return &c.Builds[0]
+}
+
+// binaryFor returns the name of the binary goreleaser builds from the main
+// package at pathSuffix in repo, or "" if no build's main is exactly there or
+// the name is a template other than the project name. As in goreleaser,
+// a config without builds builds the repository root, and the name
+// defaults to the project name, which defaults to the repository name.
+func (c *goreleaserConfig) binaryFor(pathSuffix, repo string) string {
+	project := c.ProjectName
+	if project == "" {
+		project = repo
+	}
+	builds := c.Builds
+	if len(builds) == 0 {
+		builds = []goreleaserBuild{{}}
+	}
+	for _, b := range builds {
+		if b.mainDir() != pathSuffix {
+			continue
+		}
+		name := strings.TrimSpace(b.Binary)
+		if name == "" || strings.Join(strings.Fields(name), "") == "{{.ProjectName}}" {
+			name = project
+		}
+		if strings.Contains(name, "{{") {
+			return ""
+		}
+		return path.Base(name)
+	}
+	return ""
+}
+
+// mainDir returns the build's main package directory relative to the
+// repository root, with "" for the root itself.
+func (b *goreleaserBuild) mainDir() string {
+	main := b.Main
+	if strings.HasSuffix(main, ".go") {
+		main = path.Dir(main)
+	}
+	dir := path.Clean(path.Join(b.Dir, main))
+	if dir == "." {
+		return ""
+	}
+	return strings.TrimPrefix(dir, "./")
+}
+
+// buildFlags returns the environment variables and ldflags that apply to the
+// build at pathSuffix. Only allowlisted variables with literal (non-template)
+// values are kept, since the client applies them verbatim.
+func (c *goreleaserConfig) buildFlags(pathSuffix string) (env map[string]string, ldflags string) {
+	build := c.buildFor(pathSuffix)
+
+	vars := append([]string{}, c.Env...)
+	if build != nil {
+		vars = append(vars, build.Env...)
+	}
+	for _, v := range vars {
+		key, val, ok := strings.Cut(v, "=")
+		if !ok || strings.Contains(val, "{{") || !db.IsAllowedBuildEnv(key) {
+			continue
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[key] = val
+	}
+
+	if build != nil {
+		ldflags = strings.Join(build.LDFlags, " ")
+	}
+	return env, ldflags
+}
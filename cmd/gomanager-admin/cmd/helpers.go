@@ -3,25 +3,39 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"database/sql"
+	"debug/buildinfo"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/progress"
+	"golang.org/x/mod/module"
 )
 
 // safeGoEnv returns a minimal environment for running go install on untrusted
 // packages. Only variables required by the Go toolchain are included — secrets
 // like GITHUB_TOKEN and CI runner tokens are explicitly excluded so a malicious
-// package cannot exfiltrate them (e.g. via #cgo directives).
+// package cannot exfiltrate them (e.g. via #cgo directives). Checksum database
+// verification is always on, overriding any exceptions in the go env file,
+// GOINSECURE, and -insecure in GOFLAGS, so the recorded sumdb status of every
+// package is meaningful.
 func safeGoEnv(gobin string, extra map[string]string) []string {
 	// Allowlist of environment variables safe/needed for go install.
 	allowed := []string{
 		"HOME", "USER", "PATH", "TMPDIR",
-		"GOPATH", "GOROOT", "GOMODCACHE", "GOPROXY", "GONOSUMCHECK",
-		"GONOSUMDB", "GONOPROXY", "GOPRIVATE", "GOFLAGS", "GOTOOLCHAIN",
+		"GOPATH", "GOROOT", "GOMODCACHE", "GOPROXY", "GONOPROXY",
+		"GOFLAGS", "GOTOOLCHAIN",
 		"GOTELEMETRY", "SSL_CERT_FILE", "SSL_CERT_DIR",
 		// Needed on some systems for DNS/TLS
 		"LANG", "LC_ALL",
@@ -30,17 +44,57 @@ func safeGoEnv(gobin string, extra map[string]string) []string {
 	env := make([]string, 0, len(allowed)+len(extra)+1)
 	for _, key := range allowed {
 		if val, ok := os.LookupEnv(key); ok {
+			if key == "GOFLAGS" {
+				val, _ = db.StripInsecureGOFLAGS(val)
+			}
 			env = append(env, key+"="+val)
 		}
 	}
-	env = append(env, "GOBIN="+gobin)
+	env = append(env, "GOBIN="+gobin, "GOSUMDB=sum.golang.org", "GONOSUMDB=", "GOPRIVATE=", "GOINSECURE=")
 	for k, v := range extra {
+		if k == "GOFLAGS" {
+			v, _ = db.StripInsecureGOFLAGS(v)
+		}
 		env = append(env, k+"="+v)
 	}
 	return env
 }
 
-func tryGoInstall(installPath string, envFlags map[string]string) (ok bool, flags map[string]string, errMsg string) {
+// openAdminDB opens the database at path, or the default database if path is
+// empty. Admin commands read and write data that the slim client database
+// doesn't carry, so a slim database is rejected.
+func openAdminDB(path string) (*sql.DB, error) {
+	if path == "" {
+		var err error
+		if path, err = db.DBPath(); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := db.OpenForUpdate(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RequireFull(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// installRun holds per-invocation settings for tryGoInstall that affect how
+// the build runs but are not recorded as package build flags.
+type installRun struct {
+	// events receives download progress events (may be nil).
+	events *progress.Reporter
+	// env overrides toolchain variables such as GOMODCACHE and GOPROXY.
+	env map[string]string
+	// built, if set, is called with the path of each binary a successful
+	// build produced, before the throwaway GOBIN is removed.
+	built func(path string)
+}
+
+// tryGoInstall runs go install for installPath into a throwaway GOBIN.
+func tryGoInstall(installPath string, envFlags map[string]string, run installRun) (ok bool, flags map[string]string, errMsg string) {
 	tmpDir, err := os.MkdirTemp("", "gomanager-verify-*")
 	if err != nil {
 		return false, envFlags, fmt.Sprintf("cannot create temp dir: %v", err)
@@ -49,11 +103,25 @@ func tryGoInstall(installPath string, envFlags map[string]string) (ok bool, flag
 
 	goCmd := exec.Command("go", "install", installPath)
 	goCmd.Env = safeGoEnv(tmpDir, envFlags)
+	for k, v := range run.env {
+		goCmd.Env = append(goCmd.Env, k+"="+v)
+	}
+	ev := run.events
 
-	var stderr bytes.Buffer
-	goCmd.Stderr = &stderr
+	stderr := new(bytes.Buffer)
+	goCmd.Stderr = stderr
+	var out io.WriteCloser
+	if ev.Enabled() {
+		pkg, _, _ := strings.Cut(installPath, "@")
+		out, stderr = ev.GoOutput("", pkg)
+		goCmd.Stderr = out
+	}
 
-	if err := goCmd.Run(); err != nil {
+	err = goCmd.Run()
+	if out != nil {
+		out.Close()
+	}
+	if err != nil {
 		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
 		if len(lines) > 5 {
 			lines = lines[:5]
@@ -61,9 +129,80 @@ func tryGoInstall(installPath string, envFlags map[string]string) (ok bool, flag
 		return false, envFlags, strings.Join(lines, " ")
 	}
 
+	if run.built != nil {
+		if files, err := os.ReadDir(tmpDir); err == nil {
+			for _, f := range files {
+				run.built(filepath.Join(tmpDir, f.Name()))
+			}
+		}
+	}
 	return true, envFlags, ""
 }
 
+// moduleDownloadSize returns the total size of the module zips in modCache
+// for the main module and dependencies recorded in the binary at binPath.
+// Modules missing from the cache (e.g. replaced with local directories)
+// are not counted.
+func moduleDownloadSize(binPath, modCache string) int64 {
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil || modCache == "" {
+		return 0
+	}
+	mods := append([]*debug.Module{&info.Main}, info.Deps...)
+	var total int64
+	for _, m := range mods {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		path, err := module.EscapePath(m.Path)
+		if err != nil {
+			continue
+		}
+		version, err := module.EscapeVersion(m.Version)
+		if err != nil {
+			continue
+		}
+		zip := filepath.Join(modCache, "cache", "download", path, "@v", version+".zip")
+		if fi, err := os.Stat(zip); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// builtModule returns the version and go.sum hash of the main module
+// recorded in the binary at binPath, or empty strings if it has none (e.g.
+// it was built from a local directory).
+func builtModule(binPath string) (version, sum string) {
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return "", ""
+	}
+	return info.Main.Version, info.Main.Sum
+}
+
+// goEnv returns the value of a go environment variable as reported by
+// 'go env', or an empty string if it cannot be determined.
+func goEnv(key string) string {
+	out, err := exec.Command("go", "env", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// removeModCache deletes a module cache directory. The go command makes
+// cache contents read-only, so write permission is restored first.
+func removeModCache(dir string) error {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0o755)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
 func parseEnvFlags(flagsJSON string) map[string]string {
 	if flagsJSON == "" || flagsJSON == "{}" {
 		return nil
@@ -89,11 +228,43 @@ func marshalFlags(flags map[string]string) string {
 	return string(b)
 }
 
+// checkVerifiedAge returns an error if b was never verified or its last
+// verification is older than maxDays. A maxDays of 0 disables the check.
+func checkVerifiedAge(b *db.Binary, maxDays int) error {
+	if maxDays <= 0 {
+		return nil
+	}
+	age, ok := b.VerifiedAge()
+	if !ok {
+		return fmt.Errorf("%s has never been verified", b.Name)
+	}
+	if age > time.Duration(maxDays)*24*time.Hour {
+		return fmt.Errorf("%s was last verified %d days ago (limit %d)", b.Name, int(age.Hours()/24), maxDays)
+	}
+	return nil
+}
+
+// parseAge parses a duration that may use a day suffix (e.g. "90d") in
+// addition to the units accepted by time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid day count %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// truncate shortens s to n runes followed by "...", never splitting a
+// multi-byte character.
 func truncate(s string, n int) string {
-	if len(s) <= n {
+	r := []rune(s)
+	if len(r) <= n {
 		return s
 	}
-	return s[:n] + "..."
+	return string(r[:n]) + "..."
 }
 
 func parseGitHubOwnerRepo(pkg string) (owner, repo string, ok bool) {
@@ -108,7 +279,7 @@ func parseGitHubOwnerRepo(pkg string) (owner, repo string, ok bool) {
 }
 
 func fetchModulePath(client *http.Client, owner, repo, token string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/go.mod", owner, repo)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/contents/go.mod", owner, repo)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return "", err
@@ -139,7 +310,7 @@ func fetchModulePath(client *http.Client, owner, repo, token string) (string, er
 }
 
 func fetchLatestRelease(client *http.Client, owner, repo, token string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/releases/latest", owner, repo)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return "", err
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// isolate points the user's home, XDG directories, and GitHub token at
+// throwaway values for the duration of the test.
+func isolate(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	// With a token, commands pace their requests for the authenticated
+	// rate limit, which keeps the tests fast.
+	t.Setenv("GITHUB_TOKEN", "test-token")
+}
+
+// newTestDB creates a database with the full schema at path, or in a
+// temporary directory if path is empty, holding binaries, and returns its
+// path. Binaries without a build status are inserted as unknown.
+func newTestDB(t *testing.T, path string, binaries ...db.Binary) string {
+	t.Helper()
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "database.db")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.CreatePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := db.InitSchema(conn); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	for _, b := range binaries {
+		status := b.BuildStatus
+		if status == "" {
+			status = "unknown"
+		}
+		_, err := conn.Exec(
+			`INSERT INTO binaries (name, package, version, stars, is_primary, build_status) VALUES (?, ?, ?, ?, ?, ?)`,
+			b.Name, b.Package, b.Version, b.Stars, b.IsPrimary, status,
+		)
+		if err != nil {
+			t.Fatalf("insert %s: %v", b.Package, err)
+		}
+	}
+	return path
+}
+
+// openTestDB opens the database at path for the test's assertions.
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	conn, err := db.OpenPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// queryStrings returns the rows of a query selecting text columns, each
+// row's columns joined with spaces.
+func queryStrings(t *testing.T, conn *sql.DB, query string, args ...any) []string {
+	t.Helper()
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var out []string
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatal(err)
+		}
+		fields := make([]string, len(vals))
+		for i, v := range vals {
+			fields[i] = v.String
+		}
+		out = append(out, strings.Join(fields, " "))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// runAdmin runs gomanager-admin with args against the GitHub API at api,
+// failing the test if the command fails, and returns what it printed to
+// stdout.
+func runAdmin(t *testing.T, api string, args ...string) string {
+	t.Helper()
+	out, err := captureStdout(t, func() error {
+		rootCmd.SetArgs(append([]string{"--github-api", api}, args...))
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("gomanager-admin %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// captureStdout runs f with os.Stdout redirected, returning what it
+// printed.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	return <-done, err
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyDatabase string
+	historyLimit    int
+)
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Maximum number of verifications to show")
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history <package>",
+	Short: "Show past verification results for a package",
+	Long: `Lists the recorded verification attempts for a package, newest first,
+with the Go version each ran under. A package whose status alternates between
+confirmed and failed for the same version is reported as flaky; one that fails
+every attempt is reported as persistently failing.
+
+The argument may be a package path or a binary name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(historyDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		b, err := db.GetAnyByPackage(conn, args[0])
+		if errors.Is(err, db.ErrNotFound) {
+			b, err = db.GetAnyByName(conn, args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		records, err := db.GetBuildHistory(conn, b.Package, historyLimit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%s)\n", b.Package, b.BuildStatus)
+		if len(records) == 0 {
+			fmt.Println("No verification history recorded.")
+			return nil
+		}
+		fmt.Println()
+
+		for _, r := range records {
+			fmt.Printf("%s  %-10s %-12s %s", r.VerifiedAt.Format("2006-01-02 15:04"), r.Status, r.Version, r.GoVersion)
+			if r.Strategy != "" && r.Strategy != strategyDefault {
+				fmt.Printf("  via %s", r.Strategy)
+			}
+			fmt.Println()
+			if r.Error != "" {
+				fmt.Printf("    %s\n", truncate(r.Error, 200))
+			}
+		}
+
+		if summary := historySummary(records); summary != "" {
+			fmt.Printf("\n%s\n", summary)
+		}
+		return nil
+	},
+}
+
+// historySummary classifies a package's recent failures as flaky (the same
+// version both passed and failed) or persistent (every attempt failed).
+// It returns an empty string when neither applies.
+func historySummary(records []db.BuildRecord) string {
+	passed := make(map[string]bool)
+	failed := make(map[string]bool)
+	failures := 0
+	for _, r := range records {
+		if r.Status == "confirmed" {
+			passed[r.Version] = true
+		} else {
+			failed[r.Version] = true
+			failures++
+		}
+	}
+
+	for _, version := range slices.Sorted(maps.Keys(failed)) {
+		if passed[version] {
+			return fmt.Sprintf("Flaky: %s both passed and failed verification.", version)
+		}
+	}
+	if failures == len(records) && failures > 1 {
+		return fmt.Sprintf("Persistent: all %d recorded verifications failed.", failures)
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// homebrewDirs are the directories a tap-in-repo keeps its formulae in.
+var homebrewDirs = []string{"Formula", "HomebrewFormula"}
+
+// formulaInfo holds the hints extracted from a Homebrew formula.
+type formulaInfo struct {
+	// binary is the name of the installed executable, if found.
+	binary string
+	// mainPath is the main package directory relative to the repository root
+	// ("" for the root), if the formula builds from source.
+	mainPath string
+	// env holds allowlisted build environment variables set by the formula.
+	env map[string]string
+	// ldflags holds the linker flags passed to go build.
+	ldflags string
+}
+
+var (
+	// bin.install "foo" or bin.install "build/foo" => "bar"
+	formulaBinInstall = regexp.MustCompile(`bin\.install\s+"([^"]+)"(?:\s*=>\s*"([^"]+)")?`)
+	// bin/"foo" as used by go build -o and std_go_args(output: ...)
+	formulaBinPath = regexp.MustCompile(`bin\s*/\s*"([^"]+)"`)
+	// "./cmd/foo" package arguments
+	formulaMainPath = regexp.MustCompile(`"\./((?:cmd|cli|app)/[A-Za-z0-9._/-]+)"`)
+	// ENV["CGO_ENABLED"] = "0"
+	formulaEnv = regexp.MustCompile(`ENV\["([A-Z_]+)"\]\s*=\s*"([^"]*)"`)
+	// ldflags = "..." or ldflags: "..." or %W[...] word arrays
+	formulaLDFlags = regexp.MustCompile(`ldflags\s*(?:=|:)\s*(?:"([^"]*)"|%[wW]\[([^\]]*)\])`)
+)
+
+// parseFormula extracts the binary name and build hints from the Ruby
+// source of a Homebrew formula. Interpolated values (#{...}) are dropped
+// since they can't be resolved outside Homebrew.
+func parseFormula(src string) formulaInfo {
+	var info formulaInfo
+
+	if m := formulaBinInstall.FindStringSubmatch(src); m != nil {
+		name := m[1]
+		if m[2] != "" {
+			name = m[2]
+		}
+		info.binary = path.Base(name)
+	} else if m := formulaBinPath.FindStringSubmatch(src); m != nil {
+		info.binary = m[1]
+	}
+	if strings.Contains(info.binary, "#{") {
+		info.binary = ""
+	}
+
+	if m := formulaMainPath.FindStringSubmatch(src); m != nil {
+		info.mainPath = strings.TrimSuffix(m[1], "/")
+	}
+
+	for _, m := range formulaEnv.FindAllStringSubmatch(src, -1) {
+		if !db.IsAllowedBuildEnv(m[1]) || strings.Contains(m[2], "#{") {
+			continue
+		}
+		if info.env == nil {
+			info.env = make(map[string]string)
+		}
+		info.env[m[1]] = m[2]
+	}
+
+	if m := formulaLDFlags.FindStringSubmatch(src); m != nil {
+		flags := m[1]
+		if flags == "" {
+			flags = m[2]
+		}
+		info.ldflags = strings.Join(strings.Fields(flags), " ")
+	}
+	return info
+}
+
+// homebrewFormula fetches and parses the repository's own Homebrew formula.
+// It prefers a formula named after the repository and otherwise uses the
+// first one found. Returns nil if the repository has no formula.
+func (s *scanner) homebrewFormula(owner, repo string) *formulaInfo {
+	for _, dir := range homebrewDirs {
+		var formulae []string
+		for _, item := range s.listDir(owner, repo, dir) {
+			if item.Type == "file" && strings.HasSuffix(item.Name, ".rb") {
+				formulae = append(formulae, item.Name)
+			}
+		}
+		if len(formulae) == 0 {
+			continue
+		}
+
+		name := formulae[0]
+		for _, f := range formulae {
+			if strings.EqualFold(strings.TrimSuffix(f, ".rb"), repo) {
+				name = f
+				break
+			}
+		}
+
+		data, err := s.fetchFile(owner, repo, dir+"/"+name)
+		if err != nil {
+			// The formula exists even if we can't read it
+			return &formulaInfo{}
+		}
+		info := parseFormula(string(data))
+		return &info
+	}
+	return nil
+}
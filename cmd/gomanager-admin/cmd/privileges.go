@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	privilegesDatabase string
+	privilegesSetcap   string
+	privilegesNote     string
+	privilegesClear    bool
+)
+
+func init() {
+	privilegesCmd.Flags().StringVarP(&privilegesDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	privilegesCmd.Flags().StringVar(&privilegesSetcap, "setcap", "", `File capabilities the binary needs, in setcap's form (e.g. "cap_net_raw,cap_net_admin+ep")`)
+	privilegesCmd.Flags().StringVar(&privilegesNote, "note", "", "What the binary needs elevated privileges for, shown on install")
+	privilegesCmd.Flags().BoolVar(&privilegesClear, "clear", false, "Record that the binary needs no elevated privileges")
+	privilegesCmd.MarkFlagsMutuallyExclusive("clear", "setcap")
+	privilegesCmd.MarkFlagsMutuallyExclusive("clear", "note")
+	rootCmd.AddCommand(privilegesCmd)
+}
+
+var privilegesCmd = &cobra.Command{
+	Use:   "privileges <package>",
+	Short: "Record the capabilities or privileges a package needs to work",
+	Long: `Records that a tool needs elevated privileges to function, e.g. a network
+sniffer that opens raw sockets or a server that binds ports below 1024.
+
+--setcap gives the Linux file capabilities that grant what it needs, in
+setcap's text form. After installing or upgrading it, clients print the
+setcap command and, on a terminal, offer to run it with sudo once the user
+confirms. --note explains what the privileges are for; for tools that
+can't do with capabilities, it is the only instruction clients print (e.g.
+"run with sudo to capture on all interfaces"). Both are published in the
+slim database.
+
+With neither flag, the recorded privileges are shown. The argument may be a
+package path or a binary name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if privilegesSetcap != "" && !db.ValidCapabilities(privilegesSetcap) {
+			return fmt.Errorf("invalid --setcap %q (want e.g. cap_net_raw,cap_net_admin+ep)", privilegesSetcap)
+		}
+
+		conn, err := openAdminDB(privilegesDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		b, err := db.GetAnyByPackage(conn, args[0])
+		if errors.Is(err, db.ErrNotFound) {
+			b, err = db.GetAnyByName(conn, args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		changed := cmd.Flags().Changed
+		if !privilegesClear && !changed("setcap") && !changed("note") {
+			if b.Capabilities == "" && b.PrivilegeNote == "" {
+				fmt.Printf("%s needs no elevated privileges.\n", b.Package)
+				return nil
+			}
+			fmt.Println(b.Package)
+			if b.Capabilities != "" {
+				fmt.Printf("  Capabilities: %s\n", b.Capabilities)
+			}
+			if b.PrivilegeNote != "" {
+				fmt.Printf("  Note:         %s\n", b.PrivilegeNote)
+			}
+			return nil
+		}
+
+		caps, note := b.Capabilities, b.PrivilegeNote
+		if changed("setcap") {
+			caps = privilegesSetcap
+		}
+		if changed("note") {
+			note = privilegesNote
+		}
+		if privilegesClear {
+			caps, note = "", ""
+		}
+		if err := db.SetPrivileges(conn, b.ID, caps, note); err != nil {
+			return fmt.Errorf("cannot record privileges of %s: %w", b.Package, err)
+		}
+		if caps == "" && note == "" {
+			fmt.Printf("Recorded that %s needs no elevated privileges.\n", b.Package)
+		} else {
+			fmt.Printf("Recorded the privileges %s needs.\n", b.Package)
+		}
+		return nil
+	},
+}
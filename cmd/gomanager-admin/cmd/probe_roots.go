@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -19,7 +18,7 @@ var (
 
 func init() {
 	probeRootsCmd.Flags().IntVarP(&probeBatchSize, "batch-size", "n", 50, "Max repositories to probe")
-	probeRootsCmd.Flags().StringVarP(&probeDatabase, "database", "d", "", "Path to database.db (default: ~/.config/gomanager/database.db)")
+	probeRootsCmd.Flags().StringVarP(&probeDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
 	rootCmd.AddCommand(probeRootsCmd)
 }
 
@@ -34,14 +33,7 @@ It reads go.mod to resolve the actual module path, handling v2+ modules
 (e.g. github.com/mikefarah/yq/v4) where the install path differs from the
 GitHub URL.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var conn *sql.DB
-		var err error
-
-		if probeDatabase != "" {
-			conn, err = db.OpenPath(probeDatabase)
-		} else {
-			conn, err = db.Open()
-		}
+		conn, err := openAdminDB(probeDatabase)
 		if err != nil {
 			return err
 		}
@@ -91,10 +83,7 @@ GitHub URL.`,
 
 			fmt.Printf("[%d/%d] Probing %s\n", i+1, len(candidates), installPath)
 
-			ok2, resultFlags, buildErr := tryGoInstall(installPath, nil)
-			if !ok2 {
-				ok2, resultFlags, buildErr = tryGoInstall(installPath, map[string]string{"CGO_ENABLED": "0"})
-			}
+			ok2, resultFlags, _, buildErr, _ := buildWithFallbacks(installPath, nil, installRun{})
 
 			if ok2 {
 				discovered++
@@ -118,6 +107,9 @@ GitHub URL.`,
 					"confirmed",
 					flagsJSON,
 				)
+				if err == nil {
+					err = db.SetDiscovery(conn, modulePath, "", sourceRootProbe)
+				}
 				if err != nil {
 					fmt.Printf("  Warning: failed to insert: %v\n", err)
 				} else {
@@ -126,6 +118,9 @@ GitHub URL.`,
 						fmt.Printf(" (%s)", flagsJSON)
 					}
 					fmt.Println()
+					// The probe built it, but verify records its build
+					// history, cost, and platforms
+					enqueueJob(conn, modulePath, db.JobRootProbe)
 				}
 			} else {
 				failed++
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryJSON     bool
+	queryMaxWidth int
+)
+
+func init() {
+	dbQueryCmd.Flags().BoolVar(&queryJSON, "json", false, "Print the rows as a JSON array of objects keyed by column")
+	dbQueryCmd.Flags().IntVarP(&queryMaxWidth, "max-width", "w", 60, "Truncate table cells to this many characters (0 = no limit)")
+	dbCmd.AddCommand(dbQueryCmd)
+}
+
+var dbQueryCmd = &cobra.Command{
+	Use:   "query <sql>",
+	Short: "Run a read-only SQL query against the database",
+	Long: `Runs an SQL query against the database and prints the rows as a table,
+or with --json as an array of objects keyed by column name, for answering
+ad-hoc questions without a separate sqlite client:
+
+  gomanager-admin db query "SELECT build_status, COUNT(*) FROM binaries GROUP BY 1"
+
+The database is opened read-only: statements that would modify it (INSERT,
+UPDATE, DELETE, CREATE, ...) fail, as do ATTACH and VACUUM, which could
+create files, so use the other admin commands for changes. Pass - to read the query from stdin. Table cells are shown on one
+line and truncated to --max-width characters; NULL is shown as NULL.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := args[0]
+		if query == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("cannot read the query: %w", err)
+			}
+			query = string(data)
+		}
+		if strings.TrimSpace(query) == "" {
+			return fmt.Errorf("empty query")
+		}
+
+		path := dbDatabase
+		if path == "" {
+			var err error
+			if path, err = db.DBPath(); err != nil {
+				return err
+			}
+		}
+		conn, err := db.OpenQueryOnly(path)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		result, err := db.RunQuery(conn, query)
+		if err != nil {
+			return err
+		}
+		if queryJSON {
+			return printQueryJSON(result)
+		}
+		return printQueryTable(result)
+	},
+}
+
+// printQueryJSON prints the rows of result as a JSON array of objects.
+func printQueryJSON(result *db.QueryResult) error {
+	records := make([]queryRecord, len(result.Rows))
+	for i, row := range result.Rows {
+		records[i] = queryRecord{columns: result.Columns, values: row}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// queryRecord is the JSON form of a query row: an object keyed by column,
+// in column order.
+type queryRecord struct {
+	columns []string
+	values  []any
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r queryRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		v := r.values[i]
+		if b, ok := v.([]byte); ok {
+			v = queryText(b)
+		}
+		key, err := json.Marshal(col)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// printQueryTable prints result as aligned columns followed by the row
+// count.
+func printQueryTable(result *db.QueryResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = queryCell(v)
+			if queryMaxWidth > 0 {
+				cells[i] = truncate(cells[i], queryMaxWidth)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(result.Rows) == 1 {
+		fmt.Println("(1 row)")
+	} else {
+		fmt.Printf("(%d rows)\n", len(result.Rows))
+	}
+	return nil
+}
+
+// queryCell formats a value for a table cell on a single line.
+func queryCell(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		s = queryText(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339)
+	default:
+		s = fmt.Sprint(v)
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// queryText returns a BLOB as text, or as an SQL hex literal if it isn't
+// valid UTF-8.
+func queryText(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return "x'" + hex.EncodeToString(b) + "'"
+}
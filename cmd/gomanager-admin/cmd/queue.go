@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queueDatabase string
+	queueLimit    int
+	queueReverify bool
+	queueRecheck  bool
+	queueMaxAge   string
+)
+
+func init() {
+	queueCmd.PersistentFlags().StringVarP(&queueDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	queueListCmd.Flags().IntVarP(&queueLimit, "limit", "n", 50, "Maximum number of jobs to show (0 = all)")
+	queueSeedCmd.Flags().BoolVarP(&queueReverify, "reverify", "r", false, "Also queue previously failed and codegen packages")
+	queueSeedCmd.Flags().BoolVar(&queueRecheck, "recheck", false, "Also queue confirmed packages that received version updates")
+	queueSeedCmd.Flags().StringVar(&queueMaxAge, "max-age", "", "Also queue confirmed packages last verified longer ago than this (e.g. 90d, 72h)")
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueSeedCmd)
+	queueCmd.AddCommand(queueRetryCmd)
+	rootCmd.AddCommand(queueCmd)
+}
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Inspect and manage the verification job queue",
+	Long: `The job queue holds packages waiting for 'verify --queue'. scan queues
+newly discovered packages, probe-roots newly found module roots,
+update-versions packages with a new release, and approve packages let out of
+quarantine, and import-feedback packages users report failing. Jobs are
+claimed highest priority first: log2(stars+1), plus 8 for a package never
+verified or a point per 30 days since its last verification (at most 12),
+plus 4 if its version changed since, plus 2*log2(installs+1) for the
+installs clients reported in the last 90 days (see import-telemetry), minus
+2 per failed attempt.
+
+A failed build is retried after 1h, 2h, 4h, and so on; after 5 attempts the
+job is dead and stays in the queue until 'queue retry'. Jobs for quarantined
+packages wait until they are approved.`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued jobs, highest priority first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openQueueDB()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		jobs, err := db.ListJobs(conn)
+		if err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			fmt.Println("The queue is empty.")
+			return nil
+		}
+
+		now := time.Now().UTC()
+		dead, waiting := 0, 0
+		for i, j := range jobs {
+			if j.Dead() {
+				dead++
+			} else if j.Quarantined || j.NotBefore.After(now) {
+				waiting++
+			}
+			if queueLimit > 0 && i >= queueLimit {
+				continue
+			}
+			fmt.Printf("%6.1f  %-60s %-15s %s\n", j.Priority, truncate(j.Package, 60), j.Reason, jobState(j, now))
+			if j.LastError != "" {
+				fmt.Printf("        %s\n", truncate(j.LastError, 200))
+			}
+		}
+		if queueLimit > 0 && len(jobs) > queueLimit {
+			fmt.Printf("... and %d more\n", len(jobs)-queueLimit)
+		}
+		fmt.Printf("\n%d jobs: %d ready, %d waiting, %d dead\n", len(jobs), len(jobs)-waiting-dead, waiting, dead)
+		return nil
+	},
+}
+
+// jobState describes whether j can be claimed now, and if not, why.
+func jobState(j db.Job, now time.Time) string {
+	switch {
+	case j.Dead():
+		return fmt.Sprintf("dead after %d attempts", j.Attempts)
+	case j.Quarantined:
+		return "quarantined"
+	case !j.ClaimedAt.IsZero() && now.Sub(j.ClaimedAt) <= db.JobLease:
+		return "claimed " + j.ClaimedAt.Local().Format("2006-01-02 15:04")
+	case j.NotBefore.After(now):
+		return fmt.Sprintf("retry %d after %s", j.Attempts+1, j.NotBefore.Local().Format("2006-01-02 15:04"))
+	case j.Attempts > 0:
+		return fmt.Sprintf("ready (attempt %d)", j.Attempts+1)
+	}
+	return "ready"
+}
+
+var queueSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Queue packages found by the verify queries",
+	Long: `Queues every unverified package, for databases created before the queue
+existed or packages added outside the queue producers. --reverify,
+--recheck, and --max-age select more packages, as they do for verify.
+Packages already queued keep their place but have their retries reset.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openQueueDB()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		var cutoff time.Time
+		if queueMaxAge != "" {
+			maxAge, err := parseAge(queueMaxAge)
+			if err != nil {
+				return fmt.Errorf("invalid --max-age: %w", err)
+			}
+			cutoff = time.Now().Add(-maxAge)
+		}
+		queued, err := seedQueue(conn, queueReverify, queueRecheck, cutoff)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d packages.\n", queued)
+		return nil
+	},
+}
+
+// seedQueue queues the packages verify would otherwise query for: the
+// unverified ones, plus failed ones with reverify, updated confirmed ones
+// with recheck, and confirmed ones last verified before a non-zero cutoff.
+// It returns how many packages were queued.
+func seedQueue(conn *sql.DB, reverify, recheck bool, cutoff time.Time) (int, error) {
+	// A negative LIMIT means no limit to SQLite
+	const all = -1
+
+	statuses := []string{"unknown", "pending"}
+	if reverify {
+		statuses = append(statuses, "failed", db.StatusCodegen)
+	}
+	binaries, err := db.GetUnverified(conn, statuses, all)
+	if err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	if recheck {
+		stale, err := db.GetStaleConfirmed(conn, all)
+		if err != nil {
+			return 0, fmt.Errorf("stale confirmed query failed: %w", err)
+		}
+		binaries = appendUnique(binaries, stale)
+	}
+	if !cutoff.IsZero() {
+		aged, err := db.GetVerifiedBefore(conn, cutoff, all)
+		if err != nil {
+			return 0, fmt.Errorf("aged confirmed query failed: %w", err)
+		}
+		binaries = appendUnique(binaries, aged)
+	}
+
+	for _, b := range binaries {
+		if err := db.EnqueueJob(conn, b.Package, db.JobSeeded); err != nil {
+			return 0, fmt.Errorf("queue %s: %w", b.Package, err)
+		}
+	}
+	return len(binaries), nil
+}
+
+var queueRetryCmd = &cobra.Command{
+	Use:   "retry [<package>...]",
+	Short: "Reset the retries of dead jobs",
+	Long: `Gives dead jobs (those that failed 5 times) another 5 attempts, e.g. after
+a toolchain fix. With package arguments, only those packages are requeued,
+whether or not their jobs are dead, and packages not in the queue are
+added.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openQueueDB()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if len(args) == 0 {
+			n, err := db.RequeueDeadJobs(conn)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Requeued %d dead jobs.\n", n)
+			return nil
+		}
+		for _, pkg := range args {
+			if _, err := db.GetAnyByPackage(conn, pkg); err != nil {
+				return err
+			}
+			if err := db.EnqueueJob(conn, pkg, db.JobRequeued); err != nil {
+				return err
+			}
+			fmt.Printf("Requeued %s\n", pkg)
+		}
+		return nil
+	},
+}
+
+// openQueueDB opens the database for the queue commands, creating the jobs
+// table if needed.
+func openQueueDB() (*sql.DB, error) {
+	conn, err := openAdminDB(queueDatabase)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.MigrateSchema(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("schema migration failed: %w", err)
+	}
+	return conn, nil
+}
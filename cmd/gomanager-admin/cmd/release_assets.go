@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/jmelahman/gomanager/internal/release"
+)
+
+// binPkgbuildOpts locates b's Linux release archives for its version,
+// downloads them to compute their sha256 (checking it against the release's
+// checksum file when there is one), and finds the binary, license, and
+// readme inside them. The license and readme are only installed when they
+// are at the same path in every archive.
+func binPkgbuildOpts(b *db.Binary) (pkgbuild.BinOptions, error) {
+	var opts pkgbuild.BinOptions
+	owner, repo, ok := parseGitHubOwnerRepo(b.Package)
+	if !ok {
+		return opts, fmt.Errorf("%s is not hosted on GitHub", b.Package)
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	client := &http.Client{Timeout: 5 * time.Minute}
+
+	assets, err := release.FetchAssets(client, githubAPI, owner, repo, token, b.Version)
+	if err != nil {
+		return opts, err
+	}
+	archives := release.Archives(assets, "linux")
+	if len(archives) == 0 {
+		return opts, fmt.Errorf("release %s of %s/%s has no Linux archives", b.Version, owner, repo)
+	}
+
+	sums, err := release.Checksums(client, assets)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.LicenseID = fetchLicenseID(owner, repo, token, b.Version)
+	var licenses, readmes []string
+	for _, ap := range []string{"amd64", "arm64", "arm", "386"} {
+		a, ok := archives[ap]
+		arch := pkgbuild.ArchFor(ap)
+		if !ok || arch == "" {
+			continue
+		}
+		data, err := release.Download(client, a.URL)
+		if err != nil {
+			return opts, err
+		}
+		sum, err := release.Verify(data, a.Name, sums)
+		if err != nil {
+			return opts, err
+		}
+
+		ext := release.ArchiveExt(a.Name)
+		files, err := release.Files(data, ext)
+		if err != nil {
+			return opts, fmt.Errorf("cannot read %s: %w", a.Name, err)
+		}
+		binary := release.Find(files, []string{b.Name})
+		if binary == "" {
+			return opts, fmt.Errorf("%s does not contain a %q binary", a.Name, b.Name)
+		}
+		licenses = append(licenses, release.Find(files, licenseNames))
+		readmes = append(readmes, release.Find(files, readmeNames))
+		opts.Assets = append(opts.Assets, pkgbuild.BinAsset{
+			Arch: arch, URL: a.URL, Ext: ext, SHA256: sum, Binary: binary,
+		})
+	}
+	opts.LicenseFile = commonPath(licenses)
+	opts.ReadmeFile = commonPath(readmes)
+	return opts, nil
+}
+
+// commonPath returns the path if every entry of paths is the same, or "".
+func commonPath(paths []string) string {
+	for _, p := range paths {
+		if p != paths[0] {
+			return ""
+		}
+	}
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[0]
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var renovateOutput string
+
+func init() {
+	exportRenovateCmd.Flags().StringVarP(&renovateOutput, "output", "o", "", "Directory to write the datasource files to (required)")
+	exportRenovateCmd.Flags().IntVar(&exportMaxVerifyAge, "max-verify-age", 0, "Leave out packages last verified more than this many days ago (0 = no limit)")
+	exportRenovateCmd.MarkFlagRequired("output")
+	exportCmd.AddCommand(exportRenovateCmd)
+}
+
+// renovateIndex is the file listing every exported tool.
+const renovateIndex = "index.json"
+
+var exportRenovateCmd = &cobra.Command{
+	Use:   "renovate",
+	Short: "Export confirmed versions as a Renovate custom datasource",
+	Long: `Writes a Renovate custom datasource: for every primary binary whose
+current version is confirmed to build, <output>/<name>.json and
+<output>/<package>.json list the versions verification has confirmed, in
+the format Renovate's custom datasources read by default:
+
+  {"releases": [{"version": "v0.13.0"}, {"version": "v0.13.1"}],
+   "sourceUrl": "https://github.com/wagoodman/dive"}
+
+Entries without a release version ("latest") are left out. Of several
+binaries with the same name, the most starred gets the name.
+<output>/index.json maps every exported name to its package and latest
+confirmed version.
+
+Host the directory (e.g. next to the web frontend) and point a Renovate
+custom datasource at it to bump the tools in gomanager manifests:
+
+  "customDatasources": {
+    "gomanager": {
+      "defaultRegistryUrlTemplate": "https://example.com/renovate/{{packageName}}.json"
+    }
+  }`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB("")
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return err
+		}
+		confirmed, err := db.ConfirmedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		index := make(map[string]renovateTool)
+		skipped := 0
+		for i := range binaries {
+			b := &binaries[i]
+			// Entries at "latest" have no version for Renovate to bump to
+			if !b.IsPrimary || !b.VersionConfirmed() || b.Version == "latest" {
+				continue
+			}
+			if err := checkVerifiedAge(b, exportMaxVerifyAge); err != nil {
+				fmt.Printf("Skipping %s: %v\n", b.Name, err)
+				skipped++
+				continue
+			}
+
+			data, err := json.MarshalIndent(newRenovateDatasource(b, confirmed[b.Package]), "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := writeRenovateFile(b.Package+".json", data); err != nil {
+				return err
+			}
+			// Binaries are ordered by stars, so the most popular of
+			// several with the same name gets <output>/<name>.json.
+			if _, taken := index[b.Name]; taken {
+				continue
+			}
+			if err := writeRenovateFile(b.Name+".json", data); err != nil {
+				return err
+			}
+			index[b.Name] = renovateTool{Package: b.Package, Version: b.Version}
+		}
+
+		data, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeRenovateFile(renovateIndex, data); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote datasource files for %d tools to %s (%d skipped)\n", len(index), renovateOutput, skipped)
+		return nil
+	},
+}
+
+// renovateDatasource is a package's file in the Renovate custom datasource
+// format.
+type renovateDatasource struct {
+	Releases  []renovateRelease `json:"releases"`
+	SourceURL string            `json:"sourceUrl,omitempty"`
+	Homepage  string            `json:"homepage,omitempty"`
+}
+
+// renovateRelease is a version listed in a datasource file.
+type renovateRelease struct {
+	Version string `json:"version"`
+}
+
+// renovateTool is an entry of the datasource index.
+type renovateTool struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+}
+
+// newRenovateDatasource returns b's datasource file, listing its current
+// version and the earlier versions in history, oldest first.
+func newRenovateDatasource(b *db.Binary, history []string) renovateDatasource {
+	versions := slices.Clone(history)
+	if !slices.Contains(versions, b.Version) {
+		versions = append(versions, b.Version)
+	}
+	semver.Sort(versions)
+
+	d := renovateDatasource{SourceURL: b.RepoURL, Homepage: b.Homepage}
+	for _, v := range versions {
+		d.Releases = append(d.Releases, renovateRelease{Version: v})
+	}
+	return d
+}
+
+// writeRenovateFile writes data to name under the output directory,
+// creating parent directories for package paths.
+func writeRenovateFile(name string, data []byte) error {
+	path := filepath.Join(renovateOutput, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cannot create output directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
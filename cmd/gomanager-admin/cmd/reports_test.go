@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// renderStable runs gomanager-admin with args several times, failing the
+// test if the output changes between runs, and returns it.
+func renderStable(t *testing.T, args ...string) string {
+	t.Helper()
+	first := runAdmin(t, "", args...)
+	for range 3 {
+		if again := runAdmin(t, "", args...); again != first {
+			t.Fatalf("gomanager-admin %s differs between runs:\n%s\n---\n%s", strings.Join(args, " "), first, again)
+		}
+	}
+	return first
+}
+
+func TestApproveListOrder(t *testing.T) {
+	isolate(t)
+	quarantined := func(name, pkg string, stars int) db.Binary {
+		return db.Binary{Name: name, Package: pkg, Version: "v1.0.0", Stars: stars, BuildStatus: db.StatusQuarantined}
+	}
+	path := newTestDB(t, "",
+		quarantined("zeta", "github.com/b/zeta", 100),
+		quarantined("alpha", "github.com/c/alpha", 100),
+		quarantined("mid", "github.com/a/mid", 50),
+		quarantined("beta", "github.com/a/beta", 100),
+		db.Binary{Name: "done", Package: "github.com/a/done", Version: "v1.0.0", Stars: 500, BuildStatus: "confirmed"},
+	)
+	out := renderStable(t, "approve", "-d", path)
+	t.Cleanup(func() { approveDatabase = "" })
+
+	var got []string
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[3] == "stars" {
+			got = append(got, fields[1])
+		}
+	}
+	// Most stars first, ties by package path.
+	want := []string{"github.com/a/beta", "github.com/b/zeta", "github.com/c/alpha", "github.com/a/mid"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("approve listed %q, want %q", got, want)
+	}
+}
+
+func TestHistorySummaryOrder(t *testing.T) {
+	isolate(t)
+	path := newTestDB(t, "",
+		db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "v1.1.0", BuildStatus: "confirmed"})
+	conn := openTestDB(t, path)
+	if err := db.MigrateSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+	// Two versions both passed and failed; the report names the same one
+	// every time.
+	for _, r := range []db.BuildRecord{
+		{Version: "v1.1.0", Status: "failed"},
+		{Version: "v1.0.0", Status: "failed"},
+		{Version: "v1.1.0", Status: "confirmed"},
+		{Version: "v1.0.0", Status: "confirmed"},
+	} {
+		r.Package = "github.com/acme/widget"
+		if err := db.AppendBuildHistory(conn, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := renderStable(t, "history", "widget", "-d", path)
+	if !strings.Contains(out, "Flaky: v1.0.0 both passed and failed verification.") {
+		t.Errorf("history doesn't report the first flaky version:\n%s", out)
+	}
+	t.Cleanup(func() { historyDatabase = "" })
+}
+
+func TestDBCheckOrder(t *testing.T) {
+	isolate(t)
+	path := newTestDB(t, "",
+		db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0", BuildStatus: "confirmed"})
+	conn := openTestDB(t, path)
+	for _, stmt := range []string{
+		`PRAGMA ignore_check_constraints = ON`,
+		`INSERT INTO binaries (name, package, build_status) VALUES ('a', 'github.com/acme/a', 'sandboxed')`,
+		`INSERT INTO binaries (name, package, build_status) VALUES ('b', 'github.com/acme/b', 'sandboxed')`,
+		`INSERT INTO binaries (name, package, build_status) VALUES ('c', 'github.com/acme/c', 'archived')`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { dbDatabase = "" })
+
+	var first string
+	for i := range 4 {
+		out, err := captureStdout(t, func() error {
+			rootCmd.SetArgs([]string{"db", "check", "-d", path})
+			return rootCmd.Execute()
+		})
+		if err == nil {
+			t.Fatalf("db check passed a database with unknown statuses:\n%s", out)
+		}
+		if i == 0 {
+			first = out
+		} else if out != first {
+			t.Fatalf("db check differs between runs:\n%s\n---\n%s", first, out)
+		}
+	}
+	// Ordered by status.
+	var got []string
+	for _, line := range strings.Split(first, "\n") {
+		if strings.Contains(line, "unknown status") {
+			got = append(got, line)
+		}
+	}
+	want := []string{`✗ 1 binaries have unknown status "archived"`, `✗ 2 binaries have unknown status "sandboxed"`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("db check reported:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildPkgbuildOptsPrefersSortedFiles(t *testing.T) {
+	files := map[string]bool{
+		"go.mod": true, "LICENSE-MIT": true, "LICENSE-APACHE": true,
+		"readme.rst": true, "Readme.md": true,
+	}
+	for range 10 {
+		opts := buildPkgbuildOpts(maps.Clone(files))
+		if opts.LicenseFile != "LICENSE-APACHE" || opts.ReadmeFile != "Readme.md" || !opts.HasGoMod {
+			t.Fatalf("buildPkgbuildOpts = %+v, want LICENSE-APACHE and Readme.md", opts)
+		}
+	}
+}
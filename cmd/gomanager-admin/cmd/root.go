@@ -1,12 +1,34 @@
 package cmd
 
 import (
+	"os"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
 // version is set by goreleaser via ldflags.
 var version = "dev"
 
+// githubAPI is the base URL of the GitHub REST API. It defaults to
+// $GITHUB_API_URL (set by GitHub Actions, including on GitHub Enterprise
+// Server) and can be pointed at a fake server such as internal/githubtest.
+var githubAPI = defaultGitHubAPI()
+
+func defaultGitHubAPI() string {
+	if u := os.Getenv("GITHUB_API_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return "https://api.github.com"
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&githubAPI, "github-api", githubAPI, "Base URL of the GitHub REST API")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		githubAPI = strings.TrimSuffix(githubAPI, "/")
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:     "gomanager-admin",
 	Short:   "GoManager admin tools for database maintenance and CI",
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/jmelahman/gomanager/internal/db"
@@ -86,11 +90,15 @@ var defaultSearchQueries = []string{
 var (
 	scanDatabase    string
 	scanScannedFile string
+	scanDryRun      bool
+	scanJSON        string
 )
 
 func init() {
 	scanCmd.Flags().StringVarP(&scanDatabase, "database", "d", "./database.db", "Path to database.db")
 	scanCmd.Flags().StringVar(&scanScannedFile, "scanned-repos", "./scanned_repos.json", "Path to scanned repos tracking file")
+	scanCmd.Flags().BoolVar(&scanDryRun, "dry-run", false, "Report what would be added without modifying the database or tracking file")
+	scanCmd.Flags().StringVar(&scanJSON, "json", "", "With --dry-run, also write the would-add report as JSON to this file")
 	rootCmd.AddCommand(scanCmd)
 }
 
@@ -101,9 +109,26 @@ type githubRepo struct {
 	Description string `json:"description"`
 	Stars       int    `json:"stargazers_count"`
 	HTMLURL     string `json:"html_url"`
+	Homepage    string `json:"homepage"`
 	Owner       struct {
 		Login string `json:"login"`
 	} `json:"owner"`
+	License *struct {
+		SPDXID string `json:"spdx_id"`
+	} `json:"license"`
+	Topics []string `json:"topics"`
+
+	// query is the search query that first found the repository.
+	query string
+}
+
+// spdxID returns the repository's SPDX license identifier, or an empty
+// string if GitHub couldn't identify one.
+func (r *githubRepo) spdxID() string {
+	if r.License == nil || r.License.SPDXID == "NOASSERTION" {
+		return ""
+	}
+	return r.License.SPDXID
 }
 
 // entrypoint describes a discovered binary entrypoint in a repository.
@@ -111,10 +136,28 @@ type entrypoint struct {
 	binaryName string
 	pathSuffix string // e.g. "cmd/foo" or "" for root
 	isPrimary  bool
+	// env and ldflags are build hints from the source that detected the
+	// entrypoint (e.g. a Homebrew formula), if any.
+	env     map[string]string
+	ldflags string
+	// source names the heuristic that detected the entrypoint, and is
+	// recorded as the package's discovery provenance.
+	source string
 }
 
+// Entrypoint sources, recorded by scan and probe-roots and shown by why.
+const (
+	sourceRootMain   = "root main.go"
+	sourceCmdDir     = "cmd/ directory"
+	sourceGoreleaser = "goreleaser config"
+	sourceHomebrew   = "homebrew formula"
+	sourceRootProbe  = "module root probe"
+)
+
 // scanner wraps an HTTP client with GitHub token and rate-limit awareness.
 type scanner struct {
+	// ctx cuts rate-limit waits short when the command is interrupted.
+	ctx    context.Context
 	client *http.Client
 	token  string
 }
@@ -122,6 +165,12 @@ type scanner struct {
 // apiGet performs a GET request with authorization and rate-limit handling.
 // The caller is responsible for closing the response body.
 func (s *scanner) apiGet(url string) (*http.Response, error) {
+	return s.apiGetAccept(url, "application/vnd.github.v3+json")
+}
+
+// apiGetAccept is apiGet with an explicit Accept header, e.g. to request raw
+// file contents.
+func (s *scanner) apiGetAccept(url, accept string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -129,7 +178,7 @@ func (s *scanner) apiGet(url string) (*http.Response, error) {
 	if s.token != "" {
 		req.Header.Set("Authorization", "token "+s.token)
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Accept", accept)
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -145,7 +194,10 @@ func (s *scanner) apiGet(url string) (*http.Response, error) {
 			wait := resetTime - time.Now().Unix() + 5
 			if wait > 0 {
 				fmt.Printf("Rate limit low (%d remaining). Sleeping %ds...\n", rem, wait)
-				time.Sleep(time.Duration(wait) * time.Second)
+				select {
+				case <-s.ctx.Done():
+				case <-time.After(time.Duration(wait) * time.Second):
+				}
 			}
 		}
 	}
@@ -155,7 +207,7 @@ func (s *scanner) apiGet(url string) (*http.Response, error) {
 
 // checkRateLimit proactively checks the rate limit before starting.
 func (s *scanner) checkRateLimit() {
-	resp, err := s.apiGet("https://api.github.com/rate_limit")
+	resp, err := s.apiGet(githubAPI + "/rate_limit")
 	if err != nil {
 		return
 	}
@@ -163,6 +215,10 @@ func (s *scanner) checkRateLimit() {
 	resp.Body.Close()
 }
 
+// searchInterval is the pause between search requests, to respect the
+// search API's rate limit (30 requests a minute authenticated).
+var searchInterval = 2 * time.Second
+
 // searchSortOrders defines the sort strategies used for each query.
 // Using multiple sort orders surfaces different repos: "stars" finds popular
 // ones while "updated" finds actively maintained ones that may be less known.
@@ -170,7 +226,8 @@ var searchSortOrders = []string{"stars", "updated"}
 
 // searchRepos discovers Go CLI repositories via the GitHub search API.
 // Each query is run with multiple sort orders and filters out forks and
-// archived repositories at the query level to save API calls.
+// archived repositories at the query level to save API calls. It returns
+// the context's error if the scan is interrupted.
 func (s *scanner) searchRepos(scannedRepos map[string]bool) ([]githubRepo, error) {
 	seenIDs := make(map[int]bool)
 	var allRepos []githubRepo
@@ -181,8 +238,11 @@ func (s *scanner) searchRepos(scannedRepos map[string]bool) ([]githubRepo, error
 
 		for _, sortOrder := range searchSortOrders {
 			for page := 1; page <= maxPagesPerQuery; page++ {
+				if err := s.ctx.Err(); err != nil {
+					return nil, err
+				}
 				url := fmt.Sprintf(
-					"https://api.github.com/search/repositories?q=%s&sort=%s&order=desc&per_page=%d&page=%d",
+					githubAPI+"/search/repositories?q=%s&sort=%s&order=desc&per_page=%d&page=%d",
 					query, sortOrder, resultsPerPage, page,
 				)
 
@@ -224,12 +284,12 @@ func (s *scanner) searchRepos(scannedRepos map[string]bool) ([]githubRepo, error
 					repoKey := item.Owner.Login + "/" + item.Name
 					if !seenIDs[item.ID] && !scannedRepos[repoKey] {
 						seenIDs[item.ID] = true
+						item.query = baseQuery
 						allRepos = append(allRepos, item)
 					}
 				}
 
-				// Respect search API rate limit (30 req/min authenticated)
-				time.Sleep(2 * time.Second)
+				time.Sleep(searchInterval)
 
 				// Stop paging if we've seen all results
 				if page*resultsPerPage >= result.TotalCount {
@@ -246,7 +306,7 @@ func (s *scanner) searchRepos(scannedRepos map[string]bool) ([]githubRepo, error
 
 // checkFileExists checks whether a file exists in a GitHub repository.
 func (s *scanner) checkFileExists(owner, repo, path string) bool {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/contents/%s", owner, repo, path)
 	resp, err := s.apiGet(url)
 	if err != nil {
 		return false
@@ -256,19 +316,39 @@ func (s *scanner) checkFileExists(owner, repo, path string) bool {
 	return resp.StatusCode == 200
 }
 
-// hasGoreleaserConfig checks if the repo has a goreleaser configuration file.
-func (s *scanner) hasGoreleaserConfig(owner, repo string) bool {
-	for _, path := range []string{
-		".goreleaser.yml",
-		".goreleaser.yaml",
-		"goreleaser.yml",
-		"goreleaser.yaml",
-	} {
-		if s.checkFileExists(owner, repo, path) {
-			return true
+// fetchFile returns the raw contents of a file in a GitHub repository.
+func (s *scanner) fetchFile(owner, repo, path string) ([]byte, error) {
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/contents/%s", owner, repo, path)
+	resp, err := s.apiGetAccept(url, "application/vnd.github.v3.raw")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// goreleaserConfig fetches and parses the repo's goreleaser configuration.
+// rootFiles is the repository root listing used to pick the filename without
+// probing each candidate; if it is nil every candidate is tried. Returns nil
+// if the repo has no (parseable) goreleaser config.
+func (s *scanner) goreleaserConfig(owner, repo string, rootFiles map[string]bool) *goreleaserConfig {
+	for _, path := range goreleaserFiles {
+		if rootFiles != nil && !rootFiles[path] {
+			continue
+		}
+		data, err := s.fetchFile(owner, repo, path)
+		if err != nil {
+			continue
+		}
+		if cfg := parseGoreleaser(data); cfg != nil {
+			return cfg
 		}
 	}
-	return false
+	return nil
 }
 
 // findEntrypoints discovers CLI binary entrypoints in a Go repository.
@@ -278,16 +358,25 @@ func (s *scanner) hasGoreleaserConfig(owner, repo string) bool {
 //  2. cmd/ subdirectories (primary if single entry or name matches repo)
 //  3. Goreleaser config as a fallback (implies the repo produces binaries)
 //  4. Homebrew formula as a fallback (strong signal for installable binaries)
-func (s *scanner) findEntrypoints(owner, repo string) []entrypoint {
+//
+// rootFiles is the listing of the repository root (nil if unavailable) and
+// hasGoreleaser reports whether a goreleaser config was found.
+func (s *scanner) findEntrypoints(owner, repo string, rootFiles map[string]bool, hasGoreleaser bool) []entrypoint {
 	var entrypoints []entrypoint
 
 	// Check for root-level main.go (always primary)
-	hasRoot := s.checkFileExists(owner, repo, "main.go")
+	var hasRoot bool
+	if rootFiles != nil {
+		hasRoot = rootFiles["main.go"]
+	} else {
+		hasRoot = s.checkFileExists(owner, repo, "main.go")
+	}
 	if hasRoot {
 		entrypoints = append(entrypoints, entrypoint{
 			binaryName: repo,
 			pathSuffix: "",
 			isPrimary:  true,
+			source:     sourceRootMain,
 		})
 	}
 
@@ -305,6 +394,7 @@ func (s *scanner) findEntrypoints(owner, repo string) []entrypoint {
 			binaryName: cmd,
 			pathSuffix: "cmd/" + cmd,
 			isPrimary:  isPrimary,
+			source:     sourceCmdDir,
 		})
 	}
 
@@ -313,29 +403,46 @@ func (s *scanner) findEntrypoints(owner, repo string) []entrypoint {
 	}
 
 	// Goreleaser fallback: implies the repo produces binaries
-	if s.hasGoreleaserConfig(owner, repo) {
+	if hasGoreleaser {
 		return []entrypoint{{
 			binaryName: repo,
 			pathSuffix: "",
 			isPrimary:  true,
+			source:     sourceGoreleaser,
 		}}
 	}
 
-	// Homebrew formula fallback: strong signal for installable CLI tools
-	if s.hasHomebrewFormula(owner, repo) {
-		return []entrypoint{{
+	// Homebrew formula fallback: strong signal for installable CLI tools.
+	// The formula names the installed binary and often the main package,
+	// which may differ from the repository name and root.
+	if formula := s.homebrewFormula(owner, repo); formula != nil {
+		ep := entrypoint{
 			binaryName: repo,
-			pathSuffix: "",
+			pathSuffix: formula.mainPath,
 			isPrimary:  true,
-		}}
+			env:        formula.env,
+			ldflags:    formula.ldflags,
+			source:     sourceHomebrew,
+		}
+		if formula.binary != "" {
+			ep.binaryName = formula.binary
+		}
+		return []entrypoint{ep}
 	}
 
 	return entrypoints
 }
 
-// listSubdirs returns the names of subdirectories at the given path in a repository.
-func (s *scanner) listSubdirs(owner, repo, path string) []string {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
+// contentItem is an entry of a GitHub contents API directory listing.
+type contentItem struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// listDir returns the entries at the given path in a repository, or nil if
+// the path doesn't exist or the API call fails.
+func (s *scanner) listDir(owner, repo, path string) []contentItem {
+	url := fmt.Sprintf(githubAPI+"/repos/%s/%s/contents/%s", owner, repo, path)
 	resp, err := s.apiGet(url)
 	if err != nil {
 		return nil
@@ -349,16 +456,17 @@ func (s *scanner) listSubdirs(owner, repo, path string) []string {
 		return nil
 	}
 
-	var items []struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-	}
+	var items []contentItem
 	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
 		return nil
 	}
+	return items
+}
 
+// listSubdirs returns the names of subdirectories at the given path in a repository.
+func (s *scanner) listSubdirs(owner, repo, path string) []string {
 	var dirs []string
-	for _, item := range items {
+	for _, item := range s.listDir(owner, repo, path) {
 		if item.Type == "dir" {
 			dirs = append(dirs, item.Name)
 		}
@@ -366,18 +474,20 @@ func (s *scanner) listSubdirs(owner, repo, path string) []string {
 	return dirs
 }
 
-// hasHomebrewFormula checks if the repo has a Homebrew formula, which is a
-// strong indicator that the project produces installable binaries.
-func (s *scanner) hasHomebrewFormula(owner, repo string) bool {
-	for _, path := range []string{
-		"Formula",
-		"HomebrewFormula",
-	} {
-		if dirs := s.listSubdirs(owner, repo, path); len(dirs) > 0 {
-			return true
+// listFiles returns the set of file names at the given path in a repository,
+// or nil if the listing fails.
+func (s *scanner) listFiles(owner, repo, path string) map[string]bool {
+	items := s.listDir(owner, repo, path)
+	if items == nil {
+		return nil
+	}
+	files := make(map[string]bool, len(items))
+	for _, item := range items {
+		if item.Type == "file" {
+			files[item.Name] = true
 		}
 	}
-	return false
+	return files
 }
 
 // getModulePath fetches the module path from go.mod (handles v2+ modules).
@@ -389,6 +499,44 @@ func (s *scanner) getModulePath(owner, repo string) string {
 	return modulePath
 }
 
+// mirrorOf reports whether owner/repo is a mirror of the GitHub repository
+// named by its module path, returning the canonical owner/repo. A module path
+// pointing at another owner is not treated as a mirror when that repository
+// redirects back here, which happens when a repository is renamed or
+// transferred without updating go.mod.
+func (s *scanner) mirrorOf(modulePath, owner, repo string) (string, bool) {
+	modOwner, modRepo, ok := parseGitHubOwnerRepo(modulePath)
+	if !ok {
+		// Vanity import paths can't be attributed to another repository
+		return "", false
+	}
+	if strings.EqualFold(modOwner, owner) && strings.EqualFold(modRepo, repo) {
+		return "", false
+	}
+	canonical := modOwner + "/" + modRepo
+
+	resp, err := s.apiGet(fmt.Sprintf(githubAPI+"/repos/%s", canonical))
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		// The canonical repository is gone; keep this one
+		io.Copy(io.Discard, resp.Body)
+		return "", false
+	}
+	var info struct {
+		FullName string `json:"full_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", false
+	}
+	if strings.EqualFold(info.FullName, owner+"/"+repo) {
+		return "", false
+	}
+	return info.FullName, true
+}
+
 // getLatestRelease fetches the latest release tag, or "latest" on failure.
 func (s *scanner) getLatestRelease(owner, repo string) string {
 	version, err := fetchLatestRelease(s.client, owner, repo, s.token)
@@ -419,6 +567,8 @@ func loadScannedRepos(path string) (map[string]bool, error) {
 }
 
 // saveScannedRepos writes the set of scanned repository keys to a JSON file.
+// The file is replaced atomically, so an interrupted save never loses the
+// repositories already recorded.
 func saveScannedRepos(path string, repos map[string]bool) error {
 	sorted := make([]string, 0, len(repos))
 	for r := range repos {
@@ -429,7 +579,95 @@ func saveScannedRepos(path string, repos map[string]bool) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0o644)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// scanSaveInterval is how many repositories scan processes between saves
+// of the scanned repositories file.
+const scanSaveInterval = 10
+
+// scanFinding is a binary a dry-run scan would add to the database.
+type scanFinding struct {
+	Name        string            `json:"name"`
+	Package     string            `json:"package"`
+	Version     string            `json:"version"`
+	Description string            `json:"description"`
+	RepoURL     string            `json:"repo_url"`
+	Stars       int               `json:"stars"`
+	Primary     bool              `json:"primary"`
+	License     string            `json:"license,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Query       string            `json:"query"`
+	Source      string            `json:"source"`
+	Env         map[string]string `json:"env,omitempty"`
+	Ldflags     string            `json:"ldflags,omitempty"`
+}
+
+// openScanDB opens the scan database for writing, creating it if needed. In
+// dry-run mode an existing database is opened read-only and a missing one is
+// not created, in which case conn is nil.
+func openScanDB(path string) (*sql.DB, error) {
+	if !scanDryRun {
+		conn, err := db.CreatePath(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.InitSchema(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("schema init failed: %w", err)
+		}
+		return conn, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return db.OpenReadOnly(path)
+}
+
+// printScanReport prints the binaries a dry-run scan would add and writes
+// them to the --json file, if given.
+func printScanReport(findings []scanFinding) error {
+	if len(findings) > 0 {
+		fmt.Println("\nBinaries that would be added:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPACKAGE\tVERSION\tSTARS\tLICENSE")
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", f.Name, f.Package, f.Version, f.Stars, f.License)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if scanJSON == "" {
+		return nil
+	}
+	if findings == nil {
+		findings = []scanFinding{}
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(scanJSON, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot write report: %w", err)
+	}
+	fmt.Printf("Wrote report to %s\n", scanJSON)
+	return nil
+}
+
+// recordQueryScan counts a scanned repository towards the yield of the
+// query that found it, warning rather than failing the scan on error.
+func recordQueryScan(conn *sql.DB, query, outcome string) {
+	if scanDryRun {
+		return
+	}
+	if err := db.RecordQueryScan(conn, query, outcome); err != nil {
+		fmt.Printf("  Warning: failed to record query yield: %v\n", err)
+	}
 }
 
 var scanCmd = &cobra.Command{
@@ -442,16 +680,39 @@ subdirectories, goreleaser configs) and added to the database. Module paths
 are resolved from go.mod to handle v2+ modules correctly.
 
 Already-scanned repositories are tracked in a JSON file to enable incremental
-scanning across runs and avoid GitHub API rate limits.`,
+scanning across runs and avoid GitHub API rate limits.
+
+The search query that found each repository is recorded, along with how
+its repositories turned out, for 'gomanager-admin stats queries'.
+
+Packages are written to the database as each repository is scanned, and the
+scanned repositories file is saved every 10 repositories. On SIGINT or
+SIGTERM the scan stops after the current repository and saves its progress,
+so running it again resumes where it left off.
+
+With --dry-run, discovery and entrypoint detection run as usual but nothing
+is written: the binaries that would be added are printed as a report (and
+written as JSON with --json) for review before a real scan.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		conn, err := db.CreatePath(scanDatabase)
+		if scanJSON != "" && !scanDryRun {
+			return fmt.Errorf("--json requires --dry-run")
+		}
+
+		conn, err := openScanDB(scanDatabase)
 		if err != nil {
 			return err
 		}
-		defer conn.Close()
-
-		if err := db.InitSchema(conn); err != nil {
-			return fmt.Errorf("schema init failed: %w", err)
+		existingPkgs := make(map[string]bool)
+		if conn != nil {
+			defer conn.Close()
+			if err := db.RequireFull(conn); err != nil {
+				return err
+			}
+			existingPkgs, err = db.GetExistingPackages(conn)
+			if err != nil {
+				return fmt.Errorf("failed to load existing packages: %w", err)
+			}
 		}
 
 		scannedRepos, err := loadScannedRepos(scanScannedFile)
@@ -459,12 +720,11 @@ scanning across runs and avoid GitHub API rate limits.`,
 			return fmt.Errorf("failed to load scanned repos: %w", err)
 		}
 
-		existingPkgs, err := db.GetExistingPackages(conn)
-		if err != nil {
-			return fmt.Errorf("failed to load existing packages: %w", err)
-		}
+		ctx, stop := notifyInterrupt()
+		defer stop()
 
 		sc := &scanner{
+			ctx:    ctx,
 			client: &http.Client{Timeout: 30 * time.Second},
 			token:  os.Getenv("GITHUB_TOKEN"),
 		}
@@ -472,29 +732,81 @@ scanning across runs and avoid GitHub API rate limits.`,
 		sc.checkRateLimit()
 
 		repos, err := sc.searchRepos(scannedRepos)
+		if ctx.Err() != nil {
+			return interruptedError("no repositories were scanned yet")
+		}
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
-		newCount := 0
+		// save records the scanned repositories so far; a dry run records
+		// nothing.
+		save := func() error {
+			if scanDryRun {
+				return nil
+			}
+			if err := saveScannedRepos(scanScannedFile, scannedRepos); err != nil {
+				return fmt.Errorf("failed to save scanned repos: %w", err)
+			}
+			return nil
+		}
+
+		newCount, mirrorCount := 0, 0
+		var findings []scanFinding
 		fmt.Printf("\nProcessing %d new repositories...\n", len(repos))
 
 		for i, repo := range repos {
+			if ctx.Err() != nil {
+				if err := save(); err != nil {
+					return err
+				}
+				fmt.Printf("\nInterrupted after %d of %d repositories. Added %d new binaries.\n", i, len(repos), newCount)
+				return interruptedError("progress was saved; run scan again to resume")
+			}
+			if i > 0 && i%scanSaveInterval == 0 {
+				if err := save(); err != nil {
+					return err
+				}
+			}
+
 			owner := repo.Owner.Login
 			repoKey := owner + "/" + repo.Name
 
 			fmt.Printf("[%d/%d] Scanning %s (%d stars)...\n", i+1, len(repos), repoKey, repo.Stars)
 
-			entrypoints := sc.findEntrypoints(owner, repo.Name)
+			rootFiles := sc.listFiles(owner, repo.Name, "")
+			goreleaser := sc.goreleaserConfig(owner, repo.Name, rootFiles)
+
+			entrypoints := sc.findEntrypoints(owner, repo.Name, rootFiles, goreleaser != nil)
 			if len(entrypoints) == 0 {
 				fmt.Println("  No binaries found")
+				recordQueryScan(conn, repo.query, db.ScanNoBinaries)
 				scannedRepos[repoKey] = true
 				continue
 			}
 
-			version := sc.getLatestRelease(owner, repo.Name)
 			modulePath := sc.getModulePath(owner, repo.Name)
 
+			// A go.mod naming another GitHub repository means this is a
+			// mirror or hard fork; its packages would shadow (or be
+			// installed as) the canonical project, so skip it.
+			if canonical, ok := sc.mirrorOf(modulePath, owner, repo.Name); ok {
+				fmt.Printf("  Skipping likely mirror of %s\n", canonical)
+				recordQueryScan(conn, repo.query, db.ScanMirror)
+				scannedRepos[repoKey] = true
+				mirrorCount++
+				continue
+			}
+
+			version := sc.getLatestRelease(owner, repo.Name)
+
+			// The README is fetched once the first new package needs its
+			// summary. If fetching fails the packages are left undescribed
+			// for the describe command to retry.
+			var readmeText string
+			var readmeErr error
+			readmeFetched := false
+
 			for _, ep := range entrypoints {
 				var pkgPath string
 				if ep.pathSuffix != "" {
@@ -512,6 +824,27 @@ scanning across runs and avoid GitHub API rate limits.`,
 					repoURL = "https://github.com/" + repoKey
 				}
 
+				// Seed build flags from the goreleaser build (or the hints of
+				// whatever detected the entrypoint) so verify doesn't have to
+				// discover them by retrying.
+				env, ldflags := ep.env, ep.ldflags
+				if goreleaser != nil {
+					env, ldflags = goreleaser.buildFlags(ep.pathSuffix)
+				}
+
+				if scanDryRun {
+					findings = append(findings, scanFinding{
+						Name: ep.binaryName, Package: pkgPath, Version: version,
+						Description: repo.Description, RepoURL: repoURL, Stars: repo.Stars,
+						Primary: ep.isPrimary, License: repo.spdxID(), Tags: db.NormalizeTags(repo.Topics),
+						Query: repo.query, Source: ep.source,
+						Env: env, Ldflags: ldflags,
+					})
+					existingPkgs[pkgPath] = true
+					newCount++
+					continue
+				}
+
 				if err := db.UpsertBinary(conn,
 					ep.binaryName, pkgPath, version,
 					repo.Description, repoURL, repo.Stars, ep.isPrimary,
@@ -520,18 +853,57 @@ scanning across runs and avoid GitHub API rate limits.`,
 					continue
 				}
 
+				if err := db.SetDiscovery(conn, pkgPath, repo.query, ep.source); err != nil {
+					fmt.Printf("  Warning: failed to record discovery provenance for %s: %v\n", pkgPath, err)
+				}
+				enqueueJob(conn, pkgPath, db.JobDiscovered)
+
+				if license := repo.spdxID(); license != "" {
+					if err := db.SetLicense(conn, pkgPath, license); err != nil {
+						fmt.Printf("  Warning: failed to record license for %s: %v\n", pkgPath, err)
+					}
+				}
+				if err := db.SetTags(conn, pkgPath, repo.Topics); err != nil {
+					fmt.Printf("  Warning: failed to record tags for %s: %v\n", pkgPath, err)
+				}
+
+				if !readmeFetched {
+					readmeText, readmeErr = sc.readme(owner, repo.Name)
+					readmeFetched = true
+				}
+				if readmeErr == nil || errors.Is(readmeErr, errNoReadme) {
+					if err := db.SetProjectInfo(conn, pkgPath, strings.TrimSpace(repo.Homepage), readmeSummary(readmeText)); err != nil {
+						fmt.Printf("  Warning: failed to record project info for %s: %v\n", pkgPath, err)
+					}
+				}
+
+				if len(env) > 0 || ldflags != "" {
+					if err := db.SeedBuildFlags(conn, pkgPath, marshalFlags(env), ldflags); err != nil {
+						fmt.Printf("  Warning: failed to seed build flags for %s: %v\n", pkgPath, err)
+					}
+				}
+
 				existingPkgs[pkgPath] = true
 				newCount++
 			}
 
+			recordQueryScan(conn, repo.query, db.ScanAdded)
 			scannedRepos[repoKey] = true
 		}
 
-		if err := saveScannedRepos(scanScannedFile, scannedRepos); err != nil {
-			return fmt.Errorf("failed to save scanned repos: %w", err)
+		if scanDryRun {
+			if err := printScanReport(findings); err != nil {
+				return err
+			}
+			fmt.Printf("\nDry run complete. Would add %d new binaries, skipped %d mirrors.\n", newCount, mirrorCount)
+			return nil
+		}
+
+		if err := save(); err != nil {
+			return err
 		}
 
-		fmt.Printf("\nDone. Added %d new binaries.\n", newCount)
+		fmt.Printf("\nDone. Added %d new binaries, skipped %d mirrors.\n", newCount, mirrorCount)
 		return nil
 	},
 }
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/githubtest"
+)
+
+// scanFixture is a set of fake repositories covering scan's main paths: a
+// root main package, a v2 module with cmd/ entrypoints, a library with no
+// binaries, and a mirror whose go.mod names another repository.
+func scanFixture() *githubtest.Server {
+	topics := []string{"go", "cli"}
+	return githubtest.NewServer(
+		&githubtest.Repo{
+			Owner: "acme", Name: "widget", Description: "Widgets on the command line",
+			Stars: 1200, Topics: topics, Language: "Go", License: "MIT", Release: "v1.2.0",
+			Files: map[string]string{
+				"go.mod":    "module github.com/acme/widget\n",
+				"main.go":   "package main\n",
+				"README.md": "# widget\n\nMakes widgets.\n",
+			},
+		},
+		&githubtest.Repo{
+			Owner: "acme", Name: "multi", Stars: 800, Topics: topics, Language: "Go",
+			License: "Apache-2.0", Release: "v2.1.0",
+			Files: map[string]string{
+				"go.mod":          "module github.com/acme/multi/v2\n",
+				"cmd/foo/main.go": "package main\n",
+				"cmd/bar/main.go": "package main\n",
+			},
+		},
+		&githubtest.Repo{
+			Owner: "acme", Name: "lib", Stars: 600, Topics: topics, Language: "Go",
+			Files: map[string]string{
+				"go.mod": "module github.com/acme/lib\n",
+				"lib.go": "package lib\n",
+			},
+		},
+		&githubtest.Repo{
+			Owner: "fork", Name: "widget", Stars: 20, Topics: topics, Language: "Go",
+			Files: map[string]string{
+				"go.mod":  "module github.com/acme/widget\n",
+				"main.go": "package main\n",
+			},
+		},
+	)
+}
+
+// fastScan restricts scan to one search query without pauses between
+// requests for the duration of the test.
+func fastScan(t *testing.T) {
+	queries, interval := defaultSearchQueries, searchInterval
+	defaultSearchQueries = []string{"topic:go+topic:cli"}
+	searchInterval = 0
+	t.Cleanup(func() { defaultSearchQueries, searchInterval = queries, interval })
+}
+
+func TestScan(t *testing.T) {
+	isolate(t)
+	fastScan(t)
+	gh := scanFixture()
+	defer gh.Close()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "database.db")
+	scanned := filepath.Join(dir, "scanned_repos.json")
+	runAdmin(t, gh.URL, "scan", "-d", dbPath, "--scanned-repos", scanned, "--dry-run=false")
+
+	conn := openTestDB(t, dbPath)
+	got := queryStrings(t, conn, `
+		SELECT package, name, version, build_status, stars, is_primary, license, discovery_source
+		FROM binaries ORDER BY package`)
+	want := []string{
+		"github.com/acme/multi/v2/cmd/bar bar v2.1.0 quarantined 800 0 Apache-2.0 cmd/ directory",
+		"github.com/acme/multi/v2/cmd/foo foo v2.1.0 quarantined 800 0 Apache-2.0 cmd/ directory",
+		"github.com/acme/widget widget v1.2.0 quarantined 1200 1 MIT root main.go",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("binaries after scan:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	got = queryStrings(t, conn, `SELECT reason FROM jobs WHERE package = 'github.com/acme/widget'`)
+	if len(got) != 1 {
+		t.Errorf("widget jobs = %q, want one queued job", got)
+	}
+
+	var repos []string
+	data, err := os.ReadFile(scanned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &repos); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(repos)
+	if want := []string{"acme/lib", "acme/multi", "acme/widget", "fork/widget"}; !slices.Equal(repos, want) {
+		t.Errorf("scanned repos = %q, want %q", repos, want)
+	}
+
+	// A second scan skips the repositories already scanned.
+	before := len(gh.Requests())
+	runAdmin(t, gh.URL, "scan", "-d", dbPath, "--scanned-repos", scanned, "--dry-run=false")
+	for _, req := range gh.Requests()[before:] {
+		if strings.Contains(req, "/contents") {
+			t.Errorf("second scan fetched %s", req)
+		}
+	}
+	if n := len(queryStrings(t, conn, `SELECT package FROM binaries`)); n != len(want) {
+		t.Errorf("second scan left %d binaries, want %d", n, len(want))
+	}
+}
+
+func TestScanDryRun(t *testing.T) {
+	isolate(t)
+	fastScan(t)
+	gh := scanFixture()
+	defer gh.Close()
+
+	dir := t.TempDir()
+	dbPath := newTestDB(t, filepath.Join(dir, "database.db"))
+	scanned := filepath.Join(dir, "scanned_repos.json")
+	report := filepath.Join(dir, "report.json")
+	runAdmin(t, gh.URL, "scan", "-d", dbPath, "--scanned-repos", scanned, "--dry-run", "--json", report)
+	t.Cleanup(func() { scanDryRun, scanJSON = false, "" })
+
+	conn := openTestDB(t, dbPath)
+	if got := queryStrings(t, conn, `SELECT package FROM binaries`); len(got) != 0 {
+		t.Errorf("dry run added %q", got)
+	}
+	if _, err := os.Stat(scanned); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote %s", scanned)
+	}
+	var findings []scanFinding
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &findings); err != nil {
+		t.Fatal(err)
+	}
+	var pkgs []string
+	for _, f := range findings {
+		pkgs = append(pkgs, f.Package)
+	}
+	slices.Sort(pkgs)
+	want := []string{"github.com/acme/multi/v2/cmd/bar", "github.com/acme/multi/v2/cmd/foo", "github.com/acme/widget"}
+	if !slices.Equal(pkgs, want) {
+		t.Errorf("dry run report = %q, want %q", pkgs, want)
+	}
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsSnapshots string
+	statsDatabase  string
+	statsMinRepos  int
+)
+
+func init() {
+	statsTrendsCmd.Flags().StringVar(&statsSnapshots, "snapshots", "", "Directory of historical database snapshots (*.db)")
+	statsTrendsCmd.Flags().StringVarP(&statsDatabase, "database", "d", "", "Full database whose build history to report regressions from")
+	statsTrendsCmd.MarkFlagRequired("snapshots")
+	statsQueriesCmd.Flags().StringVarP(&statsDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	statsQueriesCmd.Flags().IntVar(&statsMinRepos, "min-repos", 1, "Hide queries that found fewer repositories")
+	statsCmd.AddCommand(statsTrendsCmd)
+	statsCmd.AddCommand(statsQueriesCmd)
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Pipeline statistics",
+}
+
+// snapshotDate matches a date in a snapshot file name.
+var snapshotDate = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// snapshot is one historical database and the statistics read from it.
+type snapshot struct {
+	path     string
+	date     time.Time
+	counts   map[string]int
+	packages map[string]bool
+}
+
+func (s *snapshot) total() int {
+	n := 0
+	for _, c := range s.counts {
+		n += c
+	}
+	return n
+}
+
+var statsTrendsCmd = &cobra.Command{
+	Use:   "trends",
+	Short: "Report pipeline trends across database snapshots",
+	Long: `Reads every *.db file in the --snapshots directory (full or slim, any
+supported schema version; they are opened read-only) and reports, per
+snapshot: the number of tracked binaries, how many are confirmed and the
+confirmed rate, how many are regressed, and the scanner's yield (binaries
+not present in the previous snapshot).
+
+Snapshots are dated by a YYYY-MM-DD in their file name, or by their
+modification time.
+
+With -d, regressions recorded in that database's build history are also
+reported per month.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := filepath.Glob(filepath.Join(statsSnapshots, "*.db"))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no *.db snapshots in %s", statsSnapshots)
+		}
+
+		var snapshots []*snapshot
+		for _, path := range paths {
+			s, err := readSnapshot(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", path, err)
+				continue
+			}
+			snapshots = append(snapshots, s)
+		}
+		sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].date.Before(snapshots[j].date) })
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "DATE\tTOTAL\tCONFIRMED\tRATE\tREGRESSED\tNEW\t")
+		for i, s := range snapshots {
+			total := s.total()
+			rate := 0.0
+			if total > 0 {
+				rate = 100 * float64(s.counts["confirmed"]) / float64(total)
+			}
+			added := "-"
+			if i > 0 {
+				n := 0
+				for pkg := range s.packages {
+					if !snapshots[i-1].packages[pkg] {
+						n++
+					}
+				}
+				added = fmt.Sprint(n)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\t%d\t%s\t\n",
+				s.date.Format("2006-01-02"), total, s.counts["confirmed"], rate, s.counts["regressed"], added)
+		}
+		w.Flush()
+
+		if statsDatabase == "" {
+			return nil
+		}
+		conn, err := openAdminDB(statsDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		months, err := db.HistoryByMonth(conn, "regressed")
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nRegressions per month:")
+		if len(months) == 0 {
+			fmt.Println("  none recorded")
+			return nil
+		}
+		for _, m := range months {
+			fmt.Printf("  %s  %4d %s\n", m.Month, m.Count, strings.Repeat("#", min(m.Count, 60)))
+		}
+		return nil
+	},
+}
+
+// readSnapshot reads the statistics of one snapshot database.
+func readSnapshot(path string) (*snapshot, error) {
+	conn, err := db.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	s := &snapshot{path: path}
+	if s.counts, err = db.StatusCounts(conn); err != nil {
+		return nil, err
+	}
+	if s.packages, err = db.GetExistingPackages(conn); err != nil {
+		return nil, err
+	}
+	if date := snapshotDate.FindString(filepath.Base(path)); date != "" {
+		s.date, err = time.Parse("2006-01-02", date)
+	}
+	if s.date.IsZero() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		s.date = fi.ModTime()
+	}
+	return s, nil
+}
+
+var statsQueriesCmd = &cobra.Command{
+	Use:   "queries",
+	Short: "Rank the scanner's search queries by precision",
+	Long: `Reports, for each scanner search query, how many repositories it found,
+how many were skipped (no binaries, or mirrors), how many binaries it added
+and how those verified. Queries are ranked by precision: the fraction of
+their repositories that produced at least one confirmed binary.
+
+Only repositories scanned since discovery queries were recorded are
+counted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(statsDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		yields, err := db.QueryYields(conn)
+		if err != nil {
+			return err
+		}
+		var shown []db.QueryYield
+		for _, y := range yields {
+			if y.Repos >= statsMinRepos {
+				shown = append(shown, y)
+			}
+		}
+		if len(shown) == 0 {
+			fmt.Println("No query yields recorded yet; run scan first.")
+			return nil
+		}
+		sort.Slice(shown, func(i, j int) bool {
+			if shown[i].Precision() != shown[j].Precision() {
+				return shown[i].Precision() > shown[j].Precision()
+			}
+			if shown[i].Repos != shown[j].Repos {
+				return shown[i].Repos > shown[j].Repos
+			}
+			return shown[i].Query < shown[j].Query
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PRECISION\tREPOS\tNO-BIN\tMIRRORS\tBINARIES\tCONFIRMED\tFAILED\tQUERY")
+		for _, y := range shown {
+			fmt.Fprintf(w, "%5.1f%%\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				100*y.Precision(), y.Repos, y.NoBinaries, y.Mirrors,
+				y.Binaries, y.Confirmed, y.Failed, y.Query)
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"maps"
+	"strings"
+)
+
+// strategyDefault names a build that succeeded with the package's recorded
+// flags and no fallback.
+const strategyDefault = "default"
+
+// buildStrategy is a fallback tried when a package fails to build with its
+// recorded flags. Its env is merged on top of the recorded flags.
+type buildStrategy struct {
+	name string
+	env  map[string]string
+	// hints are substrings of the original build error suggesting that this
+	// strategy may help. A strategy without hints is tried after any failure.
+	hints []string
+}
+
+// fallbackStrategies are tried in order after the initial build fails.
+var fallbackStrategies = []buildStrategy{
+	{
+		name: "cgo-disabled",
+		env:  map[string]string{"CGO_ENABLED": "0"},
+	},
+	{
+		name:  "mod-mod",
+		env:   map[string]string{"GOFLAGS": "-mod=mod"},
+		hints: []string{"missing go.sum entry", "updates to go.mod needed", "inconsistent vendoring", "-mod=mod"},
+	},
+	{
+		name:  "toolchain-local",
+		env:   map[string]string{"GOTOOLCHAIN": "local"},
+		hints: []string{"toolchain", "GOTOOLCHAIN"},
+	},
+	{
+		name:  "no-vcs-stamp",
+		env:   map[string]string{"GOFLAGS": "-buildvcs=false"},
+		hints: []string{"error obtaining VCS status", "-buildvcs"},
+	},
+}
+
+// mergeFlags returns base with overrides applied. Keys in overrides replace
+// those in base, except GOFLAGS, whose flags are appended to base's (without
+// duplicates) so a strategy adds to the recorded GOFLAGS rather than
+// discarding them. Neither input is modified.
+func mergeFlags(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	maps.Copy(merged, base)
+	for k, v := range overrides {
+		if k == "GOFLAGS" && merged[k] != "" {
+			flags := strings.Fields(merged[k])
+			for _, f := range strings.Fields(v) {
+				if !containsString(flags, f) {
+					flags = append(flags, f)
+				}
+			}
+			merged[k] = strings.Join(flags, " ")
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// applies reports whether the strategy is worth trying for a build that
+// failed with errMsg.
+func (s buildStrategy) applies(errMsg string) bool {
+	if len(s.hints) == 0 {
+		return true
+	}
+	for _, h := range s.hints {
+		if strings.Contains(errMsg, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// strategyInstall runs each build attempt of buildWithFallbacks.
+var strategyInstall = tryGoInstall
+
+// buildWithFallbacks builds installPath with the recorded flags and, if that
+// fails, with each applicable fallback strategy merged on top of them. The
+// strategies are tried one at a time, each on the recorded flags alone, not
+// on top of the strategies tried before it. It returns the flags and name of
+// the first strategy that succeeded. On failure, the original flags and the
+// first build error are returned.
+func buildWithFallbacks(installPath string, recorded map[string]string, run installRun) (ok bool, flags map[string]string, strategy, errMsg string, tried []string) {
+	tried = []string{strategyDefault}
+	ok, _, errMsg = strategyInstall(installPath, recorded, run)
+	if ok {
+		return true, recorded, strategyDefault, "", tried
+	}
+
+	for _, s := range fallbackStrategies {
+		merged := mergeFlags(recorded, s.env)
+		if maps.Equal(merged, recorded) || !s.applies(errMsg) {
+			continue
+		}
+		tried = append(tried, s.name)
+		if ok, _, _ := strategyInstall(installPath, merged, run); ok {
+			return true, merged, s.name, "", tried
+		}
+	}
+	return false, recorded, "", errMsg, tried
+}
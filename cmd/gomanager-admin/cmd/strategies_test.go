@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestMergeFlags(t *testing.T) {
+	tests := []struct {
+		name            string
+		base, overrides map[string]string
+		want            map[string]string
+	}{
+		{
+			name:      "nil base",
+			overrides: map[string]string{"CGO_ENABLED": "0"},
+			want:      map[string]string{"CGO_ENABLED": "0"},
+		},
+		{
+			name:      "override replaces base",
+			base:      map[string]string{"CGO_ENABLED": "1", "GOOS": "linux"},
+			overrides: map[string]string{"CGO_ENABLED": "0"},
+			want:      map[string]string{"CGO_ENABLED": "0", "GOOS": "linux"},
+		},
+		{
+			name:      "GOFLAGS appended after base",
+			base:      map[string]string{"GOFLAGS": "-trimpath"},
+			overrides: map[string]string{"GOFLAGS": "-mod=mod"},
+			want:      map[string]string{"GOFLAGS": "-trimpath -mod=mod"},
+		},
+		{
+			name:      "GOFLAGS deduplicated",
+			base:      map[string]string{"GOFLAGS": "-mod=mod  -trimpath"},
+			overrides: map[string]string{"GOFLAGS": "-trimpath -mod=mod -buildvcs=false -buildvcs=false"},
+			want:      map[string]string{"GOFLAGS": "-mod=mod -trimpath -buildvcs=false"},
+		},
+		{
+			name:      "GOFLAGS with empty base",
+			base:      map[string]string{"GOFLAGS": ""},
+			overrides: map[string]string{"GOFLAGS": "-mod=mod"},
+			want:      map[string]string{"GOFLAGS": "-mod=mod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, overrides := maps.Clone(tt.base), maps.Clone(tt.overrides)
+			got := mergeFlags(tt.base, tt.overrides)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("mergeFlags(%v, %v) = %v, want %v", tt.base, tt.overrides, got, tt.want)
+			}
+			if !maps.Equal(tt.base, base) || !maps.Equal(tt.overrides, overrides) {
+				t.Errorf("mergeFlags modified its inputs: base %v, overrides %v", tt.base, tt.overrides)
+			}
+		})
+	}
+}
+
+func TestMergeFlagsDoesNotAlias(t *testing.T) {
+	base := map[string]string{"GOFLAGS": "-trimpath"}
+	merged := mergeFlags(base, nil)
+	merged["GOFLAGS"] = "-mod=mod"
+	if base["GOFLAGS"] != "-trimpath" {
+		t.Errorf("writing the merged map changed base to %v", base)
+	}
+}
+
+// fakeBuilds replaces the go install run by buildWithFallbacks, failing
+// with errMsg unless succeeds reports that the flags would work, and
+// returns the flags of every attempt.
+func fakeBuilds(t *testing.T, errMsg string, succeeds func(flags map[string]string) bool) *[]map[string]string {
+	t.Helper()
+	var attempts []map[string]string
+	saved := strategyInstall
+	strategyInstall = func(installPath string, flags map[string]string, run installRun) (bool, map[string]string, string) {
+		attempts = append(attempts, maps.Clone(flags))
+		if succeeds(flags) {
+			return true, flags, ""
+		}
+		return false, flags, errMsg
+	}
+	t.Cleanup(func() { strategyInstall = saved })
+	return &attempts
+}
+
+func TestBuildWithFallbacks(t *testing.T) {
+	t.Run("default succeeds", func(t *testing.T) {
+		attempts := fakeBuilds(t, "", func(map[string]string) bool { return true })
+		recorded := map[string]string{"GOFLAGS": "-trimpath"}
+		ok, flags, strategy, _, tried := buildWithFallbacks("example.com/x@v1.0.0", recorded, installRun{})
+		if !ok || strategy != strategyDefault || !maps.Equal(flags, recorded) {
+			t.Errorf("got ok=%v strategy=%q flags=%v, want the recorded flags", ok, strategy, flags)
+		}
+		if !slices.Equal(tried, []string{strategyDefault}) || len(*attempts) != 1 {
+			t.Errorf("tried %q in %d builds, want only the default", tried, len(*attempts))
+		}
+	})
+
+	t.Run("strategies tried in order on the recorded flags", func(t *testing.T) {
+		// A missing go.sum entry matches mod-mod's hints but not
+		// toolchain-local's or no-vcs-stamp's; cgo-disabled has none.
+		attempts := fakeBuilds(t, "missing go.sum entry for module", func(map[string]string) bool { return false })
+		recorded := map[string]string{"GOFLAGS": "-trimpath"}
+		ok, flags, _, errMsg, tried := buildWithFallbacks("example.com/x@v1.0.0", recorded, installRun{})
+		if ok || !maps.Equal(flags, recorded) || errMsg != "missing go.sum entry for module" {
+			t.Errorf("got ok=%v flags=%v err=%q, want failure with the recorded flags", ok, flags, errMsg)
+		}
+		if want := []string{strategyDefault, "cgo-disabled", "mod-mod"}; !slices.Equal(tried, want) {
+			t.Errorf("tried %q, want %q", tried, want)
+		}
+		want := []map[string]string{
+			{"GOFLAGS": "-trimpath"},
+			{"GOFLAGS": "-trimpath", "CGO_ENABLED": "0"},
+			// Not on top of cgo-disabled's CGO_ENABLED=0.
+			{"GOFLAGS": "-trimpath -mod=mod"},
+		}
+		if !slices.EqualFunc(*attempts, want, maps.Equal) {
+			t.Errorf("build attempts = %v, want %v", *attempts, want)
+		}
+		if !maps.Equal(recorded, map[string]string{"GOFLAGS": "-trimpath"}) {
+			t.Errorf("buildWithFallbacks modified the recorded flags: %v", recorded)
+		}
+	})
+
+	t.Run("first working strategy wins", func(t *testing.T) {
+		fakeBuilds(t, "error obtaining VCS status: exit status 128", func(flags map[string]string) bool {
+			return flags["GOFLAGS"] == "-buildvcs=false"
+		})
+		ok, flags, strategy, _, tried := buildWithFallbacks("example.com/x@v1.0.0", nil, installRun{})
+		if !ok || strategy != "no-vcs-stamp" {
+			t.Fatalf("got ok=%v strategy=%q, want no-vcs-stamp", ok, strategy)
+		}
+		if want := map[string]string{"GOFLAGS": "-buildvcs=false"}; !maps.Equal(flags, want) {
+			t.Errorf("flags = %v, want %v", flags, want)
+		}
+		if want := []string{strategyDefault, "cgo-disabled", "no-vcs-stamp"}; !slices.Equal(tried, want) {
+			t.Errorf("tried %q, want %q", tried, want)
+		}
+	})
+
+	t.Run("strategies already recorded are skipped", func(t *testing.T) {
+		fakeBuilds(t, "missing go.sum entry", func(map[string]string) bool { return false })
+		recorded := map[string]string{"CGO_ENABLED": "0", "GOFLAGS": "-mod=mod"}
+		_, _, _, _, tried := buildWithFallbacks("example.com/x@v1.0.0", recorded, installRun{})
+		if want := []string{strategyDefault}; !slices.Equal(tried, want) {
+			t.Errorf("tried %q, want %q", tried, want)
+		}
+	})
+}
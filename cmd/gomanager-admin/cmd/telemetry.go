@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	telemetryDatabase string
+	telemetryTop      int
+)
+
+func init() {
+	importTelemetryCmd.Flags().StringVarP(&telemetryDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	importTelemetryCmd.Flags().IntVar(&telemetryTop, "top", 20, "Number of packages to list by failures (0 = all)")
+	rootCmd.AddCommand(importTelemetryCmd)
+}
+
+var importTelemetryCmd = &cobra.Command{
+	Use:   "import-telemetry [file...]",
+	Short: "Aggregate install reports collected from clients",
+	Long: `Reads the install reports clients with telemetry turned on posted to the
+collection endpoint, as newline-delimited JSON (one report per line, as the
+endpoint received them), from the given files or stdin, and adds them to
+the install_reports table: counts of successful and failed installs per
+package version, platform, Go version, and failure class.
+
+Then lists the packages with the most reported failures, with their
+failure rate and the classes of failure, to point curation at entries
+whose build status or flags are wrong in practice. Each file should be
+imported once; lines that aren't valid reports are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var reports []telemetry.Report
+		skipped := 0
+		read := func(name string, r io.Reader) error {
+			rs, n, err := telemetry.Read(r)
+			if err != nil {
+				return fmt.Errorf("cannot read %s: %w", name, err)
+			}
+			reports = append(reports, rs...)
+			skipped += n
+			return nil
+		}
+		if len(args) == 0 {
+			if err := read("stdin", os.Stdin); err != nil {
+				return err
+			}
+		}
+		for _, path := range args {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			err = read(path, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		conn, err := openAdminDB(telemetryDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+		if err := db.RecordInstallReports(conn, reports); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d reports (%d invalid lines skipped).\n", len(reports), skipped)
+
+		failures, err := db.InstallFailuresByPackage(conn)
+		if err != nil {
+			return err
+		}
+		if len(failures) == 0 {
+			fmt.Println("No failed installs reported.")
+			return nil
+		}
+		if telemetryTop > 0 && len(failures) > telemetryTop {
+			failures = failures[:telemetryTop]
+		}
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FAILED\tINSTALLED\tRATE\tLAST FAILED\tCLASSES\tPACKAGE")
+		for _, f := range failures {
+			fmt.Fprintf(w, "%d\t%d\t%5.1f%%\t%s\t%s\t%s\n",
+				f.Failed, f.Installed, 100*f.FailureRate(), f.LastFailed, formatClasses(f.Classes), f.Package)
+		}
+		return w.Flush()
+	},
+}
+
+// formatClasses formats failure counts by class, most frequent first, e.g.
+// "cgo:12 network:2".
+func formatClasses(classes map[string]int) string {
+	names := slices.SortedFunc(maps.Keys(classes), func(a, b string) int {
+		if classes[a] != classes[b] {
+			return classes[b] - classes[a]
+		}
+		return strings.Compare(a, b)
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s:%d", name, classes[name])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trustBatchSize int
+	trustDatabase  string
+	trustRefresh   bool
+)
+
+func init() {
+	trustCmd.Flags().IntVarP(&trustBatchSize, "batch-size", "n", 100, "Max repositories to score")
+	trustCmd.Flags().StringVarP(&trustDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	trustCmd.Flags().BoolVar(&trustRefresh, "refresh", false, "Re-score repositories that already have a trust score")
+	rootCmd.AddCommand(trustCmd)
+}
+
+// wellKnownOrgs are GitHub organizations whose projects are widely used and
+// maintained by established teams. Matching is case-insensitive.
+var wellKnownOrgs = map[string]bool{
+	"golang":              true,
+	"google":              true,
+	"googlecloudplatform": true,
+	"kubernetes":          true,
+	"kubernetes-sigs":     true,
+	"hashicorp":           true,
+	"cli":                 true,
+	"github":              true,
+	"microsoft":           true,
+	"aws":                 true,
+	"azure":               true,
+	"docker":              true,
+	"moby":                true,
+	"containerd":          true,
+	"grafana":             true,
+	"prometheus":          true,
+	"cncf":                true,
+	"etcd-io":             true,
+	"charmbracelet":       true,
+	"cloudflare":          true,
+	"digitalocean":        true,
+	"gohugoio":            true,
+	"goreleaser":          true,
+	"golangci":            true,
+	"mozilla":             true,
+	"sigstore":            true,
+	"open-policy-agent":   true,
+	"helm":                true,
+	"istio":               true,
+	"traefik":             true,
+	"caddyserver":         true,
+}
+
+// scorecardAPI is the OpenSSF Scorecard results endpoint, followed by the
+// project path (e.g. github.com/owner/repo).
+const scorecardAPI = "https://api.securityscorecards.dev/projects/"
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Compute trust scores from repository owner and security metadata",
+	Long: `Fetches trust signals for each repository in the database and stores a
+composite 0-100 trust score alongside them. Clients display the score and
+can filter on it with 'gomanager search --min-trust'.
+
+Signals, with the maximum points each contributes:
+  stars              30  (logarithmic; 10k+ stars scores full marks)
+  organization owner 10
+  well-known org     15
+  owner account age  15  (3 points per year, up to 5 years)
+  funding file       5   (.github/FUNDING.yml)
+  OpenSSF Scorecard  25  (2.5 points per scorecard point)
+
+By default only repositories without a score are processed. Use --refresh
+to re-score the rest as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(trustDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+
+		// Group packages by owner/repo to avoid duplicate API calls
+		type repoGroup struct {
+			owner, repo string
+			binaries    []db.Binary
+		}
+		repoMap := make(map[string]*repoGroup)
+		var repoOrder []string
+		for _, b := range binaries {
+			if b.TrustScore >= 0 && !trustRefresh {
+				continue
+			}
+			owner, repo, ok := parseGitHubOwnerRepo(b.Package)
+			if !ok {
+				continue
+			}
+			key := owner + "/" + repo
+			if g, exists := repoMap[key]; exists {
+				g.binaries = append(g.binaries, b)
+			} else {
+				repoMap[key] = &repoGroup{owner: owner, repo: repo, binaries: []db.Binary{b}}
+				repoOrder = append(repoOrder, key)
+			}
+		}
+
+		limit := trustBatchSize
+		if limit > len(repoOrder) {
+			limit = len(repoOrder)
+		}
+		fmt.Printf("Scoring %d/%d repositories...\n\n", limit, len(repoOrder))
+
+		s := &scanner{
+			ctx:    context.Background(),
+			client: &http.Client{Timeout: 15 * time.Second},
+			token:  os.Getenv("GITHUB_TOKEN"),
+		}
+		owners := make(map[string]time.Time)
+		scored, failed := 0, 0
+
+		for i, key := range repoOrder[:limit] {
+			g := repoMap[key]
+			signals, err := s.trustSignals(g.owner, g.repo, owners)
+			if err != nil {
+				fmt.Printf("[%d/%d] %s: %v\n", i+1, limit, key, err)
+				failed++
+				continue
+			}
+
+			// Stars are per repository, so every binary in the group scores alike
+			score := trustScore(g.binaries[0].Stars, *signals)
+			for _, b := range g.binaries {
+				if err := db.UpdateTrust(conn, b.ID, *signals, score); err != nil {
+					fmt.Printf("  Warning: failed to update %s: %v\n", b.Name, err)
+				}
+			}
+			fmt.Printf("[%d/%d] %s: %d\n", i+1, limit, key, score)
+			scored++
+		}
+
+		fmt.Printf("\nDone. Scored %d repos, %d failed.\n", scored, failed)
+		return nil
+	},
+}
+
+// trustSignals gathers the trust signals for owner/repo. owners caches
+// account creation times, since many repositories share an owner.
+func (s *scanner) trustSignals(owner, repo string, owners map[string]time.Time) (*db.TrustSignals, error) {
+	resp, err := s.apiGet(fmt.Sprintf(githubAPI+"/repos/%s/%s", owner, repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var info struct {
+		Owner struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"owner"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	signals := &db.TrustSignals{
+		OwnerType: info.Owner.Type,
+		WellKnown: info.Owner.Type == "Organization" && wellKnownOrgs[strings.ToLower(info.Owner.Login)],
+		Funded:    s.checkFileExists(owner, repo, ".github/FUNDING.yml"),
+		Scorecard: s.scorecard(owner, repo),
+	}
+
+	created, ok := owners[info.Owner.Login]
+	if !ok {
+		created = s.accountCreated(info.Owner.Login)
+		owners[info.Owner.Login] = created
+	}
+	signals.OwnerCreated = created
+	return signals, nil
+}
+
+// accountCreated returns when a GitHub account was created, or the zero time
+// if it can't be determined.
+func (s *scanner) accountCreated(login string) time.Time {
+	resp, err := s.apiGet(githubAPI + "/users/" + login)
+	if err != nil {
+		return time.Time{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		io.Copy(io.Discard, resp.Body)
+		return time.Time{}
+	}
+	var user struct {
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return time.Time{}
+	}
+	return user.CreatedAt
+}
+
+// scorecard returns the OpenSSF Scorecard score for a GitHub repository, or
+// -1 if it hasn't been assessed.
+func (s *scanner) scorecard(owner, repo string) float64 {
+	resp, err := s.client.Get(scorecardAPI + "github.com/" + owner + "/" + repo)
+	if err != nil {
+		return -1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		io.Copy(io.Discard, resp.Body)
+		return -1
+	}
+	var result struct {
+		Score float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return -1
+	}
+	return result.Score
+}
+
+// trustScore combines stars and trust signals into a 0-100 score. The
+// weights are documented in the trust command's help.
+func trustScore(stars int, s db.TrustSignals) int {
+	var score float64
+
+	// log10(10000) = 4, so 10k stars earns the full 30 points
+	if stars > 0 {
+		score += math.Min(30, 7.5*math.Log10(float64(stars)))
+	}
+	if s.OwnerType == "Organization" {
+		score += 10
+	}
+	if s.WellKnown {
+		score += 15
+	}
+	if !s.OwnerCreated.IsZero() {
+		years := time.Since(s.OwnerCreated).Hours() / (24 * 365)
+		score += math.Min(15, 3*years)
+	}
+	if s.Funded {
+		score += 5
+	}
+	if s.Scorecard >= 0 {
+		score += 2.5 * s.Scorecard
+	}
+	return int(math.Min(100, math.Round(score)))
+}
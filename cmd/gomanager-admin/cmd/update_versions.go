@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,7 +17,7 @@ var (
 
 func init() {
 	updateVersionsCmd.Flags().IntVarP(&updateBatchSize, "batch-size", "n", 100, "Max repositories to check")
-	updateVersionsCmd.Flags().StringVarP(&updateDatabase, "database", "d", "", "Path to database.db (default: ~/.config/gomanager/database.db)")
+	updateVersionsCmd.Flags().StringVarP(&updateDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
 	rootCmd.AddCommand(updateVersionsCmd)
 }
 
@@ -30,14 +29,7 @@ in the database. When a version changes, the package's version is updated
 and updated_at is set, so the verify command with --recheck can detect
 packages that need re-verification and flag regressions.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var conn *sql.DB
-		var err error
-
-		if updateDatabase != "" {
-			conn, err = db.OpenPath(updateDatabase)
-		} else {
-			conn, err = db.Open()
-		}
+		conn, err := openAdminDB(updateDatabase)
 		if err != nil {
 			return err
 		}
@@ -118,6 +110,7 @@ packages that need re-verification and flag regressions.`,
 						fmt.Printf("  Warning: failed to update %s: %v\n", b.Name, err)
 						continue
 					}
+					enqueueJob(conn, b.Package, db.JobUpdated)
 					fmt.Printf("  %s: %s → %s", b.Name, b.Version, latestVersion)
 					if b.BuildStatus == "confirmed" {
 						fmt.Print(" (needs re-verify)")
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/githubtest"
+)
+
+func TestUpdateVersions(t *testing.T) {
+	isolate(t)
+	gh := githubtest.NewServer(
+		&githubtest.Repo{Owner: "acme", Name: "widget", Release: "v1.2.0"},
+		&githubtest.Repo{Owner: "acme", Name: "multi", Release: "v2.1.0"},
+		&githubtest.Repo{Owner: "acme", Name: "old"},
+	)
+	defer gh.Close()
+
+	dbPath := newTestDB(t, "",
+		db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0", BuildStatus: "confirmed", IsPrimary: true},
+		db.Binary{Name: "foo", Package: "github.com/acme/multi/v2/cmd/foo", Version: "v2.0.0", BuildStatus: "confirmed"},
+		db.Binary{Name: "bar", Package: "github.com/acme/multi/v2/cmd/bar", Version: "v2.1.0", BuildStatus: "confirmed"},
+		db.Binary{Name: "old", Package: "github.com/acme/old", Version: "v0.1.0", BuildStatus: "failed"},
+		db.Binary{Name: "vanity", Package: "example.com/vanity", Version: "v1.0.0"},
+	)
+	out := runAdmin(t, gh.URL, "update-versions", "-d", dbPath)
+
+	conn := openTestDB(t, dbPath)
+	got := queryStrings(t, conn, `SELECT package, version FROM binaries ORDER BY package`)
+	want := []string{
+		"example.com/vanity v1.0.0",
+		"github.com/acme/multi/v2/cmd/bar v2.1.0",
+		"github.com/acme/multi/v2/cmd/foo v2.1.0",
+		"github.com/acme/old v0.1.0",
+		"github.com/acme/widget v1.2.0",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("versions after update-versions:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	// Only the packages whose version changed are queued for verification.
+	got = queryStrings(t, conn, `SELECT package, reason FROM jobs ORDER BY package`)
+	want = []string{
+		"github.com/acme/multi/v2/cmd/foo " + db.JobUpdated,
+		"github.com/acme/widget " + db.JobUpdated,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("jobs after update-versions = %q, want %q", got, want)
+	}
+
+	if !strings.Contains(out, "widget: v1.0.0 → v1.2.0 (needs re-verify)") {
+		t.Errorf("output doesn't report the widget update:\n%s", out)
+	}
+	if !strings.Contains(out, "Checked 3 repos, 2 updated, 1 skipped") {
+		t.Errorf("output doesn't summarize the run:\n%s", out)
+	}
+	for _, req := range gh.Requests() {
+		if strings.Contains(req, "vanity") {
+			t.Errorf("update-versions looked up a non-GitHub package: %s", req)
+		}
+	}
+}
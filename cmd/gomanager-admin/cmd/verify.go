@@ -2,24 +2,66 @@ package cmd
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/progress"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verifyBatchSize int
-	verifyDatabase  string
-	verifyReverify  bool
-	verifyRecheck   bool
+	verifyBatchSize   int
+	verifyDatabase    string
+	verifyReverify    bool
+	verifyRecheck     bool
+	verifyProgress    string
+	verifyJobs        int
+	verifyModCache    string
+	verifyMaxAge      string
+	verifyPlatforms   []string
+	verifyQueue       bool
+	verifyMaxDuration string
+)
+
+// defaultPlatforms are the platforms --platforms cross-builds for when given
+// without a list.
+var defaultPlatforms = []string{
+	"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64", "windows/arm64",
+}
+
+// Module cache strategies for parallel verification.
+const (
+	modCacheShared      = "shared"
+	modCachePartitioned = "partitioned"
 )
 
 func init() {
 	verifyCmd.Flags().IntVarP(&verifyBatchSize, "batch-size", "n", 50, "Number of packages to verify")
-	verifyCmd.Flags().StringVarP(&verifyDatabase, "database", "d", "", "Path to database.db (default: ~/.config/gomanager/database.db)")
+	verifyCmd.Flags().StringVarP(&verifyDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
 	verifyCmd.Flags().BoolVarP(&verifyReverify, "reverify", "r", false, "Also re-verify previously failed packages")
 	verifyCmd.Flags().BoolVar(&verifyRecheck, "recheck", false, "Re-verify confirmed packages that received version updates")
+	verifyCmd.Flags().StringVar(&verifyProgress, "progress", progress.FormatText, "Progress output format: text, or json for NDJSON events on stderr")
+	verifyCmd.Flags().IntVarP(&verifyJobs, "jobs", "j", 1, "Number of packages to build concurrently")
+	verifyCmd.Flags().StringVar(&verifyModCache, "modcache", modCacheShared,
+		"Module cache strategy with --jobs > 1: shared, or partitioned for one GOMODCACHE per worker")
+	verifyCmd.Flags().StringVar(&verifyMaxAge, "max-age", "", "Also re-verify confirmed packages last verified longer ago than this (e.g. 90d, 72h)")
+	verifyCmd.Flags().StringSliceVar(&verifyPlatforms, "platforms", nil,
+		"Also cross-build confirmed packages for these goos/goarch platforms (default set if given without a list)")
+	verifyCmd.Flags().Lookup("platforms").NoOptDefVal = strings.Join(defaultPlatforms, ",")
+	verifyCmd.Flags().BoolVar(&verifyQueue, "queue", false, "Verify the highest-priority packages in the job queue instead of querying for work")
+	verifyCmd.Flags().StringVar(&verifyMaxDuration, "max-duration", "", "Stop starting builds after this long and report what remains (e.g. 50m)")
+	verifyCmd.MarkFlagsMutuallyExclusive("queue", "reverify")
+	verifyCmd.MarkFlagsMutuallyExclusive("queue", "recheck")
 	rootCmd.AddCommand(verifyCmd)
 }
 
@@ -27,18 +69,79 @@ var verifyCmd = &cobra.Command{
 	Use:   "verify",
 	Short: "Verify that packages build with go install",
 	Long: `Attempt 'go install' on unverified packages and update their build status
-in the database. If a build fails, it retries with CGO_ENABLED=0.
+in the database. If a build fails with the recorded build flags, fallback
+strategies are merged on top of those flags and tried in order:
+
+  cgo-disabled      CGO_ENABLED=0
+  mod-mod           GOFLAGS=-mod=mod (go.sum/go.mod consistency errors)
+  toolchain-local   GOTOOLCHAIN=local (toolchain download errors)
+  no-vcs-stamp      GOFLAGS=-buildvcs=false (VCS stamping errors)
+
+Strategies other than cgo-disabled are only tried when the original error
+mentions a matching symptom. The strategy that succeeded is recorded along
+with the merged flags; a failure leaves the recorded flags unchanged.
+
+The --batch-size packages verified are the highest-priority ones, ranked
+like queued jobs (see 'gomanager-admin queue'): popular, stale, bumped, and
+frequently installed packages first.
+
+With --jobs N, up to N builds run concurrently, overlapping their module
+downloads. Concurrent go commands share the module cache through file
+locks; --modcache partitioned instead gives each worker its own GOMODCACHE
+(seeded read-only from the shared cache through a file:// GOPROXY entry).
+That avoids the locks but downloads modules the workers have in common once
+per worker, so it isn't necessarily faster. The elapsed time is reported at
+the end so strategies can be compared against a --jobs 1 baseline.
+
+With --platforms, each confirmed package is also cross-built for the given
+goos/goarch platforms (by default linux, darwin, and windows on amd64 and
+arm64) using the flags that confirmed it, and the platforms it builds for
+are recorded. Cross-builds only compile: cgo is disabled for them unless the
+package's flags enable it.
+
+A build that fails because go:embed patterns match no files (assets that are
+generated or built by make before compiling, and so are missing from the
+module zip) is marked codegen rather than failed, with a reason clients
+show; clients install these from release archives or a source checkout.
+--reverify retries codegen packages along with failed ones.
+
+When go install fails for any other reason, go run of the same package and
+version is tried (compiling only; the program is not executed). If that
+works, the package keeps its failed status but is marked run-only, and
+'gomanager run' uses go run for it.
+
+Builds always verify modules against sum.golang.org, whatever GOSUMDB,
+GONOSUMDB, or GOPRIVATE say, and whether each package verified cleanly, is
+missing from the checksum database (so clients need a GONOSUMDB exception),
+or mismatched it is recorded.
+
+The go.sum hash of the module each confirmed build used is recorded per
+version (and published to clients). The first hash seen for a version is
+kept; if a later build of the same version has a different one, meaning
+the tag was moved or the module rewritten upstream, a warning is printed
+and a sum-changed event is recorded (see 'gomanager-admin why').
+
+With --queue, packages are taken from the job queue that scan, probe-roots,
+update-versions, and approve add to (see 'gomanager-admin queue') rather
+than found by querying the whole table: the --batch-size highest-priority
+jobs are claimed, ranked as 'gomanager-admin queue' describes. A confirmed build completes its job; a failed one is retried
+with exponential backoff, up to 5 attempts. --max-age adds aged packages
+to the queue before claiming.
+
+With --max-duration, no new builds are started once that much time has
+passed (e.g. 50m, to stay inside a CI job's timeout). Builds in flight
+finish and every result is committed as usual; the packages never started
+are reported, and with --queue their jobs are released so the next run
+claims them again.
 
 This can be run locally or in CI.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var conn *sql.DB
-		var err error
-
-		if verifyDatabase != "" {
-			conn, err = db.OpenPath(verifyDatabase)
-		} else {
-			conn, err = db.Open()
+		events, err := progress.New(verifyProgress, os.Stderr)
+		if err != nil {
+			return err
 		}
+
+		conn, err := openAdminDB(verifyDatabase)
 		if err != nil {
 			return err
 		}
@@ -49,14 +152,37 @@ This can be run locally or in CI.`,
 			return fmt.Errorf("schema migration failed: %w", err)
 		}
 
-		statuses := []string{"unknown", "pending"}
-		if verifyReverify {
-			statuses = append(statuses, "failed")
-		}
+		var binaries []db.Binary
+		if verifyQueue {
+			// --max-age feeds the queue rather than bypassing it, so aged
+			// packages are ranked along with everything else
+			if verifyMaxAge != "" {
+				maxAge, err := parseAge(verifyMaxAge)
+				if err != nil {
+					return fmt.Errorf("invalid --max-age: %w", err)
+				}
+				aged, err := db.GetVerifiedBefore(conn, time.Now().Add(-maxAge), verifyBatchSize)
+				if err != nil {
+					return fmt.Errorf("aged confirmed query failed: %w", err)
+				}
+				for _, b := range aged {
+					enqueueJob(conn, b.Package, db.JobSeeded)
+				}
+			}
+			binaries, err = claimQueued(conn, verifyBatchSize)
+			if err != nil {
+				return err
+			}
+		} else {
+			statuses := []string{"unknown", "pending"}
+			if verifyReverify {
+				statuses = append(statuses, "failed", db.StatusCodegen)
+			}
 
-		binaries, err := db.GetUnverified(conn, statuses, verifyBatchSize)
-		if err != nil {
-			return fmt.Errorf("query failed: %w", err)
+			binaries, err = db.GetUnverified(conn, statuses, verifyBatchSize)
+			if err != nil {
+				return fmt.Errorf("query failed: %w", err)
+			}
 		}
 
 		// If --recheck, also include confirmed packages that got version updates
@@ -67,7 +193,37 @@ This can be run locally or in CI.`,
 			}
 			if len(stale) > 0 {
 				fmt.Printf("Found %d confirmed packages with version updates to re-check\n", len(stale))
-				binaries = append(binaries, stale...)
+				binaries = appendUnique(binaries, stale)
+			}
+		}
+
+		// If --max-age, also include confirmed packages whose verification
+		// has aged out, regardless of version changes
+		if verifyMaxAge != "" && !verifyQueue {
+			maxAge, err := parseAge(verifyMaxAge)
+			if err != nil {
+				return fmt.Errorf("invalid --max-age: %w", err)
+			}
+			aged, err := db.GetVerifiedBefore(conn, time.Now().Add(-maxAge), verifyBatchSize)
+			if err != nil {
+				return fmt.Errorf("aged confirmed query failed: %w", err)
+			}
+			if len(aged) > 0 {
+				fmt.Printf("Found %d confirmed packages last verified more than %s ago\n", len(aged), verifyMaxAge)
+				binaries = appendUnique(binaries, aged)
+			}
+		}
+
+		var limit time.Duration
+		if verifyMaxDuration != "" {
+			if limit, err = parseAge(verifyMaxDuration); err != nil {
+				return fmt.Errorf("invalid --max-duration: %w", err)
+			}
+		}
+
+		for _, p := range verifyPlatforms {
+			if !validPlatform.MatchString(p) {
+				return fmt.Errorf("invalid platform %q (want goos/goarch, e.g. linux/arm64)", p)
 			}
 		}
 
@@ -76,32 +232,80 @@ This can be run locally or in CI.`,
 			return nil
 		}
 
-		fmt.Printf("Verifying %d packages\n\n", len(binaries))
+		jobs := verifyJobs
+		if jobs < 1 {
+			jobs = 1
+		}
+		if jobs > len(binaries) {
+			jobs = len(binaries)
+		}
+		fmt.Printf("Verifying %d packages (jobs=%d, modcache=%s)\n\n", len(binaries), jobs, verifyModCache)
 
-		confirmedCount, failedCount, regressedCount := 0, 0, 0
+		runs, cleanup, err := workerRuns(jobs, verifyModCache, events)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
 
-		for i, b := range binaries {
-			version := b.Version
-			if version == "" {
-				version = "latest"
+		// Workers only build; results are written to the database from this
+		// goroutine so SQLite never sees concurrent writers.
+		work := make(chan db.Binary)
+		results := make(chan verifyResult)
+		var wg sync.WaitGroup
+		for _, run := range runs {
+			wg.Add(1)
+			go func(run installRun) {
+				defer wg.Done()
+				for b := range work {
+					results <- verifyOne(b, run)
+				}
+			}(run)
+		}
+		started := time.Now()
+		// The deadline only stops dispatching; builds already handed to a
+		// worker run to completion so their results are committed.
+		var deadline <-chan time.Time
+		if limit > 0 {
+			timer := time.NewTimer(limit)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		var remaining []db.Binary
+		go func() {
+			defer func() {
+				close(work)
+				wg.Wait()
+				close(results)
+			}()
+			for i, b := range binaries {
+				select {
+				case work <- b:
+				case <-deadline:
+					remaining = binaries[i:]
+					return
+				}
 			}
-			installPath := b.Package + "@" + version
-
-			fmt.Printf("[%d/%d] %s\n", i+1, len(binaries), installPath)
+		}()
 
-			envFlags := parseEnvFlags(b.BuildFlags)
+		goVersion := goEnv("GOVERSION")
+		confirmedCount, failedCount, regressedCount, codegenCount := 0, 0, 0, 0
+		done := 0
 
-			ok, resultFlags, buildErr := tryGoInstall(installPath, envFlags)
-			if !ok && len(envFlags) == 0 {
-				// Retry with CGO_ENABLED=0
-				fmt.Println("  Retrying with CGO_ENABLED=0...")
-				ok, resultFlags, buildErr = tryGoInstall(installPath, map[string]string{"CGO_ENABLED": "0"})
+		for r := range results {
+			b := r.binary
+			done++
+			fmt.Printf("[%d/%d] %s (%s)\n", done, len(binaries), r.installPath, r.duration.Round(100*time.Millisecond))
+			if len(r.tried) > 1 {
+				fmt.Printf("  Tried strategies: %s\n", strings.Join(r.tried, ", "))
 			}
 
-			if ok {
+			if r.ok {
 				confirmedCount++
-				flagsJSON := marshalFlags(resultFlags)
+				flagsJSON := marshalFlags(r.flags)
 				fmt.Printf("  ✓ confirmed")
+				if r.strategy != strategyDefault {
+					fmt.Printf(" via %s", r.strategy)
+				}
 				if flagsJSON != "{}" {
 					fmt.Printf(" (%s)", flagsJSON)
 				}
@@ -109,25 +313,418 @@ This can be run locally or in CI.`,
 				if err := db.UpdateBuildResult(conn, b.ID, "confirmed", flagsJSON, ""); err != nil {
 					fmt.Printf("  Warning: failed to update database: %v\n", err)
 				}
+				if err := db.UpdateBuildStrategy(conn, b.ID, r.strategy); err != nil {
+					fmt.Printf("  Warning: failed to record build strategy: %v\n", err)
+				}
+				if err := db.UpdateBuildCost(conn, b.ID, r.duration, r.downloadSize); err != nil {
+					fmt.Printf("  Warning: failed to record build cost: %v\n", err)
+				}
+				recordModuleSum(conn, r)
+				recordSumDBStatus(conn, r)
+				if r.platforms != nil {
+					fmt.Printf("  platforms: %s\n", formatPlatforms(r.platforms))
+					if err := db.UpdatePlatformSupport(conn, b.ID, r.platforms); err != nil {
+						fmt.Printf("  Warning: failed to record platform support: %v\n", err)
+					}
+				}
+				recordHistory(conn, db.BuildRecord{
+					Package: b.Package, Version: r.version, Status: "confirmed",
+					GoVersion: goVersion, Strategy: r.strategy,
+				})
+				if verifyQueue {
+					if err := db.CompleteJob(conn, b.Package); err != nil {
+						fmt.Printf("  Warning: failed to complete queue job: %v\n", err)
+					}
+				}
+				events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: r.version, Status: "confirmed"})
 			} else {
-				// If this was a previously confirmed package, it's a regression
+				// If this was a previously confirmed package, it's a regression,
+				// unless the sources are just incomplete without codegen
 				status := "failed"
-				if b.BuildStatus == "confirmed" {
+				reason := codegenReason(r.buildErr)
+				switch {
+				case reason != "":
+					status = db.StatusCodegen
+					codegenCount++
+					fmt.Printf("  ⚙ needs codegen: %s\n", reason)
+				case b.BuildStatus == "confirmed":
 					status = "regressed"
 					regressedCount++
-					fmt.Printf("  ⚠ REGRESSED: %s\n", truncate(buildErr, 200))
-				} else {
+					fmt.Printf("  ⚠ REGRESSED: %s\n", truncate(r.buildErr, 200))
+				default:
 					failedCount++
-					fmt.Printf("  ✗ failed: %s\n", truncate(buildErr, 200))
+					fmt.Printf("  ✗ failed: %s\n", truncate(r.buildErr, 200))
 				}
-				if err := db.UpdateBuildResult(conn, b.ID, status, b.BuildFlags, buildErr); err != nil {
+				if err := db.UpdateBuildResult(conn, b.ID, status, b.BuildFlags, r.buildErr); err != nil {
 					fmt.Printf("  Warning: failed to update database: %v\n", err)
 				}
+				recordSumDBStatus(conn, r)
+				if reason != "" {
+					if err := db.SetStatusReason(conn, b.ID, reason); err != nil {
+						fmt.Printf("  Warning: failed to record status reason: %v\n", err)
+					}
+				}
+				if r.runOnly {
+					fmt.Println("  ↪ works with go run")
+					if err := db.SetRunOnly(conn, b.ID, true); err != nil {
+						fmt.Printf("  Warning: failed to record run-only capability: %v\n", err)
+					}
+				}
+				recordHistory(conn, db.BuildRecord{
+					Package: b.Package, Version: r.version, Status: status,
+					Error: r.buildErr, GoVersion: goVersion,
+				})
+				if verifyQueue {
+					// Codegen packages won't build no matter how often
+					// they're retried
+					jobErr := db.FailJob(conn, b.Package, truncate(r.buildErr, 500))
+					if status == db.StatusCodegen {
+						jobErr = db.CompleteJob(conn, b.Package)
+					}
+					if jobErr != nil {
+						fmt.Printf("  Warning: failed to update queue job: %v\n", jobErr)
+					}
+				}
+				events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: r.version, Status: status, Error: r.buildErr})
 			}
 		}
 
-		fmt.Printf("\nDone. Confirmed: %d, Failed: %d, Regressed: %d, Total: %d\n",
-			confirmedCount, failedCount, regressedCount, len(binaries))
+		elapsed := time.Since(started)
+		fmt.Printf("\nElapsed %s (%.1fs/package, jobs=%d, modcache=%s)\n",
+			elapsed.Round(time.Second), elapsed.Seconds()/float64(max(done, 1)), jobs, verifyModCache)
+		if len(remaining) > 0 {
+			// results is closed only after the dispatcher returned, so
+			// remaining is settled here
+			fmt.Printf("\nStopped after --max-duration %s: %d of %d packages not started\n",
+				verifyMaxDuration, len(remaining), len(binaries))
+			if verifyQueue {
+				for _, b := range remaining {
+					if err := db.ReleaseJob(conn, b.Package); err != nil {
+						fmt.Printf("  Warning: failed to release queue job for %s: %v\n", b.Package, err)
+					}
+				}
+			}
+		}
+		fmt.Printf("\nDone. Confirmed: %d, Failed: %d, Regressed: %d, Codegen: %d, Total: %d\n",
+			confirmedCount, failedCount, regressedCount, codegenCount, done)
 		return nil
 	},
 }
+
+// codegenSymptoms match build errors from packages whose module sources are
+// incomplete without a generate or asset build step. The first group
+// captures the go:embed pattern that matched nothing.
+var codegenSymptoms = []*regexp.Regexp{
+	regexp.MustCompile(`pattern (\S+): no matching files found`),
+	regexp.MustCompile(`pattern (\S+): cannot embed directory \S+: contains no embeddable files`),
+}
+
+// codegenReason returns why a build error indicates the package needs code
+// generation before building, or "" if it doesn't.
+func codegenReason(buildErr string) string {
+	for _, re := range codegenSymptoms {
+		if m := re.FindStringSubmatch(buildErr); m != nil {
+			return fmt.Sprintf("go:embed pattern %s matches no files; the assets are generated or built before compiling", m[1])
+		}
+	}
+	return ""
+}
+
+// recordModuleSum records the module hash of a confirmed build, and warns
+// and records an event if an earlier build of the same version had a
+// different one: the upstream tag was moved or the module rewritten.
+func recordModuleSum(conn *sql.DB, r verifyResult) {
+	if r.moduleSum == "" {
+		return
+	}
+	pkg := r.binary.Package
+	recorded, err := db.RecordModuleSum(conn, pkg, r.moduleVersion, r.moduleSum)
+	if err != nil {
+		fmt.Printf("  Warning: failed to record module hash: %v\n", err)
+		return
+	}
+	if recorded == "" {
+		return
+	}
+	fmt.Printf("  ⚠ MODULE HASH CHANGED: %s was %s, now %s (tag rewritten upstream?)\n", r.moduleVersion, recorded, r.moduleSum)
+	detail := fmt.Sprintf("%s: %s -> %s", r.moduleVersion, recorded, r.moduleSum)
+	if err := db.RecordEvent(conn, pkg, db.EventSumChanged, detail); err != nil {
+		fmt.Printf("  Warning: failed to record event: %v\n", err)
+	}
+}
+
+// sumdbSymptoms classify go command errors from checksum database
+// verification.
+var (
+	sumdbMismatch = regexp.MustCompile(`SECURITY ERROR|checksum mismatch`)
+	sumdbMissing  = regexp.MustCompile(`verifying (module|go\.mod): .*(404 Not Found|410 Gone|not found)`)
+)
+
+// sumDBStatus returns whether the build in r verified against the checksum
+// database, or "" if it can't tell (the build failed before or for reasons
+// other than verification, e.g. the checksum database was unreachable).
+func sumDBStatus(r verifyResult) string {
+	switch {
+	case r.ok:
+		return db.SumDBVerified
+	case sumdbMismatch.MatchString(r.buildErr):
+		return db.SumDBMismatch
+	case sumdbMissing.MatchString(r.buildErr):
+		return db.SumDBMissing
+	}
+	return ""
+}
+
+// recordSumDBStatus records whether the build in r verified against the
+// checksum database, if it can tell.
+func recordSumDBStatus(conn *sql.DB, r verifyResult) {
+	status := sumDBStatus(r)
+	if status == "" {
+		return
+	}
+	switch status {
+	case db.SumDBMismatch:
+		fmt.Println("  ⚠ SUMDB MISMATCH: the module doesn't match sum.golang.org")
+	case db.SumDBMissing:
+		fmt.Println("  sum.golang.org has no record of the module")
+	}
+	if err := db.SetSumDBStatus(conn, r.binary.ID, status); err != nil {
+		fmt.Printf("  Warning: failed to record sumdb status: %v\n", err)
+	}
+}
+
+// recordHistory appends a verification attempt to the build history,
+// warning rather than failing the run if it can't be written.
+func recordHistory(conn *sql.DB, r db.BuildRecord) {
+	if err := db.AppendBuildHistory(conn, r); err != nil {
+		fmt.Printf("  Warning: failed to record build history: %v\n", err)
+	}
+}
+
+// verifyResult is the outcome of building one package.
+type verifyResult struct {
+	binary      db.Binary
+	version     string
+	installPath string
+	ok          bool
+	strategy    string
+	tried       []string
+	flags       map[string]string
+	buildErr    string
+	duration    time.Duration
+	// runOnly reports that a failed go install compiles with go run.
+	runOnly bool
+	// downloadSize is the size of the module zips the build needed.
+	downloadSize int64
+	// platforms records which --platforms the package cross-built for.
+	platforms map[string]bool
+	// moduleVersion and moduleSum are the version and go.sum hash of the
+	// module the build used, from the binary's build info.
+	moduleVersion, moduleSum string
+}
+
+// verifyOne builds a single package with its recorded flags, falling back to
+// the applicable strategies in fallbackStrategies if that fails.
+func verifyOne(b db.Binary, run installRun) verifyResult {
+	version := b.Version
+	if version == "" {
+		version = "latest"
+	}
+	r := verifyResult{binary: b, version: version, installPath: b.Package + "@" + version}
+
+	envFlags := parseEnvFlags(b.BuildFlags)
+
+	run.events.Emit(progress.Event{Event: progress.Resolve, Name: b.Name, Package: b.Package, Version: version})
+	run.events.Emit(progress.Event{Event: progress.BuildStart, Name: b.Name, Package: b.Package, Version: version})
+	modCache := run.env["GOMODCACHE"]
+	if modCache == "" {
+		modCache = goEnv("GOMODCACHE")
+	}
+	run.built = func(path string) {
+		r.downloadSize += moduleDownloadSize(path, modCache)
+		r.moduleVersion, r.moduleSum = builtModule(path)
+	}
+	start := time.Now()
+	r.ok, r.flags, r.strategy, r.buildErr, r.tried = buildWithFallbacks(r.installPath, envFlags, run)
+	r.duration = time.Since(start)
+	if r.ok && len(verifyPlatforms) > 0 {
+		r.platforms = crossBuild(r.installPath, r.flags, verifyPlatforms, modCache, run)
+	}
+
+	end := progress.Event{
+		Event: progress.BuildEnd, Name: b.Name, Package: b.Package, Version: version,
+		Status: "ok", Duration: r.duration.Seconds(),
+	}
+	if !r.ok {
+		end.Status = "failed"
+		end.Error = r.buildErr
+	}
+	run.events.Emit(end)
+
+	// Script-like tools can still be usable with go run
+	if !r.ok && codegenReason(r.buildErr) == "" {
+		r.runOnly = tryGoRun(r.installPath, envFlags, run)
+	}
+	return r
+}
+
+// tryGoRun reports whether go run compiles installPath. The compiled
+// program is handed to true(1) instead of being executed, so nothing from
+// the package runs.
+func tryGoRun(installPath string, flags map[string]string, run installRun) bool {
+	goCmd := exec.Command("go", "run", "-exec", "true", installPath)
+	goCmd.Env = safeGoEnv("", flags)
+	for k, v := range run.env {
+		goCmd.Env = append(goCmd.Env, k+"="+v)
+	}
+	return goCmd.Run() == nil
+}
+
+// validPlatform matches a goos/goarch pair.
+var validPlatform = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+$`)
+
+// crossBuild builds installPath for each of platforms with the given flags
+// and reports which succeeded. The host platform is already known to build.
+func crossBuild(installPath string, flags map[string]string, platforms []string, modCache string, run installRun) map[string]bool {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	result := make(map[string]bool, len(platforms))
+	for _, p := range platforms {
+		if p == host {
+			result[p] = true
+			continue
+		}
+		goos, goarch, _ := strings.Cut(p, "/")
+		result[p] = tryCrossBuild(installPath, flags, goos, goarch, modCache, run)
+	}
+	return result
+}
+
+// tryCrossBuild runs go install for installPath targeting goos/goarch. go
+// install refuses to write cross-compiled binaries to GOBIN, so it installs
+// into the bin directory of a throwaway GOPATH instead, keeping the module
+// cache at modCache.
+func tryCrossBuild(installPath string, flags map[string]string, goos, goarch, modCache string, run installRun) bool {
+	tmpDir, err := os.MkdirTemp("", "gomanager-cross-*")
+	if err != nil {
+		return false
+	}
+	defer removeModCache(tmpDir)
+
+	goCmd := exec.Command("go", "install", installPath)
+	goCmd.Env = safeGoEnv("", flags)
+	for k, v := range run.env {
+		goCmd.Env = append(goCmd.Env, k+"="+v)
+	}
+	goCmd.Env = append(goCmd.Env, "GOPATH="+tmpDir, "GOOS="+goos, "GOARCH="+goarch)
+	if modCache != "" {
+		goCmd.Env = append(goCmd.Env, "GOMODCACHE="+modCache)
+	}
+	return goCmd.Run() == nil
+}
+
+// formatPlatforms renders platform results as "linux/amd64 ✓ windows/arm64 ✗",
+// sorted by platform.
+func formatPlatforms(platforms map[string]bool) string {
+	names := make([]string, 0, len(platforms))
+	for p := range platforms {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, p := range names {
+		mark := "✓"
+		if !platforms[p] {
+			mark = "✗"
+		}
+		parts[i] = p + " " + mark
+	}
+	return strings.Join(parts, "  ")
+}
+
+// workerRuns returns the per-worker install settings for the given module
+// cache strategy, along with a cleanup function removing any partitions.
+func workerRuns(jobs int, strategy string, events *progress.Reporter) ([]installRun, func(), error) {
+	runs := make([]installRun, jobs)
+	for i := range runs {
+		runs[i] = installRun{events: events}
+	}
+
+	switch strategy {
+	case modCacheShared:
+		return runs, func() {}, nil
+	case modCachePartitioned:
+	default:
+		return nil, nil, fmt.Errorf("unknown --modcache strategy %q (want %s or %s)", strategy, modCacheShared, modCachePartitioned)
+	}
+
+	base, err := os.MkdirTemp("", "gomanager-modcache-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create module cache partitions: %w", err)
+	}
+
+	// Let partitions read modules already present in the shared cache
+	// instead of re-downloading them; its download directory is laid out as
+	// a GOPROXY. Anything missing falls through to the configured proxy.
+	proxy := os.Getenv("GOPROXY")
+	if proxy == "" {
+		proxy = "https://proxy.golang.org,direct"
+	}
+	if shared := goEnv("GOMODCACHE"); shared != "" {
+		download := filepath.Join(shared, "cache", "download")
+		if _, err := os.Stat(download); err == nil {
+			proxy = "file://" + filepath.ToSlash(download) + "," + proxy
+		}
+	}
+
+	for i := range runs {
+		runs[i].env = map[string]string{
+			"GOMODCACHE": filepath.Join(base, fmt.Sprintf("worker-%d", i)),
+			"GOPROXY":    proxy,
+		}
+	}
+	return runs, func() {
+		if err := removeModCache(base); err != nil {
+			fmt.Printf("Warning: failed to remove module cache partitions: %v\n", err)
+		}
+	}, nil
+}
+
+// claimQueued claims up to n jobs from the queue and returns their
+// binaries. Jobs for packages that have since been removed are dropped.
+func claimQueued(conn *sql.DB, n int) ([]db.Binary, error) {
+	jobs, err := db.ClaimJobs(conn, n)
+	if err != nil {
+		return nil, fmt.Errorf("claim queue jobs: %w", err)
+	}
+	var binaries []db.Binary
+	for _, j := range jobs {
+		b, err := db.GetAnyByPackage(conn, j.Package)
+		if errors.Is(err, db.ErrNotFound) {
+			db.CompleteJob(conn, j.Package)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		binaries = append(binaries, *b)
+	}
+	if len(jobs) > 0 {
+		fmt.Printf("Claimed %d queued jobs\n", len(jobs))
+	}
+	return binaries, nil
+}
+
+// appendUnique appends the binaries from more that aren't already in list.
+func appendUnique(list, more []db.Binary) []db.Binary {
+	seen := make(map[int]bool, len(list))
+	for _, b := range list {
+		seen[b.ID] = true
+	}
+	for _, b := range more {
+		if !seen[b.ID] {
+			seen[b.ID] = true
+			list = append(list, b)
+		}
+	}
+	return list
+}
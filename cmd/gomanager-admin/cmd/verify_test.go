@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// benchModules is the number of packages each BenchmarkVerify iteration
+// verifies.
+const benchModules = 8
+
+// benchLatency is added to every module proxy response in BenchmarkVerify,
+// standing in for the network.
+const benchLatency = 50 * time.Millisecond
+
+// writeModule adds version v1.0.0 of module path, holding files, to the
+// GOPROXY directory proxy.
+func writeModule(b *testing.B, proxy, path string, files map[string]string) {
+	b.Helper()
+	dir := filepath.Join(proxy, filepath.FromSlash(path), "@v")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		b.Fatal(err)
+	}
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	write("list", "v1.0.0\n")
+	write("v1.0.0.info", `{"Version":"v1.0.0","Time":"2026-01-01T00:00:00Z"}`)
+	write("v1.0.0.mod", files["go.mod"])
+
+	f, err := os.Create(filepath.Join(dir, "v1.0.0.zip"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(path + "@v1.0.0/" + name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// benchProxy writes a GOPROXY of benchModules main packages that all
+// depend on one library module, and returns its directory and the
+// packages.
+func benchProxy(b *testing.B) (string, []db.Binary) {
+	b.Helper()
+	proxy := b.TempDir()
+	writeModule(b, proxy, "example.com/bench/lib", map[string]string{
+		"go.mod": "module example.com/bench/lib\n\ngo 1.21\n",
+		"lib.go": "package lib\n\nfunc Name() string { return \"lib\" }\n",
+	})
+	var binaries []db.Binary
+	for i := range benchModules {
+		path := fmt.Sprintf("example.com/bench/tool%d", i)
+		writeModule(b, proxy, path, map[string]string{
+			"go.mod":  "module " + path + "\n\ngo 1.21\n\nrequire example.com/bench/lib v1.0.0\n",
+			"main.go": "package main\n\nimport \"example.com/bench/lib\"\n\nfunc main() { println(lib.Name()) }\n",
+		})
+		binaries = append(binaries, db.Binary{Name: fmt.Sprintf("tool%d", i), Package: path, Version: "v1.0.0"})
+	}
+	return proxy, binaries
+}
+
+// BenchmarkVerify compares verifying a batch of packages serially, with
+// workers sharing one module cache, and with a module cache per worker.
+// Each iteration starts from an empty module cache, downloading from a
+// local proxy that answers after benchLatency; compiled packages stay in
+// the build cache, so the work measured is mostly module download and
+// extraction, where the strategies differ, plus linking, which only scales
+// with CPUs. The checksum database is off since the modules are local.
+//
+//	go test ./cmd/gomanager-admin/cmd -run '^$' -bench Verify
+func BenchmarkVerify(b *testing.B) {
+	proxy, binaries := benchProxy(b)
+	files := http.FileServer(http.Dir(proxy))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(benchLatency)
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	b.Setenv("GOPROXY", srv.URL)
+	b.Setenv("GOFLAGS", "-modcacherw")
+	b.Setenv("GOTOOLCHAIN", "local")
+
+	for _, bench := range []struct {
+		name     string
+		jobs     int
+		strategy string
+	}{
+		{"serial", 1, modCacheShared},
+		{"shared", 4, modCacheShared},
+		{"partitioned", 4, modCachePartitioned},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for b.Loop() {
+				b.StopTimer()
+				b.Setenv("GOMODCACHE", b.TempDir())
+				runs, cleanup, err := workerRuns(bench.jobs, bench.strategy, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for i := range runs {
+					if runs[i].env == nil {
+						runs[i].env = make(map[string]string)
+					}
+					runs[i].env["GOSUMDB"] = "off"
+				}
+				b.StartTimer()
+
+				work := make(chan db.Binary)
+				var wg sync.WaitGroup
+				var mu sync.Mutex
+				var failed []string
+				for _, run := range runs {
+					wg.Add(1)
+					go func(run installRun) {
+						defer wg.Done()
+						for bin := range work {
+							if r := verifyOne(bin, run); !r.ok {
+								mu.Lock()
+								failed = append(failed, bin.Package+": "+r.buildErr)
+								mu.Unlock()
+							}
+						}
+					}(run)
+				}
+				for _, bin := range binaries {
+					work <- bin
+				}
+				close(work)
+				wg.Wait()
+
+				b.StopTimer()
+				cleanup()
+				if len(failed) > 0 {
+					b.Fatalf("verify failed: %q", failed)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
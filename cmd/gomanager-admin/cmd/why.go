@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	whyDatabase string
+	whyLimit    int
+)
+
+func init() {
+	whyCmd.Flags().StringVarP(&whyDatabase, "database", "d", "", "Path to database.db (default: ~/.local/share/gomanager/database.db)")
+	whyCmd.Flags().IntVarP(&whyLimit, "limit", "n", 10, "Maximum number of verifications to show")
+	rootCmd.AddCommand(whyCmd)
+}
+
+var whyCmd = &cobra.Command{
+	Use:   "why <package>",
+	Short: "Explain how a package came to be in the database",
+	Long: `Shows a package's provenance: the scanner search query that found its
+repository and when, the heuristic that detected its entrypoint (root
+main.go, a cmd/ directory, a goreleaser config, a Homebrew formula, or a
+module root probe), the curation events recorded for it (approvals,
+rejections, path fixes, module hash changes), and its verification history,
+newest first.
+
+The argument may be a package path or a binary name. A package path that is
+no longer in the database still shows its recorded curation events, e.g. to
+explain a rejection. Packages added before provenance was recorded show
+only when they were added.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := openAdminDB(whyDatabase)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := db.MigrateSchema(conn); err != nil {
+			return fmt.Errorf("schema migration failed: %w", err)
+		}
+
+		b, err := db.GetAnyByPackage(conn, args[0])
+		if errors.Is(err, db.ErrNotFound) && !strings.Contains(args[0], "/") {
+			b, err = db.GetAnyByName(conn, args[0])
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			// Rejected and deduplicated packages are deleted, but their
+			// events explain where they went.
+			events, evErr := db.GetEvents(conn, args[0])
+			if evErr != nil || len(events) == 0 {
+				return err
+			}
+			fmt.Printf("%s is not in the database.\n\nCuration\n", args[0])
+			printEvents(events)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		prov, err := db.GetProvenance(conn, b.Package)
+		if err != nil {
+			return err
+		}
+		events, err := db.GetEvents(conn, b.Package)
+		if err != nil {
+			return err
+		}
+		records, err := db.GetBuildHistory(conn, b.Package, whyLimit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%s)\n", b.Package, b.BuildStatus)
+
+		fmt.Println("\nDiscovery")
+		added := "unknown"
+		if !prov.AddedAt.IsZero() {
+			added = prov.AddedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  Added:       %s\n", added)
+		if prov.Query != "" {
+			fmt.Printf("  Query:       %s\n", prov.Query)
+		} else {
+			fmt.Println("  Query:       not recorded")
+		}
+		if prov.Source != "" {
+			fmt.Printf("  Entrypoint:  %s\n", prov.Source)
+		} else {
+			fmt.Println("  Entrypoint:  not recorded")
+		}
+
+		fmt.Println("\nCuration")
+		if len(events) == 0 {
+			fmt.Println("  No curation events recorded.")
+		}
+		printEvents(events)
+
+		fmt.Println("\nVerification")
+		if len(records) == 0 {
+			fmt.Println("  No verification history recorded.")
+			return nil
+		}
+		for _, r := range records {
+			fmt.Printf("  %s  %-10s %-12s %s", r.VerifiedAt.Format("2006-01-02 15:04"), r.Status, r.Version, r.GoVersion)
+			if r.Strategy != "" && r.Strategy != strategyDefault {
+				fmt.Printf("  via %s", r.Strategy)
+			}
+			fmt.Println()
+			if r.Error != "" {
+				fmt.Printf("      %s\n", truncate(r.Error, 200))
+			}
+		}
+		if summary := historySummary(records); summary != "" {
+			fmt.Printf("\n%s\n", summary)
+		}
+		return nil
+	},
+}
+
+// printEvents prints curation events, one per line.
+func printEvents(events []db.Event) {
+	for _, e := range events {
+		fmt.Printf("  %s  %s\n", e.At.Format("2006-01-02 15:04"), describeEvent(e))
+	}
+}
+
+// describeEvent renders a curation event as a short sentence.
+func describeEvent(e db.Event) string {
+	switch e.Event {
+	case db.EventApproved:
+		return "approved as " + e.Detail
+	case db.EventRejected:
+		return "rejected and removed"
+	case db.EventRenamed:
+		return "renamed from " + e.Detail
+	case db.EventDuplicate:
+		return "removed as a duplicate of " + e.Detail
+	case db.EventNameFixed:
+		return "binary renamed from " + e.Detail
+	}
+	if e.Detail != "" {
+		return e.Event + ": " + e.Detail
+	}
+	return e.Event
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"database/sql"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/attest"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var adoptDir string
+
+func init() {
+	adoptCmd.Flags().StringVar(&adoptDir, "dir", "", "Scan this directory instead of the go install directory")
+	adoptCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be adopted without changing the install state")
+	rootCmd.AddCommand(adoptCmd)
+}
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Track Go binaries installed without gomanager",
+	Long: `Scans the go install directory ($GOBIN) for Go binaries gomanager doesn't
+track, reads the package and version embedded in each (as 'go version -m'
+shows), and matches them against the database by package path. Matches are
+recorded in the install state as if gomanager had installed them, so they
+can be upgraded, pinned, and uninstalled. Nothing is rebuilt.
+
+Binaries built from a local checkout have no version and are skipped, as
+are binaries whose file name differs from the database entry's and files
+that aren't Go binaries.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := adoptDir
+		if dir == "" {
+			var err error
+			if dir, err = goBinDir(); err != nil {
+				return err
+			}
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("cannot scan %s: %w", dir, err)
+		}
+
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		t := newTable("NAME", "PACKAGE", "VERSION", "STATUS")
+		adopted, skipped := 0, 0
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), ".exe")
+			if e.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if _, ok := st.Installed[name]; ok {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := buildinfo.ReadFile(path)
+			if err != nil {
+				continue // not a Go binary
+			}
+			status, b := adoptStatus(conn, name, info)
+			if b == nil {
+				t.row(name, info.Path, info.Main.Version, status)
+				skipped++
+				continue
+			}
+			if !dryRun {
+				st.MarkInstalled(name, b.Package, info.Main.Version, dir)
+				ib := st.Installed[name]
+				ib.Sum = info.Main.Sum
+				ib.SHA256, _ = attest.FileDigest(path)
+				if fi, err := e.Info(); err == nil {
+					// The file's age is a better guess at when it was
+					// installed than now.
+					ib.InstalledAt = fi.ModTime()
+				}
+				st.Installed[name] = ib
+			}
+			t.row(name, b.Package, info.Main.Version, status)
+			adopted++
+		}
+		if adopted+skipped == 0 {
+			fmt.Printf("No untracked Go binaries in %s.\n", dir)
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		t.flush()
+
+		if dryRun {
+			fmt.Printf("\n%d binaries would be adopted, %d skipped.\n", adopted, skipped)
+			return nil
+		}
+		if adopted > 0 {
+			if err := st.Save(); err != nil {
+				return fmt.Errorf("cannot save install state: %w", err)
+			}
+		}
+		fmt.Printf("\nAdopted %d binaries, skipped %d.\n", adopted, skipped)
+		if adopted == 0 {
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		return nil
+	},
+}
+
+// adoptStatus matches the Go binary named name, with build info info,
+// against the database. It returns the entry to adopt it as, or nil and the
+// reason it can't be adopted.
+func adoptStatus(conn *sql.DB, name string, info *buildinfo.BuildInfo) (string, *db.Binary) {
+	if info.Path == "" {
+		return "no package path recorded", nil
+	}
+	if v := info.Main.Version; v == "" || v == "(devel)" {
+		return "built from a local checkout", nil
+	}
+	b, err := lookupPackage(conn, info.Path)
+	if errors.Is(err, db.ErrNotFound) {
+		return "not in database", nil
+	}
+	if err != nil {
+		return fmt.Sprintf("cannot look up: %v", err), nil
+	}
+	if b.Name != name {
+		return fmt.Sprintf("database names it %s", b.Name), nil
+	}
+	if dryRun {
+		return "would adopt", b
+	}
+	return "adopted", b
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmelahman/gomanager/internal/archive"
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var archiveCleanKeep int
+
+func init() {
+	archiveCleanCmd.Flags().IntVar(&archiveCleanKeep, "keep", -1,
+		"Previous versions to keep per binary (default: the configured archive.keep)")
+	archiveCmd.AddCommand(archiveListCmd)
+	archiveCmd.AddCommand(archiveCleanCmd)
+	archiveCmd.AddCommand(archiveRestoreCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Manage copies of binaries replaced by upgrades",
+	Long: `When archiving is enabled, upgrade copies the binary it is about to replace
+into ~/.local/share/gomanager/archive/<name>/<version> (under $XDG_DATA_HOME
+if set), so a bad upgrade can be rolled back with a file copy instead of a
+rebuild. Enable it in ~/.config/gomanager/config.toml:
+
+  [archive]
+  keep = 3   # previous versions kept per binary; 0 disables archiving`,
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:   "list [name]",
+	Short: "List archived binaries",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		entries, err := archive.List(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No archived binaries.")
+			return nil
+		}
+
+		t := newTable("NAME", "VERSION", "SIZE", "ARCHIVED")
+		for _, e := range entries {
+			t.row(e.Name, e.Version, formatBytes(e.Size), e.ArchivedAt.Format("2006-01-02 15:04"))
+		}
+		t.flush()
+		return nil
+	},
+}
+
+var archiveCleanCmd = &cobra.Command{
+	Use:   "clean [name]",
+	Short: "Remove archived binaries beyond the retention limit",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keep := archiveCleanKeep
+		if keep < 0 {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			keep = cfg.Archive.Keep
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		removed, err := archive.Prune(name, keep)
+		var freed int64
+		for _, e := range removed {
+			fmt.Printf("Removed %s %s\n", e.Name, e.Version)
+			freed += e.Size
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			fmt.Println("Nothing to clean.")
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		fmt.Printf("Freed %s.\n", formatBytes(freed))
+		return nil
+	},
+}
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "restore <name> [version]",
+	Short: "Roll back to an archived binary (the newest, or the given version)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, version := args[0], ""
+		if len(args) == 2 {
+			version = args[1]
+		}
+
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		installed, ok := st.Installed[name]
+		if !ok {
+			return withExitCode(ExitNotFound, fmt.Errorf("%s is not installed", name))
+		}
+		e, err := archive.Find(name, version)
+		if err != nil {
+			return withExitCode(ExitNotFound, err)
+		}
+		if e.Version == installed.Version {
+			fmt.Printf("%s is already at %s\n", name, e.Version)
+			return withExitCode(ExitNothingToDo, nil)
+		}
+
+		dest, err := installedPath(name)
+		if err != nil {
+			return err
+		}
+		// Keep the version being replaced so the rollback can be undone.
+		if _, err := os.Stat(dest); err == nil && installed.Version != "" {
+			if _, err := archive.Save(name, installed.Version, dest); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		if err := archive.Restore(e, dest); err != nil {
+			return err
+		}
+
+		st.MarkInstalled(name, installed.Package, e.Version, filepath.Dir(dest))
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("cannot save install state: %w", err)
+		}
+		fmt.Printf("Restored %s %s (was %s)\n", name, e.Version, installed.Version)
+		return nil
+	},
+}
+
+// archiveInstalled archives the installed copy of name before an upgrade
+// replaces it, and prunes older copies, if archiving is enabled. Failures
+// are reported but don't stop the upgrade.
+func archiveInstalled(name, version string) {
+	cfg, err := config.Load()
+	if err != nil || cfg.Archive.Keep <= 0 || version == "" {
+		return
+	}
+	path, err := installedPath(name)
+	if err != nil {
+		logf(name, "Warning: cannot archive %s: %v\n", name, err)
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if _, err := archive.Save(name, version, path); err != nil {
+		logf(name, "Warning: %v\n", err)
+		return
+	}
+	if _, err := archive.Prune(name, cfg.Archive.Keep); err != nil {
+		logf(name, "Warning: cannot prune archive of %s: %v\n", name, err)
+	}
+}
+
+// formatBytes formats a byte count with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/attest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyAttestKey    string
+	verifyAttestBinDir string
+)
+
+func init() {
+	verifyAttestationCmd.Flags().StringVar(&verifyAttestKey, "key", "", "PEM Ed25519 public key the attestation must be signed with (required)")
+	verifyAttestationCmd.Flags().StringVar(&verifyAttestBinDir, "bin-dir", "", "Check the binaries in this directory instead of the one recorded in the attestation")
+	verifyAttestationCmd.MarkFlagRequired("key")
+	rootCmd.AddCommand(verifyAttestationCmd)
+}
+
+var verifyAttestationCmd = &cobra.Command{
+	Use:   "verify-attestation <file>",
+	Short: "Check a manifest install attestation and the binaries it lists",
+	Long: `Checks that an attestation written by 'install --from-manifest --attest'
+is signed by the given key, lists the tools it records, and compares the
+SHA-256 digest of each installed binary against it. It fails if the
+signature doesn't match or any binary is missing or has changed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pub, err := attest.LoadPublicKey(verifyAttestKey)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		var env attest.Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return fmt.Errorf("invalid attestation %s: %w", args[0], err)
+		}
+		st, err := attest.Verify(&env, pub)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+
+		p := st.Predicate
+		binDir := p.BinDir
+		if verifyAttestBinDir != "" {
+			binDir = verifyAttestBinDir
+		}
+		fmt.Printf("Signed attestation of %s (cache key %s, %s), %s\n", p.Manifest, p.ManifestKey, p.Platform,
+			p.Timestamp.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("Checking %s\n\n", binDir)
+
+		t := newTable("NAME", "PACKAGE", "VERSION", "BINARY")
+		bad := 0
+		for _, tool := range p.Tools {
+			status := "ok"
+			digest, err := attest.FileDigest(filepath.Join(binDir, executableName(tool.Name)))
+			switch {
+			case err != nil:
+				status = "missing"
+				bad++
+			case digest != tool.SHA256:
+				status = "changed"
+				bad++
+			}
+			t.row(tool.Name, tool.Package, tool.Version, status)
+		}
+		t.flush()
+		if bad > 0 {
+			return fmt.Errorf("%d of %d binaries don't match the attestation", bad, len(p.Tools))
+		}
+		return nil
+	},
+}
+
+// writeAttestation writes a signed attestation of the manifest install in
+// binDir with the given key, from its stamp, to path. cached is set if
+// nothing had to be built.
+func writeAttestation(path string, signer ed25519.PrivateKey, binDir, key string, cached bool) error {
+	stamp, err := readStamp(binDir)
+	if err != nil || stamp.Key != key {
+		return fmt.Errorf("cannot attest: %s records no complete install of %s", binDir, installManifest)
+	}
+	tools := stamp.Tools
+	if len(tools) == 0 {
+		// Stamps from older versions only name the binaries.
+		for _, name := range stamp.Binaries {
+			tools = append(tools, stampTool{Name: name})
+		}
+	}
+
+	manifestPath, err := filepath.Abs(installManifest)
+	if err != nil {
+		return err
+	}
+	p := attest.Predicate{
+		Manifest:    manifestPath,
+		ManifestKey: key,
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		BinDir:      binDir,
+		Cached:      cached,
+		Timestamp:   time.Now().UTC(),
+	}
+	for _, t := range tools {
+		file := filepath.Join(binDir, executableName(t.Name))
+		digest, err := attest.FileDigest(file)
+		if err != nil {
+			return fmt.Errorf("cannot attest %s: %w", t.Name, err)
+		}
+		tool := attest.Tool{Name: t.Name, Package: t.Package, Version: t.Version, Env: t.Env, SHA256: digest}
+		if info, err := buildinfo.ReadFile(file); err == nil {
+			tool.GoVersion = info.GoVersion
+		}
+		p.Tools = append(p.Tools, tool)
+	}
+
+	env, err := attest.Sign(attest.NewStatement(p), signer)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot write attestation: %w", err)
+	}
+	fmt.Printf("Wrote a signed attestation of %d tools to %s\n", len(p.Tools), path)
+	return nil
+}
+
+// executableName returns the file name of the binary named name on this
+// platform.
+func executableName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/dirs"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/jmelahman/gomanager/internal/release"
+)
+
+// Install backends, selected with --backend.
+const (
+	backendAuto    = "auto"
+	backendProxy   = "proxy"
+	backendRelease = "release"
+	backendSource  = "source"
+)
+
+// installBackend is bound to the --backend flag of every command that
+// installs.
+var installBackend = backendAuto
+
+const installBackendUsage = "How to install: auto, proxy (go install), release (prebuilt archive), or source (clone and build)"
+
+// githubAPI is the GitHub REST API used to find release archives.
+const githubAPI = "https://api.github.com"
+
+// checkBackend validates --backend.
+func checkBackend() error {
+	switch installBackend {
+	case backendAuto, backendProxy, backendRelease, backendSource:
+		return nil
+	}
+	return fmt.Errorf("unknown backend %q (want auto, proxy, release, or source)", installBackend)
+}
+
+// usesGoInstall reports whether installBinary installs b with go install.
+func usesGoInstall(b *db.Binary) bool {
+	switch installBackend {
+	case backendProxy:
+		return true
+	case backendAuto:
+		return b.BuildStatus != db.StatusCodegen
+	}
+	return false
+}
+
+// installBinary installs b with the --backend backend. The auto backend
+// uses go install, except for packages marked as needing code generation,
+// which can't be built from the module proxy: those are installed from a
+// release archive for this platform, or built from a source checkout if
+// there is none. Builds are served from and added to the binary cache, if
+// one is configured. The outcome is reported if telemetry is turned on.
+func installBinary(b *db.Binary) (err error) {
+	defer func() { reportInstall(b, err) }()
+	cache := openCache(b)
+	if cache != nil && installFromCache(cache, b) {
+		return nil
+	}
+	if err := buildBinary(b); err != nil {
+		return err
+	}
+	if cache != nil {
+		uploadToCache(cache, b)
+	}
+	return nil
+}
+
+// buildBinary installs b without the binary cache.
+func buildBinary(b *db.Binary) error {
+	switch {
+	case usesGoInstall(b):
+		return runGoInstall(b)
+	case installBackend == backendRelease:
+		return installFromRelease(b)
+	case installBackend == backendSource:
+		return installFromSource(b)
+	}
+
+	logf(b.Name, "%s can't be built with go install: %s\n", b.Name, b.StatusReason)
+	err := installFromRelease(b)
+	if err == nil {
+		return nil
+	}
+	logf(b.Name, "No usable release archive (%v); building from source.\n", err)
+	return installFromSource(b)
+}
+
+// binaryFile returns the file name of b's executable on this platform.
+func binaryFile(b *db.Binary) string {
+	if runtime.GOOS == "windows" {
+		return b.Name + ".exe"
+	}
+	return b.Name
+}
+
+// installFromRelease installs b from the GitHub release archive for this
+// platform, checking it against the release's checksum file if there is
+// one.
+func installFromRelease(b *db.Binary) error {
+	parts := strings.Split(b.Package, "/")
+	if len(parts) < 3 || parts[0] != "github.com" {
+		return fmt.Errorf("%s is not hosted on GitHub", b.Package)
+	}
+	owner, repo := parts[1], parts[2]
+	if b.Version == "" || b.Version == "latest" {
+		return fmt.Errorf("%s has no release version", b.Name)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	assets, err := release.FetchAssets(client, githubAPI, owner, repo, githubToken(), b.Version)
+	if err != nil {
+		return err
+	}
+	a, ok := release.Archives(assets, runtime.GOOS)[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("release %s has no archive for %s/%s", b.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	sums, err := release.Checksums(client, assets)
+	if err != nil {
+		return err
+	}
+
+	logf(b.Name, "Downloading %s\n", a.URL)
+	data, err := release.Download(client, a.URL)
+	if err != nil {
+		return err
+	}
+	if _, err := release.Verify(data, a.Name, sums); err != nil {
+		return err
+	}
+	ext := release.ArchiveExt(a.Name)
+	files, err := release.Files(data, ext)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", a.Name, err)
+	}
+	path := release.Find(files, []string{binaryFile(b)})
+	if path == "" {
+		return fmt.Errorf("%s does not contain a %q binary", a.Name, binaryFile(b))
+	}
+	contents, err := release.Extract(data, ext, path)
+	if err != nil {
+		return err
+	}
+
+	binDir, tmpBin, err := buildDirs(b.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpBin)
+	if err := os.WriteFile(filepath.Join(tmpBin, binaryFile(b)), contents, 0o755); err != nil {
+		return err
+	}
+	if err := moveBuilt(tmpBin, binDir, binaryFile(b)); err != nil {
+		return err
+	}
+	recordInstall(b, b.Version)
+	return nil
+}
+
+// installFromSource clones b's repository at its version, runs go generate
+// (and, if that isn't enough, make) and builds the binary.
+func installFromSource(b *db.Binary) error {
+	repoURL := b.RepoURL
+	if repoURL == "" {
+		repoURL = "https://" + strings.Join(strings.SplitN(b.Package, "/", 4)[:3], "/")
+	}
+
+	work, err := cacheWorkDir(sourceCache)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+	src := filepath.Join(work, "src")
+
+	clone := []string{"-c", "advice.detachedHead=false", "clone", "--depth", "1"}
+	if b.Version != "" && b.Version != "latest" {
+		clone = append(clone, "--branch", b.Version)
+	}
+	clone = append(clone, repoURL, src)
+	if err := runIn(b.Name, "", nil, "git", clone...); err != nil {
+		return withExitCode(ExitBuildFailed, fmt.Errorf("cannot clone %s: %w", repoURL, err))
+	}
+
+	binDir, tmpBin, err := buildDirs(b.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpBin)
+
+	paths := pkgbuild.ResolvePaths(b.Package)
+	moduleDir := filepath.Join(src, paths.ModuleDir)
+	env := buildEnv(b)
+	sumdb, err := sumdbEnv(b, env)
+	if err != nil {
+		return err
+	}
+	env = append(env, sumdb...)
+	build := []string{"build", "-trimpath", "-o", filepath.Join(tmpBin, binaryFile(b)), paths.Build}
+
+	if err := runIn(b.Name, moduleDir, env, "go", "generate", "./..."); err != nil {
+		logf(b.Name, "Warning: go generate failed: %v\n", err)
+	}
+	err = runIn(b.Name, moduleDir, env, "go", build...)
+	if _, statErr := os.Stat(filepath.Join(src, "Makefile")); err != nil && statErr == nil {
+		logf(b.Name, "Build failed after go generate; running make to build the assets.\n")
+		if err = runIn(b.Name, src, env, "make"); err == nil {
+			err = runIn(b.Name, moduleDir, env, "go", build...)
+		}
+	}
+	if err != nil {
+		return withExitCode(ExitBuildFailed, fmt.Errorf("building %s from source failed: %w", b.Name, err))
+	}
+	if err := moveBuilt(tmpBin, binDir, binaryFile(b)); err != nil {
+		return err
+	}
+	recordInstall(b, b.Version)
+	return nil
+}
+
+// Directories in the cache directory that builds work in (see
+// cacheWorkDir).
+const (
+	sourceCache = "src"
+	diffCache   = "diff"
+)
+
+// cacheWorkDir creates a new directory for one build under the directory
+// name in the cache directory. Checkouts and their builds can be large, so
+// they go there rather than in the system temp directory, often a small
+// tmpfs; the caller removes the directory when done.
+func cacheWorkDir(name string) (string, error) {
+	dir, err := dirs.CacheFile(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	return os.MkdirTemp(dir, "build-*")
+}
+
+// runIn runs a command in dir with extra environment variables, echoing it
+// and its output as part of building the binary named bin.
+func runIn(bin, dir string, env []string, name string, args ...string) error {
+	logf(bin, "Running: %s\n", strings.Join(append([]string{name}, args...), " "))
+	cmd := osexec.Command(name, args...)
+	cmd.Dir = dir
+	stdout, stderr, done := buildOutput(bin)
+	defer done()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
@@ -0,0 +1,496 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of files in a backup, which are also their names in the archive.
+const (
+	backupConfig = "config.toml"
+	backupState  = "installed.json"
+	backupLocal  = "local.db"
+	backupPolicy = "policy.toml"
+)
+
+// backupManifestName is the archive entry describing the backup.
+const backupManifestName = "gomanager-backup.json"
+
+// maxBackupEntry caps the size of a file read from a backup.
+const maxBackupEntry = 256 << 20
+
+var (
+	restoreYes         bool
+	restoreInstall     bool
+	restorePolicyPaths bool
+)
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Don't ask for confirmation before replacing existing files")
+	restoreCmd.Flags().BoolVar(&restoreInstall, "install", false, "Also install the recorded versions of binaries that are missing")
+	restoreCmd.Flags().BoolVar(&restorePolicyPaths, "allow-policy-path", false, "Restore the policy file where the backed up configuration names it, even outside the config directory")
+	restoreCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	restoreCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	restoreCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// backupManifest describes a backup archive.
+type backupManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Home is the home directory of the backed up setup, so paths under
+	// it can be moved to the home directory of the machine restored to.
+	Home  string       `json:"home,omitempty"`
+	Files []backupFile `json:"files"`
+}
+
+// backupFile is a file in a backup archive.
+type backupFile struct {
+	// Kind is backupConfig, backupState, backupLocal, or backupPolicy,
+	// and the file's name in the archive.
+	Kind string `json:"kind"`
+	// Path is where the file was backed up from.
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [file]",
+	Short: "Save config, install state, and local entries to an archive",
+	Long: `Bundles everything that makes up your gomanager setup into a single
+.tar.gz archive (gomanager-backup-<date>.tar.gz by default, or - for
+stdout), for moving to another machine or recovering from a lost one:
+
+  config.toml     the configuration file
+  installed.json  the install state: what is installed at which version,
+                  release channels, pins, and snapshots
+  local.db        entries added with 'gomanager db add-local'
+  policy file     the policy file the configuration names, if any
+
+The binaries themselves aren't included: 'gomanager restore --install'
+reinstalls the recorded versions. The published database isn't either,
+since 'gomanager update-db' downloads it again.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := "gomanager-backup-" + time.Now().Format("20060102") + ".tar.gz"
+		if len(args) > 0 {
+			out = args[0]
+		}
+
+		paths, err := backupPaths()
+		if err != nil {
+			return err
+		}
+		m := backupManifest{Version: 1, CreatedAt: time.Now().UTC()}
+		m.Home, _ = os.UserHomeDir()
+		contents := make(map[string][]byte)
+		for _, kind := range []string{backupConfig, backupState, backupLocal, backupPolicy} {
+			path := paths[kind]
+			if path == "" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("cannot back up %s: %w", path, err)
+			}
+			sum := sha256.Sum256(data)
+			m.Files = append(m.Files, backupFile{Kind: kind, Path: path, SHA256: hex.EncodeToString(sum[:])})
+			contents[kind] = data
+		}
+		if len(m.Files) == 0 {
+			return withExitCode(ExitNothingToDo, fmt.Errorf("nothing to back up: no config, install state, or local entries"))
+		}
+
+		archive, err := writeBackup(m, contents)
+		if err != nil {
+			return err
+		}
+		if out == "-" {
+			_, err := os.Stdout.Write(archive)
+			return err
+		}
+		if err := os.WriteFile(out, archive, 0o600); err != nil {
+			return fmt.Errorf("cannot write backup: %w", err)
+		}
+		for _, f := range m.Files {
+			fmt.Printf("Backed up %s\n", f.Path)
+		}
+		fmt.Printf("Wrote %s\n", out)
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore config, install state, and local entries from a backup",
+	Long: `Restores the files in an archive written by 'gomanager backup' (or - for
+stdin) to this machine's gomanager directories, after checking them
+against the digests the backup recorded. Files that would be replaced are
+listed first and, on a terminal, only replaced once you confirm (or with
+--yes); the current copies are kept with a .bak suffix.
+
+Install directories recorded under the backed up home directory are moved
+to this machine's home directory. With --install, binaries the restored
+state records that aren't on disk are installed at their recorded
+versions, keeping their release channels and pins.
+
+The policy file is restored where the backed up configuration names it
+only if that is inside the config directory or is where the current
+configuration already keeps it, since a backup from elsewhere could name
+any file. Pass --allow-policy-path to restore it to another path.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read backup: %w", err)
+		}
+		m, contents, err := readBackup(data)
+		if err != nil {
+			return fmt.Errorf("invalid backup %s: %w", args[0], err)
+		}
+
+		if data, ok := contents[backupState]; ok {
+			if contents[backupState], err = rehomeState(data, m.Home); err != nil {
+				return fmt.Errorf("invalid backup %s: %w", args[0], err)
+			}
+		}
+
+		paths, err := backupPaths()
+		if err != nil {
+			return err
+		}
+		var replaced []string
+		for _, f := range m.Files {
+			dest, err := restoreDest(f.Kind, paths, contents)
+			if err != nil {
+				return err
+			}
+			paths[f.Kind] = dest
+			if cur, err := os.ReadFile(dest); err == nil && !bytes.Equal(cur, contents[f.Kind]) {
+				replaced = append(replaced, dest)
+			}
+		}
+		fmt.Printf("Backup of %s\n", m.CreatedAt.Local().Format("2006-01-02 15:04"))
+		if len(replaced) > 0 {
+			fmt.Println("These files will be replaced (the current copies are kept as .bak):")
+			for _, path := range replaced {
+				fmt.Printf("  %s\n", path)
+			}
+			if !restoreYes && stdinIsTerminal() {
+				fmt.Print("Continue? [y/N] ")
+				var answer string
+				fmt.Scanln(&answer)
+				if strings.ToLower(answer) != "y" {
+					return withExitCode(ExitDeclined, nil)
+				}
+			}
+		}
+
+		for _, f := range m.Files {
+			dest := paths[f.Kind]
+			changed, err := restoreFile(dest, contents[f.Kind])
+			if err != nil {
+				return fmt.Errorf("cannot restore %s: %w", dest, err)
+			}
+			if changed {
+				fmt.Printf("Restored %s\n", dest)
+			} else {
+				fmt.Printf("Unchanged %s\n", dest)
+			}
+		}
+
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		return restoreBinaries(st)
+	},
+}
+
+// backupPaths returns the paths of the files backup saves, by kind. The
+// policy path is empty if the configuration names none.
+func backupPaths() (map[string]string, error) {
+	paths := make(map[string]string)
+	var err error
+	if paths[backupConfig], err = config.Path(); err != nil {
+		return nil, err
+	}
+	if paths[backupState], err = state.Path(); err != nil {
+		return nil, err
+	}
+	if paths[backupLocal], err = db.LocalPath(); err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadPath(paths[backupConfig])
+	if err != nil {
+		return nil, err
+	}
+	paths[backupPolicy] = cfg.Policy
+	return paths, nil
+}
+
+// restoreDest returns where a backed up file of kind is restored to. The
+// policy file goes where the configuration being restored, or the current
+// one if the backup has none, names it, which without --allow-policy-path
+// must be in the config directory or the current policy path.
+func restoreDest(kind string, paths map[string]string, contents map[string][]byte) (string, error) {
+	if kind != backupPolicy {
+		return paths[kind], nil
+	}
+	policy := paths[backupPolicy]
+	if data, ok := contents[backupConfig]; ok {
+		var cfg config.Config
+		if _, err := toml.Decode(string(data), &cfg); err != nil {
+			return "", fmt.Errorf("invalid config.toml in backup: %w", err)
+		}
+		policy = cfg.Policy
+		if policy != "" && !filepath.IsAbs(policy) {
+			policy = filepath.Join(filepath.Dir(paths[backupConfig]), policy)
+		}
+	}
+	if policy == "" {
+		return "", fmt.Errorf("the backup has a policy file, but the configuration doesn't name one")
+	}
+	policy = filepath.Clean(policy)
+	configDir := filepath.Dir(paths[backupConfig])
+	if !restorePolicyPaths && !pathWithin(configDir, policy) && policy != filepath.Clean(paths[backupPolicy]) {
+		return "", fmt.Errorf("the backup's configuration names policy file %s, outside %s; check it and pass --allow-policy-path to restore it there", policy, configDir)
+	}
+	return policy, nil
+}
+
+// pathWithin reports whether path is inside the directory dir.
+func pathWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeBackup returns a .tar.gz archive of the manifest m and the contents
+// of its files.
+func writeBackup(m backupManifest, contents map[string][]byte) ([]byte, error) {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: m.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(backupManifestName, append(manifest, '\n')); err != nil {
+		return nil, err
+	}
+	for _, f := range m.Files {
+		if err := add(f.Kind, contents[f.Kind]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readBackup reads a backup archive, returning its manifest and the
+// contents of its files by kind after checking their digests.
+func readBackup(data []byte) (*backupManifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxBackupEntry {
+			continue
+		}
+		if entries[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	raw, ok := entries[backupManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("no %s; not a gomanager backup", backupManifestName)
+	}
+	var m backupManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", backupManifestName, err)
+	}
+	if m.Version != 1 {
+		return nil, nil, fmt.Errorf("unsupported backup version %d", m.Version)
+	}
+	contents := make(map[string][]byte)
+	for _, f := range m.Files {
+		switch f.Kind {
+		case backupConfig, backupState, backupLocal, backupPolicy:
+		default:
+			return nil, nil, fmt.Errorf("unknown file %q", f.Kind)
+		}
+		data, ok := entries[f.Kind]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is missing", f.Kind)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%s doesn't match its recorded digest", f.Kind)
+		}
+		contents[f.Kind] = data
+	}
+	return &m, contents, nil
+}
+
+// restoreFile replaces the file at path with data, keeping any current
+// copy as path.bak, and reports whether it did: a file that already has
+// the contents is left alone.
+func restoreFile(path string, data []byte) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		if cur, err := os.ReadFile(path); err == nil && bytes.Equal(cur, data) {
+			return false, nil
+		}
+		mode = fi.Mode().Perm()
+		if err := os.Rename(path, path+".bak"); err != nil {
+			return false, err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// rehomeState moves the install directories and desktop entries recorded
+// under the home directory from in the install state data to this
+// machine's home directory, returning the state to restore.
+func rehomeState(data []byte, from string) ([]byte, error) {
+	to, err := os.UserHomeDir()
+	if err != nil || from == "" || to == from {
+		return data, nil
+	}
+	var st state.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", backupState, err)
+	}
+	rehome := func(path string) (string, bool) {
+		rel, err := filepath.Rel(from, path)
+		if err != nil || path == "" || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, false
+		}
+		return filepath.Join(to, rel), true
+	}
+	moved := 0
+	for name, b := range st.Installed {
+		var dirMoved, entryMoved bool
+		b.BinDir, dirMoved = rehome(b.BinDir)
+		b.DesktopEntry, entryMoved = rehome(b.DesktopEntry)
+		if dirMoved || entryMoved {
+			st.Installed[name] = b
+			moved++
+		}
+	}
+	if moved == 0 {
+		return data, nil
+	}
+	fmt.Printf("Moving %d install directories from %s to %s\n", moved, from, to)
+	// Formatted as State.Save writes it, so restoring again finds it
+	// unchanged.
+	return json.MarshalIndent(&st, "", "  ")
+}
+
+// restoreBinaries reports the binaries st records that aren't on disk and,
+// with --install, installs their recorded versions.
+func restoreBinaries(st *state.State) error {
+	var missing []string
+	for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+		path, err := installedPath(name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !restoreInstall {
+		fmt.Printf("\n%d recorded binaries aren't installed on this machine: %s\n", len(missing), strings.Join(missing, ", "))
+		fmt.Println("Run 'gomanager restore --install' with the backup to install them.")
+		return nil
+	}
+
+	if err := ensureDB(); err != nil {
+		return err
+	}
+	conn, err := db.Open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	failed := 0
+	for _, name := range missing {
+		if err := restoreSnapshotBinary(conn, name, st.Installed[name], true); err != nil {
+			fmt.Printf("Failed to install %s %s: %v\n", name, st.Installed[name].Version, err)
+			failed++
+		}
+	}
+	fmt.Printf("\nInstalled %d of %d missing binaries.\n", len(missing)-failed, len(missing))
+	if failed > 0 {
+		return withExitCode(ExitBuildFailed, fmt.Errorf("%d binaries could not be installed", failed))
+	}
+	return nil
+}
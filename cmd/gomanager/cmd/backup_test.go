@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreDestPolicy(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "gomanager")
+	paths := map[string]string{
+		backupConfig: filepath.Join(configDir, "config.toml"),
+		backupPolicy: "/etc/gomanager/policy.toml",
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		allow  bool
+		want   string // "" if an error is expected
+	}{
+		{"relative", "policy.toml", false, filepath.Join(configDir, "policy.toml")},
+		{"inside config dir", filepath.Join(configDir, "team", "policy.toml"), false, filepath.Join(configDir, "team", "policy.toml")},
+		{"current policy path", "/etc/gomanager/policy.toml", false, "/etc/gomanager/policy.toml"},
+		{"absolute elsewhere", "/home/user/.bashrc", false, ""},
+		{"relative escape", "../../.bashrc", false, ""},
+		{"config dir itself", ".", false, ""},
+		{"allowed elsewhere", "/home/user/policy.toml", true, "/home/user/policy.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restorePolicyPaths = tt.allow
+			t.Cleanup(func() { restorePolicyPaths = false })
+			contents := map[string][]byte{
+				backupConfig: []byte("policy = " + `"` + tt.policy + `"` + "\n"),
+				backupPolicy: []byte("[deny]\n"),
+			}
+			got, err := restoreDest(backupPolicy, paths, contents)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("restoreDest() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("restoreDest() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("restoreDest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Explore the database in an interactive full-screen browser",
+	Long: `Opens a full-screen browser over the database. Typing fuzzy-searches
+binary names and package paths (and matches descriptions), best matches
+first; installed binaries are marked with ●.
+
+  ↑/↓, PgUp/PgDn  move through the results
+  Enter           show the selected binary's details
+  Esc             clear the search, or quit
+  Ctrl+C          quit
+
+In the details view, i installs the binary and u uninstalls it, running
+'gomanager install' or 'gomanager uninstall' with the terminal handed back
+to them, so their checks and prompts apply as usual. Esc returns to the
+results.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("browse needs a terminal; use 'gomanager search' in scripts")
+		}
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		binaries, err := searchBinaries(conn, "", db.SearchFilter{})
+		if err != nil {
+			return err
+		}
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot locate gomanager: %w", err)
+		}
+
+		b := &browser{all: binaries, matches: binaries, installed: st.Installed, self: self}
+		return b.run()
+	},
+}
+
+// Escape sequences the browser draws with.
+const (
+	escEnterScreen = "\x1b[?1049h\x1b[?25l" // alternate screen, hidden cursor
+	escLeaveScreen = "\x1b[?25h\x1b[?1049l"
+	escHome        = "\x1b[H\x1b[2J"
+	escBold        = "\x1b[1m"
+	escReverse     = "\x1b[7m"
+	escFaint       = "\x1b[2m"
+	escReset       = "\x1b[0m"
+)
+
+// browser is the state of the browse TUI.
+type browser struct {
+	all     []db.Binary
+	matches []db.Binary
+	query   string
+	// cursor indexes the selected match; offset is the first match shown.
+	cursor, offset int
+	// detail shows the selected binary's details instead of the results.
+	detail    bool
+	installed map[string]state.InstalledBinary
+	// status reports the outcome of the last install or uninstall.
+	status        string
+	width, height int
+	// self is the gomanager executable, run for installs and uninstalls.
+	self string
+	// restore is the terminal state before raw mode.
+	restore *term.State
+}
+
+// run takes over the terminal and handles key presses until the user
+// quits.
+func (b *browser) run() error {
+	if err := b.enter(); err != nil {
+		return err
+	}
+	defer b.leave()
+
+	buf := make([]byte, 256)
+	for {
+		b.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			quit, err := b.key(k)
+			if quit || err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// enter puts the terminal in raw mode on the alternate screen.
+func (b *browser) enter() error {
+	restore, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	b.restore = restore
+	fmt.Print(escEnterScreen)
+	return nil
+}
+
+// leave restores the terminal enter changed.
+func (b *browser) leave() {
+	fmt.Print(escLeaveScreen)
+	term.Restore(int(os.Stdin.Fd()), b.restore)
+}
+
+// key handles a key press, reporting whether to quit.
+func (b *browser) key(k string) (bool, error) {
+	if k == "ctrl+c" {
+		return true, nil
+	}
+	if b.detail {
+		return false, b.detailKey(k)
+	}
+
+	switch k {
+	case "esc":
+		if b.query == "" {
+			return true, nil
+		}
+		b.setQuery("")
+	case "up", "ctrl+p":
+		b.move(-1)
+	case "down", "ctrl+n":
+		b.move(1)
+	case "pgup":
+		b.move(-b.pageSize())
+	case "pgdown":
+		b.move(b.pageSize())
+	case "enter":
+		b.detail = len(b.matches) > 0
+	case "backspace":
+		if r := []rune(b.query); len(r) > 0 {
+			b.setQuery(string(r[:len(r)-1]))
+		}
+	default:
+		if utf8.RuneCountInString(k) == 1 {
+			b.setQuery(b.query + k)
+		}
+	}
+	return false, nil
+}
+
+// detailKey handles a key press in the details view.
+func (b *browser) detailKey(k string) error {
+	sel := &b.matches[b.cursor]
+	switch k {
+	case "esc", "backspace", "enter", "q":
+		b.detail = false
+	case "i":
+		return b.exec("install", sel.Name, sel.Package)
+	case "u":
+		if !b.isInstalled(sel) {
+			b.status = sel.Name + " is not installed via gomanager"
+			return nil
+		}
+		return b.exec("uninstall", sel.Name, sel.Name)
+	}
+	return nil
+}
+
+// exec runs 'gomanager <action> <arg>' on the restored terminal, so its
+// checks and prompts work as usual, then waits for Enter before returning
+// to the browser.
+func (b *browser) exec(action, name, arg string) error {
+	b.leave()
+	args := []string{action, arg}
+	if systemInstall {
+		args = append([]string{"--system"}, args...)
+	}
+	run := osexec.Command(b.self, args...)
+	run.Stdin, run.Stdout, run.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := run.Run(); err != nil {
+		b.status = fmt.Sprintf("%s %s failed: %v", action, name, err)
+	} else {
+		b.status = fmt.Sprintf("%s %s: done", action, name)
+	}
+	fmt.Print("\nPress Enter to return to the browser...")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if st, err := state.Load(); err == nil {
+		b.installed = st.Installed
+	}
+	return b.enter()
+}
+
+// setQuery changes the search and re-filters the results.
+func (b *browser) setQuery(q string) {
+	b.query = q
+	b.matches = fuzzyFilter(b.all, q)
+	b.cursor, b.offset = 0, 0
+}
+
+// move moves the cursor by delta matches, scrolling to keep it visible.
+func (b *browser) move(delta int) {
+	b.cursor = max(0, min(b.cursor+delta, len(b.matches)-1))
+	page := b.pageSize()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+page {
+		b.offset = b.cursor - page + 1
+	}
+}
+
+// pageSize is how many results fit on screen below the search lines and
+// above the help line.
+func (b *browser) pageSize() int {
+	return max(1, b.height-3)
+}
+
+func (b *browser) isInstalled(bin *db.Binary) bool {
+	inst, ok := b.installed[bin.Name]
+	return ok && inst.Package == bin.Package
+}
+
+// draw redraws the screen at the terminal's current size.
+func (b *browser) draw() {
+	b.width, b.height = 80, 24
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		b.width, b.height = w, h
+	}
+	// Keep the cursor on screen after a resize
+	b.move(0)
+
+	var lines []string
+	var help string
+	if b.detail {
+		lines, help = b.detailLines()
+	} else {
+		lines, help = b.resultLines()
+	}
+	for len(lines) < b.height-1 {
+		lines = append(lines, "")
+	}
+	if b.status != "" {
+		help = b.status + " · " + help
+	}
+	lines = append(lines[:b.height-1], escFaint+clip(help, b.width)+escReset)
+	fmt.Print(escHome + strings.Join(lines, "\r\n"))
+}
+
+// resultLines renders the search line and the visible results.
+func (b *browser) resultLines() ([]string, string) {
+	lines := []string{
+		escBold + "Search: " + escReset + b.query + "█",
+		escFaint + fmt.Sprintf("%d of %d binaries", len(b.matches), len(b.all)) + escReset,
+	}
+	end := min(b.offset+b.pageSize(), len(b.matches))
+	for i := b.offset; i < end; i++ {
+		bin := &b.matches[i]
+		mark := " "
+		if b.isInstalled(bin) {
+			mark = "●"
+		}
+		line := clip(fmt.Sprintf("%s %s %7d  %-10s %s", mark, padRight(clip(bin.Name, 24), 24), bin.Stars, bin.BuildStatus, bin.Description), b.width)
+		if i == b.cursor {
+			line = escReverse + line + escReset
+		}
+		lines = append(lines, line)
+	}
+	return lines, "↑/↓ move · Enter details · Esc clear/quit · Ctrl+C quit"
+}
+
+// detailLines renders the selected binary's details.
+func (b *browser) detailLines() ([]string, string) {
+	bin := &b.matches[b.cursor]
+	fields := [][2]string{
+		{"Name", bin.Name},
+		{"Package", bin.Package},
+		{"Version", bin.Version},
+		{"Description", bin.Description},
+		{"Repository", bin.RepoURL},
+		{"Stars", strconv.Itoa(bin.Stars)},
+		{"License", bin.License},
+		{"Trust", trustLabel(bin)},
+		{"Confidence", confidenceLabel(bin)},
+		{"Build status", bin.BuildStatus},
+		{"Status reason", bin.StatusReason},
+		{"Last verified", verifiedLabel(bin)},
+	}
+	if bin.Archived {
+		fields = append(fields, [2]string{"Upstream", archivedMarker + " (no longer maintained)"})
+	}
+	if bin.RunOnly {
+		fields = append(fields, [2]string{"Run", "gomanager run " + bin.Name})
+	} else {
+		fields = append(fields, [2]string{"Install", bin.InstallCommand()})
+	}
+	help := "i install · Esc back · Ctrl+C quit"
+	if b.isInstalled(bin) {
+		inst := b.installed[bin.Name]
+		fields = append(fields, [2]string{"Installed", fmt.Sprintf("%s (%s)", inst.Version, inst.InstalledAt.Format("2006-01-02"))})
+		help = "i reinstall · u uninstall · Esc back · Ctrl+C quit"
+	}
+
+	var lines []string
+	for _, f := range fields {
+		if f[1] != "" {
+			label := fmt.Sprintf("%-15s", f[0]+":")
+			lines = append(lines, escBold+label+escReset+clip(f[1], b.width-len(label)))
+		}
+	}
+	return lines, help
+}
+
+// parseKeys splits terminal input into key names: "up", "down", "pgup",
+// "pgdown", "enter", "esc", "backspace", "ctrl+c", "ctrl+n", "ctrl+p", or
+// the typed character. Unrecognized escape sequences are dropped.
+func parseKeys(in []byte) []string {
+	var keys []string
+	for len(in) > 0 {
+		switch c := in[0]; {
+		case c == 0x1b && len(in) == 1:
+			keys = append(keys, "esc")
+			in = in[1:]
+		case c == 0x1b:
+			// CSI or SS3 sequence: ESC [ or ESC O, parameters, and a
+			// final byte in @..~
+			end := 2
+			for end < len(in) && (in[end] < '@' || in[end] > '~') {
+				end++
+			}
+			end = min(end+1, len(in))
+			switch string(in[:end]) {
+			case "\x1b[A", "\x1bOA":
+				keys = append(keys, "up")
+			case "\x1b[B", "\x1bOB":
+				keys = append(keys, "down")
+			case "\x1b[5~":
+				keys = append(keys, "pgup")
+			case "\x1b[6~":
+				keys = append(keys, "pgdown")
+			}
+			in = in[end:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+			in = in[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, "backspace")
+			in = in[1:]
+		case c == 0x03:
+			keys = append(keys, "ctrl+c")
+			in = in[1:]
+		case c == 0x0e:
+			keys = append(keys, "ctrl+n")
+			in = in[1:]
+		case c == 0x10:
+			keys = append(keys, "ctrl+p")
+			in = in[1:]
+		case c < 0x20:
+			in = in[1:]
+		default:
+			r, size := utf8.DecodeRune(in)
+			keys = append(keys, string(r))
+			in = in[size:]
+		}
+	}
+	return keys
+}
+
+// fuzzyFilter returns the binaries matching query, best matches first and
+// otherwise in their original order. Names and package paths are matched
+// fuzzily; descriptions only by substring.
+func fuzzyFilter(binaries []db.Binary, query string) []db.Binary {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return binaries
+	}
+	type match struct {
+		b     db.Binary
+		score int
+	}
+	var matches []match
+	for _, b := range binaries {
+		score, ok := fuzzyScore(query, b.Name)
+		if ok {
+			// Name matches rank above package path matches
+			score += 10
+			if strings.EqualFold(b.Name, query) {
+				score += 100
+			}
+		} else if score, ok = fuzzyScore(query, b.Package); !ok {
+			if !strings.Contains(strings.ToLower(b.Description), query) {
+				continue
+			}
+			score = 0
+		}
+		matches = append(matches, match{b, score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	result := make([]db.Binary, len(matches))
+	for i, m := range matches {
+		result[i] = m.b
+	}
+	return result
+}
+
+// fuzzyScore reports whether query's characters appear in order in s,
+// ignoring case, and scores the match: a point per character, with bonuses
+// for a match at the start and for consecutive characters.
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(query)
+	score, qi, prev := 0, 0, -2
+	for i, r := range []rune(strings.ToLower(s)) {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if i == 0 {
+			score += 3
+		}
+		if i == prev+1 {
+			score += 2
+		}
+		prev = i
+		qi++
+	}
+	return score, qi == len(q)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/bincache"
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// binCache is the configured binary cache and the key of one build.
+type binCache struct {
+	store    bincache.Store
+	url      string
+	key      string
+	readOnly bool
+}
+
+// openCache returns the binary cache entry for building b, or nil if no
+// cache is configured or b isn't cacheable. Only builds of a pinned version
+// are cached; release archives are downloaded directly instead.
+func openCache(b *db.Binary) *binCache {
+	if b.Version == "" || b.Version == "latest" || !(usesGoInstall(b) || installBackend == backendSource) {
+		return nil
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.Cache.URL == "" {
+		return nil
+	}
+	store, err := bincache.Open(cfg.Cache.URL)
+	if err != nil {
+		logf(b.Name, "Warning: binary cache disabled: %v\n", err)
+		return nil
+	}
+	out, err := osexec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return nil
+	}
+	goVersion := strings.TrimSpace(string(out))
+	return &binCache{
+		store:    store,
+		url:      cfg.Cache.URL,
+		key:      bincache.Key(b.Package, b.Version, runtime.GOOS, runtime.GOARCH, goVersion, buildEnv(b)),
+		readOnly: cfg.Cache.ReadOnly,
+	}
+}
+
+// installFromCache installs b from the cache and reports whether it did. A
+// miss or a cache error falls back to building.
+func installFromCache(c *binCache, b *db.Binary) bool {
+	binDir, tmpDir, err := buildDirs(b.Name)
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmp := filepath.Join(tmpDir, binaryFile(b))
+	if err := bincache.Fetch(c.store, c.key, tmp); err != nil {
+		if !errors.Is(err, bincache.ErrMiss) {
+			logf(b.Name, "Warning: cannot use the binary cache: %v\n", err)
+		}
+		return false
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		logf(b.Name, "Warning: cannot use the binary cache: %v\n", err)
+		return false
+	}
+	if err := os.Rename(tmp, filepath.Join(binDir, binaryFile(b))); err != nil {
+		logf(b.Name, "Warning: cannot use the binary cache: %v\n", err)
+		return false
+	}
+	logf(b.Name, "Fetched %s %s from the binary cache\n", b.Name, b.Version)
+	recordInstall(b, b.Version)
+	return true
+}
+
+// uploadToCache adds the binary just built for b to the cache, unless the
+// cache is read-only. Failures only warn: the install itself succeeded.
+func uploadToCache(c *binCache, b *db.Binary) {
+	if c.readOnly {
+		return
+	}
+	path, err := installedPath(b.Name)
+	if err != nil {
+		return
+	}
+	if err := bincache.Upload(c.store, c.key, path); err != nil {
+		logf(b.Name, "Warning: cannot upload %s to the binary cache: %v\n", b.Name, err)
+		return
+	}
+	logf(b.Name, "Uploaded %s %s to the binary cache at %s\n", b.Name, b.Version, c.url)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	channelCmd.AddCommand(channelSetCmd)
+	rootCmd.AddCommand(channelCmd)
+}
+
+var channelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Choose which release channel an installed binary follows",
+	Long: `Each installed binary follows a release channel that upgrade respects:
+
+  stable  the version confirmed to build in the database (default)
+  latest  the newest version the Go module proxy reports for @latest
+
+The latest channel queries the first http(s):// or file:// proxy in
+$GOPROXY (default https://proxy.golang.org), using its version list when
+it doesn't answer @latest, and may install versions the build pipeline
+hasn't verified yet. 'gomanager list' shows each binary's channel.`,
+}
+
+var channelSetCmd = &cobra.Command{
+	Use:   "set <name> <stable|latest>",
+	Short: "Set the release channel of an installed binary",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, channel := args[0], args[1]
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		if _, ok := st.Installed[name]; !ok {
+			return withExitCode(ExitNotFound, fmt.Errorf("%s is not installed", name))
+		}
+		if err := st.SetChannel(name, channel); err != nil {
+			return err
+		}
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("cannot save install state: %w", err)
+		}
+		fmt.Printf("%s now follows the %s channel.\n", name, channel)
+		return nil
+	},
+}
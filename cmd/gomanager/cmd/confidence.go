@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// lowConfidenceOK installs binaries whose database entry scores below
+// db.LowConfidence. It is bound to the --low-confidence-ok flag of every
+// command that installs.
+var lowConfidenceOK bool
+
+const lowConfidenceOKUsage = "Install even if the database entry was inferred from weak signals"
+
+// checkConfidence refuses to install a binary with a low confidence score
+// unless --low-confidence-ok is given. Unscored binaries are allowed.
+func checkConfidence(b *db.Binary) error {
+	if b.Confidence < 0 || b.Confidence >= db.LowConfidence {
+		return nil
+	}
+	if lowConfidenceOK {
+		fmt.Printf("Warning: %s has low confidence (%d/100); continuing because of --low-confidence-ok.\n", b.Name, b.Confidence)
+		return nil
+	}
+	return withExitCode(ExitLowConfidence, fmt.Errorf(
+		"%s has low confidence (%d/100): the entry may not be a working binary; use --low-confidence-ok to install anyway",
+		b.Name, b.Confidence))
+}
+
+// confidenceLabel describes a binary's confidence score.
+func confidenceLabel(b *db.Binary) string {
+	if b.Confidence < 0 {
+		return "not scored"
+	}
+	label := fmt.Sprintf("%d/100", b.Confidence)
+	if b.Confidence < db.LowConfidence {
+		label += " (low)"
+	}
+	return label
+}
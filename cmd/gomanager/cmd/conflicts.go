@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// pathDirs returns the directories on PATH in order, cleaned and without
+// duplicates.
+func pathDirs() []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// dirOnPath reports whether dir is one of the PATH directories.
+func dirOnPath(dir string) bool {
+	for _, d := range pathDirs() {
+		if d == filepath.Clean(dir) || sameFile(d, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// onPath returns the executables named name in the PATH directories, in
+// the order the shell would find them.
+func onPath(name string) []string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	var found []string
+	for _, dir := range pathDirs() {
+		path := filepath.Join(dir, name)
+		fi, err := os.Stat(path)
+		if err != nil || fi.IsDir() || (runtime.GOOS != "windows" && fi.Mode()&0o111 == 0) {
+			continue
+		}
+		found = append(found, path)
+	}
+	return found
+}
+
+// sameFile reports whether a and b are the same file, e.g. through a
+// symlinked PATH directory.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}
+
+// pathConflict describes the other executables on PATH with the same name
+// as a binary gomanager installs to target.
+type pathConflict struct {
+	target string
+	// others are same-named executables elsewhere on PATH, in PATH order.
+	others []string
+	// winner is the executable the shell runs, or "" if none is on PATH.
+	winner string
+}
+
+// findConflict looks for executables named name on PATH other than
+// target. It returns nil if there are none.
+func findConflict(name, target string) *pathConflict {
+	c := &pathConflict{target: target}
+	for _, path := range onPath(name) {
+		if c.winner == "" {
+			c.winner = path
+		}
+		if path != target && !sameFile(path, target) {
+			c.others = append(c.others, path)
+		}
+	}
+	if len(c.others) == 0 {
+		return nil
+	}
+	return c
+}
+
+// shadowed reports whether another executable runs instead of target.
+func (c *pathConflict) shadowed() bool {
+	return c.winner != c.target && !sameFile(c.winner, c.target)
+}
+
+// warnPathConflict tells the user about same-named binaries elsewhere on
+// PATH (from brew, apt, or a manual install) before installing name, and
+// which one the shell will run.
+func warnPathConflict(name string) {
+	target, err := installedPath(name)
+	if err != nil {
+		return
+	}
+	c := findConflict(name, target)
+	if c == nil {
+		return
+	}
+	for _, path := range c.others {
+		logf(name, "Note: %s is also installed at %s.\n", name, path)
+	}
+	switch {
+	case !dirOnPath(filepath.Dir(target)):
+		logf(name, "  %s is not on PATH, so %s will run.\n", filepath.Dir(target), c.winner)
+	case c.shadowed():
+		logf(name, "  %s comes first on PATH and will run instead of %s.\n", c.winner, target)
+	default:
+		logf(name, "  %s comes first on PATH and will take precedence.\n", target)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <name or package> <version> <version>",
+	Short: "Compare the binaries two versions build",
+	Long: `Builds both versions of a binary into temporary directories, without
+installing either, and compares them: binary size, the Go version each was
+built with, and the module dependencies recorded in their build info.
+
+Use it to judge whether an upgrade pulls in unexpected dependencies or bloat
+before running it. Either version may be "latest".`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		b, err := resolveBinary(conn, args[0])
+		if err != nil {
+			return err
+		}
+
+		work, err := cacheWorkDir(diffCache)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(work)
+
+		var builds [2]*builtVersion
+		for i, version := range args[1:] {
+			fmt.Printf("Building %s@%s ...\n", b.Package, version)
+			if builds[i], err = buildVersion(b, version, filepath.Join(work, fmt.Sprint(i))); err != nil {
+				return withExitCode(ExitBuildFailed, err)
+			}
+		}
+		printBuildDiff(b, builds[0], builds[1])
+		return nil
+	},
+}
+
+// builtVersion is a binary built by buildVersion.
+type builtVersion struct {
+	size int64
+	info *debug.BuildInfo
+}
+
+// buildVersion builds b at version into dir with go install and reads the
+// result's size and build info.
+func buildVersion(b *db.Binary, version, dir string) (*builtVersion, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	goCmd := osexec.Command("go", "install", b.Package+"@"+version)
+	goCmd.Stdout = &output
+	goCmd.Stderr = &output
+	goCmd.Env = append(os.Environ(), buildEnv(b)...)
+	goCmd.Env = append(goCmd.Env, "GOBIN="+dir)
+	if err := goCmd.Run(); err != nil {
+		return nil, fmt.Errorf("building %s@%s failed: %w\n%s", b.Package, version, err, bytes.TrimSpace(output.Bytes()))
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("building %s@%s produced %d files, want 1", b.Package, version, len(files))
+	}
+	path := filepath.Join(dir, files[0].Name())
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read build info of %s@%s: %w", b.Package, version, err)
+	}
+	return &builtVersion{size: fi.Size(), info: info}, nil
+}
+
+// printBuildDiff prints the differences between two builds of b.
+func printBuildDiff(b *db.Binary, from, to *builtVersion) {
+	fmt.Printf("\n%s %s -> %s\n", b.Name, from.info.Main.Version, to.info.Main.Version)
+
+	delta := to.size - from.size
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	fmt.Printf("  Size:        %s -> %s (%s%s", formatBytes(from.size), formatBytes(to.size), sign, formatBytes(delta))
+	if from.size > 0 {
+		fmt.Printf(", %s%.1f%%", sign, float64(delta)*100/float64(from.size))
+	}
+	fmt.Println(")")
+
+	if from.info.GoVersion == to.info.GoVersion {
+		fmt.Printf("  Go version:  %s\n", to.info.GoVersion)
+	} else {
+		fmt.Printf("  Go version:  %s -> %s\n", from.info.GoVersion, to.info.GoVersion)
+	}
+
+	old, cur := depVersions(from.info), depVersions(to.info)
+	var added, removed, changed []string
+	for path, v := range cur {
+		switch ov, ok := old[path]; {
+		case !ok:
+			added = append(added, fmt.Sprintf("%s %s", path, v))
+		case ov != v:
+			changed = append(changed, fmt.Sprintf("%s %s -> %s", path, ov, v))
+		}
+	}
+	for path, v := range old {
+		if _, ok := cur[path]; !ok {
+			removed = append(removed, fmt.Sprintf("%s %s", path, v))
+		}
+	}
+	fmt.Printf("  Dependencies: %d -> %d (%d added, %d removed, %d changed)\n",
+		len(old), len(cur), len(added), len(removed), len(changed))
+	printDepList("Added", "+", added)
+	printDepList("Removed", "-", removed)
+	printDepList("Changed", "~", changed)
+}
+
+// depVersions maps each module dependency in info to its version, following
+// replacements.
+func depVersions(info *debug.BuildInfo) map[string]string {
+	deps := make(map[string]string, len(info.Deps))
+	for _, d := range info.Deps {
+		v := d.Version
+		if d.Replace != nil {
+			v = "=> " + d.Replace.Path + " " + d.Replace.Version
+		}
+		deps[d.Path] = v
+	}
+	return deps
+}
+
+// printDepList prints a sorted list of dependency changes under a heading.
+func printDepList(heading, marker string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	sort.Strings(lines)
+	fmt.Printf("\n  %s:\n", heading)
+	for _, l := range lines {
+		fmt.Printf("    %s %s\n", marker, l)
+	}
+}
@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/dirs"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorSection is one group of checks run by doctor. run prints its
+// findings and returns how many problems it found.
+type doctorSection struct {
+	title string
+	run   func() int
+}
+
+// doctorSections are run by doctor in order.
+var doctorSections = []doctorSection{
+	{"Configuration", doctorConfig},
+	{"Go toolchain", doctorToolchain},
+	{"Install directory", doctorInstallDir},
+	{"Database", doctorDatabase},
+	{"State", doctorState},
+	{"Orphans", doctorOrphans},
+	{"Dangerous names", doctorDangerous},
+	{"Conflicts", doctorConflicts},
+}
+
+// staleDatabase is the age after which doctor suggests updating the
+// database.
+const staleDatabase = 30 * 24 * time.Hour
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose problems with installed binaries",
+	Long: `Checks the environment gomanager installs into and reports problems,
+each with a suggested fix:
+
+  Configuration      whether config.toml and the GOMANAGER_* environment
+                     variables are valid. Doctor runs with the default
+                     settings if they aren't.
+  Go toolchain       whether the go command is available, and its version.
+  Install directory  whether the directories binaries are installed to are
+                     on PATH.
+  Database           whether the database is present, readable, and less
+                     than 30 days old, and whether an older version left
+                     copies of it in the config directory.
+  State              whether the install state file can be read and its
+                     entries are complete.
+  Orphans            installed binaries missing from disk, and executables
+                     in the install directory gomanager doesn't track.
+  Dangerous names    installed binaries named like common system tools
+                     (sh, git, gcc, ...), which could intercept them.
+  Conflicts          binaries in the go install directory that share a name
+                     with another executable on PATH (from brew, apt, or a
+                     manual install), and which copy the shell runs.
+
+Doctor exits with code 16 if it found any problems.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := 0
+		for i, s := range doctorSections {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(s.title)
+			problems += s.run()
+		}
+		if problems > 0 {
+			fmt.Println()
+			return withExitCode(ExitUnhealthy, fmt.Errorf("%d problems found", problems))
+		}
+		return nil
+	},
+}
+
+// doctorConfig checks that the configuration file and environment
+// overrides can be loaded.
+func doctorConfig() int {
+	path, err := config.Path()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	if _, err := config.Load(); err != nil {
+		fmt.Printf("  %v\n", err)
+		doctorFix("correct or remove %s (restore a backup with 'gomanager restore'), or unset the GOMANAGER_* variable", path)
+		return 1
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Printf("  No configuration file at %s; using the defaults.\n", path)
+		return 0
+	}
+	fmt.Printf("  %s is valid.\n", path)
+	return 0
+}
+
+// doctorConflicts reports every binary in the go install directory that
+// shares a name with another executable on PATH.
+func doctorConflicts() int {
+	binDir, err := goBinDir()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	st, err := state.Load()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+
+	problems := 0
+	for _, name := range knownBinaries(st, binDir) {
+		file := name
+		if runtime.GOOS == "windows" {
+			file += ".exe"
+		}
+		target := filepath.Join(binDir, file)
+		c := findConflict(name, target)
+		if c == nil {
+			continue
+		}
+		problems++
+		managed := ""
+		if _, ok := st.Installed[name]; ok {
+			managed = " (installed by gomanager)"
+		}
+		if c.shadowed() {
+			fmt.Printf("  %s: %s runs instead of %s%s\n", name, c.winner, target, managed)
+			doctorFix("put %s earlier on PATH, or remove the other copy", binDir)
+		} else {
+			fmt.Printf("  %s: %s%s shadows %s\n", name, target, managed, strings.Join(c.others, ", "))
+			doctorFix("uninstall one of them if they are the same tool")
+		}
+	}
+	if problems == 0 {
+		fmt.Println("  No binaries share a name with another executable on PATH.")
+	}
+	return problems
+}
+
+// knownBinaries returns the names of the tracked binaries and of the files
+// in binDir, sorted.
+func knownBinaries(st *state.State, binDir string) []string {
+	names := make(map[string]bool)
+	for name := range st.Installed {
+		names[name] = true
+	}
+	if entries, err := os.ReadDir(binDir); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+				names[strings.TrimSuffix(e.Name(), ".exe")] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// doctorFix prints the suggested fix for the finding printed before it.
+func doctorFix(format string, args ...any) {
+	fmt.Printf("    Fix: "+format+"\n", args...)
+}
+
+// doctorToolchain checks that the go command is available.
+func doctorToolchain() int {
+	out, err := osexec.Command("go", "env", "GOVERSION", "GOROOT").Output()
+	if err != nil {
+		fmt.Printf("  The go command is not available: %v\n", err)
+		doctorFix("install Go from https://go.dev/dl/ and add its bin directory to PATH")
+		return 1
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	version, goroot := lines[0], ""
+	if len(lines) > 1 {
+		goroot = lines[1]
+	}
+	fmt.Printf("  %s (%s)\n", version, goroot)
+	return 0
+}
+
+// doctorInstallDir checks that the directories binaries are installed to
+// are on PATH.
+func doctorInstallDir() int {
+	binDir, err := goBinDir()
+	if err != nil {
+		fmt.Printf("  Cannot determine the install directory: %v\n", err)
+		doctorFix("set GOBIN, or bin_dir under [install] in config.toml")
+		return 1
+	}
+	dirs := []string{binDir}
+	if st, err := state.Load(); err == nil {
+		for _, b := range st.Installed {
+			if b.BinDir != "" && !slices.Contains(dirs, b.BinDir) {
+				dirs = append(dirs, b.BinDir)
+			}
+		}
+	}
+	sort.Strings(dirs[1:])
+
+	problems := 0
+	for _, dir := range dirs {
+		if dirOnPath(dir) {
+			fmt.Printf("  %s is on PATH.\n", dir)
+			continue
+		}
+		fmt.Printf("  %s is not on PATH; binaries installed there won't be found by the shell.\n", dir)
+		doctorFix("add it to PATH in your shell profile: export PATH=\"$PATH:%s\"", dir)
+		problems++
+	}
+	return problems
+}
+
+// doctorDatabase checks that the database is present, readable, and
+// recent, and that no copies of it or of the caches were left in the
+// config directory.
+func doctorDatabase() int {
+	path, err := db.DBPath()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	problems := 0
+	for _, name := range []string{"database.db", "local.db"} {
+		if old := dirs.LeftBehind(name); old != "" {
+			fmt.Printf("  %s is no longer used: the database now lives in %s.\n", old, filepath.Dir(path))
+			doctorFix("copy what you need from it (e.g. local entries), then delete it")
+			problems++
+		}
+	}
+	for _, name := range []string{sourceCache, diffCache} {
+		if old := dirs.LeftBehind(name); old != "" {
+			fmt.Printf("  %s is no longer used: build directories now live in the cache directory.\n", old)
+			doctorFix("delete it")
+			problems++
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Printf("  No database at %s.\n", path)
+		doctorFix("run 'gomanager update-db'")
+		return problems + 1
+	}
+	conn, err := db.Open()
+	if err == nil {
+		// Opening is lazy; a query reads the file.
+		_, err = db.PackageExists(conn, "")
+		conn.Close()
+	}
+	if err != nil {
+		fmt.Printf("  %s cannot be read: %v\n", path, err)
+		doctorFix("run 'gomanager update-db' to download it again")
+		return problems + 1
+	}
+	age := time.Since(info.ModTime())
+	if age > staleDatabase {
+		fmt.Printf("  %s was last updated %d days ago.\n", path, int(age.Hours()/24))
+		doctorFix("run 'gomanager update-db'")
+		return problems + 1
+	}
+	fmt.Printf("  %s was updated %s.\n", path, info.ModTime().Format("2006-01-02"))
+	return problems
+}
+
+// doctorState checks that the install state can be read and that its
+// entries are complete.
+func doctorState() int {
+	path, err := state.Path()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	st, err := state.Load()
+	if err != nil {
+		fmt.Printf("  %s is corrupted: %v\n", path, err)
+		doctorFix("restore it from a backup, or move it aside and reinstall your binaries")
+		return 1
+	}
+	problems := 0
+	for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+		if b := st.Installed[name]; b.Package == "" || b.Version == "" {
+			fmt.Printf("  %s has no recorded package or version.\n", name)
+			doctorFix("run 'gomanager install %s' to record it again", name)
+			problems++
+		}
+	}
+	if problems == 0 {
+		fmt.Printf("  %s tracks %d binaries.\n", path, len(st.Installed))
+	}
+	return problems
+}
+
+// orphan is drift between the install state and the install directory: a
+// tracked binary whose file is missing, or a file nobody tracks.
+type orphan struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Status is "missing" or "untracked".
+	Status string `json:"status"`
+}
+
+// findOrphans returns the tracked binaries missing from disk, by name, and
+// the executables in the go install directory (binDir) that aren't
+// tracked. binDir is "" if the install directory can't be determined, in
+// which case only missing binaries are reported.
+func findOrphans(st *state.State) (orphans []orphan, binDir string) {
+	for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+		path, err := installedPath(name)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			orphans = append(orphans, orphan{Name: name, Path: path, Status: "missing"})
+		}
+	}
+
+	binDir, err := goBinDir()
+	if err != nil {
+		return orphans, ""
+	}
+	entries, _ := os.ReadDir(binDir)
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".exe")
+		if e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if _, ok := st.Installed[name]; !ok {
+			orphans = append(orphans, orphan{Name: name, Path: filepath.Join(binDir, e.Name()), Status: "untracked"})
+		}
+	}
+	return orphans, binDir
+}
+
+// doctorOrphans reports tracked binaries missing from disk and executables
+// in the install directory that aren't tracked.
+func doctorOrphans() int {
+	st, err := state.Load()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	orphans, binDir := findOrphans(st)
+	problems := 0
+	var untracked []string
+	for _, o := range orphans {
+		if o.Status == "untracked" {
+			untracked = append(untracked, o.Name)
+			continue
+		}
+		fmt.Printf("  %s is tracked but %s is missing.\n", o.Name, o.Path)
+		doctorFix("run 'gomanager install %s' to reinstall it, or 'gomanager uninstall %s' to stop tracking it", o.Name, o.Name)
+		problems++
+	}
+	if len(untracked) > 0 {
+		fmt.Printf("  %d executables in %s aren't tracked by gomanager: %s\n", len(untracked), binDir, strings.Join(untracked, ", "))
+		doctorFix("run 'gomanager adopt' to track the Go binaries among them, or remove the ones you no longer use")
+		problems++
+	}
+	if problems == 0 {
+		fmt.Println("  Every tracked binary is installed, and nothing else is in the install directory.")
+	}
+	return problems
+}
+
+// doctorDangerous reports binaries in the install directory that share a
+// name with a common system tool.
+func doctorDangerous() int {
+	binDir, err := goBinDir()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	st, err := state.Load()
+	if err != nil {
+		fmt.Printf("  Cannot check: %v\n", err)
+		return 1
+	}
+	problems := 0
+	for _, name := range knownBinaries(st, binDir) {
+		if !dangerousNames[name] {
+			continue
+		}
+		problems++
+		if b, ok := st.Installed[name]; ok {
+			fmt.Printf("  %s (%s) is named like a common system tool and could intercept calls to it.\n", name, b.Package)
+			doctorFix("run 'gomanager uninstall %s' unless you meant to replace the system %s", name, name)
+		} else {
+			fmt.Printf("  %s in %s is named like a common system tool and could intercept calls to it.\n", name, binDir)
+			doctorFix("remove %s unless you meant to replace the system %s", filepath.Join(binDir, name), name)
+		}
+	}
+	if problems == 0 {
+		fmt.Println("  No installed binary is named like a system tool.")
+	}
+	return problems
+}
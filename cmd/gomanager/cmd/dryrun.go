@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// dryRun is bound to the --dry-run flag of install and upgrade: binaries
+// are resolved and checked as usual, but instead of being built, what
+// would happen is printed and nothing is changed.
+var dryRun bool
+
+const dryRunUsage = "Print the commands, build environment, and state changes without installing anything"
+
+// confirmContinue asks whether to go ahead despite a warning. In a dry run
+// the answer is assumed to be yes, so the rest of the plan can be shown.
+func confirmContinue() bool {
+	fmt.Print("Continue anyway? [y/N] ")
+	if dryRun {
+		fmt.Println("y (dry run)")
+		return true
+	}
+	var answer string
+	fmt.Scanln(&answer)
+	return strings.ToLower(answer) == "y"
+}
+
+// printPlan describes an action ("install" or "upgrade") of b as the binary
+// named name, replacing version from ("" if it isn't installed): the build
+// command and its environment, where the binary goes, and how the install
+// state changes.
+func printPlan(action, name string, b *db.Binary, from string) {
+	version := b.Version
+	if version == "" {
+		version = "latest"
+	}
+	fmt.Printf("Would %s %s %s (%s)\n", action, name, version, b.Package)
+
+	fmt.Printf("  Command:     %s\n", planCommand(b))
+	env := db.FormatEnv(buildEnv(b))
+	if env == "" {
+		env = "(none)"
+	}
+	fmt.Printf("  Environment: %s\n", env)
+	cfg, err := config.Load()
+	if err == nil && cfg.Cache.URL != "" && openCache(b) != nil {
+		fmt.Printf("  Cache:       %s (used instead of building on a hit)\n", cfg.Cache.URL)
+	}
+	if path, err := installedPath(name); err != nil {
+		fmt.Printf("  Destination: unknown (%v)\n", err)
+	} else {
+		fmt.Printf("  Destination: %s\n", path)
+	}
+	if from == "" {
+		fmt.Printf("  State:       %s not installed -> %s\n", name, version)
+		return
+	}
+	fmt.Printf("  State:       %s %s -> %s\n", name, from, version)
+	if action == "upgrade" && err == nil && cfg.Archive.Keep > 0 {
+		fmt.Printf("  Archive:     keep %s %s (up to %d versions)\n", name, from, cfg.Archive.Keep)
+	}
+}
+
+// installedVersion returns the installed version of b, or "" if it isn't
+// installed (or another package is installed under its name).
+func installedVersion(b *db.Binary) string {
+	st, err := state.Load()
+	if err != nil {
+		return ""
+	}
+	if inst, ok := st.Installed[b.Name]; ok && inst.Package == b.Package {
+		return inst.Version
+	}
+	return ""
+}
+
+// planCommand describes how installBinary would install b with the
+// --backend backend.
+func planCommand(b *db.Binary) string {
+	switch {
+	case usesGoInstall(b):
+		return installCommand(b)
+	case installBackend == backendRelease:
+		return "download the release archive for this platform"
+	case installBackend == backendSource:
+		return "clone the repository and build from source"
+	}
+	return fmt.Sprintf("download the release archive for this platform, or build from source (%s can't be built with go install)", b.Name)
+}
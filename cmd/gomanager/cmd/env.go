@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/archive"
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/dirs"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env [name...]",
+	Short: "Print where gomanager keeps its files",
+	Long: `Prints the directories and files gomanager uses. Following the XDG base
+directory layout, settings live in the config directory
+($XDG_CONFIG_HOME/gomanager), the database and archived binaries in the
+data directory ($XDG_DATA_HOME/gomanager, by default
+~/.local/share/gomanager), and files that can be recreated at will in the
+cache directory ($XDG_CACHE_HOME/gomanager): source checkouts for
+--backend source and the builds diff compares. A database or cache left
+in the config directory by an older version is moved to the data or
+cache directory when it's first used.
+
+With names, only their paths are printed, one per line, for scripts:
+
+  du -sh "$(gomanager env data-dir)"
+
+The policy path is empty if the configuration names none.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := envEntries()
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			if entries, err = selectEnv(entries, args); err != nil {
+				return err
+			}
+			if !jsonOutput {
+				for _, e := range entries {
+					fmt.Println(e.Path)
+				}
+				return nil
+			}
+		}
+		return printResult(entries, func() {
+			t := newTable("NAME", "PATH")
+			for _, e := range entries {
+				t.row(e.Name, e.Path)
+			}
+			t.flush()
+		})
+	},
+}
+
+// envEntry is a file or directory env prints.
+type envEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// envEntries returns the directories and files gomanager uses, in the
+// order env prints them.
+func envEntries() ([]envEntry, error) {
+	var entries []envEntry
+	for _, e := range []struct {
+		name string
+		path func() (string, error)
+	}{
+		{"config-dir", dirs.Config},
+		{"data-dir", dirs.Data},
+		{"cache-dir", dirs.Cache},
+		{"config", config.Path},
+		{"policy", envPolicy},
+		{"state", state.Path},
+		{"database", db.DBPath},
+		{"local-database", db.LocalPath},
+		{"archive", archive.Dir},
+		{"source-cache", func() (string, error) { return dirs.CacheFile(sourceCache) }},
+		{"diff-cache", func() (string, error) { return dirs.CacheFile(diffCache) }},
+		{"bin-dir", goBinDir},
+	} {
+		path, err := e.path()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine %s: %w", e.name, err)
+		}
+		entries = append(entries, envEntry{Name: e.name, Path: path})
+	}
+	return entries, nil
+}
+
+// selectEnv returns the entries with the given names, in that order.
+func selectEnv(entries []envEntry, names []string) ([]envEntry, error) {
+	var selected []envEntry
+	for _, name := range names {
+		i := slices.IndexFunc(entries, func(e envEntry) bool { return e.Name == name })
+		if i < 0 {
+			known := make([]string, len(entries))
+			for j, e := range entries {
+				known[j] = e.Name
+			}
+			return nil, fmt.Errorf("unknown name %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		selected = append(selected, entries[i])
+	}
+	return selected, nil
+}
+
+// envPolicy returns the path of the configured policy file, or "" (also
+// when an invalid configuration file is ignored).
+func envPolicy() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		if ignoreConfig {
+			return "", nil
+		}
+		return "", err
+	}
+	return cfg.Policy, nil
+}
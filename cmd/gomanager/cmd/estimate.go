@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"golang.org/x/term"
+)
+
+// batchEstimate is the expected cost of building a batch of binaries, from
+// the durations and download sizes recorded by the build pipeline.
+type batchEstimate struct {
+	count    int
+	duration time.Duration
+	download int64
+	// unmeasured counts binaries without a recorded build duration.
+	unmeasured int
+}
+
+func estimateBatch(bins []*db.Binary) batchEstimate {
+	e := batchEstimate{count: len(bins)}
+	for _, b := range bins {
+		if b.BuildDuration <= 0 {
+			e.unmeasured++
+			continue
+		}
+		e.duration += b.BuildDuration
+		e.download += b.DownloadSize
+	}
+	return e
+}
+
+func (e batchEstimate) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d binaries to build", e.count)
+	if measured := e.count - e.unmeasured; measured > 0 {
+		fmt.Fprintf(&sb, ": about %s of build time", e.duration.Round(time.Second))
+		if e.download > 0 {
+			// Shared and already cached modules aren't downloaded twice, so
+			// the sum is an upper bound.
+			fmt.Fprintf(&sb, " and up to %s of module downloads", formatBytes(e.download))
+		}
+		if e.unmeasured > 0 {
+			fmt.Fprintf(&sb, " (%d without estimates)", e.unmeasured)
+		}
+	} else {
+		sb.WriteString(" (no estimates recorded)")
+	}
+	return sb.String()
+}
+
+// confirmBatch prints the estimate for building bins and, if stdin is a
+// terminal and assumeYes is false, asks whether to continue.
+func confirmBatch(bins []*db.Binary, assumeYes bool) bool {
+	fmt.Printf("%s.\n", estimateBatch(bins))
+	if assumeYes || !stdinIsTerminal() {
+		return true
+	}
+	fmt.Print("Continue? [y/N] ")
+	var answer string
+	fmt.Scanln(&answer)
+	return strings.ToLower(answer) == "y"
+}
+
+// stdinIsTerminal reports whether standard input is an interactive
+// terminal, so scripted runs aren't blocked by prompts.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// Exit codes returned by gomanager. These are part of the public interface so
+// that wrappers and CI scripts can branch on the failure class without
+// parsing human-oriented output. Never renumber an existing code.
+const (
+	ExitOK            = 0  // success
+	ExitError         = 1  // unclassified error
+	ExitNotFound      = 2  // binary or package not found in the database
+	ExitAmbiguous     = 3  // name matches multiple packages and no selection was made
+	ExitBuildFailed   = 4  // go install (or another build step) failed
+	ExitNetwork       = 5  // a network request failed (e.g. database download)
+	ExitDenylisted    = 6  // refused because the binary name or package is denylisted
+	ExitNothingToDo   = 7  // the command completed but had nothing to do
+	ExitPolicy        = 8  // refused because the binary violates the configured policy
+	ExitLowConfidence = 9  // refused because the database entry has low confidence
+	ExitVulnerable    = 10 // refused because the version has a known critical vulnerability
+	ExitRolledBack    = 11 // an upgrade failed its smoke test and was rolled back
+	ExitVerifyFailed  = 12 // an installed binary failed the --verify smoke test
+	ExitOutdated      = 13 // upgrade --check found binaries with a newer version
+	ExitModified      = 14 // verify-local found binaries changed since they were installed
+	ExitDeclined      = 15 // the user answered no to a confirmation prompt
+	ExitUnhealthy     = 16 // doctor found problems
+)
+
+// exitCodeHelp documents the exit codes in the root command's help text.
+const exitCodeHelp = `
+Exit codes:
+  0  success
+  1  unclassified error
+  2  binary or package not found
+  3  ambiguous name (multiple packages match)
+  4  build failed
+  5  network error
+  6  denylisted binary name (or package denylisted by policy)
+  7  nothing to do
+  8  blocked by policy
+  9  low-confidence entry (see --low-confidence-ok)
+  10 version has a known critical vulnerability (see --accept-vulnerable)
+  11 upgrade rolled back after its smoke test failed (see --no-rollback)
+  12 installed binary failed to run with --verify
+  13 binaries are outdated (upgrade --check)
+  14 installed binaries were modified or replaced (verify-local)
+  15 declined at a confirmation prompt
+  16 problems found (doctor)`
+
+// exitError attaches an exit code to an error. An exitError with a nil err
+// exits with its code without printing anything.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that the process exits with the given code.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		return ExitNotFound
+	}
+	return ExitError
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// Export formats.
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+	exportFilter []string
+)
+
+func init() {
+	exportCmd.PersistentFlags().StringVarP(&exportFormat, "format", "f", formatJSON, "Output format: json or csv")
+	exportCmd.PersistentFlags().StringVarP(&exportOutput, "output", "o", "", "File to write to (default: stdout)")
+	exportDBCmd.Flags().StringSliceVar(&exportFilter, "filter", nil, "Only export binaries with these build statuses (e.g. confirmed,regressed)")
+	exportCmd.AddCommand(exportListCmd)
+	exportCmd.AddCommand(exportDBCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export installed binaries or the database as JSON or CSV",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+		if exportFormat != formatJSON && exportFormat != formatCSV {
+			return fmt.Errorf("unknown format %q (want json or csv)", exportFormat)
+		}
+		return nil
+	},
+}
+
+var exportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Export installed binaries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		installed := make([]state.InstalledBinary, 0, len(st.Installed))
+		for _, b := range st.Installed {
+			installed = append(installed, b)
+		}
+		sort.Slice(installed, func(i, j int) bool { return installed[i].Name < installed[j].Name })
+
+		header := []string{"name", "package", "version", "installed_at"}
+		rows := make([][]string, len(installed))
+		for i, b := range installed {
+			rows[i] = []string{b.Name, b.Package, b.Version, b.InstalledAt.UTC().Format(time.RFC3339)}
+		}
+		return writeExport(installed, header, rows)
+	},
+}
+
+// exportedBinary is the JSON form of a database entry.
+type exportedBinary struct {
+	Name         string `json:"name"`
+	Package      string `json:"package"`
+	Version      string `json:"version"`
+	Description  string `json:"description"`
+	RepoURL      string `json:"repo_url"`
+	Stars        int    `json:"stars"`
+	BuildStatus  string `json:"build_status"`
+	BuildFlags   string `json:"build_flags"`
+	LastVerified string `json:"last_verified,omitempty"`
+	Archived     bool   `json:"archived"`
+	TrustScore   *int   `json:"trust_score,omitempty"`
+}
+
+var exportDBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Export database entries",
+	Long: `Exports entries from the local database, ordered by stars. Use --filter
+to limit the export to binaries with the given build statuses.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		binaries, err := db.ListAll(conn)
+		if err != nil {
+			return err
+		}
+
+		var records []exportedBinary
+		for _, b := range binaries {
+			// Quarantined packages await approval and aren't published.
+			if b.BuildStatus == db.StatusQuarantined {
+				continue
+			}
+			if len(exportFilter) > 0 && !containsString(exportFilter, b.BuildStatus) {
+				continue
+			}
+			r := exportedBinary{
+				Name:        b.Name,
+				Package:     b.Package,
+				Version:     b.Version,
+				Description: b.Description,
+				RepoURL:     b.RepoURL,
+				Stars:       b.Stars,
+				BuildStatus: b.BuildStatus,
+				BuildFlags:  b.EnvFlags(),
+				Archived:    b.Archived,
+			}
+			if !b.LastVerified.IsZero() {
+				r.LastVerified = b.LastVerified.Format(time.RFC3339)
+			}
+			if b.TrustScore >= 0 {
+				score := b.TrustScore
+				r.TrustScore = &score
+			}
+			records = append(records, r)
+		}
+
+		header := []string{"name", "package", "version", "description", "repo_url", "stars",
+			"build_status", "build_flags", "last_verified", "archived", "trust_score"}
+		rows := make([][]string, len(records))
+		for i, r := range records {
+			trust := ""
+			if r.TrustScore != nil {
+				trust = strconv.Itoa(*r.TrustScore)
+			}
+			rows[i] = []string{r.Name, r.Package, r.Version, r.Description, r.RepoURL,
+				strconv.Itoa(r.Stars), r.BuildStatus, r.BuildFlags, r.LastVerified,
+				strconv.FormatBool(r.Archived), trust}
+		}
+		if records == nil {
+			records = []exportedBinary{}
+		}
+		return writeExport(records, header, rows)
+	},
+}
+
+// writeExport writes records as indented JSON, or header and rows as CSV,
+// to the --output file or stdout.
+func writeExport(records any, header []string, rows [][]string) error {
+	var w io.Writer = os.Stdout
+	if exportOutput != "" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return fmt.Errorf("cannot create %s: %w", exportOutput, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if exportFormat == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
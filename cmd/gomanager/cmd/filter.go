@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// activeFilter is the [filter] section of the config file, loaded on first
+// use.
+var (
+	activeFilter       config.FilterConfig
+	activeFilterLoaded bool
+)
+
+func loadFilter() (config.FilterConfig, error) {
+	if activeFilterLoaded {
+		return activeFilter, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return config.FilterConfig{}, err
+	}
+	activeFilter = cfg.Filter
+	activeFilterLoaded = true
+	return activeFilter, nil
+}
+
+// checkVisible returns an error wrapping db.ErrNotFound if the configured
+// filter hides b. Local entries are never hidden.
+func checkVisible(b *db.Binary) error {
+	f, err := loadFilter()
+	if err != nil {
+		return err
+	}
+	if b.Local || f.Allows(b.Package) {
+		return nil
+	}
+	return fmt.Errorf("package %q %w (hidden by the [filter] section of config.toml)", b.Package, db.ErrNotFound)
+}
+
+// filterVisible drops the binaries the configured filter hides.
+func filterVisible(binaries []db.Binary) ([]db.Binary, error) {
+	f, err := loadFilter()
+	if err != nil || !f.Active() {
+		return binaries, err
+	}
+	var kept []db.Binary
+	for _, b := range binaries {
+		if b.Local || f.Allows(b.Package) {
+			kept = append(kept, b)
+		}
+	}
+	return kept, nil
+}
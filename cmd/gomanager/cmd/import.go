@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFrom  string
+	importInput string
+	importYes   bool
+)
+
+func init() {
+	importCmd.Flags().StringVar(&importFrom, "from", "", "Package manager to import from: brew, asdf, mise, or scoop")
+	importCmd.Flags().StringVar(&importInput, "input", "", "Read the package manager's listing from this file instead of running it")
+	importCmd.Flags().BoolVarP(&importYes, "yes", "y", false, "Install all matches without prompting")
+	importCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	importCmd.Flags().BoolVar(&lowConfidenceOK, "low-confidence-ok", false, lowConfidenceOKUsage)
+	importCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	importCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	importCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(importCmd)
+}
+
+// importedTool is a tool installed by another package manager. pkg is set
+// when the manager records the Go package path (mise's go backend).
+type importedTool struct {
+	name string
+	pkg  string
+}
+
+// importSource describes how to list another package manager's tools.
+type importSource struct {
+	command []string
+	parse   func(data []byte) ([]importedTool, error)
+}
+
+var importSources = map[string]importSource{
+	"brew":  {command: []string{"brew", "list", "--formula", "-1"}, parse: parseLines},
+	"asdf":  {command: []string{"asdf", "plugin", "list"}, parse: parseLines},
+	"mise":  {command: []string{"mise", "ls", "--installed", "--json"}, parse: parseMise},
+	"scoop": {command: []string{"scoop", "export"}, parse: parseScoop},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import --from <manager>",
+	Short: "Install equivalents of tools managed by brew, asdf, mise, or scoop",
+	Long: `Lists the tools installed by another package manager, matches them against
+the database by name, and offers to install the matches with gomanager.
+Tools already installed with gomanager are skipped, as are tools without a
+confirmed build in the database. The other manager's installs are left alone.
+
+mise tools installed through its go backend (go:<package>) are matched by
+package path; everything else is matched by binary name, preferring the
+most-starred package when several share a name.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		src, ok := importSources[importFrom]
+		if !ok {
+			return fmt.Errorf("unknown package manager %q (want brew, asdf, mise, or scoop)", importFrom)
+		}
+
+		data, err := readImportSource(src)
+		if err != nil {
+			return err
+		}
+		tools, err := src.parse(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s output: %w", importFrom, err)
+		}
+
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		var matches []*db.Binary
+		for _, t := range tools {
+			b, err := matchImported(conn, t)
+			if err != nil {
+				return err
+			}
+			if b == nil || b.BuildStatus != "confirmed" {
+				continue
+			}
+			if _, installed := st.Installed[b.Name]; installed {
+				continue
+			}
+			matches = append(matches, b)
+		}
+
+		fmt.Printf("Found %d %s tools, %d available to install with gomanager.\n", len(tools), importFrom, len(matches))
+		if len(matches) == 0 {
+			return withExitCode(ExitNothingToDo, nil)
+		}
+
+		if len(matches) > 1 {
+			fmt.Printf("%s.\n", estimateBatch(matches))
+		}
+
+		failed, installed := 0, 0
+		for _, b := range matches {
+			fmt.Printf("\n%s (%s, %s)\n", b.Name, b.Package, b.Version)
+			if !importYes {
+				fmt.Print("Install? [y/N] ")
+				var answer string
+				fmt.Scanln(&answer)
+				if strings.ToLower(answer) != "y" {
+					continue
+				}
+			}
+			if err := checkPolicy(b); err != nil {
+				fmt.Printf("Skipping: %v\n", err)
+				continue
+			}
+			if err := checkConfidence(b); err != nil {
+				fmt.Printf("Skipping: %v\n", err)
+				continue
+			}
+			if err := checkVulnerable(conn, b); err != nil {
+				fmt.Printf("Skipping: %v\n", err)
+				continue
+			}
+			if usesGoInstall(b) {
+				fmt.Printf("Running: %s\n", installCommand(b))
+			}
+			if err := installBinary(b); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				failed++
+				continue
+			}
+			installed++
+		}
+
+		fmt.Printf("\nInstalled %d, failed %d.\n", installed, failed)
+		if failed > 0 {
+			return withExitCode(ExitBuildFailed, fmt.Errorf("%d imports failed to install", failed))
+		}
+		if installed == 0 {
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		return nil
+	},
+}
+
+// readImportSource returns the --input file's contents, or the output of the
+// package manager's listing command.
+func readImportSource(src importSource) ([]byte, error) {
+	if importInput != "" {
+		return os.ReadFile(importInput)
+	}
+	out, err := osexec.Command(src.command[0], src.command[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list %s tools (%s): %w", importFrom, strings.Join(src.command, " "), err)
+	}
+	return out, nil
+}
+
+// matchImported finds the database entry for an imported tool, or nil.
+func matchImported(conn *sql.DB, t importedTool) (*db.Binary, error) {
+	if t.pkg != "" {
+		b, err := lookupPackage(conn, t.pkg)
+		if err == nil {
+			return b, nil
+		}
+	}
+	if b := localBinary(t.name); b != nil {
+		return b, nil
+	}
+	matches, err := db.FindByName(conn, t.name)
+	if err == nil {
+		matches, err = filterVisible(matches)
+	}
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	// FindByName orders by stars
+	return &matches[0], nil
+}
+
+// parseLines parses one tool name per line, ignoring blank lines and any
+// trailing columns.
+func parseLines(data []byte) ([]importedTool, error) {
+	var tools []importedTool
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		tools = append(tools, importedTool{name: fields[0]})
+	}
+	return tools, sc.Err()
+}
+
+// parseMise parses `mise ls --json`, an object keyed by tool. Keys may carry
+// a backend prefix (e.g. "aqua:cli/cli", "go:github.com/owner/repo/cmd/x");
+// the binary name is taken from the last path element.
+func parseMise(data []byte) ([]importedTool, error) {
+	var ls map[string]json.RawMessage
+	if err := json.Unmarshal(data, &ls); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(ls))
+	for k := range ls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tools []importedTool
+	for _, key := range keys {
+		backend, name, found := strings.Cut(key, ":")
+		if !found {
+			name, backend = backend, ""
+		}
+		t := importedTool{name: name}
+		if backend == "go" {
+			t.pkg = name
+		}
+		if i := strings.LastIndex(t.name, "/"); i >= 0 {
+			t.name = t.name[i+1:]
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// parseScoop parses `scoop export`, a JSON object with an apps list.
+func parseScoop(data []byte) ([]importedTool, error) {
+	var export struct {
+		Apps []struct {
+			Name string `json:"Name"`
+		} `json:"apps"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	tools := make([]importedTool, 0, len(export.Apps))
+	for _, app := range export.Apps {
+		tools = append(tools, importedTool{name: app.Name})
+	}
+	return tools, nil
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <name or package>",
+	Short: "Show database details for a Go binary",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		b, err := resolveBinary(conn, args[0])
+		if err != nil {
+			return err
+		}
+		advisories, _ := criticalAdvisories(conn, b, b.Version)
+		st, _ := state.Load()
+
+		if jsonOutput {
+			r := infoRecord{
+				binaryRecord:    newBinaryRecord(b, st),
+				Homepage:        b.Homepage,
+				LongDescription: b.LongDescription,
+				BuildFlags:      b.EnvFlags(),
+				BuildError:      b.BuildError,
+				RunOnly:         b.RunOnly,
+				SumDB:           b.SumDB,
+				Capabilities:    b.Capabilities,
+				PrivilegeNote:   b.PrivilegeNote,
+			}
+			if !b.RunOnly {
+				r.InstallCommand = b.InstallCommand()
+			}
+			if len(advisories) > 0 {
+				r.Vulnerable = strings.Split(advisoryIDs(advisories), ", ")
+			}
+			return printResult(r, nil)
+		}
+
+		fmt.Printf("Name:          %s\n", b.Name)
+		fmt.Printf("Package:       %s\n", b.Package)
+		fmt.Printf("Version:       %s\n", b.Version)
+		if b.Local {
+			fmt.Printf("Source:        %s entry (gomanager db remove-local %s)\n", localMarker, b.Name)
+		}
+		if b.Description != "" {
+			fmt.Printf("Description:   %s\n", b.Description)
+		}
+		if b.RepoURL != "" {
+			fmt.Printf("Repository:    %s\n", b.RepoURL)
+		}
+		if b.Homepage != "" {
+			fmt.Printf("Homepage:      %s\n", b.Homepage)
+		}
+		if b.Archived {
+			fmt.Printf("Upstream:      %s (no longer maintained)\n", archivedMarker)
+		}
+		fmt.Printf("Stars:         %d\n", b.Stars)
+		if b.License != "" {
+			fmt.Printf("License:       %s\n", b.License)
+		}
+		if len(b.Tags) > 0 {
+			fmt.Printf("Tags:          %s\n", strings.Join(b.Tags, ", "))
+		}
+		fmt.Printf("Trust:         %s\n", trustLabel(b))
+		fmt.Printf("Confidence:    %s\n", confidenceLabel(b))
+		fmt.Printf("Build status:  %s\n", b.BuildStatus)
+		if b.StatusReason != "" {
+			fmt.Printf("Status reason: %s\n", b.StatusReason)
+		}
+		if len(advisories) > 0 {
+			fmt.Printf("Vulnerable:    %s (critical)\n", advisoryIDs(advisories))
+		}
+		fmt.Printf("Last verified: %s\n", verifiedLabel(b))
+		if label := sumdbLabel(b); label != "" {
+			fmt.Printf("Checksum DB:   %s\n", label)
+		}
+		if flags := b.EnvFlags(); flags != "" {
+			fmt.Printf("Build flags:   %s\n", flags)
+		}
+		if b.BuildError != "" {
+			fmt.Printf("Build error:   %s\n", b.BuildError)
+		}
+		if b.Capabilities != "" || b.PrivilegeNote != "" {
+			fmt.Printf("Privileges:    %s\n", privilegeLabel(b))
+		}
+		if b.RunOnly {
+			fmt.Printf("Run:           gomanager run %s (go install fails, go run works)\n", b.Name)
+		} else {
+			fmt.Printf("Install:       %s\n", b.InstallCommand())
+		}
+
+		if st != nil {
+			if inst, ok := st.Installed[b.Name]; ok && inst.Package == b.Package {
+				fmt.Printf("Installed:     %s (%s)\n", inst.Version, inst.InstalledAt.Format("2006-01-02"))
+			}
+		}
+		if b.LongDescription != "" {
+			fmt.Println()
+			for _, line := range wrapText(b.LongDescription, 76) {
+				fmt.Println("  " + line)
+			}
+		}
+		return nil
+	},
+}
+
+// infoRecord is the JSON form of info output.
+type infoRecord struct {
+	binaryRecord
+	Homepage        string `json:"homepage,omitempty"`
+	LongDescription string `json:"long_description,omitempty"`
+	BuildFlags      string `json:"build_flags,omitempty"`
+	BuildError      string `json:"build_error,omitempty"`
+	InstallCommand  string `json:"install_command,omitempty"`
+	// RunOnly reports that the binary can only be used with 'gomanager run'.
+	RunOnly bool `json:"run_only,omitempty"`
+	// Vulnerable lists critical advisories affecting the version.
+	Vulnerable []string `json:"vulnerable,omitempty"`
+	// SumDB is whether the module verified against the checksum database.
+	SumDB string `json:"sumdb,omitempty"`
+	// Capabilities and PrivilegeNote describe the elevated privileges the
+	// binary needs to work.
+	Capabilities  string `json:"capabilities,omitempty"`
+	PrivilegeNote string `json:"privilege_note,omitempty"`
+}
+
+// privilegeLabel describes the elevated privileges b needs, e.g.
+// "cap_net_raw+ep (captures packets)".
+func privilegeLabel(b *db.Binary) string {
+	switch {
+	case b.Capabilities == "":
+		return b.PrivilegeNote
+	case b.PrivilegeNote == "":
+		return b.Capabilities
+	}
+	return fmt.Sprintf("%s (%s)", b.Capabilities, b.PrivilegeNote)
+}
+
+// sumdbLabel describes whether b's module verified against the checksum
+// database, or returns "" if that isn't known.
+func sumdbLabel(b *db.Binary) string {
+	switch b.SumDB {
+	case db.SumDBVerified:
+		return "verified against sum.golang.org"
+	case db.SumDBMissing:
+		return "not in sum.golang.org (needs a GONOSUMDB or GOPRIVATE exception)"
+	case db.SumDBMismatch:
+		return "⚠ does not match sum.golang.org"
+	}
+	return ""
+}
+
+// staleVerification is the age after which a verification is flagged as
+// stale in client output. Toolchain updates break builds over time.
+const staleVerification = 90 * 24 * time.Hour
+
+// verifiedLabel describes when a binary was last verified, flagging
+// verifications older than staleVerification.
+func verifiedLabel(b *db.Binary) string {
+	age, ok := b.VerifiedAge()
+	if !ok {
+		return "never"
+	}
+	label := fmt.Sprintf("%s (%s ago)", b.LastVerified.Format("2006-01-02"), humanAge(age))
+	if age > staleVerification {
+		label += " — stale"
+	}
+	return label
+}
+
+// trustLabel describes a binary's trust score and owner type.
+func trustLabel(b *db.Binary) string {
+	if b.TrustScore < 0 {
+		return "not scored"
+	}
+	label := fmt.Sprintf("%d/100", b.TrustScore)
+	if b.OwnerType != "" {
+		label += " (" + strings.ToLower(b.OwnerType) + ")"
+	}
+	return label
+}
+
+// humanAge formats a duration as a coarse age such as "3d" or "5h".
+func humanAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	osexec "os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/jmelahman/gomanager/internal/attest"
 	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/progress"
 	"github.com/jmelahman/gomanager/internal/state"
 	"github.com/spf13/cobra"
 )
@@ -27,7 +35,29 @@ var dangerousNames = map[string]bool{
 	"env": true, "sudo": true, "su": true, "xargs": true,
 }
 
+// archivedMarker flags binaries whose upstream repository is archived.
+const archivedMarker = "⚠ upstream archived"
+
+// installYes skips confirming the estimate before installing several
+// binaries.
+var installYes bool
+
 func init() {
+	installCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	installCmd.Flags().BoolVar(&lowConfidenceOK, "low-confidence-ok", false, lowConfidenceOKUsage)
+	installCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	installCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	installCmd.Flags().StringVar(&installManifest, "from-manifest", "", "Install every tool listed in this manifest file instead of a single binary")
+	installCmd.Flags().StringVar(&installBinDir, "bin-dir", "", "Install into this directory instead of the go install directory, tracked in a state file there rather than the usual install state")
+	installCmd.Flags().BoolVar(&installPrintPath, "print-path", false, "Print the install directory as the last line of output")
+	installCmd.Flags().BoolVarP(&installYes, "yes", "y", false, "Don't ask for confirmation before installing several binaries")
+	installCmd.Flags().IntVarP(&installJobs, "jobs", "j", 0, "Number of binaries to build at once (0 = one per CPU)")
+	installCmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunUsage)
+	installCmd.Flags().BoolVar(&installVerify, "verify", false, installVerifyUsage)
+	installCmd.Flags().StringVar(&installAttest, "attest", "", "With --from-manifest, write a signed attestation of the installed tools to this file")
+	installCmd.Flags().StringArrayVar(&installAliases, "alias", nil, "Also link the binary under this name in the install directory (repeatable)")
+	installCmd.Flags().BoolVar(&installDesktop, "desktop", false, "Create a desktop entry so launchers list the binary (Linux and BSD)")
+	installCmd.Flags().StringVar(&installAttestKey, "attest-key", "", "PEM Ed25519 private key to sign the --attest attestation with")
 	rootCmd.AddCommand(installCmd)
 }
 
@@ -35,17 +65,29 @@ func init() {
 // looks like a Go module path (contains a slash), it resolves by package path.
 // If multiple packages share the same name, the user is prompted to pick one.
 func resolveBinary(conn *sql.DB, arg string) (*db.Binary, error) {
+	// Local entries take precedence over the published database
+	if b := localBinary(arg); b != nil {
+		return b, nil
+	}
+
 	// If it looks like a package path, look up directly
 	if strings.Contains(arg, "/") {
-		return db.GetByPackage(conn, arg)
+		b, err := db.GetByPackage(conn, arg)
+		if err != nil {
+			return nil, err
+		}
+		return b, checkVisible(b)
 	}
 
 	matches, err := db.FindByName(conn, arg)
 	if err != nil {
 		return nil, err
 	}
+	if matches, err = filterVisible(matches); err != nil {
+		return nil, err
+	}
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("binary %q not found in database", arg)
+		return nil, fmt.Errorf("binary %q %w", arg, db.ErrNotFound)
 	}
 	if len(matches) == 1 {
 		return &matches[0], nil
@@ -64,16 +106,97 @@ func resolveBinary(conn *sql.DB, arg string) (*db.Binary, error) {
 
 	var choice int
 	if _, err := fmt.Scanln(&choice); err != nil || choice < 1 || choice > len(matches) {
-		return nil, fmt.Errorf("invalid selection")
+		return nil, withExitCode(ExitAmbiguous,
+			fmt.Errorf("%q is ambiguous: specify a package path or select a number", arg))
 	}
 	return &matches[choice-1], nil
 }
 
 var installCmd = &cobra.Command{
-	Use:   "install <name or package>",
-	Short: "Install a Go binary by name or package path",
-	Args:  cobra.ExactArgs(1),
+	Use:   "install <name or package>...",
+	Short: "Install Go binaries by name or package path",
+	Long: `Installs Go binaries by name or package path.
+
+With several arguments, each is resolved and checked first, the expected
+build time and download size are shown (confirm unless --yes is given),
+and then they are installed, --jobs at a time (one per CPU by default).
+While several builds run at once, each line of output is prefixed with the
+binary's name. A failure doesn't stop the others; a summary lists what was
+and wasn't installed, and the exit code is that of the first failure.
+
+With --dry-run, binaries are resolved and checked as usual, but instead
+of installing them, the exact go install command (or other install
+method), the curated build environment, the destination, and the state
+change are printed for each. Warnings that would prompt are answered yes
+so the whole plan is shown.
+
+With --verify, each installed binary is run with --version (or, if that
+fails, --help) with a 10s timeout, catching tools that build but crash on
+start. The version it reports is recorded in the install state. A binary
+that fails is left installed, and the install exits with code 12.
+
+With --from-manifest, every tool in a manifest file is installed instead,
+without prompting, which suits CI runners:
+
+  [tools]
+  dive = "v0.13.1"
+  golangci-lint = "latest"
+  gopls = "^0.16"
+  "github.com/owner/repo/cmd/tool" = ""
+
+An empty version or "latest" installs the database's version. A "^" or "~"
+constraint installs the highest version the module proxy lists in range:
+"^1.2" any v1 version from v1.2.0 ("^0.9" only v0.9.x), and "~1.2" any
+v1.2.x. Builds run --jobs at a time. After a complete install the
+directory is stamped with a key hashed from the manifest's tools and the
+platform, recording the versions constraints resolved to, and later runs
+with the same key skip building rather than resolving them again. The
+tools are tracked in a state file in the directory, not the user's
+install state, and copies installed elsewhere are left alone. In
+GitHub Actions the install directory is added to GITHUB_PATH, and
+cache-key, cache-hit, and bin-dir step outputs are set so the directory
+can be cached with actions/cache:
+
+  gomanager install --from-manifest tools.toml \
+    --bin-dir "$RUNNER_TOOL_CACHE/gomanager" --print-path
+
+With --attest and --attest-key, a bill of materials of the install is
+written as an in-toto statement in a DSSE envelope signed with the Ed25519
+key: the SHA-256 digest, package, version, Go version, and build
+environment of every tool. Check one with 'gomanager verify-attestation'.
+
+With --alias, the binary is also linked under another name in the install
+directory (e.g. --alias kubectl-ns for kubens), and with --desktop a
+desktop entry that opens it in a terminal is written to
+$XDG_DATA_HOME/applications, so launchers list TUI apps. Both are recorded
+in the install state, kept across upgrades, and removed on uninstall.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if installManifest != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		if installBinDir != "" {
+			if systemInstall {
+				return fmt.Errorf("--bin-dir and --system can't be used together")
+			}
+			dir, err := filepath.Abs(installBinDir)
+			if err != nil {
+				return err
+			}
+			installBinDir = dir
+			useBinDirState(dir)
+		}
+		if installManifest != "" {
+			return runManifestInstall()
+		}
+		if installAttest != "" {
+			return fmt.Errorf("--attest needs --from-manifest")
+		}
 		if err := ensureDB(); err != nil {
 			return err
 		}
@@ -83,39 +206,286 @@ var installCmd = &cobra.Command{
 		}
 		defer conn.Close()
 
-		b, err := resolveBinary(conn, args[0])
+		if len(args) > 1 {
+			if len(installAliases) > 0 || installDesktop {
+				return fmt.Errorf("--alias and --desktop need a single binary")
+			}
+			return installSeveral(conn, args)
+		}
+		b, err := planInstall(conn, args[0])
 		if err != nil {
 			return err
 		}
+		if err := checkAliases(b.Name); err != nil {
+			return err
+		}
+		if dryRun {
+			printPlan("install", b.Name, b, installedVersion(b))
+			return nil
+		}
+		if err := runInstall(b); err != nil {
+			return err
+		}
+		grantPrivileges([]*db.Binary{b})
+		return nil
+	},
+}
 
-		if dangerousNames[b.Name] {
-			fmt.Printf("Warning: %q shadows a common system tool.\n", b.Name)
-			fmt.Printf("  If $HOME/go/bin is on your PATH, this could intercept calls\n")
-			fmt.Printf("  to the real %q by other tools (including go install).\n", b.Name)
-			fmt.Print("Continue anyway? [y/N] ")
-			var answer string
-			fmt.Scanln(&answer)
-			if strings.ToLower(answer) != "y" {
-				return nil
-			}
+// planInstall resolves arg and runs the pre-install checks and prompts,
+// returning the binary to build. Declining a prompt returns a silent
+// ExitDeclined error.
+func planInstall(conn *sql.DB, arg string) (*db.Binary, error) {
+	b, err := resolveBinary(conn, arg)
+	if err != nil {
+		return nil, err
+	}
+	events.Emit(progress.Event{Event: progress.Resolve, Name: b.Name, Package: b.Package, Version: b.Version})
+
+	if err := checkPolicy(b); err != nil {
+		return nil, err
+	}
+	if err := checkConfidence(b); err != nil {
+		return nil, err
+	}
+	if b, err = resolveLatest(conn, b); err != nil {
+		return nil, err
+	}
+	if err := checkVulnerable(conn, b); err != nil {
+		return nil, err
+	}
+
+	if dangerousNames[b.Name] {
+		fmt.Printf("Warning: %q shadows a common system tool.\n", b.Name)
+		fmt.Printf("  If $HOME/go/bin is on your PATH, this could intercept calls\n")
+		fmt.Printf("  to the real %q by other tools (including go install).\n", b.Name)
+		if !confirmContinue() {
+			return nil, withExitCode(ExitDenylisted,
+				fmt.Errorf("refusing to install %q: name shadows a system tool", b.Name))
+		}
+	}
+
+	if b.Archived {
+		fmt.Printf("Warning: %q is %s; it no longer receives fixes.\n", b.Name, archivedMarker)
+		if !confirmContinue() {
+			return nil, withExitCode(ExitDeclined, nil)
 		}
+	}
 
-		if b.BuildStatus == "failed" {
-			fmt.Printf("Warning: %q is marked as a failed build.\n", b.Name)
+	if b.BuildStatus == "failed" {
+		fmt.Printf("Warning: %q is marked as a failed build.\n", b.Name)
+		if b.BuildError != "" {
 			fmt.Printf("  Error: %s\n", b.BuildError)
-			fmt.Print("Continue anyway? [y/N] ")
-			var answer string
-			fmt.Scanln(&answer)
-			if strings.ToLower(answer) != "y" {
-				return nil
+		}
+		if b.RunOnly {
+			fmt.Printf("  It works with go run: try 'gomanager run %s' instead.\n", b.Name)
+		}
+		if !confirmContinue() {
+			return nil, withExitCode(ExitDeclined, nil)
+		}
+	}
+
+	return b, nil
+}
+
+// runInstall builds and installs a binary planInstall returned.
+func runInstall(b *db.Binary) error {
+	warnPathConflict(b.Name)
+	if usesGoInstall(b) {
+		logf(b.Name, "Running: %s\n", installCommand(b))
+	}
+	if err := installBinary(b); err != nil {
+		return err
+	}
+	if installVerify {
+		return verifyInstalled(b.Name)
+	}
+	return nil
+}
+
+// installSeveral installs each of args, continuing past failures, and
+// summarizes the results. Every argument is resolved and checked before
+// anything is built, so the batch can be estimated and confirmed first.
+// It exits with the code of the first failure.
+func installSeveral(conn *sql.DB, args []string) error {
+	var planned []*db.Binary
+	var failed []string
+	var firstErr error
+	fail := func(name string, err error) {
+		failed = append(failed, name)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, arg := range args {
+		b, err := planInstall(conn, arg)
+		if err != nil {
+			if err.Error() == "" {
+				fmt.Printf("Skipping %s\n", arg)
+			} else {
+				fmt.Printf("Skipping %s: %v\n", arg, err)
 			}
+			fail(arg, err)
+			continue
+		}
+		planned = append(planned, b)
+	}
+	if len(planned) > 1 && !confirmBatch(planned, installYes || dryRun) {
+		return withExitCode(ExitDeclined, nil)
+	}
+	if dryRun {
+		for _, b := range planned {
+			fmt.Println()
+			printPlan("install", b.Name, b, installedVersion(b))
+		}
+		fmt.Printf("\nDry run: %d of %d binaries would be installed.\n", len(planned), len(args))
+		if len(failed) == 0 {
+			return nil
 		}
+		fmt.Printf("Not installable: %s\n", strings.Join(failed, ", "))
+		return withExitCode(ExitCode(firstErr), fmt.Errorf("%d of %d binaries can't be installed", len(failed), len(args)))
+	}
 
-		installCmd := b.InstallCommand()
-		fmt.Printf("Running: %s\n", installCmd)
+	jobs := jobCount(installJobs, len(planned))
+	if jobs > 1 {
+		fmt.Printf("Installing %d binaries (%d at a time).\n", len(planned), jobs)
+	}
+	errs := installConcurrently(planned, jobs, func(b *db.Binary) error {
+		if jobs == 1 {
+			fmt.Printf("\n==> %s\n", b.Name)
+		}
+		err := runInstall(b)
+		if err != nil {
+			logf(b.Name, "Failed to install %s: %v\n", b.Name, err)
+		}
+		return err
+	})
 
-		return runGoInstall(b)
-	},
+	var installed []string
+	var done []*db.Binary
+	for i, b := range planned {
+		if errs[i] != nil {
+			fail(b.Name, errs[i])
+			continue
+		}
+		installed = append(installed, b.Name)
+		done = append(done, b)
+	}
+	grantPrivileges(done)
+
+	fmt.Printf("\nInstalled %d of %d binaries", len(installed), len(args))
+	if len(installed) > 0 {
+		fmt.Printf(": %s", strings.Join(installed, ", "))
+	}
+	fmt.Println()
+	if len(failed) == 0 {
+		return nil
+	}
+	fmt.Printf("Not installed: %s\n", strings.Join(failed, ", "))
+	return withExitCode(ExitCode(firstErr), fmt.Errorf("%d of %d installs failed", len(failed), len(args)))
+}
+
+// resolveLatest returns b with its version resolved to the newest version
+// without a critical vulnerability if it is unset or "latest", or b itself.
+func resolveLatest(conn *sql.DB, b *db.Binary) (*db.Binary, error) {
+	if b.Version != "" && b.Version != "latest" {
+		return b, nil
+	}
+	version, err := safeLatest(conn, b, b.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version == b.Version {
+		return b, nil
+	}
+	resolved := *b
+	resolved.Version = version
+	return &resolved, nil
+}
+
+// goBinDir returns the directory new binaries are installed to: the system
+// bin directory with --system, the --bin-dir directory, the configured
+// bin_dir, otherwise where go install writes them (GOBIN, or the bin
+// directory of the first GOPATH entry).
+func goBinDir() (string, error) {
+	if systemBinDir != "" {
+		return systemBinDir, nil
+	}
+	if installBinDir != "" {
+		return installBinDir, nil
+	}
+	if configBinDir != "" {
+		return configBinDir, nil
+	}
+	out, err := osexec.Command("go", "env", "GOBIN", "GOPATH").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot run go env: %w", err)
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+		return strings.TrimSpace(lines[0]), nil
+	}
+	if len(lines) < 2 || strings.TrimSpace(lines[1]) == "" {
+		return "", fmt.Errorf("cannot determine the go install directory: GOBIN and GOPATH are empty")
+	}
+	return filepath.Join(filepath.SplitList(strings.TrimSpace(lines[1]))[0], "bin"), nil
+}
+
+// binDirState is the state file, in the --bin-dir directory, recording
+// the binaries installed there.
+const binDirState = ".gomanager-installed.json"
+
+// useBinDirState records installs to dir in a state file of its own, so
+// that installing a copy there never changes the user's install state or
+// touches the binaries it tracks.
+func useBinDirState(dir string) {
+	state.UseFile(filepath.Join(dir, binDirState))
+}
+
+// installDir returns the directory the binary named name is installed to:
+// the one it was installed to before, so upgrades and uninstalls find it,
+// unless --system or --bin-dir is given; otherwise goBinDir.
+func installDir(name string) (string, error) {
+	if systemBinDir == "" && installBinDir == "" {
+		if dir := recordedBinDir(name); dir != "" {
+			return dir, nil
+		}
+	}
+	return goBinDir()
+}
+
+// recordedBinDir returns the directory the install state records name as
+// installed to, or "".
+func recordedBinDir(name string) string {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	st, err := state.Load()
+	if err != nil {
+		return ""
+	}
+	return st.Installed[name].BinDir
+}
+
+// targetDir returns the directory to install the binary named name to:
+// the configured bin_dir if there is one and neither --system nor
+// --bin-dir is given, so that changing bin_dir moves binaries there as
+// they are installed or upgraded; otherwise installDir.
+func targetDir(name string) (string, error) {
+	if systemBinDir == "" && installBinDir == "" && configBinDir != "" {
+		return configBinDir, nil
+	}
+	return installDir(name)
+}
+
+// installedPath returns where the binary named name is installed.
+func installedPath(name string) (string, error) {
+	dir, err := installDir(name)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name), nil
 }
 
 func runGoInstall(b *db.Binary) error {
@@ -126,33 +496,170 @@ func runGoInstall(b *db.Binary) error {
 	pkg := fmt.Sprintf("%s@%s", b.Package, version)
 
 	goCmd := osexec.Command("go", "install", pkg)
-	goCmd.Stdout = os.Stdout
-	goCmd.Stderr = os.Stderr
+	stdout, stderr, done := buildOutput(b.Name)
+	defer done()
+	goCmd.Stdout = stdout
+	goCmd.Stderr = stderr
+
+	// In JSON progress mode, go's stderr is parsed for download events and
+	// otherwise folded into the build-end event instead of being printed.
+	var goStderr io.WriteCloser
+	var goOutput *bytes.Buffer
+	if events.Enabled() {
+		goStderr, goOutput = events.GoOutput(b.Name, b.Package)
+		goCmd.Stdout = goStderr
+		goCmd.Stderr = goStderr
+	} else if telemetryEndpoint != "" {
+		// Keep a copy to classify a failure by.
+		goOutput = new(bytes.Buffer)
+		goCmd.Stderr = io.MultiWriter(stderr, goOutput)
+	}
 
 	// Apply build flags as environment variables
+	env := buildEnv(b)
+	sumdb, err := sumdbEnv(b, env)
+	if err != nil {
+		return err
+	}
 	goCmd.Env = os.Environ()
-	flags := b.EnvFlags()
-	if flags != "" {
-		for _, f := range strings.Split(flags, " ") {
-			goCmd.Env = append(goCmd.Env, f)
+	goCmd.Env = append(goCmd.Env, env...)
+	goCmd.Env = append(goCmd.Env, sumdb...)
+
+	binDir, tmpBin, err := buildDirs(b.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpBin)
+	goCmd.Env = append(goCmd.Env, "GOBIN="+tmpBin)
+
+	events.Emit(progress.Event{Event: progress.BuildStart, Name: b.Name, Package: b.Package, Version: version})
+	start := time.Now()
+	err = goCmd.Run()
+	if goStderr != nil {
+		goStderr.Close()
+	}
+	if err == nil {
+		err = moveBuilt(tmpBin, binDir, binaryFile(b))
+	}
+	end := progress.Event{
+		Event: progress.BuildEnd, Name: b.Name, Package: b.Package, Version: version,
+		Status: "ok", Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		end.Status = "failed"
+		end.Error = err.Error()
+		if goOutput != nil && goOutput.Len() > 0 {
+			end.Error = strings.TrimSpace(goOutput.String())
+		}
+	}
+	events.Emit(end)
+	if err != nil {
+		events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: version, Status: "failed", Error: end.Error})
+		err = fmt.Errorf("go install failed: %w", err)
+		if goOutput != nil {
+			err = &buildFailure{err: err, output: goOutput.String()}
 		}
+		return withExitCode(ExitBuildFailed, err)
 	}
+	recordInstall(b, version)
+	return nil
+}
 
-	if err := goCmd.Run(); err != nil {
-		return fmt.Errorf("go install failed: %w", err)
+// buildDirs returns the directory to install the binary named name to and
+// a new temporary directory next to it to build into. Builds only move
+// their result into place once they succeed, so a failed or interrupted
+// build never leaves a broken or missing binary behind. The caller removes
+// tmpDir.
+func buildDirs(name string) (binDir, tmpDir string, err error) {
+	binDir, err = targetDir(name)
+	if err != nil {
+		return "", "", err
+	}
+	if err := requireSystemAccess(binDir); err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		return "", "", fmt.Errorf("cannot create %s: %w", binDir, err)
 	}
+	tmpDir, err = os.MkdirTemp(binDir, ".gomanager-build-*")
+	if err != nil {
+		return "", "", fmt.Errorf("cannot create build directory: %w", err)
+	}
+	return binDir, tmpDir, nil
+}
 
-	// Track installation
+// stateMu serializes install state updates from concurrent installs.
+var stateMu sync.Mutex
+
+// recordInstall tracks a successful installation in the install state. A
+// copy left in the directory the binary was installed to before (when
+// bin_dir changed since) is removed.
+func recordInstall(b *db.Binary, version string) {
+	binDir, err := targetDir(b.Name)
+	if err != nil {
+		logf(b.Name, "Warning: could not save install state: %v\n", err)
+		return
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
 	st, err := state.Load()
 	if err != nil {
-		fmt.Printf("Warning: could not save install state: %v\n", err)
-		return nil
+		logf(b.Name, "Warning: could not save install state: %v\n", err)
+		return
+	}
+	if prev := st.Installed[b.Name].BinDir; prev != "" && prev != binDir {
+		old := filepath.Join(prev, binaryFile(b))
+		if err := os.Remove(old); err == nil {
+			logf(b.Name, "Removed the copy in %s\n", prev)
+		} else if !os.IsNotExist(err) {
+			logf(b.Name, "Warning: cannot remove the copy in %s: %v\n", prev, err)
+		}
+	}
+	prev := st.Installed[b.Name]
+	st.MarkInstalled(b.Name, b.Package, version, binDir)
+	installed := st.Installed[b.Name]
+	path := filepath.Join(binDir, binaryFile(b))
+	installed.Sum = checkModuleSum(b.Name, path, prev)
+	if installed.SHA256, err = attest.FileDigest(path); err != nil {
+		logf(b.Name, "Warning: cannot record the digest of %s: %v\n", b.Name, err)
 	}
-	st.MarkInstalled(b.Name, b.Package, version)
+	addLaunchers(b, &installed, prev.BinDir)
+	st.Installed[b.Name] = installed
 	if err := st.Save(); err != nil {
-		fmt.Printf("Warning: could not save install state: %v\n", err)
+		logf(b.Name, "Warning: could not save install state: %v\n", err)
 	}
 
-	fmt.Printf("Successfully installed %s\n", b.Name)
+	events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: version, Status: "installed"})
+	logf(b.Name, "Successfully installed %s\n", b.Name)
+}
+
+// moveBuilt moves the binaries go install wrote to tmpDir into binDir. The
+// directories are on the same filesystem, so each binary is replaced
+// atomically. go install names a binary after its package path, which may
+// not be the name the database gives it (e.g. cmd/cli built as mytool), so
+// a single binary is installed as file.
+func moveBuilt(tmpDir, binDir, file string) error {
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, e.Name())
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("go install produced no binary in %s", tmpDir)
+	}
+	for _, f := range files {
+		dest := f
+		if len(files) == 1 {
+			dest = file
+		}
+		if err := os.Rename(filepath.Join(tmpDir, f), filepath.Join(binDir, dest)); err != nil {
+			return fmt.Errorf("cannot install %s: %w", dest, err)
+		}
+	}
 	return nil
 }
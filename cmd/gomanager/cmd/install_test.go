@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// testHome points the home and XDG directories into a temporary directory
+// and returns it. Go telemetry is turned off there, since commands that
+// run go env would otherwise leave it writing to the directory after the
+// test returns.
+func testHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	telemetry := filepath.Join(home, "config", "go", "telemetry")
+	if err := os.MkdirAll(telemetry, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(telemetry, "mode"), []byte("off"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// installedFixture records hello v1.1.0 as installed in a bin directory of
+// a temporary home (see testHome), and returns the directory.
+func installedFixture(t *testing.T) string {
+	t.Helper()
+	home := testHome(t)
+	t.Cleanup(func() {
+		installBinDir, configBinDir = "", ""
+		state.UseFile("")
+	})
+
+	binDir := filepath.Join(home, "bin")
+	writeBinary(t, binDir, "hello")
+	st := &state.State{Installed: make(map[string]state.InstalledBinary)}
+	st.MarkInstalled("hello", "github.com/acme/hello", "v1.1.0", binDir)
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return binDir
+}
+
+// writeBinary creates an executable named name in dir.
+func writeBinary(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// installedHello returns hello's entry in the per-user install state.
+func installedHello(t *testing.T) state.InstalledBinary {
+	t.Helper()
+	state.UseFile("")
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Installed["hello"]
+}
+
+func TestRecordInstallBinDirLeavesUserInstall(t *testing.T) {
+	binDir := installedFixture(t)
+	toolCache := filepath.Join(t.TempDir(), "tools")
+	installBinDir = toolCache
+	useBinDirState(toolCache)
+	writeBinary(t, toolCache, "hello")
+
+	recordInstall(&db.Binary{Name: "hello", Package: "github.com/acme/hello"}, "v1.2.0")
+
+	if _, err := os.Stat(filepath.Join(binDir, "hello")); err != nil {
+		t.Errorf("installing with --bin-dir removed the user's copy: %v", err)
+	}
+	if got := installedHello(t); got.Version != "v1.1.0" || got.BinDir != binDir {
+		t.Errorf("user state records hello %s in %s, want v1.1.0 in %s", got.Version, got.BinDir, binDir)
+	}
+	state.UseFile(filepath.Join(toolCache, binDirState))
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.Installed["hello"]; got.Version != "v1.2.0" || got.BinDir != toolCache {
+		t.Errorf("%s records hello %s in %s, want v1.2.0 in %s", binDirState, got.Version, got.BinDir, toolCache)
+	}
+}
+
+func TestRecordInstallMovesToConfiguredBinDir(t *testing.T) {
+	binDir := installedFixture(t)
+	configBinDir = filepath.Join(t.TempDir(), "bin")
+	writeBinary(t, configBinDir, "hello")
+
+	recordInstall(&db.Binary{Name: "hello", Package: "github.com/acme/hello"}, "v1.2.0")
+
+	if _, err := os.Stat(filepath.Join(binDir, "hello")); !os.IsNotExist(err) {
+		t.Errorf("the copy in the old bin_dir wasn't removed: %v", err)
+	}
+	if got := installedHello(t); got.Version != "v1.2.0" || got.BinDir != configBinDir {
+		t.Errorf("state records hello %s in %s, want v1.2.0 in %s", got.Version, got.BinDir, configBinDir)
+	}
+}
+
+// warningFixture creates a database holding an archived binary and two
+// whose builds failed, one without a recorded error as in the slim
+// database, in a temporary home (see testHome), and opens it.
+func warningFixture(t *testing.T) *sql.DB {
+	t.Helper()
+	home := testHome(t)
+	conn, err := db.CreatePath(filepath.Join(home, "database.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := db.InitSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO binaries (name, package, version, build_status, archived) VALUES ('old', 'github.com/acme/old', 'v1.0.0', 'confirmed', 1)`,
+		`INSERT INTO binaries (name, package, version, build_status) VALUES ('broken', 'github.com/acme/broken', 'v1.0.0', 'failed')`,
+		`INSERT INTO binaries (name, package, version, build_status, build_error) VALUES ('flaky', 'github.com/acme/flaky', 'v1.0.0', 'failed', 'undefined: x')`,
+	} {
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return conn
+}
+
+// answer feeds input to the next prompts on standard input.
+func answer(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
+
+func TestPlanInstallDeclined(t *testing.T) {
+	conn := warningFixture(t)
+	for _, name := range []string{"old", "broken"} {
+		answer(t, "n\n")
+		if _, err := planInstall(conn, name); ExitCode(err) != ExitDeclined {
+			t.Errorf("declining the warning for %s: got %v (exit %d), want exit %d", name, err, ExitCode(err), ExitDeclined)
+		}
+		answer(t, "y\n")
+		if b, err := planInstall(conn, name); err != nil || b.Name != name {
+			t.Errorf("accepting the warning for %s: got %v, %v", name, b, err)
+		}
+	}
+}
+
+func TestFailedBuildWarningOmitsEmptyError(t *testing.T) {
+	conn := warningFixture(t)
+	for name, want := range map[string]bool{"broken": false, "flaky": true} {
+		answer(t, "n\n")
+		out := captureStdout(t, func() { planInstall(conn, name) })
+		if got := strings.Contains(out, "Error:"); got != want {
+			t.Errorf("warning for %s shows an error line = %v, want %v:\n%s", name, got, want, out)
+		}
+		if want && !strings.Contains(out, "  Error: undefined: x\n") {
+			t.Errorf("warning for %s doesn't show its build error:\n%s", name, out)
+		}
+	}
+}
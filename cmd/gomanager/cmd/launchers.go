@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+var (
+	// installAliases are the --alias names to link to the installed
+	// binary.
+	installAliases []string
+	// installDesktop is bound to install's --desktop flag.
+	installDesktop bool
+)
+
+// checkAliases validates the --alias names for installing the binary name:
+// each must be a plain file name that isn't the binary's own or that of
+// another installed binary.
+func checkAliases(name string) error {
+	st, err := state.Load()
+	if err != nil {
+		return err
+	}
+	for _, alias := range installAliases {
+		if alias == "" || alias == "." || alias == ".." || strings.ContainsAny(alias, `/\`) {
+			return fmt.Errorf("invalid alias %q: want a file name", alias)
+		}
+		if alias == name {
+			return fmt.Errorf("alias %q is the binary's own name", alias)
+		}
+		if _, ok := st.Installed[alias]; ok {
+			return fmt.Errorf("alias %q is the name of an installed binary", alias)
+		}
+	}
+	return nil
+}
+
+// addLaunchers creates the aliases and desktop entry of the binary b just
+// installed as ib: those it had before, which move with it if its install
+// directory changed from prevDir, and those --alias and --desktop ask for.
+// Failures are only warned about, since the binary itself is installed.
+func addLaunchers(b *db.Binary, ib *state.InstalledBinary, prevDir string) {
+	file := binaryFile(b)
+	if prevDir != "" && prevDir != ib.BinDir {
+		for _, alias := range ib.Aliases {
+			removeAlias(prevDir, alias, file)
+		}
+	}
+	aliases := ib.Aliases
+	ib.Aliases = nil
+	for _, alias := range append(aliases, installAliases...) {
+		if slices.Contains(ib.Aliases, alias) {
+			continue
+		}
+		if err := linkAlias(ib.BinDir, alias, file); err != nil {
+			logf(b.Name, "Warning: cannot create alias %s: %v\n", alias, err)
+			continue
+		}
+		if !slices.Contains(aliases, alias) {
+			logf(b.Name, "Linked %s -> %s\n", alias, b.Name)
+		}
+		ib.Aliases = append(ib.Aliases, alias)
+	}
+
+	if !installDesktop && ib.DesktopEntry == "" {
+		return
+	}
+	path, err := writeDesktopEntry(b, filepath.Join(ib.BinDir, file))
+	if err != nil {
+		logf(b.Name, "Warning: cannot create a desktop entry: %v\n", err)
+		return
+	}
+	if ib.DesktopEntry == "" {
+		logf(b.Name, "Created desktop entry %s\n", path)
+	}
+	ib.DesktopEntry = path
+}
+
+// removeLaunchers removes the aliases and desktop entry of the installed
+// binary ib, which was installed in dir.
+func removeLaunchers(ib state.InstalledBinary, dir string) {
+	file := filepath.Base(ib.Name)
+	if runtime.GOOS == "windows" {
+		file += ".exe"
+	}
+	for _, alias := range ib.Aliases {
+		if removeAlias(dir, alias, file) {
+			fmt.Printf("Removed alias %s\n", alias)
+		}
+	}
+	if ib.DesktopEntry != "" {
+		if err := os.Remove(ib.DesktopEntry); err == nil {
+			fmt.Printf("Removed %s\n", ib.DesktopEntry)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("Warning: cannot remove %s: %v\n", ib.DesktopEntry, err)
+		}
+	}
+}
+
+// aliasPath returns the path of alias in dir.
+func aliasPath(dir, alias string) string {
+	if runtime.GOOS == "windows" && !strings.HasSuffix(alias, ".exe") {
+		alias += ".exe"
+	}
+	return filepath.Join(dir, alias)
+}
+
+// linkAlias creates a symlink named alias in dir to file, the binary in the
+// same directory. An existing link to file is kept; anything else at the
+// alias's path is left alone and reported.
+func linkAlias(dir, alias, file string) error {
+	path := aliasPath(dir, alias)
+	if fi, err := os.Lstat(path); err == nil {
+		if target, err := os.Readlink(path); err == nil && target == file {
+			return nil
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s already links elsewhere", path)
+		}
+		return fmt.Errorf("%s already exists", path)
+	}
+	return os.Symlink(file, path)
+}
+
+// removeAlias removes the alias symlink in dir if it still links to file,
+// reporting whether it did.
+func removeAlias(dir, alias, file string) bool {
+	path := aliasPath(dir, alias)
+	if target, err := os.Readlink(path); err != nil || target != file {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// desktopDir returns the directory desktop entries are written to:
+// share/applications under the system prefix with --system, and otherwise
+// $XDG_DATA_HOME/applications (~/.local/share/applications by default).
+func desktopDir() (string, error) {
+	if systemBinDir != "" {
+		return filepath.Join(filepath.Dir(systemBinDir), "share", "applications"), nil
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine data directory: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "applications"), nil
+}
+
+// writeDesktopEntry writes a desktop entry that opens the binary b, at path,
+// in a terminal, so launchers list TUI apps, and returns its path.
+func writeDesktopEntry(b *db.Binary, path string) (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "plan9":
+		return "", fmt.Errorf("desktop entries are not supported on %s", runtime.GOOS)
+	}
+	dir, err := desktopDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[Desktop Entry]\n")
+	sb.WriteString("Type=Application\n")
+	fmt.Fprintf(&sb, "Name=%s\n", desktopValue(b.Name))
+	if b.Description != "" {
+		fmt.Fprintf(&sb, "Comment=%s\n", desktopValue(b.Description))
+	}
+	fmt.Fprintf(&sb, "Exec=%s\n", desktopExec(path))
+	sb.WriteString("Terminal=true\n")
+	sb.WriteString("Categories=Utility;ConsoleOnly;\n")
+
+	entry := filepath.Join(dir, "gomanager-"+b.Name+".desktop")
+	if err := os.WriteFile(entry, []byte(sb.String()), 0o644); err != nil {
+		return "", err
+	}
+	return entry, nil
+}
+
+// desktopValue escapes s for a desktop entry string value.
+func desktopValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+}
+
+// desktopExec quotes path as the program of a desktop entry's Exec key,
+// which takes quoted arguments with their own escaping on top of the
+// string value's.
+func desktopExec(path string) string {
+	if !strings.ContainsAny(path, " \t\n\"'\\><~|&;$*?#()`=%") {
+		return desktopValue(path)
+	}
+	quoted := strings.NewReplacer(`"`, `\"`, "`", "\\`", "$", `\$`, `\`, `\\`).Replace(path)
+	quoted = strings.ReplaceAll(quoted, "%", "%%")
+	return desktopValue(`"` + quoted + `"`)
+}
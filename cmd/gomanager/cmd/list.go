@@ -3,38 +3,294 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"sort"
+	"strings"
 
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
 	"github.com/jmelahman/gomanager/internal/state"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// Orders for list --sort.
+const (
+	sortName    = "name"
+	sortDate    = "date"
+	sortVersion = "version"
+)
+
+var (
+	listTree    bool
+	listSort    string
+	listOrphans bool
 )
 
 func init() {
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "Group binaries built from the same module")
+	listCmd.Flags().BoolVar(&listOrphans, "orphans", false, "List untracked binaries in the install directory and tracked binaries missing from disk instead")
+	listCmd.Flags().StringVar(&listSort, "sort", sortName, "Order by name, date (newest install first), or version (highest first)")
 	rootCmd.AddCommand(listCmd)
 }
 
+// listedBinary is an installed binary annotated from the database.
+type listedBinary struct {
+	state.InstalledBinary
+	// status is the database build status, or "-" if the binary isn't in
+	// the database.
+	status string
+	// update is a newer database version, or "".
+	update string
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List installed Go binaries",
+	Long: `Lists installed binaries with their database build status and, in the
+UPDATE column, a newer database version if there is one ('gomanager
+outdated' also checks the module proxy for binaries on the latest channel).
+The RUNS column shows whether the binary passed its last smoke test
+(install --verify, or [install] verify = true): "yes", "no" for a broken
+install that failed to run, or "-" if it wasn't tested.
+
+With --tree, binaries built from the same module (e.g. several commands of
+one repository) are grouped under it.
+
+With --orphans, the drift between the install state and disk is listed
+instead: executables in the go install directory gomanager doesn't track
+(track Go binaries among them with 'gomanager adopt'), and tracked binaries
+whose file no longer exists (reinstall or uninstall them).`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if listSort != sortName && listSort != sortDate && listSort != sortVersion {
+			return fmt.Errorf("unknown sort order %q (want name, date, or version)", listSort)
+		}
 		st, err := state.Load()
 		if err != nil {
 			return err
 		}
+		if listOrphans {
+			return listOrphaned(st)
+		}
+
+		installed := make([]state.InstalledBinary, 0, len(st.Installed))
+		for _, b := range st.Installed {
+			installed = append(installed, b)
+		}
+		sortInstalled(installed, listSort)
+
+		if jsonOutput {
+			for i := range installed {
+				installed[i].Channel = installed[i].ReleaseChannel()
+			}
+			return printResult(installed, nil)
+		}
 
-		if len(st.Installed) == 0 {
+		if len(installed) == 0 {
 			fmt.Println("No binaries installed via gomanager.")
 			return nil
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintf(w, "NAME\tPACKAGE\tVERSION\tINSTALLED\n")
-		for _, b := range st.Installed {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-				b.Name, b.Package, b.Version,
-				b.InstalledAt.Format("2006-01-02"))
+		listed := annotateInstalled(installed)
+		t := newTable("NAME", "PACKAGE", "VERSION", "UPDATE", "STATUS", "RUNS", "CHANNEL", "PINNED", "INSTALLED")
+		if listTree {
+			for _, group := range groupByModule(listed) {
+				t.section(pkgbuild.ResolvePaths(group[0].Package).Module)
+				for i, b := range group {
+					branch := "├─ "
+					if i == len(group)-1 {
+						branch = "└─ "
+					}
+					listRow(t, branch, b)
+				}
+			}
+		} else {
+			for _, b := range listed {
+				listRow(t, "", b)
+			}
 		}
-		w.Flush()
+		t.flush()
+		fmt.Println()
+		fmt.Println(listSummary(listed))
 		return nil
 	},
 }
+
+// listOrphaned prints the untracked and missing binaries for --orphans.
+func listOrphaned(st *state.State) error {
+	orphans, binDir := findOrphans(st)
+	if orphans == nil {
+		orphans = []orphan{}
+	}
+	return printResult(orphans, func() {
+		if len(orphans) == 0 {
+			fmt.Printf("No orphans: every tracked binary is installed, and nothing else is in %s.\n", binDir)
+			return
+		}
+		t := newTable("NAME", "STATUS", "PATH")
+		for _, o := range orphans {
+			t.row(o.Name, o.Status, o.Path)
+		}
+		t.flush()
+	})
+}
+
+// listRow adds b to the list table, prefixing its name with indent.
+func listRow(t *table, indent string, b listedBinary) {
+	pinned := "-"
+	if b.Pinned {
+		pinned = "yes"
+	}
+	update := b.update
+	if update == "" {
+		update = "-"
+	}
+	runs := "-"
+	switch {
+	case b.Broken():
+		runs = "no"
+	case b.Smoke != nil:
+		runs = "yes"
+	}
+	t.row(indent+b.Name, b.Package, b.Version, update, b.status, runs, b.ReleaseChannel(), pinned,
+		b.InstalledAt.Format("2006-01-02"))
+}
+
+// sortInstalled orders installed binaries by name, by install date (newest
+// first), or by version (highest first, non-semver versions last), with
+// ties broken by name.
+func sortInstalled(installed []state.InstalledBinary, by string) {
+	sort.Slice(installed, func(i, j int) bool {
+		a, b := installed[i], installed[j]
+		switch by {
+		case sortDate:
+			if !a.InstalledAt.Equal(b.InstalledAt) {
+				return a.InstalledAt.After(b.InstalledAt)
+			}
+		case sortVersion:
+			av, bv := semver.IsValid(a.Version), semver.IsValid(b.Version)
+			if av != bv {
+				return av
+			}
+			if c := semver.Compare(a.Version, b.Version); av && c != 0 {
+				return c > 0
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// annotateInstalled looks the installed binaries up in the database for
+// their build status and newer versions. Without a local database every
+// binary is listed with status "-".
+func annotateInstalled(installed []state.InstalledBinary) []listedBinary {
+	listed := make([]listedBinary, len(installed))
+	for i, b := range installed {
+		listed[i] = listedBinary{InstalledBinary: b, status: "-"}
+	}
+
+	path, err := db.DBPath()
+	if err != nil {
+		return listed
+	}
+	if _, err := os.Stat(path); err != nil {
+		return listed
+	}
+	conn, err := db.Open()
+	if err != nil {
+		return listed
+	}
+	defer conn.Close()
+
+	for i := range listed {
+		b, err := lookupPackage(conn, listed[i].Package)
+		if err != nil {
+			continue
+		}
+		listed[i].status = b.BuildStatus
+		if newerVersion(b.Version, listed[i].Version) {
+			listed[i].update = b.Version
+		}
+	}
+	return listed
+}
+
+// newerVersion reports whether available is newer than installed. Versions
+// that aren't both semver only count as newer if they differ and available
+// names a version.
+func newerVersion(available, installed string) bool {
+	if semver.IsValid(available) && semver.IsValid(installed) {
+		return semver.Compare(available, installed) > 0
+	}
+	return available != "" && available != "latest" && available != installed
+}
+
+// groupByModule groups binaries by the module they are built from, keeping
+// the binaries' order within groups and ordering groups by their first
+// binary.
+func groupByModule(listed []listedBinary) [][]listedBinary {
+	var groups [][]listedBinary
+	index := make(map[string]int)
+	for _, b := range listed {
+		module := pkgbuild.ResolvePaths(b.Package).Module
+		i, ok := index[module]
+		if !ok {
+			i = len(groups)
+			index[module] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], b)
+	}
+	return groups
+}
+
+// listSummary counts the listed binaries by build status, most common
+// first, the available updates, and the broken installs, e.g. "3 installed:
+// 2 confirmed, 1 failed; 1 update available; 1 fails to run".
+func listSummary(listed []listedBinary) string {
+	counts := make(map[string]int)
+	updates, broken := 0, 0
+	for _, b := range listed {
+		counts[b.status]++
+		if b.update != "" {
+			updates++
+		}
+		if b.Broken() {
+			broken++
+		}
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if counts[statuses[i]] != counts[statuses[j]] {
+			return counts[statuses[i]] > counts[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		label := s
+		if s == "-" {
+			label = "not in the database"
+		}
+		parts[i] = fmt.Sprintf("%d %s", counts[s], label)
+	}
+	summary := fmt.Sprintf("%d installed: %s", len(listed), strings.Join(parts, ", "))
+	switch updates {
+	case 0:
+	case 1:
+		summary += "; 1 update available"
+	default:
+		summary += fmt.Sprintf("; %d updates available", updates)
+	}
+	switch broken {
+	case 0:
+	case 1:
+		summary += "; 1 fails to run"
+	default:
+		summary += fmt.Sprintf("; %d fail to run", broken)
+	}
+	return summary
+}
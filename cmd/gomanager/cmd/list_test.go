@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// listFixture installs binaries with tied install dates, versions, and
+// modules, in a temporary home with a database recording their statuses.
+func listFixture(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := &state.State{Installed: make(map[string]state.InstalledBinary)}
+	for _, b := range []state.InstalledBinary{
+		{Name: "foo", Package: "github.com/acme/multi/v2/cmd/foo", Version: "v2.0.0", InstalledAt: day},
+		{Name: "bar", Package: "github.com/acme/multi/v2/cmd/bar", Version: "v2.0.0", InstalledAt: day},
+		{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0", InstalledAt: day.AddDate(0, 0, 1)},
+		{Name: "gizmo", Package: "github.com/acme/gizmo", Version: "v1.0.0", InstalledAt: day},
+		{Name: "legacy", Package: "github.com/acme/legacy", Version: "devel", InstalledAt: day},
+		{Name: "alpha", Package: "github.com/acme/alpha", Version: "v0.1.0", InstalledAt: day},
+	} {
+		st.Installed[b.Name] = b
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := db.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.CreatePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := db.InitSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []db.Binary{
+		{Name: "foo", Package: "github.com/acme/multi/v2/cmd/foo", Version: "v2.1.0", BuildStatus: "confirmed"},
+		{Name: "bar", Package: "github.com/acme/multi/v2/cmd/bar", Version: "v2.1.0", BuildStatus: "confirmed"},
+		{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0", BuildStatus: "failed"},
+		{Name: "gizmo", Package: "github.com/acme/gizmo", Version: "v1.0.0", BuildStatus: "pending"},
+		{Name: "alpha", Package: "github.com/acme/alpha", Version: "v0.1.0", BuildStatus: "regressed"},
+	} {
+		if _, err := conn.Exec(`INSERT INTO binaries (name, package, version, build_status) VALUES (?, ?, ?, ?)`,
+			b.Name, b.Package, b.Version, b.BuildStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runList runs gomanager list with args and returns what it printed.
+func runList(t *testing.T, args ...string) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	rootCmd.SetArgs(append([]string{"list"}, args...))
+	err = rootCmd.Execute()
+	os.Stdout = stdout
+	w.Close()
+	out := <-done
+	if err != nil {
+		t.Fatalf("gomanager list %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// rowKeys returns the first two fields of each table row in out (the
+// name and package, or the tree branch and name), skipping the header and
+// stopping at the first blank line.
+func rowKeys(out string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			break
+		}
+		names = append(names, strings.Join(fields[:min(2, len(fields))], " "))
+	}
+	return names
+}
+
+func TestListOrderIsStable(t *testing.T) {
+	listFixture(t)
+	t.Cleanup(func() { listSort, listTree = sortName, false })
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"--sort", "name", "--tree=false"},
+			want: []string{"alpha github.com/acme/alpha", "bar github.com/acme/multi/v2/cmd/bar",
+				"foo github.com/acme/multi/v2/cmd/foo", "gizmo github.com/acme/gizmo",
+				"legacy github.com/acme/legacy", "widget github.com/acme/widget"},
+		},
+		{
+			args: []string{"--sort", "date", "--tree=false"},
+			want: []string{"widget github.com/acme/widget", "alpha github.com/acme/alpha",
+				"bar github.com/acme/multi/v2/cmd/bar", "foo github.com/acme/multi/v2/cmd/foo",
+				"gizmo github.com/acme/gizmo", "legacy github.com/acme/legacy"},
+		},
+		{
+			args: []string{"--sort", "version", "--tree=false"},
+			want: []string{"bar github.com/acme/multi/v2/cmd/bar", "foo github.com/acme/multi/v2/cmd/foo",
+				"gizmo github.com/acme/gizmo", "widget github.com/acme/widget",
+				"alpha github.com/acme/alpha", "legacy github.com/acme/legacy"},
+		},
+		{
+			args: []string{"--sort", "name", "--tree"},
+			want: []string{"github.com/acme/alpha", "└─ alpha", "github.com/acme/multi/v2", "├─ bar", "└─ foo",
+				"github.com/acme/gizmo", "└─ gizmo", "github.com/acme/legacy", "└─ legacy",
+				"github.com/acme/widget", "└─ widget"},
+		},
+	}
+	for _, tt := range tests {
+		name := strings.Join(tt.args, " ")
+		first := runList(t, tt.args...)
+		// Map iteration order changes from run to run, so a listing built
+		// from one differs between renders.
+		for range 3 {
+			if again := runList(t, tt.args...); again != first {
+				t.Fatalf("list %s differs between runs:\n%s\n---\n%s", name, first, again)
+			}
+		}
+		got := rowKeys(first)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("list %s order:\n  %s\nwant:\n  %s", name, strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+		}
+	}
+	out := runList(t, "--sort", "name", "--tree=false")
+	if !strings.Contains(out, "6 installed: 2 confirmed, 1 not in the database, 1 failed, 1 pending, 1 regressed; 2 updates available") {
+		t.Errorf("list summary isn't ordered by count, then status:\n%s", out)
+	}
+}
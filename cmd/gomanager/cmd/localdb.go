@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/spf13/cobra"
+)
+
+var (
+	addLocalName        string
+	addLocalVersion     string
+	addLocalDescription string
+	addLocalRepo        string
+	addLocalEnv         []string
+)
+
+func init() {
+	dbAddLocalCmd.Flags().StringVar(&addLocalName, "name", "", "Binary name (default: the last element of the package path)")
+	dbAddLocalCmd.Flags().StringVar(&addLocalVersion, "version", "", "Version to install (default: the module proxy's latest)")
+	dbAddLocalCmd.Flags().StringVar(&addLocalDescription, "description", "", "Description shown by search and info")
+	dbAddLocalCmd.Flags().StringVar(&addLocalRepo, "repo", "", "Repository URL, used to build from source")
+	dbAddLocalCmd.Flags().StringSliceVar(&addLocalEnv, "env", nil, "Build environment variable as KEY=VALUE (repeatable), e.g. CGO_ENABLED=0")
+	dbCmd.AddCommand(dbAddLocalCmd)
+	dbCmd.AddCommand(dbRemoveLocalCmd)
+	dbCmd.AddCommand(dbListLocalCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage local database entries",
+	Long: `Local entries describe binaries that aren't in the published database, such
+as internal company tools or personal forks. They are kept in
+~/.local/share/gomanager/local.db, which update-db never replaces, and are
+consulted before the published database: a local entry with the same name
+or package path as a published one takes precedence in install, info, run,
+and upgrade.`,
+}
+
+var dbAddLocalCmd = &cobra.Command{
+	Use:   "add-local <package>",
+	Short: "Add or replace a local database entry",
+	Long: `Adds a local entry for the main package at the given path. Without
+--version, the version is resolved from the module proxy in GOPROXY; modules
+the proxy can't see (e.g. behind GOPRIVATE) need an explicit --version.
+
+Adding a package or name that already has a local entry replaces it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pkg := strings.TrimSuffix(args[0], "/")
+		if !strings.Contains(pkg, "/") {
+			return fmt.Errorf("%q is not a package path", args[0])
+		}
+		name := addLocalName
+		if name == "" {
+			name = db.InstallName(pkg)
+		}
+
+		flags := make(map[string]string)
+		for _, kv := range addLocalEnv {
+			key, val, ok := strings.Cut(kv, "=")
+			if !ok {
+				return fmt.Errorf("invalid --env %q: want KEY=VALUE", kv)
+			}
+			if !db.IsAllowedBuildEnv(key) {
+				return fmt.Errorf("invalid --env %q: %s is not an allowed build variable", kv, key)
+			}
+			flags[key] = val
+		}
+		buildFlags := "{}"
+		if len(flags) > 0 {
+			data, err := json.Marshal(flags)
+			if err != nil {
+				return err
+			}
+			buildFlags = string(data)
+		}
+
+		version := addLocalVersion
+		if version == "" {
+			proxy, err := goproxy.New()
+			if err != nil {
+				return fmt.Errorf("%w; pass --version", err)
+			}
+			version, err = proxy.Latest(pkgbuild.ResolvePaths(pkg).Module)
+			if err != nil {
+				return withExitCode(ExitNetwork, fmt.Errorf("%w; pass --version for modules the proxy can't reach", err))
+			}
+		}
+
+		conn, err := db.OpenLocal()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		b := db.Binary{
+			Name:        name,
+			Package:     pkg,
+			Version:     version,
+			Description: addLocalDescription,
+			RepoURL:     addLocalRepo,
+			BuildFlags:  buildFlags,
+		}
+		if err := db.PutLocal(conn, b); err != nil {
+			return fmt.Errorf("cannot add local entry: %w", err)
+		}
+		fmt.Printf("Added local entry %s (%s@%s)\n", name, pkg, version)
+		return nil
+	},
+}
+
+var dbRemoveLocalCmd = &cobra.Command{
+	Use:   "remove-local <name or package>",
+	Short: "Remove a local database entry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !db.HasLocal() {
+			return withExitCode(ExitNotFound, fmt.Errorf("no local entry for %q", args[0]))
+		}
+		conn, err := db.OpenLocal()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		removed, err := db.DeleteLocal(conn, args[0])
+		if err != nil {
+			return fmt.Errorf("cannot remove local entry: %w", err)
+		}
+		if !removed {
+			return withExitCode(ExitNotFound, fmt.Errorf("no local entry for %q", args[0]))
+		}
+		fmt.Printf("Removed local entry %s\n", args[0])
+		return nil
+	},
+}
+
+var dbListLocalCmd = &cobra.Command{
+	Use:   "list-local",
+	Short: "List local database entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !db.HasLocal() {
+			fmt.Println("No local entries.")
+			return nil
+		}
+		conn, err := db.OpenLocal()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		binaries, err := db.ListLocal(conn)
+		if err != nil {
+			return err
+		}
+		if len(binaries) == 0 {
+			fmt.Println("No local entries.")
+			return nil
+		}
+		t := newTable("NAME", "PACKAGE", "VERSION", "BUILD FLAGS")
+		for _, b := range binaries {
+			t.row(b.Name, b.Package, b.Version, b.EnvFlags())
+		}
+		t.flush()
+		return nil
+	},
+}
+
+// localMarker flags entries from the local overlay database.
+const localMarker = "local"
+
+// localBinary returns the local overlay entry for a name or package path,
+// or nil if there is none.
+func localBinary(arg string) *db.Binary {
+	if !db.HasLocal() {
+		return nil
+	}
+	conn, err := db.OpenLocal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read local database: %v\n", err)
+		return nil
+	}
+	defer conn.Close()
+
+	b, err := db.LocalByNameOrPackage(conn, arg)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Warning: cannot read local database: %v\n", err)
+		}
+		return nil
+	}
+	return b
+}
+
+// openExistingDB opens the database if it has been downloaded, without
+// downloading it, for commands that can do without it.
+func openExistingDB() (*sql.DB, error) {
+	path, err := db.DBPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return db.Open()
+}
+
+// lookupPackage finds a package by exact path, preferring a local entry to
+// the published database. Published entries hidden by the configured
+// filter are not found.
+func lookupPackage(conn *sql.DB, pkg string) (*db.Binary, error) {
+	if b := localBinary(pkg); b != nil && b.Package == pkg {
+		return b, nil
+	}
+	b, err := db.GetByPackage(conn, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return b, checkVisible(b)
+}
+
+// searchLocal returns local entries matching query and f, or nil if there
+// are none or the overlay can't be read.
+func searchLocal(query string, f db.SearchFilter) []db.Binary {
+	if !db.HasLocal() {
+		return nil
+	}
+	conn, err := db.OpenLocal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read local database: %v\n", err)
+		return nil
+	}
+	defer conn.Close()
+
+	results, err := db.SearchLocal(conn, query, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot search local database: %v\n", err)
+		return nil
+	}
+	return results
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"database/sql"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// lockVersion is the lockfile format version lock export writes and lock
+// install reads.
+const lockVersion = 1
+
+var lockJobs int
+
+func init() {
+	lockInstallCmd.Flags().IntVarP(&lockJobs, "jobs", "j", 0, "Number of binaries to build at once (0 = one per CPU)")
+	lockInstallCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	lockInstallCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	lockInstallCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	lockCmd.AddCommand(lockExportCmd)
+	lockCmd.AddCommand(lockInstallCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+// lockFile is the TOML form of a lockfile.
+type lockFile struct {
+	Version int        `toml:"version"`
+	Tools   []lockTool `toml:"tool"`
+}
+
+// lockTool is an installed binary pinned by a lockfile.
+type lockTool struct {
+	Name    string `toml:"name"`
+	Package string `toml:"package"`
+	Version string `toml:"version"`
+	// Env lists the build environment variables as KEY=VALUE.
+	Env []string `toml:"env,omitempty"`
+	// Sum is the go.sum hash of the module the binary was built from. If
+	// set, lock install refuses a build with a different one.
+	Sum string `toml:"sum,omitempty"`
+}
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Reproduce the installed binaries on another machine",
+	Long: `A lockfile records every installed binary with its exact package path,
+version, build environment, and module hash, so a team can install the same
+toolbelt everywhere:
+
+  gomanager lock export > gomanager.lock
+  gomanager lock install gomanager.lock
+
+Unlike a manifest (install --from-manifest), a lockfile never resolves
+versions: every tool is installed at exactly the recorded version.`,
+}
+
+var lockExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print a lockfile of the installed binaries",
+	Long: `Prints a lockfile of the installed binaries to stdout. Build environments
+are taken from the database entries of the installed packages, and module
+hashes from the install state (or, for binaries installed before hashes
+were recorded, from the binaries' build info).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		if len(st.Installed) == 0 {
+			return withExitCode(ExitNothingToDo, fmt.Errorf("no binaries installed via gomanager"))
+		}
+
+		conn, _ := openExistingDB()
+		if conn != nil {
+			defer conn.Close()
+		}
+		lock := lockFile{Version: lockVersion}
+		for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+			b := st.Installed[name]
+			if b.Package == "" || b.Version == "" {
+				fmt.Fprintf(os.Stderr, "Skipping %s: its package or version isn't recorded\n", name)
+				continue
+			}
+			t := lockTool{Name: name, Package: b.Package, Version: b.Version, Sum: b.Sum}
+			if conn != nil {
+				if entry, err := lookupPackage(conn, b.Package); err == nil {
+					t.Env = entry.EnvVars()
+				}
+			}
+			if t.Sum == "" {
+				if path, err := installedPath(name); err == nil {
+					if info, err := buildinfo.ReadFile(path); err == nil {
+						t.Sum = info.Main.Sum
+					}
+				}
+			}
+			lock.Tools = append(lock.Tools, t)
+		}
+
+		fmt.Println("# Generated by 'gomanager lock export'. Install with 'gomanager lock install'.")
+		return toml.NewEncoder(os.Stdout).Encode(lock)
+	},
+}
+
+var lockInstallCmd = &cobra.Command{
+	Use:   "install <lockfile>",
+	Short: "Install the binaries in a lockfile at their locked versions",
+	Long: `Installs each binary in the lockfile that is missing or at another version,
+with the locked build environment, --jobs at a time (one per CPU by
+default). Binaries already installed at the locked version are left alone,
+and installed binaries the lockfile doesn't list are kept.
+
+If the lockfile records a module hash for a tool, the built binary must
+have been built from a module with the same hash; otherwise it is removed
+again and reported as failed, since the version's tag was moved or the
+module rewritten since the lockfile was written.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		tools, err := loadLockFile(args[0])
+		if err != nil {
+			return err
+		}
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		var planned []*db.Binary
+		locked := make(map[*db.Binary]lockTool)
+		unchanged, failed := 0, 0
+		for _, t := range tools {
+			cur, installed := st.Installed[t.Name]
+			if installed && cur.Package == t.Package && cur.Version == t.Version && (t.Sum == "" || cur.Sum == "" || cur.Sum == t.Sum) {
+				if path, err := installedPath(t.Name); err == nil {
+					if _, err := os.Stat(path); err == nil {
+						unchanged++
+						continue
+					}
+				}
+			}
+			b, err := lockedBinary(conn, t)
+			if err == nil {
+				err = checkPolicy(b)
+			}
+			if err == nil {
+				err = checkVulnerable(conn, b)
+			}
+			if err != nil {
+				fmt.Printf("Skipping %s: %v\n", t.Name, err)
+				failed++
+				continue
+			}
+			planned = append(planned, b)
+			locked[b] = t
+		}
+
+		installed := 0
+		if len(planned) > 0 {
+			jobs := jobCount(lockJobs, len(planned))
+			errs := installConcurrently(planned, jobs, func(b *db.Binary) error {
+				t := locked[b]
+				if cur, ok := st.Installed[t.Name]; ok {
+					archiveInstalled(t.Name, cur.Version)
+				}
+				logf(t.Name, "Installing %s %s\n", t.Name, t.Version)
+				err := installBinary(b)
+				if err == nil {
+					err = checkLockedSum(t)
+				}
+				if err != nil {
+					logf(t.Name, "Failed to install %s: %v\n", t.Name, err)
+				}
+				return err
+			})
+			for _, err := range errs {
+				if err != nil {
+					failed++
+				} else {
+					installed++
+				}
+			}
+		}
+
+		fmt.Printf("\nInstalled %s: %d installed, %d already current, %d failed.\n", args[0], installed, unchanged, failed)
+		switch {
+		case failed > 0:
+			return withExitCode(ExitBuildFailed, fmt.Errorf("%d of %d binaries could not be installed", failed, len(tools)))
+		case installed == 0:
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		return nil
+	},
+}
+
+// loadLockFile reads and checks a lockfile, returning its tools.
+func loadLockFile(path string) ([]lockTool, error) {
+	var lock lockFile
+	if _, err := toml.DecodeFile(path, &lock); err != nil {
+		return nil, fmt.Errorf("cannot read lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockVersion {
+		return nil, fmt.Errorf("lockfile %s has format version %d; this gomanager reads version %d", path, lock.Version, lockVersion)
+	}
+	if len(lock.Tools) == 0 {
+		return nil, fmt.Errorf("lockfile %s lists no tools", path)
+	}
+	seen := make(map[string]bool)
+	for _, t := range lock.Tools {
+		switch {
+		case t.Name == "" || t.Package == "":
+			return nil, fmt.Errorf("lockfile %s: every tool needs a name and a package", path)
+		case seen[t.Name]:
+			return nil, fmt.Errorf("lockfile %s: %s is listed twice", path, t.Name)
+		case !semver.IsValid(t.Version) || goproxy.IsConstraint(t.Version):
+			return nil, fmt.Errorf("lockfile %s: %s: %q is not an exact version", path, t.Name, t.Version)
+		}
+		seen[t.Name] = true
+		for _, kv := range t.Env {
+			key, _, ok := strings.Cut(kv, "=")
+			if !ok || !db.IsAllowedBuildEnv(key) {
+				return nil, fmt.Errorf("lockfile %s: %s: %q is not an allowed build variable", path, t.Name, kv)
+			}
+		}
+	}
+	return lock.Tools, nil
+}
+
+// lockedBinary returns the binary to build for t: its database entry, if
+// any, at the locked version and with the locked build environment.
+func lockedBinary(conn *sql.DB, t lockTool) (*db.Binary, error) {
+	b, err := lookupPackage(conn, t.Package)
+	if errors.Is(err, db.ErrNotFound) {
+		// Packages missing from this machine's database can still be
+		// built from the lockfile alone.
+		b, err = &db.Binary{Package: t.Package, BuildStatus: "unknown", Confidence: -1, TrustScore: -1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	locked := *b
+	locked.Name = t.Name
+	locked.Version = t.Version
+	locked.BuildFlags = "{}"
+	if len(t.Env) > 0 {
+		flags := make(map[string]string, len(t.Env))
+		for _, kv := range t.Env {
+			key, val, _ := strings.Cut(kv, "=")
+			flags[key] = val
+		}
+		data, err := json.Marshal(flags)
+		if err != nil {
+			return nil, err
+		}
+		locked.BuildFlags = string(data)
+	}
+	return &locked, nil
+}
+
+// checkLockedSum checks that the binary just installed for t was built from
+// the module the lockfile records. A mismatched binary is uninstalled.
+func checkLockedSum(t lockTool) error {
+	if t.Sum == "" {
+		return nil
+	}
+	path, err := installedPath(t.Name)
+	if err != nil {
+		return err
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil || info.Main.Sum == "" {
+		logf(t.Name, "Warning: cannot check %s against the locked module hash: its build info records none\n", t.Name)
+		return nil
+	}
+	if info.Main.Sum == t.Sum {
+		return nil
+	}
+
+	if _, err := removeBinary(t.Name); err != nil {
+		logf(t.Name, "Warning: %v\n", err)
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if st, err := state.Load(); err == nil {
+		st.Remove(t.Name)
+		if err := st.Save(); err != nil {
+			logf(t.Name, "Warning: could not save install state: %v\n", err)
+		}
+	}
+	return fmt.Errorf("module hash of %s %s is %s, but the lockfile records %s; the tag may have been rewritten upstream",
+		t.Package, t.Version, info.Main.Sum, t.Sum)
+}
@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jmelahman/gomanager/internal/attest"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/jmelahman/gomanager/internal/progress"
+)
+
+var (
+	installManifest  string
+	installBinDir    string
+	installPrintPath bool
+	installJobs      int
+	installAttest    string
+	installAttestKey string
+)
+
+// manifestStamp is the file in the install directory recording the last
+// manifest installed there in full.
+const manifestStamp = ".gomanager-manifest.json"
+
+// manifest lists the tools to provision with install --from-manifest.
+type manifest struct {
+	// Tools maps a binary name or package path to a version. An empty
+	// version or "latest" installs the database's version, and a "^" or
+	// "~" constraint the highest version the module proxy lists in range.
+	Tools map[string]string `toml:"tools"`
+}
+
+// manifestEntry is one tool from a manifest.
+type manifestEntry struct {
+	arg     string
+	version string
+}
+
+// stampFile is the JSON form of manifestStamp.
+type stampFile struct {
+	Key      string   `json:"key"`
+	Binaries []string `json:"binaries"`
+	// Tools records what each binary was built from. Stamps written
+	// before it was added only have Binaries.
+	Tools []stampTool `json:"tools,omitempty"`
+}
+
+// stampTool is a binary of a manifest install.
+type stampTool struct {
+	Name    string   `json:"name"`
+	Package string   `json:"package"`
+	Version string   `json:"version"`
+	Env     []string `json:"env,omitempty"`
+}
+
+// loadManifest reads a manifest, returning its tools sorted by name.
+func loadManifest(path string) ([]manifestEntry, error) {
+	var m manifest
+	if _, err := toml.DecodeFile(path, &m); err != nil {
+		return nil, fmt.Errorf("cannot read manifest %s: %w", path, err)
+	}
+	if len(m.Tools) == 0 {
+		return nil, fmt.Errorf("manifest %s has no [tools]", path)
+	}
+	entries := make([]manifestEntry, 0, len(m.Tools))
+	for arg, version := range m.Tools {
+		if goproxy.IsConstraint(version) {
+			if _, err := goproxy.ParseConstraint(version); err != nil {
+				return nil, fmt.Errorf("manifest %s: %s: %w", path, arg, err)
+			}
+		}
+		entries = append(entries, manifestEntry{arg: arg, version: version})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].arg < entries[j].arg })
+	return entries, nil
+}
+
+// manifestKey returns the cache key for installing entries on this
+// platform: a hash of the tools and versions, independent of how the
+// manifest file is formatted.
+func manifestKey(entries []manifestEntry) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s\n", runtime.GOOS, runtime.GOARCH)
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s=%s\n", e.arg, e.version)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// readStamp reads binDir's manifest stamp.
+func readStamp(binDir string) (*stampFile, error) {
+	data, err := os.ReadFile(filepath.Join(binDir, manifestStamp))
+	if err != nil {
+		return nil, err
+	}
+	var stamp stampFile
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestStamp, err)
+	}
+	return &stamp, nil
+}
+
+// stampCurrent reports whether binDir holds a complete install of the
+// manifest with the given key, so nothing needs to be built.
+func stampCurrent(binDir, key string) bool {
+	stamp, err := readStamp(binDir)
+	if err != nil || stamp.Key != key {
+		return false
+	}
+	for _, name := range stamp.Binaries {
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		if _, err := os.Stat(filepath.Join(binDir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeStamp records a complete install of the manifest with the given key.
+func writeStamp(binDir, key string, binaries []*db.Binary) error {
+	stamp := stampFile{Key: key}
+	for _, b := range binaries {
+		stamp.Binaries = append(stamp.Binaries, b.Name)
+		stamp.Tools = append(stamp.Tools, stampTool{Name: b.Name, Package: b.Package, Version: b.Version, Env: buildEnv(b)})
+	}
+	data, err := json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(binDir, manifestStamp), append(data, '\n'), 0o644)
+}
+
+// resolveManifestEntry looks up a manifest tool and applies its version,
+// running the same checks as install. Prompts are replaced by refusals or
+// warnings, since manifests are installed unattended.
+func resolveManifestEntry(conn *sql.DB, e manifestEntry) (*db.Binary, error) {
+	b, err := resolveBinary(conn, e.arg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPolicy(b); err != nil {
+		return nil, err
+	}
+	if err := checkConfidence(b); err != nil {
+		return nil, err
+	}
+	switch {
+	case goproxy.IsConstraint(e.version):
+		version, err := resolveConstraint(conn, b, e.version)
+		if err != nil {
+			return nil, err
+		}
+		pinned := *b
+		pinned.Version = version
+		b = &pinned
+	case e.version != "" && e.version != "latest":
+		pinned := *b
+		pinned.Version = e.version
+		b = &pinned
+	default:
+		if b, err = resolveLatest(conn, b); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkVulnerable(conn, b); err != nil {
+		return nil, err
+	}
+	if dangerousNames[b.Name] {
+		return nil, withExitCode(ExitDenylisted,
+			fmt.Errorf("refusing to install %q: name shadows a system tool", b.Name))
+	}
+	if b.Archived {
+		fmt.Printf("Warning: %q is %s; it no longer receives fixes.\n", b.Name, archivedMarker)
+	}
+	return b, nil
+}
+
+// resolveConstraint returns the highest version of b's module the proxy
+// lists within the version constraint s, preferring versions without
+// critical advisories.
+func resolveConstraint(conn *sql.DB, b *db.Binary, s string) (string, error) {
+	c, err := goproxy.ParseConstraint(s)
+	if err != nil {
+		return "", err
+	}
+	proxy, err := goproxy.New()
+	if err != nil {
+		return "", err
+	}
+	versions, err := proxy.Versions(pkgbuild.ResolvePaths(b.Package).Module)
+	if err != nil {
+		return "", withExitCode(ExitNetwork, err)
+	}
+	matching := c.Matching(versions)
+	if len(matching) == 0 {
+		return "", withExitCode(ExitNotFound, fmt.Errorf("no version of %s matches %s", b.Package, c))
+	}
+	advisories, err := criticalAdvisories(conn, b, "")
+	if err != nil {
+		return "", err
+	}
+	version := matching[0]
+	for _, v := range matching {
+		if !slices.ContainsFunc(advisories, func(a db.Advisory) bool { return a.Affects(v) }) {
+			version = v
+			break
+		}
+	}
+	if version != matching[0] {
+		fmt.Printf("Resolved %s %s to %s, skipping newer versions with critical vulnerabilities\n", b.Name, c, version)
+	} else {
+		fmt.Printf("Resolved %s %s to %s\n", b.Name, c, version)
+	}
+	return version, nil
+}
+
+// runManifestInstall provisions every tool in the --from-manifest file. When
+// the install directory already holds a complete install of the same
+// manifest, nothing is built. Builds run --jobs at a time. With --attest, a
+// signed attestation of the installed tools is written afterwards. With
+// --dry-run nothing is built and neither the attestation nor GitHub Actions
+// outputs are written.
+func runManifestInstall() error {
+	entries, err := loadManifest(installManifest)
+	if err != nil {
+		return err
+	}
+	binDir, err := goBinDir()
+	if err != nil {
+		return err
+	}
+	// Every tool goes in binDir, even ones installed elsewhere before, so
+	// the directory can be stamped and cached as a whole. The installs are
+	// recorded in binDir's own state, leaving the user's install state and
+	// the binaries it tracks alone.
+	installBinDir = binDir
+	useBinDirState(binDir)
+	key := manifestKey(entries)
+
+	// Load the signing key first so a bad key fails before any building.
+	var signer ed25519.PrivateKey
+	if installAttest != "" {
+		if installAttestKey == "" {
+			return fmt.Errorf("--attest needs --attest-key")
+		}
+		if signer, err = attest.LoadPrivateKey(installAttestKey); err != nil {
+			return err
+		}
+	}
+
+	hit := stampCurrent(binDir, key)
+	if hit {
+		fmt.Printf("All %d tools in %s are installed in %s (cache key %s).\n", len(entries), installManifest, binDir, key)
+	} else if err := installManifestEntries(entries, binDir, key); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if signer != nil {
+			if err := writeAttestation(installAttest, signer, binDir, key, hit); err != nil {
+				return err
+			}
+		}
+		if err := emitGitHub(binDir, key, hit); err != nil {
+			return err
+		}
+	}
+	if installPrintPath {
+		fmt.Println(binDir)
+	}
+	return nil
+}
+
+// installManifestEntries resolves and installs entries, then stamps binDir
+// with key if they all succeeded. With --dry-run it only prints the plan.
+func installManifestEntries(entries []manifestEntry, binDir, key string) error {
+	if err := ensureDB(); err != nil {
+		return err
+	}
+	conn, err := db.Open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var binaries []*db.Binary
+	failed := 0
+	for _, e := range entries {
+		b, err := resolveManifestEntry(conn, e)
+		if err != nil {
+			fmt.Printf("Cannot install %s: %v\n", e.arg, err)
+			failed++
+			continue
+		}
+		events.Emit(progress.Event{Event: progress.Resolve, Name: b.Name, Package: b.Package, Version: b.Version})
+		binaries = append(binaries, b)
+	}
+	if failed > 0 {
+		return withExitCode(ExitBuildFailed, fmt.Errorf("%d of %d manifest tools could not be resolved", failed, len(entries)))
+	}
+
+	if dryRun {
+		for _, b := range binaries {
+			printPlan("install", b.Name, b, installedVersion(b))
+			fmt.Println()
+		}
+		fmt.Printf("Dry run: %d tools would be installed into %s and it would be stamped with cache key %s.\n", len(binaries), binDir, key)
+		return nil
+	}
+
+	jobs := jobCount(installJobs, len(binaries))
+	fmt.Printf("Installing %d tools into %s (%d at a time).\n", len(binaries), binDir, jobs)
+
+	errs := installConcurrently(binaries, jobs, func(b *db.Binary) error {
+		if usesGoInstall(b) {
+			logf(b.Name, "Running: %s\n", installCommand(b))
+		}
+		err := installBinary(b)
+		if err != nil {
+			logf(b.Name, "Failed to install %s: %v\n", b.Name, err)
+		}
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return withExitCode(ExitBuildFailed, fmt.Errorf("%d of %d manifest tools failed to install", failed, len(binaries)))
+	}
+	if err := writeStamp(binDir, key, binaries); err != nil {
+		fmt.Printf("Warning: could not record the manifest install: %v\n", err)
+	}
+	return nil
+}
+
+// emitGitHub adds binDir to the job's PATH and sets the cache-key and
+// cache-hit step outputs when running in GitHub Actions.
+func emitGitHub(binDir, key string, hit bool) error {
+	if path := os.Getenv("GITHUB_PATH"); path != "" {
+		if err := appendLine(path, binDir); err != nil {
+			return fmt.Errorf("cannot update GITHUB_PATH: %w", err)
+		}
+	}
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		out := fmt.Sprintf("cache-key=gomanager-%s\ncache-hit=%t\nbin-dir=%s", key, hit, binDir)
+		if err := appendLine(path, out); err != nil {
+			return fmt.Errorf("cannot update GITHUB_OUTPUT: %w", err)
+		}
+	}
+	return nil
+}
+
+// appendLine appends line and a newline to the file at path.
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strings.TrimRight(line, "\n") + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"debug/buildinfo"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// checkModuleSum returns the go.sum hash of the module the binary named name
+// at path was built from, or "" if its build info records none (e.g. it
+// was built from a source checkout). It warns if the hash differs from the
+// one recorded for the same version by the previous install, prev, or by
+// the database's verification: either means the version's tag was moved
+// or the module rewritten upstream.
+func checkModuleSum(name, path string, prev state.InstalledBinary) string {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil || info.Main.Sum == "" {
+		return ""
+	}
+	pkg, version, sum := info.Path, info.Main.Version, info.Main.Sum
+
+	if prev.Sum != "" && prev.Sum != sum && prev.Package == pkg && prev.Version == version {
+		logf(name, "Warning: the module hash of %s %s changed since it was last installed (%s, now %s); the tag may have been rewritten upstream\n",
+			pkg, version, prev.Sum, sum)
+	}
+	if verified := verifiedModuleSum(pkg, version); verified != "" && verified != sum {
+		logf(name, "Warning: the module hash of %s %s differs from the one recorded when it was verified (%s, now %s); the tag may have been rewritten upstream\n",
+			pkg, version, verified, sum)
+	}
+	return sum
+}
+
+// verifiedModuleSum returns the module hash the database recorded for pkg
+// at version, or "" if there is no database or it records none.
+func verifiedModuleSum(pkg, version string) string {
+	conn, err := openExistingDB()
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	return db.ModuleSum(conn, pkg, version)
+}
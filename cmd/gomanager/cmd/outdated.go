@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+// outdatedRecord is the JSON form of an installed binary with a newer
+// version available.
+type outdatedRecord struct {
+	Name      string `json:"name"`
+	Package   string `json:"package"`
+	Installed string `json:"installed"`
+	Available string `json:"available"`
+	Channel   string `json:"channel"`
+	Pinned    bool   `json:"pinned"`
+	// Confirmed reports that the build pipeline has confirmed the
+	// available version builds.
+	Confirmed bool `json:"confirmed"`
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List installed binaries with a newer version available",
+	Long: `Lists installed binaries whose database version, or for binaries on the
+latest channel the module proxy's @latest version, is newer than the
+installed one. Binaries that can't be checked are counted, and the exit
+code reports why (see 'gomanager --help'). Pinned binaries are listed but marked, since
+'gomanager upgrade --all' skips them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(st.Installed))
+		for name := range st.Installed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		records := []outdatedRecord{}
+		var proxy *goproxy.Client
+		notFound, unreachable := 0, 0
+		for _, name := range names {
+			installed := st.Installed[name]
+			b, err := lookupPackage(conn, installed.Package)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: cannot check %s: %v\n", name, err)
+				notFound++
+				continue
+			}
+			if installed.ReleaseChannel() == state.ChannelLatest {
+				if proxy == nil {
+					if proxy, err = goproxy.New(); err != nil {
+						return err
+					}
+				}
+				if b, err = latestRelease(conn, proxy, b); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: cannot check %s: %v\n", name, err)
+					unreachable++
+					continue
+				}
+			}
+			if sameVersion(installed.Version, b.Version) || aheadOf(installed.Version, b.Version) {
+				continue
+			}
+			records = append(records, outdatedRecord{
+				Name:      name,
+				Package:   b.Package,
+				Installed: installed.Version,
+				Available: b.Version,
+				Channel:   installed.ReleaseChannel(),
+				Pinned:    installed.Pinned,
+				Confirmed: b.VersionConfirmed(),
+			})
+		}
+
+		unchecked := notFound + unreachable
+		err = printResult(records, func() {
+			if len(records) == 0 {
+				if unchecked > 0 {
+					fmt.Printf("None of the binaries checked are outdated, but %d could not be checked.\n", unchecked)
+				} else {
+					fmt.Println("All installed binaries are up to date.")
+				}
+				return
+			}
+			t := newTable("NAME", "INSTALLED", "AVAILABLE", "CHANNEL", "NOTE")
+			for _, r := range records {
+				var notes []string
+				if r.Pinned {
+					notes = append(notes, "pinned")
+				}
+				if !r.Confirmed {
+					notes = append(notes, "unconfirmed")
+				}
+				note := "-"
+				if len(notes) > 0 {
+					note = strings.Join(notes, ", ")
+				}
+				t.row(r.Name, r.Installed, r.Available, r.Channel, note)
+			}
+			t.flush()
+			if unchecked > 0 {
+				fmt.Printf("\n%d binaries could not be checked.\n", unchecked)
+			}
+		})
+		switch {
+		case err != nil:
+			return err
+		case notFound > 0:
+			return withExitCode(ExitNotFound, fmt.Errorf("%d binaries could not be resolved", notFound))
+		case unreachable > 0:
+			return withExitCode(ExitNetwork, fmt.Errorf("%d binaries could not be checked against the module proxy", unreachable))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+func TestOutdatedCountsUnchecked(t *testing.T) {
+	upgradeFixture(t)
+	// Lookups of the latest channel fail: the proxy directory is empty.
+	t.Setenv("GOPROXY", "file://"+t.TempDir())
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"hello", "widget"} {
+		b := st.Installed[name]
+		b.Channel = state.ChannelLatest
+		st.Installed[name] = b
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runClient(t, "outdated")
+	if ExitCode(err) != ExitNetwork {
+		t.Errorf("outdated: got %v (exit %d), want exit %d", err, ExitCode(err), ExitNetwork)
+	}
+	if strings.Contains(out, "up to date") || !strings.Contains(out, "2 could not be checked") {
+		t.Errorf("outdated with unchecked binaries printed:\n%s", out)
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// jsonOutput makes the query commands (search, list, info, outdated,
+// platforms, and env) print their results as JSON instead of tables.
+var jsonOutput bool
+
+// printResult writes v to stdout as indented JSON with --json, and
+// otherwise calls text to print it for people.
+func printResult(v any, text func()) error {
+	if !jsonOutput {
+		text()
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// table writes aligned columns to stdout.
+type table struct {
+	rows []tableRow
+}
+
+// tableRow is a row of cells, or a section line spanning the table.
+type tableRow struct {
+	cells   []string
+	section string
+}
+
+// newTable starts a table with the given column headers.
+func newTable(header ...string) *table {
+	t := &table{}
+	t.row(header...)
+	return t
+}
+
+// row adds a row of cells.
+func (t *table) row(cells ...string) {
+	t.rows = append(t.rows, tableRow{cells: cells})
+}
+
+// section adds a line printed as is, e.g. a group heading, which doesn't
+// affect the column widths.
+func (t *table) section(line string) {
+	t.rows = append(t.rows, tableRow{section: line})
+}
+
+// flush writes the table, padding each column to its widest cell plus two
+// spaces. Widths are measured in terminal columns, so wide characters such
+// as emoji stay aligned.
+func (t *table) flush() {
+	var widths []int
+	for _, r := range t.rows {
+		for i, c := range r.cells {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], textWidth(c))
+		}
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for _, r := range t.rows {
+		if r.cells == nil {
+			fmt.Fprintln(w, r.section)
+			continue
+		}
+		for i, c := range r.cells {
+			if i == len(r.cells)-1 {
+				fmt.Fprintln(w, c)
+				break
+			}
+			fmt.Fprint(w, padRight(c, widths[i]+2))
+		}
+	}
+}
+
+// binaryRecord is the JSON form of a database entry in search and info
+// output.
+type binaryRecord struct {
+	Name         string    `json:"name"`
+	Package      string    `json:"package"`
+	Version      string    `json:"version"`
+	Description  string    `json:"description"`
+	RepoURL      string    `json:"repo_url,omitempty"`
+	Stars        int       `json:"stars"`
+	License      string    `json:"license,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	BuildStatus  string    `json:"build_status"`
+	StatusReason string    `json:"status_reason,omitempty"`
+	LastVerified time.Time `json:"last_verified,omitzero"`
+	TrustScore   *int      `json:"trust_score,omitempty"`
+	Confidence   *int      `json:"confidence,omitempty"`
+	Archived     bool      `json:"archived"`
+	Local        bool      `json:"local,omitempty"`
+	// Installed is set if the entry is installed via gomanager.
+	Installed *installedRecord `json:"installed,omitempty"`
+}
+
+// installedRecord is the JSON form of a binary's install state.
+type installedRecord struct {
+	Version     string    `json:"version"`
+	InstalledAt time.Time `json:"installed_at"`
+	Channel     string    `json:"channel"`
+	Pinned      bool      `json:"pinned"`
+}
+
+// newBinaryRecord returns the JSON form of b, with its install state from
+// st if st is non-nil.
+func newBinaryRecord(b *db.Binary, st *state.State) binaryRecord {
+	r := binaryRecord{
+		Name:         b.Name,
+		Package:      b.Package,
+		Version:      b.Version,
+		Description:  b.Description,
+		RepoURL:      b.RepoURL,
+		Stars:        b.Stars,
+		License:      b.License,
+		Tags:         b.Tags,
+		BuildStatus:  b.BuildStatus,
+		StatusReason: b.StatusReason,
+		LastVerified: b.LastVerified,
+		Archived:     b.Archived,
+		Local:        b.Local,
+	}
+	if b.TrustScore >= 0 {
+		score := b.TrustScore
+		r.TrustScore = &score
+	}
+	if b.Confidence >= 0 {
+		score := b.Confidence
+		r.Confidence = &score
+	}
+	if st != nil {
+		if inst, ok := st.Installed[b.Name]; ok && inst.Package == b.Package {
+			r.Installed = &installedRecord{
+				Version:     inst.Version,
+				InstalledAt: inst.InstalledAt,
+				Channel:     inst.ReleaseChannel(),
+				Pinned:      inst.Pinned,
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// Concurrent installs share stdout, so while more than one runs, every line
+// of a binary's output (its progress messages and the build tools' output)
+// is prefixed with the binary's name, e.g. "fzf     | go: downloading ...".
+var (
+	// outputMu keeps lines from concurrent installs whole.
+	outputMu sync.Mutex
+	// prefixWidth is the width names are padded to in line prefixes, or 0
+	// when installs run one at a time and output isn't prefixed.
+	prefixWidth int
+)
+
+// jobCount returns how many installs to run at once for a --jobs value,
+// where 0 or less means one per CPU, and n installs.
+func jobCount(jobs, n int) int {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	return max(1, min(jobs, n))
+}
+
+// installConcurrently calls install for each binary, jobs at a time, and
+// returns the resulting errors by index. With more than one job, output is
+// prefixed with each binary's name until all installs finish.
+func installConcurrently(binaries []*db.Binary, jobs int, install func(*db.Binary) error) []error {
+	errs := make([]error, len(binaries))
+	if jobs > 1 {
+		for _, b := range binaries {
+			prefixWidth = max(prefixWidth, textWidth(b.Name))
+		}
+		defer func() { prefixWidth = 0 }()
+	}
+
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for range jobs {
+		wg.Go(func() {
+			for i := range work {
+				errs[i] = install(binaries[i])
+			}
+		})
+	}
+	for i := range binaries {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return errs
+}
+
+// logf prints a progress message about the binary named name, prefixed
+// with the name while installs run concurrently.
+func logf(name, format string, args ...any) {
+	if prefixWidth == 0 {
+		fmt.Printf(format, args...)
+		return
+	}
+	w := &prefixWriter{w: os.Stdout, prefix: linePrefix(name)}
+	fmt.Fprintf(w, format, args...)
+	w.Close()
+}
+
+// buildOutput returns the writers for the stdout and stderr of a build tool
+// run for the binary named name, and a function to call once it exits.
+func buildOutput(name string) (stdout, stderr io.Writer, done func()) {
+	if prefixWidth == 0 {
+		return os.Stdout, os.Stderr, func() {}
+	}
+	out := &prefixWriter{w: os.Stdout, prefix: linePrefix(name)}
+	errOut := &prefixWriter{w: os.Stderr, prefix: linePrefix(name)}
+	return out, errOut, func() {
+		out.Close()
+		errOut.Close()
+	}
+}
+
+// linePrefix returns the prefix for lines about the binary named name.
+func linePrefix(name string) string {
+	return padRight(name, prefixWidth) + " | "
+}
+
+// prefixWriter writes each complete line written to it to w with a prefix,
+// holding outputMu so lines from concurrent writers don't mix.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.emit(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Close writes a final unterminated line, if any.
+func (p *prefixWriter) Close() error {
+	if len(p.buf) > 0 {
+		p.emit(append(p.buf, '\n'))
+		p.buf = nil
+	}
+	return nil
+}
+
+func (p *prefixWriter) emit(line []byte) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+}
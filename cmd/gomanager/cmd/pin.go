@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+}
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <name>...",
+	Short: "Keep installed binaries at their current version",
+	Long: `Pins installed binaries so 'gomanager upgrade --all' leaves them at their
+current version. Upgrading a pinned binary by name still works and keeps
+the pin. 'gomanager list' shows which binaries are pinned.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPinned(args, true)
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <name>...",
+	Short: "Let upgrade --all upgrade pinned binaries again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setPinned(args, false)
+	},
+}
+
+// setPinned pins or unpins the named installed binaries.
+func setPinned(names []string, pinned bool) error {
+	st, err := state.Load()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := st.Installed[name]; !ok {
+			return withExitCode(ExitNotFound, fmt.Errorf("%s is not installed", name))
+		}
+	}
+	for _, name := range names {
+		st.SetPinned(name, pinned)
+	}
+	if err := st.Save(); err != nil {
+		return fmt.Errorf("cannot save install state: %w", err)
+	}
+	for _, name := range names {
+		if pinned {
+			fmt.Printf("Pinned %s at %s.\n", name, st.Installed[name].Version)
+		} else {
+			fmt.Printf("Unpinned %s.\n", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/release"
+	"github.com/spf13/cobra"
+)
+
+var platformsOffline bool
+
+func init() {
+	platformsCmd.Flags().BoolVar(&platformsOffline, "offline", false, "Don't look up the release's prebuilt archives on GitHub")
+	rootCmd.AddCommand(platformsCmd)
+}
+
+// matrixOSes and matrixArches are the rows and columns of the platforms
+// matrix, by GOOS and GOARCH.
+var (
+	matrixOSes   = []string{"linux", "darwin", "windows"}
+	matrixArches = []string{"amd64", "arm64"}
+)
+
+// osLabels are the names the platforms matrix shows for GOOS values.
+var osLabels = map[string]string{"linux": "linux", "darwin": "macos", "windows": "windows"}
+
+// platformRecord is the JSON form of one platform of the matrix.
+type platformRecord struct {
+	Platform string `json:"platform"`
+	// Builds is whether the build pipeline cross-built the package for
+	// the platform, or null if it wasn't checked.
+	Builds *bool `json:"builds"`
+	// Release is whether the release has a prebuilt archive for the
+	// platform, or null if it wasn't looked up.
+	Release *bool `json:"release"`
+}
+
+// String describes the platform's support for a matrix cell, e.g.
+// "builds, release" or "fails".
+func (r platformRecord) String() string {
+	var parts []string
+	switch {
+	case r.Builds == nil:
+		parts = append(parts, "untested")
+	case *r.Builds:
+		parts = append(parts, "builds")
+	default:
+		parts = append(parts, "fails")
+	}
+	if r.Release != nil && *r.Release {
+		parts = append(parts, "release")
+	}
+	return strings.Join(parts, ", ")
+}
+
+var platformsCmd = &cobra.Command{
+	Use:   "platforms <name or package>",
+	Short: "Show which platforms a Go binary installs on",
+	Long: `Shows a matrix of the platforms (linux, macos, and windows on amd64 and
+arm64) a package supports, so that users on less common platforms know
+whether installing will work before trying. Each cell combines:
+
+  builds     the build pipeline cross-built the package for the platform
+  fails      the cross-build failed
+  untested   the pipeline hasn't cross-built the package
+  release    its GitHub release has a prebuilt archive for the platform,
+             which 'gomanager install --backend release' installs
+
+Release archives are looked up on GitHub for the database's version unless
+--offline is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		b, err := resolveBinary(conn, args[0])
+		if err != nil {
+			return err
+		}
+
+		var archives map[string]map[string]release.Asset
+		if !platformsOffline {
+			if archives, err = releaseArchives(b); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: cannot list release archives: %v\n", err)
+			}
+		}
+		records := platformMatrix(b, archives)
+
+		return printResult(records, func() {
+			fmt.Printf("%s %s (%s)\n\n", b.Name, b.Version, b.Package)
+			t := newTable(append([]string{"OS"}, matrixArches...)...)
+			for i, goos := range matrixOSes {
+				row := []string{osLabels[goos]}
+				for k := range matrixArches {
+					row = append(row, records[i*len(matrixArches)+k].String())
+				}
+				t.row(row...)
+			}
+			t.flush()
+
+			here := runtime.GOOS + "/" + runtime.GOARCH
+			for _, r := range records {
+				if r.Platform != here {
+					continue
+				}
+				fmt.Println()
+				switch {
+				case r.Builds != nil && *r.Builds:
+					fmt.Printf("%s builds on this platform (%s).\n", b.Name, here)
+				case r.Release != nil && *r.Release:
+					fmt.Printf("%s has a release archive for this platform (%s): install it with --backend release.\n", b.Name, here)
+				case r.Builds != nil:
+					fmt.Printf("%s fails to build on this platform (%s).\n", b.Name, here)
+				}
+			}
+		})
+	},
+}
+
+// platformMatrix returns the support of b for each platform of the matrix,
+// row by row, from its cross-build results and, if archives isn't nil, the
+// release archives by GOOS and GOARCH.
+func platformMatrix(b *db.Binary, archives map[string]map[string]release.Asset) []platformRecord {
+	var records []platformRecord
+	for _, goos := range matrixOSes {
+		for _, goarch := range matrixArches {
+			r := platformRecord{Platform: goos + "/" + goarch}
+			if ok, known := b.PlatformSupport[r.Platform]; known {
+				r.Builds = &ok
+			}
+			if archives != nil {
+				_, ok := archives[goos][goarch]
+				r.Release = &ok
+			}
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// releaseArchives returns the archives of b's GitHub release for each OS of
+// the matrix, keyed by GOOS and GOARCH.
+func releaseArchives(b *db.Binary) (map[string]map[string]release.Asset, error) {
+	parts := strings.Split(b.Package, "/")
+	if len(parts) < 3 || parts[0] != "github.com" {
+		return nil, fmt.Errorf("%s is not hosted on GitHub", b.Package)
+	}
+	if b.Version == "" || b.Version == "latest" {
+		return nil, fmt.Errorf("%s has no release version", b.Name)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	assets, err := release.FetchAssets(client, githubAPI, parts[1], parts[2], githubToken(), b.Version)
+	if err != nil {
+		return nil, err
+	}
+	archives := make(map[string]map[string]release.Asset, len(matrixOSes))
+	for _, goos := range matrixOSes {
+		archives[goos] = release.Archives(assets, goos)
+	}
+	return archives, nil
+}
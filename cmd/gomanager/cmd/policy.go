@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/policy"
+)
+
+// policyOverride installs binaries that violate an enforced policy. It is
+// bound to the --policy-override flag of every command that installs.
+var policyOverride bool
+
+const policyOverrideUsage = "Install even if the binary violates the configured policy"
+
+// activePolicy is the policy referenced from the config file, loaded on
+// first use. It is nil when no policy is configured.
+var (
+	activePolicy       *policy.Policy
+	activePolicyLoaded bool
+)
+
+func loadPolicy() (*policy.Policy, error) {
+	if activePolicyLoaded {
+		return activePolicy, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Policy != "" {
+		if activePolicy, err = policy.Load(cfg.Policy); err != nil {
+			return nil, err
+		}
+	}
+	activePolicyLoaded = true
+	return activePolicy, nil
+}
+
+// checkPolicy checks b against the configured policy. Violations are
+// printed; in enforce mode they're returned as an error unless
+// --policy-override is given.
+func checkPolicy(b *db.Binary) error {
+	p, err := loadPolicy()
+	if err != nil || p == nil {
+		return err
+	}
+	violations := p.Check(b)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, len(violations))
+	denylisted := false
+	for i, v := range violations {
+		reasons[i] = v.Reason
+		denylisted = denylisted || v.Denylisted
+	}
+	fmt.Printf("Policy: %s violates the policy:\n", b.Name)
+	for _, r := range reasons {
+		fmt.Printf("  - %s\n", r)
+	}
+
+	switch {
+	case p.Mode == policy.ModeWarn:
+		return nil
+	case policyOverride:
+		fmt.Println("  Continuing because of --policy-override.")
+		return nil
+	}
+	code := ExitPolicy
+	if denylisted {
+		code = ExitDenylisted
+	}
+	return withExitCode(code, fmt.Errorf("%s is blocked by policy (%s); use --policy-override to install anyway",
+		b.Name, strings.Join(reasons, "; ")))
+}
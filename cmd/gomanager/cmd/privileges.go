@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// grantPrivileges tells the user about the installed binaries among bins
+// that need elevated privileges to work (e.g. raw sockets for a packet
+// sniffer): why, and the setcap command that grants the capabilities the
+// database records. On a terminal it offers to run the command, with sudo
+// unless already root, only after the user confirms. File capabilities
+// belong to the file, so they are lost and offered again on every install
+// and upgrade.
+func grantPrivileges(bins []*db.Binary) {
+	for _, b := range bins {
+		if b.Capabilities == "" && b.PrivilegeNote == "" {
+			continue
+		}
+		fmt.Printf("\n%s needs elevated privileges to work", b.Name)
+		if b.PrivilegeNote != "" {
+			fmt.Printf(": %s", b.PrivilegeNote)
+		}
+		fmt.Println()
+		if b.Capabilities == "" {
+			continue
+		}
+		if !db.ValidCapabilities(b.Capabilities) {
+			fmt.Printf("Warning: ignoring invalid capabilities %q recorded for %s\n", b.Capabilities, b.Name)
+			continue
+		}
+		if runtime.GOOS != "linux" {
+			fmt.Printf("It needs the Linux capabilities %s; on %s, run it with elevated privileges instead.\n", b.Capabilities, runtime.GOOS)
+			continue
+		}
+		path, err := installedPath(b.Name)
+		if err != nil {
+			fmt.Printf("Warning: cannot locate %s to grant it %s: %v\n", b.Name, b.Capabilities, err)
+			continue
+		}
+
+		args := []string{"setcap", b.Capabilities, path}
+		if os.Geteuid() != 0 {
+			args = append([]string{"sudo"}, args...)
+		}
+		fmt.Printf("Grant them with:\n  %s\n", formatCommand(args))
+		if !stdinIsTerminal() {
+			continue
+		}
+		fmt.Print("Run it now? [y/N] ")
+		var answer string
+		fmt.Scanln(&answer)
+		if strings.ToLower(answer) != "y" {
+			continue
+		}
+		cmd := osexec.Command(args[0], args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Printf("Warning: cannot grant %s to %s: %v\n", b.Capabilities, b.Name, err)
+			continue
+		}
+		fmt.Printf("Granted %s to %s\n", b.Capabilities, path)
+	}
+}
+
+// formatCommand joins args for display, quoting those a shell would split.
+func formatCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\$`*?[]{}()<>|&;#~") {
+			a = strconv.Quote(a)
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// upgradeNoRollback is bound to upgrade --no-rollback.
+var upgradeNoRollback bool
+
+// upgradeBackup is a copy of the binary an upgrade replaces, kept until the
+// new binary passes its smoke test.
+type upgradeBackup struct {
+	name string
+	// path is where the binary is installed and kept where it is copied.
+	path, kept string
+	// before is the smoke test result of the binary being replaced.
+	before smokeResult
+	// prev is its install state.
+	prev state.InstalledBinary
+}
+
+// backupInstalled copies the installed binary name before an upgrade
+// replaces it and smoke tests it, for comparison with the new binary. It
+// returns nil if there is nothing to roll back to.
+func backupInstalled(name string) *upgradeBackup {
+	path, err := installedPath(name)
+	if err != nil {
+		return nil
+	}
+	st, err := state.Load()
+	if err != nil {
+		return nil
+	}
+	prev, ok := st.Installed[name]
+	if !ok {
+		return nil
+	}
+	kept, err := copyToTemp(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logf(name, "Warning: cannot keep a copy of %s to roll back to: %v\n", name, err)
+		}
+		return nil
+	}
+	return &upgradeBackup{name: name, path: path, kept: kept, before: smokeTest(path), prev: prev}
+}
+
+// verify smoke tests the upgraded binary. If it fails where the binary it
+// replaced didn't, the previous binary and its install state are restored
+// and an error describing the failure is returned. The copy is removed
+// either way.
+func (u *upgradeBackup) verify() error {
+	defer os.Remove(u.kept)
+	after := smokeTest(u.path)
+	if !after.regressedFrom(u.before) {
+		return nil
+	}
+
+	logf(u.name, "%s --version failed after the upgrade (%s); rolling back to %s\n", u.name, after, u.prev.Version)
+	if err := os.Rename(u.kept, u.path); err != nil {
+		return fmt.Errorf("upgraded %s fails its smoke test (%s) and cannot be rolled back: %w", u.name, after, err)
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	st, err := state.Load()
+	if err == nil {
+		st.Installed[u.name] = u.prev
+		err = st.Save()
+	}
+	if err != nil {
+		logf(u.name, "Warning: could not restore install state: %v\n", err)
+	}
+	return withExitCode(ExitRolledBack, fmt.Errorf("upgraded %s fails its smoke test (%s); rolled back to %s", u.name, after, u.prev.Version))
+}
+
+// discard removes the copy after a failed upgrade, which leaves the
+// previous binary in place.
+func (u *upgradeBackup) discard() {
+	os.Remove(u.kept)
+}
+
+// copyToTemp copies the file at path to a new hidden file in the same
+// directory, keeping its mode, and returns the copy's path.
+func copyToTemp(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+	dst, err := os.CreateTemp(filepath.Dir(path), ".gomanager-rollback-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(dst.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
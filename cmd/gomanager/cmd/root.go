@@ -1,12 +1,32 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/jmelahman/gomanager/internal/progress"
 	"github.com/spf13/cobra"
 )
 
 // version is set by goreleaser via ldflags.
 var version = "dev"
 
+var (
+	progressFormat string
+	// events receives machine-readable progress events. It is nil (and
+	// discards everything) unless --progress json is given.
+	events *progress.Reporter
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&progressFormat, "progress", progress.FormatText,
+		"Progress output format: text, or json for NDJSON events on stderr")
+	rootCmd.PersistentFlags().BoolVar(&systemInstall, "system", false,
+		"Manage system-wide installs in /usr/local/bin (or the configured [system] prefix) instead of your own")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false,
+		"Print search, list, info, outdated, platforms, and env results as JSON")
+}
+
 var rootCmd = &cobra.Command{
 	Use:     "gomanager",
 	Short:   "Manage Go binaries from a curated database",
@@ -14,10 +34,42 @@ var rootCmd = &cobra.Command{
 	Long: `GoManager is a package manager for Go binaries.
 
 It downloads a curated database of Go CLI tools and lets you
-search, install, upgrade, and manage them.`,
+search, install, upgrade, and manage them.
+` + exitCodeHelp,
+	// Errors are printed by Execute so that silent exit codes (e.g. "nothing
+	// to do") don't produce an empty "Error:" line.
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments have been validated by now; runtime failures shouldn't
+		// dump the usage text.
+		cmd.SilenceUsage = true
+
+		var err error
+		if events, err = progress.New(progressFormat, os.Stderr); err != nil {
+			return err
+		}
+		if err := applyConfig(cmd); err != nil {
+			if !configOptional(cmd) {
+				return err
+			}
+			// Commands for recovering run with the defaults instead, so
+			// a broken configuration file can be diagnosed and repaired.
+			fmt.Fprintf(os.Stderr, "Warning: %v; using the default settings\n", err)
+			ignoreConfig = true
+		}
+		return setupSystem()
+	},
 }
 
-// Execute runs the root command.
+// Execute runs the root command. Use ExitCode to map the returned error to
+// a process exit code.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	sendTelemetry()
+	if err != nil {
+		if msg := err.Error(); msg != "" {
+			fmt.Fprintln(os.Stderr, "Error:", msg)
+		}
+	}
+	return err
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().BoolVar(&policyOverride, "policy-override", false, "Run even if the binary violates the configured policy")
+	runCmd.Flags().BoolVar(&lowConfidenceOK, "low-confidence-ok", false, "Run even if the database entry was inferred from weak signals")
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run <name or package> [args...]",
+	Short: "Run a Go binary, using go run if it isn't installed",
+	Long: `Runs a binary with the given arguments. An installed binary is run
+directly; anything else is run with 'go run <package>@<version>', which
+builds it into the Go build cache without installing it.
+
+Packages marked run-only (go install fails for them but go run works) are
+always run with go run. Arguments after the name are passed to the binary
+unchanged, and its exit code is returned.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		b, err := resolveBinary(conn, args[0])
+		if err != nil {
+			return err
+		}
+		if err := checkPolicy(b); err != nil {
+			return err
+		}
+		if err := checkConfidence(b); err != nil {
+			return err
+		}
+
+		var run *osexec.Cmd
+		if path, ok := installedBinary(b); ok && !b.RunOnly {
+			run = osexec.Command(path, args[1:]...)
+		} else {
+			version := b.Version
+			if version == "" {
+				version = "latest"
+			}
+			if (b.BuildStatus == "failed" || b.BuildStatus == "regressed") && !b.RunOnly {
+				fmt.Fprintf(os.Stderr, "Warning: %q is marked as a failed build.\n", b.Name)
+			}
+			run = osexec.Command("go", append([]string{"run", b.Package + "@" + version}, args[1:]...)...)
+			run.Env = append(os.Environ(), buildEnv(b)...)
+		}
+		run.Stdin = os.Stdin
+		run.Stdout = os.Stdout
+		run.Stderr = os.Stderr
+
+		err = run.Run()
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			return withExitCode(exitErr.ExitCode(), nil)
+		}
+		return err
+	},
+}
+
+// installedBinary returns the path of b's binary if gomanager installed it
+// and it is still there.
+func installedBinary(b *db.Binary) (string, bool) {
+	st, err := state.Load()
+	if err != nil {
+		return "", false
+	}
+	if inst, ok := st.Installed[b.Name]; !ok || inst.Package != b.Package {
+		return "", false
+	}
+	path, err := installedPath(b.Name)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
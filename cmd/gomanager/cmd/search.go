@@ -1,23 +1,79 @@
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
-	"os"
-	"text/tabwriter"
+	"strconv"
+	"strings"
 
 	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
 	"github.com/spf13/cobra"
 )
 
+var (
+	searchVerbose      bool
+	searchMinTrust     int
+	searchInstalled    bool
+	searchNotInstalled bool
+	searchTags         []string
+	searchMinStars     int
+	searchStatuses     []string
+	searchLicenses     []string
+)
+
 func init() {
+	searchCmd.Flags().BoolVarP(&searchVerbose, "verbose", "v", false, "Show package paths and verification age")
+	searchCmd.Flags().IntVar(&searchMinTrust, "min-trust", 0, "Only show binaries with at least this trust score (0-100)")
+	searchCmd.Flags().BoolVar(&searchInstalled, "installed", false, "Only show binaries installed via gomanager")
+	searchCmd.Flags().BoolVar(&searchNotInstalled, "not-installed", false, "Only show binaries not installed via gomanager")
+	searchCmd.Flags().IntVar(&searchMinStars, "min-stars", 0, "Only show binaries with at least this many GitHub stars")
+	searchCmd.Flags().StringSliceVar(&searchStatuses, "status", nil, "Only show binaries with one of these build statuses, e.g. confirmed (repeatable)")
+	searchCmd.Flags().StringSliceVar(&searchLicenses, "license", nil, "Only show binaries under one of these SPDX licenses, e.g. MIT (repeatable)")
+	searchCmd.Flags().StringArrayVar(&searchTags, "tag", nil, "Only show binaries with this tag, e.g. cli or kubernetes (repeatable; all must match)")
 	rootCmd.AddCommand(searchCmd)
 }
 
 var searchCmd = &cobra.Command{
-	Use:   "search <query>",
+	Use:   "search [query]",
 	Short: "Search for Go binaries in the database",
-	Args:  cobra.ExactArgs(1),
+	Long: `Searches the database for binaries whose name, package path, or
+description contains the query. A binary named exactly the query comes
+first, then names starting with it, names containing it, package paths
+containing it, and last descriptions mentioning it. Within each,
+confirmed builds rank above untested ones and failed builds last, and
+then more stars rank higher.
+
+Filters narrow the results:
+
+  --min-stars N      at least N GitHub stars
+  --min-trust N      a trust score of at least N (unscored binaries are hidden)
+  --status S         one of the given build statuses, e.g. confirmed
+  --license L        one of the given SPDX licenses, e.g. MIT or Apache-2.0
+                     (case-insensitive; unknown licenses are hidden)
+  --tag T            every given tag; tags are the GitHub topics of their
+                     repositories, such as cli or kubernetes
+
+--status and --license take a comma-separated list or may be repeated.
+With any filter, the query may be omitted to browse everything that
+passes:
+
+  gomanager search --status confirmed --license MIT --min-stars 1000`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if !searchFilter().Empty() {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if searchInstalled && searchNotInstalled {
+			return fmt.Errorf("--installed and --not-installed can't be used together")
+		}
+		for _, status := range searchStatuses {
+			if !db.KnownStatus(status) {
+				return fmt.Errorf("unknown build status %q (known: %s)", status, strings.Join(db.Statuses, ", "))
+			}
+		}
 		if err := ensureDB(); err != nil {
 			return err
 		}
@@ -27,27 +83,123 @@ var searchCmd = &cobra.Command{
 		}
 		defer conn.Close()
 
-		results, err := db.Search(conn, args[0])
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		results, err := searchBinaries(conn, query, searchFilter())
 		if err != nil {
-			return fmt.Errorf("search failed: %w", err)
+			return err
+		}
+
+		if searchInstalled || searchNotInstalled {
+			if results, err = filterInstalled(results, searchInstalled); err != nil {
+				return err
+			}
 		}
 
 		if len(results) == 0 {
-			fmt.Println("No results found.")
-			return nil
+			err := printResult([]binaryRecord{}, func() { fmt.Println("No results found.") })
+			return withExitCode(ExitNotFound, err)
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintf(w, "NAME\tSTARS\tSTATUS\tVERSION\tDESCRIPTION\n")
+		if jsonOutput {
+			st, _ := state.Load()
+			records := make([]binaryRecord, len(results))
+			for i := range results {
+				records[i] = newBinaryRecord(&results[i], st)
+			}
+			return printResult(records, nil)
+		}
+
+		var t *table
+		if searchVerbose {
+			t = newTable("NAME", "PACKAGE", "STARS", "TRUST", "STATUS", "VERSION", "VERIFIED", "DESCRIPTION")
+		} else {
+			t = newTable("NAME", "STARS", "STATUS", "VERSION", "DESCRIPTION")
+		}
 		for _, b := range results {
 			desc := b.Description
-			if len(desc) > 60 {
-				desc = desc[:57] + "..."
+			if b.Archived {
+				desc = archivedMarker + " · " + desc
+			}
+			if b.Local {
+				desc = localMarker + " · " + desc
 			}
-			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
-				b.Name, b.Stars, b.BuildStatus, b.Version, desc)
+			desc = clip(desc, 60)
+			stars := strconv.Itoa(b.Stars)
+			if searchVerbose {
+				t.row(b.Name, b.Package, stars, trustColumn(&b), b.BuildStatus, b.Version, verifiedLabel(&b), desc)
+				continue
+			}
+			t.row(b.Name, stars, b.BuildStatus, b.Version, desc)
 		}
-		w.Flush()
+		t.flush()
 		return nil
 	},
 }
+
+// searchFilter returns the filter given by the search flags.
+func searchFilter() db.SearchFilter {
+	return db.SearchFilter{
+		MinStars: searchMinStars,
+		MinTrust: searchMinTrust,
+		Statuses: searchStatuses,
+		Licenses: searchLicenses,
+		Tags:     searchTags,
+	}
+}
+
+// searchBinaries returns the visible entries matching query and f, local
+// entries first, best matches first (see db.Search). An empty query
+// matches every entry.
+func searchBinaries(conn *sql.DB, query string, f db.SearchFilter) ([]db.Binary, error) {
+	results, err := db.Search(conn, query, f)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	if results, err = filterVisible(results); err != nil {
+		return nil, err
+	}
+	if local := searchLocal(query, f); len(local) > 0 {
+		// Local entries come first and hide the published entries they
+		// override.
+		overridden := make(map[string]bool)
+		for _, b := range local {
+			overridden[b.Package] = true
+		}
+		for _, b := range results {
+			if !overridden[b.Package] {
+				local = append(local, b)
+			}
+		}
+		results = local
+	}
+	return results, nil
+}
+
+// filterInstalled keeps the binaries that are installed via gomanager, or
+// with installed false, those that aren't. A binary counts as installed
+// when an entry of the same name tracks the same package.
+func filterInstalled(binaries []db.Binary, installed bool) ([]db.Binary, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, err
+	}
+	var kept []db.Binary
+	for _, b := range binaries {
+		inst, ok := st.Installed[b.Name]
+		if (ok && inst.Package == b.Package) == installed {
+			kept = append(kept, b)
+		}
+	}
+	return kept, nil
+}
+
+// trustColumn formats a trust score for a table column.
+func trustColumn(b *db.Binary) string {
+	if b.TrustScore < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", b.TrustScore)
+}
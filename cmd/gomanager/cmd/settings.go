@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// defaultGOFLAGS are go command flags from the [install] goflags
+	// setting, added to every build.
+	defaultGOFLAGS string
+	// githubTokenCommand is the [github] token_command setting.
+	githubTokenCommand string
+	// configBinDir is the [install] bin_dir setting.
+	configBinDir string
+	// allowSumDBBypass is the [install] allow_sumdb_bypass setting.
+	allowSumDBBypass bool
+	// telemetryEndpoint is the [telemetry] endpoint setting.
+	telemetryEndpoint string
+	// ignoreConfig is set when an invalid configuration file is ignored
+	// for a command listed in configOptionalCommands.
+	ignoreConfig bool
+)
+
+// configOptionalCommands are the top-level commands that still run when
+// the configuration file is invalid, since they diagnose or repair it.
+var configOptionalCommands = map[string]bool{
+	"backup":     true,
+	"completion": true,
+	"doctor":     true,
+	"env":        true,
+	"help":       true,
+	"restore":    true,
+}
+
+// configOptional reports whether cmd, or the top-level command it belongs
+// to, is in configOptionalCommands.
+func configOptional(cmd *cobra.Command) bool {
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if !c.Parent().HasParent() {
+			return configOptionalCommands[c.Name()]
+		}
+	}
+	return false
+}
+
+// applyConfig fills in the settings cmd's flags leave at their defaults
+// from the configuration file and GOMANAGER_* environment variables.
+// Flags given on the command line always win.
+func applyConfig(cmd *cobra.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	changed := cmd.Flags().Changed
+
+	if cfg.Database.URL != "" && !changed("url") {
+		dbURL = cfg.Database.URL
+	}
+	if cfg.Install.BinDir != "" {
+		if configBinDir, err = filepath.Abs(cfg.Install.BinDir); err != nil {
+			return fmt.Errorf("invalid bin_dir: %w", err)
+		}
+	}
+	if cfg.Install.Jobs != 0 && !changed("jobs") {
+		installJobs = cfg.Install.Jobs
+		upgradeJobs = cfg.Install.Jobs
+	}
+	if cfg.Install.AssumeYes {
+		installYes = true
+		upgradeYes = true
+	}
+	if cfg.Install.Verify {
+		installVerify = true
+	}
+	defaultGOFLAGS = cfg.Install.GOFLAGS
+	allowSumDBBypass = cfg.Install.AllowSumDBBypass
+	githubTokenCommand = cfg.GitHub.TokenCommand
+	telemetryEndpoint = cfg.Telemetry.Endpoint
+	return nil
+}
+
+// buildEnv returns the environment variables to build b with: its recorded
+// build flags, with the configured default GOFLAGS ahead of any GOFLAGS
+// recorded for it.
+func buildEnv(b *db.Binary) []string {
+	env := b.EnvVars()
+	if defaultGOFLAGS == "" {
+		return env
+	}
+	for i, v := range env {
+		if flags, ok := strings.CutPrefix(v, "GOFLAGS="); ok {
+			env[i] = "GOFLAGS=" + defaultGOFLAGS + " " + flags
+			return env
+		}
+	}
+	return append([]string{"GOFLAGS=" + defaultGOFLAGS}, env...)
+}
+
+// installCommand returns the go install command that installs b, with
+// the environment from buildEnv.
+func installCommand(b *db.Binary) string {
+	version := b.Version
+	if version == "" {
+		version = "latest"
+	}
+	cmd := fmt.Sprintf("go install %s@%s", b.Package, version)
+	if env := db.FormatEnv(buildEnv(b)); env != "" {
+		cmd = env + " " + cmd
+	}
+	return cmd
+}
+
+// githubToken returns the token for GitHub API requests: GITHUB_TOKEN, or
+// the output of the configured token command. It returns "" (anonymous
+// access) if neither gives one.
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	args := strings.Fields(githubTokenCommand)
+	if len(args) == 0 {
+		return ""
+	}
+	out, err := osexec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: GitHub token command %q failed: %v\n", githubTokenCommand, err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
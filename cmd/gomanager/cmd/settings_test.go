@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInvalidConfigSparesRecoveryCommands(t *testing.T) {
+	home := testHome(t)
+	dir := filepath.Join(home, "config", "gomanager")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte("[install\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ignoreConfig = false })
+
+	for _, args := range [][]string{{"env", "config"}, {"help"}} {
+		ignoreConfig = false
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("gomanager %s with an invalid config: %v", strings.Join(args, " "), err)
+		}
+	}
+	// Doctor reports the broken config as a problem.
+	ignoreConfig = false
+	out, err := runClient(t, "doctor")
+	if ExitCode(err) != ExitUnhealthy || !strings.Contains(out, "Configuration\n  invalid config") {
+		t.Errorf("gomanager doctor with an invalid config: got %v (exit %d), want the config reported and exit %d\n%s", err, ExitCode(err), ExitUnhealthy, out)
+	}
+
+	ignoreConfig = false
+	rootCmd.SetArgs([]string{"info", "hello"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("gomanager info with an invalid config: got %v, want an invalid config error", err)
+	}
+}
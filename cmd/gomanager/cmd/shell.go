@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shellJobs int
+	shellKeep bool
+)
+
+func init() {
+	shellCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	shellCmd.Flags().BoolVar(&lowConfidenceOK, "low-confidence-ok", false, lowConfidenceOKUsage)
+	shellCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	shellCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	shellCmd.Flags().IntVarP(&shellJobs, "jobs", "j", 0, "Number of binaries to build at once (0 = one per CPU)")
+	shellCmd.Flags().BoolVar(&shellKeep, "keep", false, "Keep the temporary prefix after the shell exits and print its path")
+	rootCmd.AddCommand(shellCmd)
+}
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <name or package>... [-- command [args...]]",
+	Short: "Start a shell with Go binaries installed in a temporary prefix",
+	Long: `Installs the given binaries into a temporary prefix and starts a
+subshell ($SHELL, or /bin/sh) with the prefix's bin directory first on
+PATH, for trying tools without installing them for good. The go install
+directory, the install state, and everything else installed are left
+untouched. When the shell exits, the prefix is removed (unless --keep is
+given) and the shell's exit code is returned.
+
+After --, a command is run with the tools on PATH instead of a shell:
+
+  gomanager shell dive lazydocker -- dive nginx:latest
+
+Binaries are checked and prompted for as by install, and built --jobs at
+a time. GOMANAGER_SHELL is set to the prefix's bin directory in the
+shell, e.g. for a prompt to show that it's active.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if systemBinDir != "" {
+			return fmt.Errorf("shell can't be used with --system")
+		}
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		names, command := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			names, command = args[:dash], args[dash:]
+			if len(names) == 0 {
+				return fmt.Errorf("no binaries given before --")
+			}
+		}
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		var planned []*db.Binary
+		for _, name := range names {
+			b, err := planInstall(conn, name)
+			if err != nil {
+				return err
+			}
+			planned = append(planned, b)
+		}
+
+		prefix, err := os.MkdirTemp("", "gomanager-shell-*")
+		if err != nil {
+			return err
+		}
+		if shellKeep {
+			defer fmt.Printf("Kept the shell's tools in %s\n", prefix)
+		} else {
+			defer os.RemoveAll(prefix)
+		}
+		binDir := filepath.Join(prefix, "bin")
+		if err := os.MkdirAll(binDir, 0o755); err != nil {
+			return err
+		}
+		// Installs go to the prefix and are recorded in its own state
+		// file, leaving the user's untouched.
+		installBinDir = binDir
+		state.UseFile(filepath.Join(prefix, "installed.json"))
+
+		errs := installConcurrently(planned, jobCount(shellJobs, len(planned)), func(b *db.Binary) error {
+			if usesGoInstall(b) {
+				logf(b.Name, "Running: %s\n", installCommand(b))
+			}
+			return installBinary(b)
+		})
+		for i, err := range errs {
+			if err != nil {
+				return fmt.Errorf("cannot install %s: %w", planned[i].Name, err)
+			}
+		}
+
+		if len(command) == 0 {
+			command = []string{userShell()}
+			fmt.Printf("\nStarting %s with %s on PATH; exit to leave.\n", command[0], shellNames(planned))
+		}
+		return runInShell(binDir, command)
+	},
+}
+
+// userShell returns the user's interactive shell.
+func userShell() string {
+	if runtime.GOOS == "windows" {
+		if sh := os.Getenv("COMSPEC"); sh != "" {
+			return sh
+		}
+		return "cmd.exe"
+	}
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
+
+// shellNames joins the names of binaries for display.
+func shellNames(binaries []*db.Binary) string {
+	names := make([]string, len(binaries))
+	for i, b := range binaries {
+		names[i] = b.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// runInShell runs command with binDir prepended to PATH and returns its
+// exit code. Interrupts are left to the command, so that Ctrl-C in the
+// shell doesn't kill gomanager before it cleans up.
+func runInShell(binDir string, command []string) error {
+	// The command may be one of the tools, which aren't on gomanager's
+	// own PATH.
+	program := command[0]
+	if !strings.ContainsRune(program, filepath.Separator) {
+		if path, err := osexec.LookPath(filepath.Join(binDir, program)); err == nil {
+			program = path
+		}
+	}
+	run := osexec.Command(program, command[1:]...)
+	run.Env = append(os.Environ(),
+		"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"GOMANAGER_SHELL="+binDir,
+	)
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	err := run.Run()
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) {
+		return withExitCode(exitErr.ExitCode(), nil)
+	}
+	return err
+}
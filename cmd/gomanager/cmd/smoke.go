@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// smokeTimeout bounds how long a smoke test waits for a binary to exit.
+const smokeTimeout = 10 * time.Second
+
+// smokeResult is the outcome of running a binary with --version.
+type smokeResult struct {
+	// err is set if the binary couldn't be started, was killed by a
+	// signal, or didn't exit within smokeTimeout.
+	err error
+	// exitCode is the binary's exit code if it exited.
+	exitCode int
+	// output is the first line of its combined output.
+	output string
+}
+
+// ok reports whether the binary ran and exited successfully.
+func (r smokeResult) ok() bool {
+	return r.err == nil && r.exitCode == 0
+}
+
+func (r smokeResult) String() string {
+	switch {
+	case r.err != nil:
+		return r.err.Error()
+	case r.exitCode != 0 && r.output != "":
+		return fmt.Sprintf("exit status %d: %s", r.exitCode, r.output)
+	case r.exitCode != 0:
+		return fmt.Sprintf("exit status %d", r.exitCode)
+	}
+	return "ok"
+}
+
+// regressedFrom reports whether r is worse than before, the result of the
+// same test on the binary it replaced: it didn't run to completion where
+// before did, or it failed where before succeeded. Tools without a
+// --version flag exit with a usage error either way, and tools whose
+// --version hangs time out either way, neither of which is a regression.
+func (r smokeResult) regressedFrom(before smokeResult) bool {
+	if r.err != nil {
+		return before.err == nil
+	}
+	return r.exitCode != 0 && before.ok()
+}
+
+// smokeTest runs the binary at path with --version, with no input and a
+// timeout, to check that it starts.
+func smokeTest(path string) smokeResult {
+	return smokeRun(path, "--version")
+}
+
+// smokeRun runs the binary at path with a single argument, with no input,
+// a timeout, and the restricted environment of smokeEnv.
+func smokeRun(path, arg string) smokeResult {
+	dir, err := os.MkdirTemp("", "gomanager-smoke-")
+	if err != nil {
+		return smokeResult{err: fmt.Errorf("cannot run: %w", err)}
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), smokeTimeout)
+	defer cancel()
+	cmd := osexec.CommandContext(ctx, path, arg)
+	cmd.Dir = dir
+	cmd.Env = smokeEnv(dir)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	first, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	r := smokeResult{output: strings.TrimSpace(first)}
+	var exitErr *osexec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		r.err = fmt.Errorf("timed out after %s", smokeTimeout)
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		r.exitCode = exitErr.ExitCode()
+	case errors.As(err, &exitErr):
+		r.err = fmt.Errorf("crashed: %v", exitErr)
+	case err != nil:
+		r.err = fmt.Errorf("cannot run: %w", err)
+	}
+	return r
+}
+
+// smokeEnv returns the environment smoke tests run binaries in: only PATH
+// (and on Windows, SystemRoot) is kept from gomanager's, and the home,
+// XDG, and temporary directories point into dir, a throwaway working
+// directory, so that neither credentials nor the user's configuration
+// reach the binary and whatever it writes on first run is removed.
+func smokeEnv(dir string) []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"USERPROFILE=" + dir,
+		"TMPDIR=" + dir,
+		"XDG_CONFIG_HOME=" + filepath.Join(dir, "config"),
+		"XDG_CACHE_HOME=" + filepath.Join(dir, "cache"),
+		"XDG_DATA_HOME=" + filepath.Join(dir, "data"),
+	}
+	if root := os.Getenv("SystemRoot"); root != "" {
+		env = append(env, "SystemRoot="+root)
+	}
+	return env
+}
+
+// installVerify is bound to the --verify flag of install and upgrade.
+var installVerify bool
+
+const installVerifyUsage = "Check that each installed binary runs with --version or --help, and record the result and the version it reports"
+
+// reportedVersionRE matches a version number in a tool's --version output.
+var reportedVersionRE = regexp.MustCompile(`\bv?\d+\.\d+(?:\.\d+)?(?:[-+][0-9A-Za-z.-]+)?\b`)
+
+// verifyInstalled smoke tests the installed binary name for --verify: it
+// must exit successfully with --version, or, for tools without that flag,
+// with --help. The result, and the version it reports if any, is recorded
+// in the install state. It returns an ExitVerifyFailed error if neither
+// succeeds.
+func verifyInstalled(name string) error {
+	path, err := installedPath(name)
+	if err != nil {
+		return err
+	}
+	r := smokeTest(path)
+	var reported string
+	if r.ok() {
+		reported = reportedVersionRE.FindString(r.output)
+	} else if r.err == nil {
+		r = smokeRun(path, "--help")
+	}
+	if !r.ok() {
+		recordSmoke(name, r, "")
+		return withExitCode(ExitVerifyFailed, fmt.Errorf("%s is installed but fails to run (%s)", name, r))
+	}
+
+	if reported == "" {
+		logf(name, "Verified %s runs\n", name)
+	} else {
+		logf(name, "Verified %s runs and reports version %s\n", name, reported)
+	}
+	recordSmoke(name, r, reported)
+	return nil
+}
+
+// recordSmoke records the smoke test result r of the installed binary name,
+// and the version it reported, in the install state.
+func recordSmoke(name string, r smokeResult, reported string) {
+	smoke := &state.SmokeTest{OK: r.ok(), TestedAt: time.Now()}
+	if !r.ok() {
+		smoke.Error = r.String()
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	st, err := state.Load()
+	if err == nil {
+		if b, ok := st.Installed[name]; ok {
+			b.ReportedVersion = reported
+			b.Smoke = smoke
+			st.Installed[name] = b
+			err = st.Save()
+		}
+	}
+	if err != nil {
+		logf(name, "Warning: could not save install state: %v\n", err)
+	}
+}
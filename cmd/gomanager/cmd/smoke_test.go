@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegressedFrom(t *testing.T) {
+	ok := smokeResult{output: "tool v1.2.3"}
+	usage := smokeResult{exitCode: 2, output: "unknown flag: --version"}
+	failed := smokeResult{exitCode: 1, output: "panic: nil map"}
+	timeout := smokeResult{err: errors.New("timed out after 10s")}
+	crashed := smokeResult{err: errors.New("crashed: signal: segmentation fault")}
+
+	tests := []struct {
+		name          string
+		before, after smokeResult
+		want          bool
+	}{
+		{"ok/ok", ok, ok, false},
+		{"ok/failed", ok, failed, true},
+		{"ok/timeout", ok, timeout, true},
+		{"usage error/usage error", usage, usage, false},
+		{"usage error/ok", usage, ok, false},
+		{"usage error/timeout", usage, timeout, true},
+		{"timeout/timeout", timeout, timeout, false},
+		{"timeout/crashed", timeout, crashed, false},
+		{"timeout/usage error", timeout, usage, false},
+		{"crashed/ok", crashed, ok, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.after.regressedFrom(tt.before); got != tt.want {
+				t.Errorf("regressedFrom(%s) from %s = %v, want %v", tt.after, tt.before, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/jmelahman/gomanager/internal/archive"
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var snapshotPrune bool
+
+func init() {
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotPrune, "prune", false, "Uninstall binaries that aren't in the snapshot")
+	snapshotRestoreCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	snapshotRestoreCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	snapshotRestoreCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore sets of installed binaries",
+	Long: `A snapshot records every installed binary with its package, version, and
+release channel and pin. Restoring it installs whatever is missing or at another
+version, so you can switch between tool sets (e.g. work and OSS) or recover
+after experimenting. Snapshots are kept in the install state.`,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save the installed binaries as a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		_, replaced := st.Snapshots[args[0]]
+		snap := st.SaveSnapshot(args[0])
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("cannot save install state: %w", err)
+		}
+		verb := "Saved"
+		if replaced {
+			verb = "Replaced"
+		}
+		fmt.Printf("%s snapshot %s (%d binaries)\n", verb, args[0], len(snap.Binaries))
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Install the binaries and versions recorded in a snapshot",
+	Long: `Installs each binary in the snapshot that is missing or at another version.
+Archived copies from earlier upgrades are used when they match, and
+everything else is built. With --prune, installed binaries that aren't in
+the snapshot are uninstalled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		snap, ok := st.Snapshots[args[0]]
+		if !ok {
+			return withExitCode(ExitNotFound, fmt.Errorf("no snapshot named %q", args[0]))
+		}
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		names := make([]string, 0, len(snap.Binaries))
+		for name := range snap.Binaries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		restored, unchanged, failed, removed := 0, 0, 0, 0
+		for _, name := range names {
+			want := snap.Binaries[name]
+			cur, installed := st.Installed[name]
+			path, err := installedPath(name)
+			if err != nil {
+				return err
+			}
+			if _, statErr := os.Stat(path); installed && statErr == nil &&
+				cur.Package == want.Package && cur.Version == want.Version {
+				unchanged++
+				continue
+			}
+
+			if installed {
+				archiveInstalled(name, cur.Version)
+			}
+			if err := restoreSnapshotBinary(conn, name, want, installed && cur.Package == want.Package); err != nil {
+				fmt.Printf("Failed to restore %s %s: %v\n", name, want.Version, err)
+				failed++
+				continue
+			}
+			restored++
+		}
+
+		// Installs record their own state, so reload it before restoring
+		// channels, pins, and pruning.
+		if st, err = state.Load(); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if b, ok := st.Installed[name]; ok && b.Package == snap.Binaries[name].Package {
+				b.Channel = snap.Binaries[name].Channel
+				b.Pinned = snap.Binaries[name].Pinned
+				st.Installed[name] = b
+			}
+		}
+		if snapshotPrune {
+			for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+				if _, keep := snap.Binaries[name]; keep {
+					continue
+				}
+				path, err := removeBinary(name)
+				if err != nil {
+					fmt.Printf("Warning: %v\n", err)
+					continue
+				}
+				st.Remove(name)
+				fmt.Printf("Removed %s\n", path)
+				removed++
+			}
+		}
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("cannot save install state: %w", err)
+		}
+
+		fmt.Printf("\nRestored snapshot %s: %d installed, %d already current, %d failed", args[0], restored, unchanged, failed)
+		if snapshotPrune {
+			fmt.Printf(", %d removed", removed)
+		}
+		fmt.Println(".")
+		if failed > 0 {
+			return withExitCode(ExitBuildFailed, fmt.Errorf("%d binaries could not be restored", failed))
+		}
+		return nil
+	},
+}
+
+// restoreSnapshotBinary installs want as name, from the archive if there is
+// a matching copy of the same package, or by building it.
+func restoreSnapshotBinary(conn *sql.DB, name string, want state.InstalledBinary, samePackage bool) error {
+	if e, err := archive.Find(name, want.Version); err == nil && samePackage {
+		path, err := installedPath(name)
+		if err != nil {
+			return err
+		}
+		if err := archive.Restore(e, path); err != nil {
+			return err
+		}
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		st.MarkInstalled(name, want.Package, want.Version, filepath.Dir(path))
+		if err := st.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s %s from the archive\n", name, want.Version)
+		return nil
+	}
+
+	// Packages since dropped from the database can still be built, just
+	// without their recorded build flags.
+	b, err := lookupPackage(conn, want.Package)
+	if errors.Is(err, db.ErrNotFound) {
+		b, err = &db.Binary{Name: name, Package: want.Package, BuildStatus: "unknown", Confidence: -1, TrustScore: -1}, nil
+	}
+	if err != nil {
+		return err
+	}
+	pinned := *b
+	pinned.Name = name
+	pinned.Version = want.Version
+	if err := checkPolicy(&pinned); err != nil {
+		return err
+	}
+	if err := checkVulnerable(conn, &pinned); err != nil {
+		return err
+	}
+	fmt.Printf("Installing %s %s\n", name, want.Version)
+	return installBinary(&pinned)
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved snapshots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		if len(st.Snapshots) == 0 {
+			fmt.Println("No snapshots saved.")
+			return nil
+		}
+		names := make([]string, 0, len(st.Snapshots))
+		for name := range st.Snapshots {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		t := newTable("NAME", "BINARIES", "SAVED")
+		for _, name := range names {
+			snap := st.Snapshots[name]
+			t.row(name, strconv.Itoa(len(snap.Binaries)), snap.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		t.flush()
+		return nil
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a saved snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		if _, ok := st.Snapshots[args[0]]; !ok {
+			return withExitCode(ExitNotFound, fmt.Errorf("no snapshot named %q", args[0]))
+		}
+		delete(st.Snapshots, args[0])
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("cannot save install state: %w", err)
+		}
+		fmt.Printf("Deleted snapshot %s\n", args[0])
+		return nil
+	},
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"golang.org/x/mod/module"
+)
+
+// defaultSumDB is the checksum database go uses when GOSUMDB is unset.
+const defaultSumDB = "sum.golang.org"
+
+// sumdbSettings is the checksum database configuration of the go
+// environment, from the environment and the go env file. go env reports
+// GONOSUMDB and GONOPROXY as GOPRIVATE when they are unset.
+type sumdbSettings struct {
+	GOSUMDB    string
+	GONOSUMDB  string
+	GOPRIVATE  string
+	GONOPROXY  string
+	GOINSECURE string
+	GOFLAGS    string
+}
+
+// loadSumDBSettings reads the go environment's checksum database settings
+// once.
+var loadSumDBSettings = sync.OnceValues(func() (sumdbSettings, error) {
+	var s sumdbSettings
+	out, err := osexec.Command("go", "env", "-json", "GOSUMDB", "GONOSUMDB", "GOPRIVATE", "GONOPROXY", "GOINSECURE", "GOFLAGS").Output()
+	if err != nil {
+		return s, fmt.Errorf("cannot run go env: %w", err)
+	}
+	if err := json.Unmarshal(out, &s); err != nil {
+		return s, fmt.Errorf("cannot parse go env output: %w", err)
+	}
+	return s, nil
+})
+
+// sumdbEnv returns environment variables that keep the go command from
+// skipping checksum database verification of b, to be set after env, the
+// build environment from buildEnv. The settings that skip or weaken it are
+// GOSUMDB=off, GOINSECURE (which also makes -mod=mod fetch insecurely),
+// -insecure in GOFLAGS, and a GONOSUMDB or GOPRIVATE pattern matching b's
+// module.
+//
+// The first three are overridden. So is a GONOSUMDB/GOPRIVATE exception
+// for a module the build pipeline verified against sum.golang.org, which
+// doesn't need one; for a module it hasn't checked, which may be private,
+// the exception is kept, with a warning unless it's a local entry. A module sum.golang.org has no
+// record of needs a bypass, so none is overridden for it. With the
+// allow_sumdb_bypass setting nothing is overridden and the bypass is
+// warned about instead. Installing a module whose contents didn't match
+// sum.golang.org without verifying it is an error either way.
+func sumdbEnv(b *db.Binary, env []string) ([]string, error) {
+	s, err := loadSumDBSettings()
+	if err != nil {
+		return nil, nil
+	}
+	mod := pkgbuild.ResolvePaths(b.Package).Module
+	goflags := s.GOFLAGS
+	for _, v := range env {
+		if flags, ok := strings.CutPrefix(v, "GOFLAGS="); ok {
+			goflags = flags
+		}
+	}
+
+	// Each setting that skips or weakens verification of the module, and
+	// the variables that undo it.
+	type bypass struct {
+		setting  string
+		override []string
+	}
+	var bypasses []bypass
+	if s.GOSUMDB == "off" {
+		bypasses = append(bypasses, bypass{"GOSUMDB=off", []string{"GOSUMDB=" + defaultSumDB}})
+	}
+	if s.GOINSECURE != "" {
+		bypasses = append(bypasses, bypass{"GOINSECURE=" + s.GOINSECURE, []string{"GOINSECURE="}})
+	}
+	if safe, removed := db.StripInsecureGOFLAGS(goflags); len(removed) > 0 {
+		bypasses = append(bypasses, bypass{"GOFLAGS=" + strings.Join(removed, " "), []string{"GOFLAGS=" + safe}})
+	}
+	exception := ""
+	if module.MatchPrefixPatterns(s.GONOSUMDB, mod) {
+		exception = "GONOSUMDB=" + s.GONOSUMDB
+		if os.Getenv("GONOSUMDB") == "" && s.GONOSUMDB == s.GOPRIVATE {
+			exception = "GOPRIVATE=" + s.GOPRIVATE
+		}
+		if b.SumDB != db.SumDBMissing {
+			bypasses = append(bypasses, bypass{exception, []string{"GONOSUMDB=", "GOPRIVATE=", "GONOPROXY=" + s.GONOPROXY}})
+		}
+	}
+
+	if len(bypasses) == 0 {
+		switch {
+		case b.SumDB == db.SumDBMissing && exception != "":
+			logf(b.Name, "Note: %s is not in the public checksum database and is excluded from verification by %s\n", mod, exception)
+		case b.SumDB == db.SumDBMissing:
+			logf(b.Name, "Warning: %s is not in the public checksum database; installing it needs GONOSUMDB=%s\n", mod, mod)
+		}
+		return nil, nil
+	}
+	settings := make([]string, len(bypasses))
+	for i, bp := range bypasses {
+		settings[i] = bp.setting
+	}
+	skipped := strings.Join(settings, ", ")
+	switch {
+	case b.SumDB == db.SumDBMismatch:
+		return nil, fmt.Errorf("%s doesn't match the checksum database, and %s would skip verifying it", mod, skipped)
+	case allowSumDBBypass:
+		logf(b.Name, "Warning: %s skips checksum database verification of %s (allowed by allow_sumdb_bypass)\n", skipped, mod)
+		return nil, nil
+	case b.SumDB == db.SumDBMissing:
+		logf(b.Name, "Note: %s is not in the public checksum database; installing it unverified because of %s\n", mod, skipped)
+		return nil, nil
+	}
+
+	var overrides, overridden []string
+	for _, bp := range bypasses {
+		if bp.setting == exception && b.SumDB != db.SumDBVerified {
+			if b.Local {
+				logf(b.Name, "Note: %s is excluded from checksum database verification by %s\n", mod, exception)
+			} else {
+				logf(b.Name, "Warning: %s skips checksum database verification of %s, which the database hasn't recorded as verifying against %s\n", exception, mod, defaultSumDB)
+			}
+			continue
+		}
+		overrides = append(overrides, bp.override...)
+		overridden = append(overridden, bp.setting)
+	}
+	if len(overridden) > 0 {
+		logf(b.Name, "Note: verifying %s against %s despite %s (set allow_sumdb_bypass under [install] to skip it)\n", mod, defaultSumDB, strings.Join(overridden, ", "))
+	}
+	return overrides, nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+func TestSumDBEnv(t *testing.T) {
+	const pkg = "github.com/acme/tool/cmd/tool"
+	tests := []struct {
+		name     string
+		settings sumdbSettings
+		status   string
+		local    bool
+		env      []string
+		allow    bool
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "default settings",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB},
+			status:   db.SumDBVerified,
+		},
+		{
+			name:     "sumdb off",
+			settings: sumdbSettings{GOSUMDB: "off"},
+			want:     []string{"GOSUMDB=" + defaultSumDB},
+		},
+		{
+			name:     "sumdb off allowed",
+			settings: sumdbSettings{GOSUMDB: "off"},
+			allow:    true,
+		},
+		{
+			name:     "insecure",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB, GOINSECURE: "*", GOFLAGS: "-insecure -trimpath"},
+			want:     []string{"GOINSECURE=", "GOFLAGS=-trimpath"},
+		},
+		{
+			name:     "insecure flag from the build environment",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB},
+			env:      []string{"GOFLAGS=-mod=mod --insecure"},
+			want:     []string{"GOFLAGS=-mod=mod"},
+		},
+		{
+			name:     "blanket exception for a verified module",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB, GONOSUMDB: "*", GOPRIVATE: "*", GONOPROXY: "*"},
+			status:   db.SumDBVerified,
+			want:     []string{"GONOSUMDB=", "GOPRIVATE=", "GONOPROXY=*"},
+		},
+		{
+			name:     "exception for an unchecked module",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB, GONOSUMDB: "github.com/acme", GOPRIVATE: "github.com/acme"},
+		},
+		{
+			name:     "exception for a local entry",
+			settings: sumdbSettings{GOSUMDB: "off", GONOSUMDB: "github.com/acme"},
+			local:    true,
+			want:     []string{"GOSUMDB=" + defaultSumDB},
+		},
+		{
+			name:     "exception for a missing module",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB, GONOSUMDB: "github.com/acme"},
+			status:   db.SumDBMissing,
+		},
+		{
+			name:     "sumdb off for a missing module",
+			settings: sumdbSettings{GOSUMDB: "off"},
+			status:   db.SumDBMissing,
+		},
+		{
+			name:     "exception for a mismatched module",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB, GONOSUMDB: "*"},
+			status:   db.SumDBMismatch,
+			allow:    true,
+			wantErr:  true,
+		},
+		{
+			name:     "mismatched module verified",
+			settings: sumdbSettings{GOSUMDB: defaultSumDB},
+			status:   db.SumDBMismatch,
+		},
+	}
+	defer func(load func() (sumdbSettings, error)) { loadSumDBSettings = load }(loadSumDBSettings)
+	defer func(allow bool) { allowSumDBBypass = allow }(allowSumDBBypass)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadSumDBSettings = func() (sumdbSettings, error) { return tt.settings, nil }
+			allowSumDBBypass = tt.allow
+			b := &db.Binary{Name: "tool", Package: pkg, SumDB: tt.status, Local: tt.local}
+			got, err := sumdbEnv(b, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("sumdbEnv() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sumdbEnv() error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sumdbEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
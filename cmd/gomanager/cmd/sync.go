@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/progress"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// projectFile is the per-project tool manifest sync looks for.
+const projectFile = ".gomanager.toml"
+
+var (
+	syncFile string
+	syncJobs int
+)
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncFile, "file", "f", "", "Project manifest to sync (default: the nearest "+projectFile+")")
+	syncCmd.Flags().IntVarP(&syncJobs, "jobs", "j", 0, "Number of binaries to build at once (0 = one per CPU)")
+	syncCmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunUsage)
+	syncCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	syncCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	rootCmd.AddCommand(syncCmd)
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Install the tools a project needs",
+	Long: `Installs the tools listed in the project's ` + projectFile + `, found in the
+current directory or the nearest parent directory that has one, so a
+checkout can bootstrap its development tooling without a tools.go or
+changes to go.mod:
+
+  [tools]
+  golangci-lint = "^1.59"
+  stringer = "v0.22.0"
+  goreleaser = "latest"
+
+The format is the same as install --from-manifest's: each tool is a binary
+name or package path, with an exact version, a "^" or "~" constraint, or
+"latest" (or "") for the database's version. Tools that are already
+installed at a version satisfying the file are left alone; missing and
+outdated ones are installed, --jobs at a time, into the usual install
+directory. Tools the file doesn't list are kept.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkBackend(); err != nil {
+			return err
+		}
+		path := syncFile
+		if path == "" {
+			var err error
+			if path, err = findProjectFile(); err != nil {
+				return err
+			}
+		}
+		entries, err := loadManifest(path)
+		if err != nil {
+			return err
+		}
+		if err := ensureDB(); err != nil {
+			return err
+		}
+		conn, err := db.Open()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		var planned []*db.Binary
+		current, failed := 0, 0
+		for _, e := range entries {
+			b, err := resolveBinary(conn, e.arg)
+			if err == nil && syncSatisfied(b, e.version, st) {
+				current++
+				continue
+			}
+			if err == nil {
+				b, err = resolveManifestEntry(conn, e)
+			}
+			if err != nil {
+				fmt.Printf("Cannot install %s: %v\n", e.arg, err)
+				failed++
+				continue
+			}
+			events.Emit(progress.Event{Event: progress.Resolve, Name: b.Name, Package: b.Package, Version: b.Version})
+			planned = append(planned, b)
+		}
+
+		if dryRun {
+			for _, b := range planned {
+				printPlan("install", b.Name, b, installedVersion(b))
+				fmt.Println()
+			}
+			fmt.Printf("Dry run: %d tools would be installed, %d are current, %d can't be installed.\n", len(planned), current, failed)
+			return nil
+		}
+
+		installed := 0
+		if len(planned) > 0 {
+			jobs := jobCount(syncJobs, len(planned))
+			errs := installConcurrently(planned, jobs, func(b *db.Binary) error {
+				if cur, ok := st.Installed[b.Name]; ok {
+					archiveInstalled(b.Name, cur.Version)
+				}
+				if usesGoInstall(b) {
+					logf(b.Name, "Running: %s\n", installCommand(b))
+				}
+				err := installBinary(b)
+				if err != nil {
+					logf(b.Name, "Failed to install %s: %v\n", b.Name, err)
+				}
+				return err
+			})
+			var done []*db.Binary
+			for i, err := range errs {
+				if err != nil {
+					failed++
+				} else {
+					installed++
+					done = append(done, planned[i])
+				}
+			}
+			grantPrivileges(done)
+		}
+
+		fmt.Printf("\nSynced %s: %d installed, %d already current, %d failed.\n", path, installed, current, failed)
+		switch {
+		case failed > 0:
+			return withExitCode(ExitBuildFailed, fmt.Errorf("%d of %d tools could not be installed", failed, len(entries)))
+		case installed == 0:
+			return withExitCode(ExitNothingToDo, nil)
+		}
+		return nil
+	},
+}
+
+// findProjectFile returns the path of the projectFile in the current
+// directory or its nearest parent directory that has one.
+func findProjectFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, projectFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", withExitCode(ExitNotFound, fmt.Errorf("no %s in this directory or any parent", projectFile))
+		}
+		dir = parent
+	}
+}
+
+// syncSatisfied reports whether b is installed at a version satisfying the
+// project's version requirement: the exact version, a version in the
+// constraint's range, or, for "latest", at least the database's version.
+func syncSatisfied(b *db.Binary, version string, st *state.State) bool {
+	inst, ok := st.Installed[b.Name]
+	if !ok || inst.Package != b.Package {
+		return false
+	}
+	if path, err := installedPath(b.Name); err != nil {
+		return false
+	} else if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	switch {
+	case goproxy.IsConstraint(version):
+		c, err := goproxy.ParseConstraint(version)
+		return err == nil && c.Allows(inst.Version)
+	case version != "" && version != "latest":
+		return inst.Version == version
+	case b.Version == "" || b.Version == "latest":
+		return true
+	}
+	return semver.Compare(inst.Version, b.Version) >= 0
+}
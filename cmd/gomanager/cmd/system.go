@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jmelahman/gomanager/internal/config"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// systemInstall is bound to the global --system flag.
+var systemInstall bool
+
+// systemBinDir is where --system installs binaries. It is empty unless
+// --system is given.
+var systemBinDir string
+
+// setupSystem switches to the system-wide install directory and state file
+// when --system is given. Per-user installs are the default.
+func setupSystem() error {
+	if !systemInstall {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("--system is not supported on Windows")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		if !ignoreConfig {
+			return err
+		}
+		cfg = &config.Config{}
+	}
+	prefix := cfg.System.Prefix
+	if prefix == "" {
+		prefix = config.DefaultSystemPrefix
+	}
+	statePath := cfg.System.State
+	if statePath == "" {
+		statePath = config.DefaultSystemState
+	}
+	systemBinDir = filepath.Join(prefix, "bin")
+	state.UseFile(statePath)
+	return nil
+}
+
+// requireSystemAccess fails with a hint to use root if --system is given
+// and dir can't be written. It is a no-op for per-user installs.
+func requireSystemAccess(dir string) error {
+	if !systemInstall {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err == nil {
+		f, err := os.CreateTemp(dir, ".gomanager-access-*")
+		if err == nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil
+		}
+		if !errors.Is(err, fs.ErrPermission) {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	return fmt.Errorf("system-wide installs need write access to %s; run as root (e.g. sudo gomanager --system ...)", dir)
+}
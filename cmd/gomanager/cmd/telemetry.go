@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	osexec "os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/jmelahman/gomanager/internal/telemetry"
+	"golang.org/x/mod/module"
+)
+
+var (
+	telemetryMu sync.Mutex
+	// telemetryReports are the install reports sent when the command
+	// finishes.
+	telemetryReports []telemetry.Report
+)
+
+// goToolchainVersion returns the version of the go command builds use, or
+// "" if it can't be run.
+var goToolchainVersion = sync.OnceValue(func() string {
+	out, err := osexec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+})
+
+// buildFailure is a build error that keeps the build's output, so its
+// failure class can be reported without printing the output again.
+type buildFailure struct {
+	err    error
+	output string
+}
+
+func (e *buildFailure) Error() string { return e.err.Error() }
+
+func (e *buildFailure) Unwrap() error { return e.err }
+
+// reportInstall records the outcome of installing b, if telemetry is turned
+// on. Entries that aren't in the published database and modules excluded by
+// GOPRIVATE or GONOSUMDB are never reported, so private package paths don't
+// leave the machine.
+func reportInstall(b *db.Binary, err error) {
+	if telemetryEndpoint == "" || b.Local || b.ID == 0 {
+		return
+	}
+	if s, serr := loadSumDBSettings(); serr != nil {
+		return
+	} else if module.MatchPrefixPatterns(s.GOPRIVATE+","+s.GONOSUMDB, pkgbuild.ResolvePaths(b.Package).Module) {
+		return
+	}
+	version := b.Version
+	if version == "" {
+		version = "latest"
+	}
+	r := telemetry.Report{
+		Package:   b.Package,
+		Version:   version,
+		GoVersion: goToolchainVersion(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		Status:    telemetry.StatusInstalled,
+		Date:      time.Now().UTC().Format(time.DateOnly),
+	}
+	if err != nil {
+		r.Status = telemetry.StatusFailed
+		output := err.Error()
+		var bf *buildFailure
+		if errors.As(err, &bf) && bf.output != "" {
+			output = bf.output
+		}
+		r.Class = telemetry.Classify(output)
+	}
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+	telemetryReports = append(telemetryReports, r)
+}
+
+// sendTelemetry posts the reports recorded by reportInstall. Telemetry never
+// fails or noticeably delays a command: the request has a short timeout and
+// a failure to send is only mentioned, and the reports dropped.
+func sendTelemetry() {
+	telemetryMu.Lock()
+	reports := telemetryReports
+	telemetryReports = nil
+	telemetryMu.Unlock()
+	if len(reports) == 0 {
+		return
+	}
+	client := &http.Client{Timeout: 3 * time.Second}
+	if err := telemetry.Send(client, telemetryEndpoint, reports); err != nil {
+		fmt.Fprintf(os.Stderr, "Note: could not send install telemetry: %v\n", err)
+	}
+}
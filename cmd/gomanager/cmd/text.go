@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Descriptions and names can contain emoji and CJK text, which take two
+// terminal columns per character, and combining marks, which take none, so
+// columns are measured in display width rather than bytes or runes.
+
+// textWidth returns the number of terminal columns s occupies.
+func textWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// clip shortens s to at most width columns, marking the cut with "…". It
+// cuts between grapheme clusters, so multi-rune emoji and accented
+// characters are kept whole.
+func clip(s string, width int) string {
+	if width < 1 {
+		return ""
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// padRight pads s with spaces to width columns.
+func padRight(s string, width int) string {
+	return runewidth.FillRight(s, width)
+}
+
+// wrapText splits s into lines of at most width columns, breaking at
+// spaces. Words wider than width get a line of their own.
+func wrapText(s string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	n := 0
+	for _, word := range strings.Fields(s) {
+		w := textWidth(word)
+		if n > 0 && n+1+w > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			n = 0
+		}
+		if n > 0 {
+			line.WriteByte(' ')
+			n++
+		}
+		line.WriteString(word)
+		n += w
+	}
+	if n > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
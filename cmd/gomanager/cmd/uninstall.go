@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmelahman/gomanager/internal/archive"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var uninstallPurge bool
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, "Also remove archived versions and other data kept for the binary")
+	rootCmd.AddCommand(uninstallCmd)
+}
+
+// purgeStep removes one kind of data kept for an uninstalled binary. It
+// returns a description of what it removed, or "" if there was nothing.
+type purgeStep func(name string) (string, error)
+
+// purgeSteps are run by uninstall --purge after the binary is removed.
+var purgeSteps = []purgeStep{
+	purgeArchives,
+}
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall <name>...",
+	Short: "Remove binaries installed by gomanager",
+	Long: `Removes each binary from the go install directory and forgets it, along
+with its release channel, pin, and other per-binary settings. Aliases and
+desktop entries created by 'install --alias' and '--desktop' are removed
+with it.
+
+With --purge, data kept for the binary outside the install directory is
+removed too: copies archived by earlier upgrades. Without it, those are
+kept so a reinstall can still roll back.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		notFound := 0
+		for _, name := range args {
+			ib, ok := st.Installed[name]
+			if !ok {
+				fmt.Printf("%s was not installed by gomanager\n", name)
+				notFound++
+				continue
+			}
+			path, err := removeBinary(name)
+			if err != nil {
+				return err
+			}
+			st.Remove(name)
+			if err := st.Save(); err != nil {
+				return fmt.Errorf("cannot save install state: %w", err)
+			}
+			fmt.Printf("Removed %s\n", path)
+			removeLaunchers(ib, filepath.Dir(path))
+
+			if uninstallPurge {
+				for _, step := range purgeSteps {
+					what, err := step(name)
+					if err != nil {
+						fmt.Printf("Warning: %v\n", err)
+						continue
+					}
+					if what != "" {
+						fmt.Printf("Purged %s\n", what)
+					}
+				}
+			}
+		}
+
+		if notFound > 0 {
+			return withExitCode(ExitNotFound, fmt.Errorf("%d of %d binaries were not installed", notFound, len(args)))
+		}
+		return nil
+	},
+}
+
+// removeBinary deletes name from the go install directory, if it is there,
+// and returns its path.
+func removeBinary(name string) (string, error) {
+	path, err := installedPath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := requireSystemAccess(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("cannot remove %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// purgeArchives removes the copies of name archived by upgrades.
+func purgeArchives(name string) (string, error) {
+	entries, err := archive.Prune(name, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot remove archived copies of %s: %w", name, err)
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%d archived versions of %s", len(entries), name), nil
+}
@@ -11,8 +11,9 @@ import (
 )
 
 // Default URL where the database is hosted (raw content from master branch).
-// Override with --url flag.
-var dbURL = "https://raw.githubusercontent.com/jmelahman/gomanager/master/database.db"
+// This is the slim client database, without the build errors and history the
+// admin tools keep. Override with --url flag.
+var dbURL = "https://raw.githubusercontent.com/jmelahman/gomanager/master/database-slim.db"
 
 func init() {
 	updateDBCmd.Flags().StringVar(&dbURL, "url", dbURL, "URL to download database.db from")
@@ -36,29 +37,53 @@ func downloadDB() error {
 	fmt.Printf("Downloading database from %s ...\n", dbURL)
 	resp, err := http.Get(dbURL)
 	if err != nil {
-		return fmt.Errorf("download failed: %w", err)
+		return withExitCode(ExitNetwork, fmt.Errorf("download failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+		return withExitCode(ExitNetwork, fmt.Errorf("download failed: HTTP %d", resp.StatusCode))
 	}
 
-	f, err := os.Create(dest)
+	// Download next to the destination and only replace the current
+	// database once the new one is known to be readable by this build.
+	tmp := dest + ".download"
+	defer os.Remove(tmp)
+	f, err := os.Create(tmp)
 	if err != nil {
 		return fmt.Errorf("cannot write database: %w", err)
 	}
-	defer f.Close()
-
 	n, err := io.Copy(f, resp.Body)
+	f.Close()
 	if err != nil {
-		return fmt.Errorf("write error: %w", err)
+		return withExitCode(ExitNetwork, fmt.Errorf("write error: %w", err))
+	}
+
+	if err := checkDownloadedDB(tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return fmt.Errorf("cannot write database: %w", err)
 	}
 
 	fmt.Printf("Database saved to %s (%d bytes)\n", dest, n)
 	return nil
 }
 
+// checkDownloadedDB verifies that a downloaded database has a schema
+// version this build supports.
+func checkDownloadedDB(path string) error {
+	conn, err := db.CreatePath(path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := db.CheckSchema(conn); err != nil {
+		return fmt.Errorf("downloaded database is unusable, keeping the current one: %w", err)
+	}
+	return nil
+}
+
 // ensureDB checks if the database exists locally and downloads it if not.
 func ensureDB() error {
 	path, err := db.DBPath()
@@ -66,7 +91,8 @@ func ensureDB() error {
 		return err
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Println("Database not found locally. Downloading...")
+		// On stderr, so it doesn't corrupt --json output
+		fmt.Fprintln(os.Stderr, "Database not found locally. Downloading...")
 		return downloadDB()
 	}
 	return nil
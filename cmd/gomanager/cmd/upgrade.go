@@ -1,27 +1,108 @@
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"github.com/jmelahman/gomanager/internal/progress"
 	"github.com/jmelahman/gomanager/internal/state"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
 )
 
-var upgradeAll bool
+var (
+	upgradeAll           bool
+	upgradeOnlyConfirmed bool
+	upgradeYes           bool
+	upgradeJobs          int
+	upgradeCheck         bool
+)
+
+// plannedUpgrade is an upgrade that has passed all checks: the installed
+// name, the version it replaces, and the binary to build.
+type plannedUpgrade struct {
+	name   string
+	from   string
+	binary *db.Binary
+}
 
 func init() {
 	upgradeCmd.Flags().BoolVar(&upgradeAll, "all", false, "Upgrade all installed binaries")
+	upgradeCmd.Flags().BoolVar(&upgradeOnlyConfirmed, "only-confirmed", false,
+		"Only upgrade to versions the build pipeline has confirmed")
+	upgradeCmd.Flags().BoolVarP(&upgradeYes, "yes", "y", false, "Don't ask for confirmation before upgrading several binaries")
+	upgradeCmd.Flags().IntVarP(&upgradeJobs, "jobs", "j", 0, "Number of binaries to build at once (0 = one per CPU)")
+	upgradeCmd.Flags().BoolVar(&policyOverride, "policy-override", false, policyOverrideUsage)
+	upgradeCmd.Flags().BoolVar(&acceptVulnerable, "accept-vulnerable", false, acceptVulnerableUsage)
+	upgradeCmd.Flags().StringVar(&installBackend, "backend", backendAuto, installBackendUsage)
+	upgradeCmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunUsage)
+	upgradeCmd.Flags().BoolVar(&installVerify, "verify", false, installVerifyUsage)
+	upgradeCmd.Flags().BoolVar(&upgradeNoRollback, "no-rollback", false, "Keep upgrades whose binary fails to run --version")
+	upgradeCmd.Flags().BoolVar(&upgradeCheck, "check", false, "List what would be upgraded and exit 13 if anything is outdated, without installing (all binaries unless names are given)")
+	upgradeCmd.MarkFlagsMutuallyExclusive("check", "dry-run")
 	rootCmd.AddCommand(upgradeCmd)
 }
 
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade [name]",
 	Short: "Upgrade installed Go binaries to their latest database version",
+	Long: `Upgrades installed binaries to the version recorded in the database, or,
+for binaries on the latest channel (see 'gomanager channel'), to the module
+proxy's @latest version.
+
+With --all, pinned binaries (see 'gomanager pin') are skipped; naming a
+pinned binary upgrades it and keeps the pin.
+
+With --only-confirmed, a binary is only upgraded once the build pipeline has
+confirmed its new version builds. Binaries whose new version is still
+awaiting verification (or failed it) are skipped and left at their
+installed version.
+
+Before upgrading several binaries, the expected build time and module
+download size are shown (from the build pipeline's measurements) and, on
+a terminal, confirmation is requested unless --yes is given. Upgrades are
+built --jobs at a time (one per CPU by default), with each line of output
+prefixed by the binary's name while several run at once.
+
+With --dry-run, each upgrade's command, build environment, and state change
+(and, with archiving enabled, the copy that would be archived) is printed
+instead, and nothing is built.
+
+With --check, the binaries that would be upgraded (all installed ones
+unless names are given) are listed with their installed and available
+versions, and nothing is built. The exit code is 13 if any are outdated
+and 0 if all are current, so a CI job can enforce fresh tooling; binaries
+skipped by --only-confirmed, pins, policy, or advisories don't count as
+outdated.
+
+A binary installed at a newer version than the one it would be upgraded
+to (from a version constraint, a manifest, or the latest channel) is left
+alone rather than downgraded, and isn't outdated.
+
+After each upgrade, the new binary is run with --version (with a 10s
+timeout). If it crashes, hangs, or fails where the binary it replaced
+succeeded, the previous binary and its install state are restored and the
+upgrade is reported as rolled back (exit code 11). --no-rollback keeps
+the new binary regardless.
+
+With --verify, each upgraded binary that wasn't rolled back must also exit
+successfully with --version (or, failing that, --help); the version it
+reports is recorded in the install state. Upgrades that fail this check
+are kept but reported as failed (exit code 12).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if !upgradeAll && len(args) == 0 {
+		// A bare --check checks everything installed.
+		all := upgradeAll || upgradeCheck && len(args) == 0
+		if !all && len(args) == 0 {
 			return fmt.Errorf("specify a binary name or use --all")
 		}
+		if err := checkBackend(); err != nil {
+			return err
+		}
 
 		if err := ensureDB(); err != nil {
 			return err
@@ -38,8 +119,12 @@ var upgradeCmd = &cobra.Command{
 		}
 
 		var toUpgrade []string
-		if upgradeAll {
-			for name := range st.Installed {
+		if all {
+			for _, name := range slices.Sorted(maps.Keys(st.Installed)) {
+				if b := st.Installed[name]; b.Pinned {
+					fmt.Printf("Skipping %s: pinned at %s\n", name, b.Version)
+					continue
+				}
 				toUpgrade = append(toUpgrade, name)
 			}
 		} else {
@@ -48,36 +133,219 @@ var upgradeCmd = &cobra.Command{
 
 		if len(toUpgrade) == 0 {
 			fmt.Println("No binaries to upgrade.")
-			return nil
+			if upgradeCheck {
+				return nil
+			}
+			return withExitCode(ExitNothingToDo, nil)
 		}
 
+		// Resolve every target first so the whole batch can be estimated
+		// before anything is built.
+		var planned []plannedUpgrade
+		var proxy *goproxy.Client
+		upgraded, failed, rolledBack, unverified, notFound, blocked, vulnerable, unreachable := 0, 0, 0, 0, 0, 0, 0, 0
 		for _, name := range toUpgrade {
 			// If we have the package path from install state, use it directly
 			// to avoid ambiguity with duplicate names.
 			var b *db.Binary
 			if installed, ok := st.Installed[name]; ok && installed.Package != "" {
-				b, err = db.GetByPackage(conn, installed.Package)
+				b, err = lookupPackage(conn, installed.Package)
 			}
 			if b == nil {
 				b, err = resolveBinary(conn, name)
 			}
 			if err != nil {
 				fmt.Printf("Skipping %s: %v\n", name, err)
+				notFound++
 				continue
 			}
 
 			installed, ok := st.Installed[name]
-			if ok && installed.Version == b.Version {
+			if ok && installed.ReleaseChannel() == state.ChannelLatest {
+				if proxy == nil {
+					if proxy, err = goproxy.New(); err != nil {
+						return err
+					}
+				}
+				latest, err := latestRelease(conn, proxy, b)
+				if err != nil {
+					fmt.Printf("Skipping %s: %v\n", name, err)
+					unreachable++
+					continue
+				}
+				b = latest
+			}
+
+			events.Emit(progress.Event{Event: progress.Resolve, Name: b.Name, Package: b.Package, Version: b.Version})
+
+			if ok && aheadOf(installed.Version, b.Version) {
+				fmt.Printf("%s is at %s, newer than %s; not downgrading\n", name, installed.Version, b.Version)
+				events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: b.Version, Status: "newer"})
+				continue
+			}
+			if ok && sameVersion(installed.Version, b.Version) {
 				fmt.Printf("%s is already at %s\n", name, b.Version)
+				events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: b.Version, Status: "up-to-date"})
+				continue
+			}
+
+			if upgradeOnlyConfirmed && !b.VersionConfirmed() {
+				fmt.Printf("Skipping %s: %s is not confirmed (%s)\n", name, b.Version, unconfirmedReason(b))
+				events.Emit(progress.Event{Event: progress.Result, Name: b.Name, Package: b.Package, Version: b.Version, Status: "unconfirmed"})
 				continue
 			}
 
-			fmt.Printf("Upgrading %s: %s -> %s\n", name, installed.Version, b.Version)
-			if err := runGoInstall(b); err != nil {
-				fmt.Printf("Failed to upgrade %s: %v\n", name, err)
+			if err := checkPolicy(b); err != nil {
+				fmt.Printf("Skipping %s: %v\n", name, err)
+				blocked++
+				continue
 			}
+			if err := checkVulnerable(conn, b); err != nil {
+				fmt.Printf("Skipping %s: %v\n", name, err)
+				vulnerable++
+				continue
+			}
+
+			planned = append(planned, plannedUpgrade{name: name, from: installed.Version, binary: b})
+		}
+
+		if upgradeCheck && len(planned) > 0 {
+			fmt.Println()
+			t := newTable("NAME", "INSTALLED", "AVAILABLE")
+			for _, p := range planned {
+				from := p.from
+				if from == "" {
+					from = "-"
+				}
+				t.row(p.name, from, p.binary.Version)
+			}
+			t.flush()
+			return withExitCode(ExitOutdated, fmt.Errorf("%d of %d binaries are outdated", len(planned), len(toUpgrade)))
 		}
 
+		batch := make([]*db.Binary, len(planned))
+		plans := make(map[*db.Binary]plannedUpgrade, len(planned))
+		for i, p := range planned {
+			batch[i] = p.binary
+			plans[p.binary] = p
+		}
+		if len(batch) > 1 && !confirmBatch(batch, upgradeYes || dryRun) {
+			return withExitCode(ExitDeclined, nil)
+		}
+		if dryRun {
+			for _, p := range planned {
+				fmt.Println()
+				printPlan("upgrade", p.name, p.binary, p.from)
+			}
+			fmt.Printf("\nDry run: %d binaries would be upgraded.\n", len(planned))
+			upgraded = len(planned)
+		} else {
+			errs := installConcurrently(batch, jobCount(upgradeJobs, len(batch)), func(b *db.Binary) error {
+				p := plans[b]
+				logf(p.name, "Upgrading %s: %s -> %s\n", p.name, p.from, b.Version)
+				archiveInstalled(p.name, p.from)
+				var backup *upgradeBackup
+				if !upgradeNoRollback {
+					backup = backupInstalled(p.name)
+				}
+				err := installBinary(b)
+				switch {
+				case backup == nil:
+				case err == nil:
+					err = backup.verify()
+				default:
+					backup.discard()
+				}
+				if err == nil && installVerify {
+					err = verifyInstalled(p.name)
+				}
+				if err != nil && ExitCode(err) != ExitRolledBack {
+					logf(p.name, "Failed to upgrade %s: %v\n", p.name, err)
+				}
+				return err
+			})
+			var done []*db.Binary
+			for i, err := range errs {
+				switch {
+				case err == nil:
+					upgraded++
+					done = append(done, batch[i])
+				case ExitCode(err) == ExitRolledBack:
+					rolledBack++
+				case ExitCode(err) == ExitVerifyFailed:
+					unverified++
+				default:
+					failed++
+				}
+			}
+			grantPrivileges(done)
+		}
+
+		switch {
+		case failed > 0:
+			return withExitCode(ExitBuildFailed, fmt.Errorf("%d of %d upgrades failed", failed, len(toUpgrade)))
+		case rolledBack > 0:
+			return withExitCode(ExitRolledBack, fmt.Errorf("%d of %d upgrades were rolled back", rolledBack, len(toUpgrade)))
+		case unverified > 0:
+			return withExitCode(ExitVerifyFailed, fmt.Errorf("%d of %d upgraded binaries fail to run", unverified, len(toUpgrade)))
+		case notFound > 0:
+			return withExitCode(ExitNotFound, fmt.Errorf("%d binaries could not be resolved", notFound))
+		case unreachable > 0:
+			return withExitCode(ExitNetwork, fmt.Errorf("%d binaries could not be checked against the module proxy", unreachable))
+		case blocked > 0:
+			return withExitCode(ExitPolicy, fmt.Errorf("%d upgrades were blocked by policy", blocked))
+		case vulnerable > 0:
+			return withExitCode(ExitVulnerable, fmt.Errorf("%d upgrades were refused for critical vulnerabilities", vulnerable))
+		case upgradeCheck:
+			fmt.Println("All binaries are up to date.")
+		case upgraded == 0:
+			return withExitCode(ExitNothingToDo, nil)
+		}
 		return nil
 	},
 }
+
+// aheadOf reports whether the installed version is newer than target, as
+// after installing from a constraint, a manifest pin, or the latest
+// channel. Versions that aren't both semver are never ahead.
+func aheadOf(installed, target string) bool {
+	return semver.IsValid(installed) && semver.IsValid(target) && semver.Compare(installed, target) > 0
+}
+
+// sameVersion reports whether installed and target are the same version,
+// ignoring build metadata for semver versions.
+func sameVersion(installed, target string) bool {
+	if semver.IsValid(installed) && semver.IsValid(target) {
+		return semver.Compare(installed, target) == 0
+	}
+	return installed == target
+}
+
+// latestRelease returns b at the module proxy's @latest version, for
+// binaries on the latest channel, avoiding versions with critical
+// advisories.
+func latestRelease(conn *sql.DB, proxy *goproxy.Client, b *db.Binary) (*db.Binary, error) {
+	version, err := proxy.Latest(pkgbuild.ResolvePaths(b.Package).Module)
+	if err == nil {
+		version, err = safeLatest(conn, b, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	latest := *b
+	latest.Version = version
+	return &latest, nil
+}
+
+// unconfirmedReason describes why b's current version isn't confirmed.
+func unconfirmedReason(b *db.Binary) string {
+	switch {
+	case b.BuildStatus == "failed" || b.BuildStatus == "regressed":
+		return "build " + b.BuildStatus
+	case b.BuildStatus == db.StatusCodegen:
+		return "needs codegen"
+	case b.BuildStatus == "confirmed" && b.VerifiedVersion != "":
+		return "last confirmed at " + b.VerifiedVersion
+	}
+	return "awaiting verification"
+}
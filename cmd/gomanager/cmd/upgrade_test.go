@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/state"
+)
+
+// upgradeFixture installs hello ahead of the database's version, widget
+// behind it, and gizmo at it, in a temporary home (see testHome).
+func upgradeFixture(t *testing.T) {
+	t.Helper()
+	testHome(t)
+	t.Cleanup(func() {
+		upgradeAll, upgradeCheck, dryRun = false, false, false
+		for _, name := range []string{"all", "check", "dry-run"} {
+			upgradeCmd.Flags().Lookup(name).Changed = false
+		}
+	})
+
+	st := &state.State{Installed: make(map[string]state.InstalledBinary)}
+	for _, b := range []state.InstalledBinary{
+		{Name: "hello", Package: "github.com/acme/hello", Version: "v1.2.0"},
+		{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0"},
+		{Name: "gizmo", Package: "github.com/acme/gizmo", Version: "v1.0.0+incompatible"},
+	} {
+		b.InstalledAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		st.Installed[b.Name] = b
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := db.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.CreatePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := db.InitSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []db.Binary{
+		{Name: "hello", Package: "github.com/acme/hello", Version: "v1.0.0"},
+		{Name: "widget", Package: "github.com/acme/widget", Version: "v1.1.0"},
+		{Name: "gizmo", Package: "github.com/acme/gizmo", Version: "v1.0.0"},
+	} {
+		if _, err := conn.Exec(`INSERT INTO binaries (name, package, version, build_status) VALUES (?, ?, ?, 'confirmed')`,
+			b.Name, b.Package, b.Version); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runClient runs gomanager with args and returns what it printed and the
+// error it returned.
+func runClient(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var err error
+	out := captureStdout(t, func() {
+		rootCmd.SetArgs(args)
+		err = rootCmd.Execute()
+	})
+	return out, err
+}
+
+// captureStdout runs f and returns what it printed.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return <-done
+}
+
+func TestUpgradeCheckNewerInstall(t *testing.T) {
+	upgradeFixture(t)
+
+	out, err := runClient(t, "upgrade", "hello", "gizmo", "--check")
+	if err != nil {
+		t.Errorf("upgrade --check of binaries at or ahead of the database: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "hello is at v1.2.0, newer than v1.0.0; not downgrading") {
+		t.Errorf("upgrade --check doesn't report hello as newer:\n%s", out)
+	}
+	if !strings.Contains(out, "gizmo is already at v1.0.0") {
+		t.Errorf("upgrade --check doesn't report gizmo as current:\n%s", out)
+	}
+
+	// With no names, --check checks everything; only widget is outdated.
+	out, err = runClient(t, "upgrade", "--check", "--all=false")
+	if ExitCode(err) != ExitOutdated || !strings.Contains(err.Error(), "1 of 3 binaries are outdated") {
+		t.Errorf("upgrade --check: got %v (exit %d), want 1 of 3 outdated (exit %d)\n%s", err, ExitCode(err), ExitOutdated, out)
+	}
+	if strings.Contains(out, "v1.2.0  ") || !strings.Contains(out, "widget") {
+		t.Errorf("upgrade --check lists the wrong binaries:\n%s", out)
+	}
+
+	// Flags stay set between runs, and --check excludes --dry-run.
+	upgradeCmd.Flags().Lookup("check").Changed = false
+	upgradeCheck = false
+	out, err = runClient(t, "upgrade", "hello", "--dry-run")
+	if strings.Contains(out, "v1.2.0 -> v1.0.0") || ExitCode(err) != ExitNothingToDo {
+		t.Errorf("upgrade --dry-run of a newer install: got %v, want nothing to do\n%s", err, out)
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+
+	"github.com/jmelahman/gomanager/internal/attest"
+	"github.com/jmelahman/gomanager/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// Statuses verify-local reports for an installed binary.
+const (
+	localOK         = "ok"
+	localModified   = "modified"
+	localMissing    = "missing"
+	localUnrecorded = "unrecorded"
+	localRecorded   = "recorded"
+)
+
+var verifyLocalRecord bool
+
+func init() {
+	verifyLocalCmd.Flags().BoolVar(&verifyLocalRecord, "record", false, "Record the digest of binaries installed before digests were recorded")
+	rootCmd.AddCommand(verifyLocalCmd)
+}
+
+// localCheck is the JSON form of a verify-local result.
+type localCheck struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// SHA256 is the digest of the file now, if it exists.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+var verifyLocalCmd = &cobra.Command{
+	Use:   "verify-local [name...]",
+	Short: "Check installed binaries haven't changed since they were installed",
+	Long: `Re-hashes installed binaries (all of them, or those named) and compares
+each SHA-256 digest with the one recorded in the install state when it was
+installed. A binary whose file has been modified or replaced since, e.g.
+overwritten by another package manager or tampered with, is reported as
+modified, and one whose file is gone as missing; either makes the command
+exit with code 14.
+
+Binaries installed before digests were recorded are reported as
+unrecorded; --record records their current digest so later runs can check
+them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		names := args
+		if len(names) == 0 {
+			names = slices.Sorted(maps.Keys(st.Installed))
+		}
+		if len(names) == 0 {
+			fmt.Println("No binaries installed via gomanager.")
+			return withExitCode(ExitNothingToDo, nil)
+		}
+
+		checks := []localCheck{}
+		changed, recorded := 0, 0
+		for _, name := range names {
+			b, ok := st.Installed[name]
+			if !ok {
+				return withExitCode(ExitNotFound, fmt.Errorf("%s is not installed", name))
+			}
+			c, err := checkLocal(b)
+			if err != nil {
+				return err
+			}
+			switch c.Status {
+			case localModified, localMissing:
+				changed++
+			case localUnrecorded:
+				if verifyLocalRecord {
+					b.SHA256 = c.SHA256
+					st.Installed[name] = b
+					c.Status = localRecorded
+					recorded++
+				}
+			}
+			checks = append(checks, c)
+		}
+		if recorded > 0 {
+			if err := st.Save(); err != nil {
+				return err
+			}
+		}
+
+		err = printResult(checks, func() {
+			t := newTable("NAME", "STATUS", "PATH")
+			for _, c := range checks {
+				t.row(c.Name, c.Status, c.Path)
+			}
+			t.flush()
+			if recorded > 0 {
+				fmt.Printf("\nRecorded the digest of %d binaries.\n", recorded)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if changed > 0 {
+			return withExitCode(ExitModified, fmt.Errorf("%d of %d binaries changed since they were installed", changed, len(checks)))
+		}
+		return nil
+	},
+}
+
+// checkLocal hashes the installed binary b and compares the digest with the
+// one recorded when it was installed.
+func checkLocal(b state.InstalledBinary) (localCheck, error) {
+	path, err := installedPath(b.Name)
+	if err != nil {
+		return localCheck{}, err
+	}
+	c := localCheck{Name: b.Name, Path: path}
+	c.SHA256, err = attest.FileDigest(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		c.Status = localMissing
+	case err != nil:
+		return c, fmt.Errorf("cannot hash %s: %w", b.Name, err)
+	case b.SHA256 == "":
+		c.Status = localUnrecorded
+	case b.SHA256 != c.SHA256:
+		c.Status = localModified
+	default:
+		c.Status = localOK
+	}
+	return c, nil
+}
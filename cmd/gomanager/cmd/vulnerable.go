@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/goproxy"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+	"golang.org/x/mod/semver"
+)
+
+// acceptVulnerable installs versions with known critical vulnerabilities.
+// It is bound to the --accept-vulnerable flag of every command that
+// installs.
+var acceptVulnerable bool
+
+const acceptVulnerableUsage = "Install even if the version has a known critical vulnerability"
+
+// criticalAdvisories returns the critical advisories recorded for b's
+// module. version restricts them to those affecting it, unless empty.
+func criticalAdvisories(conn *sql.DB, b *db.Binary, version string) ([]db.Advisory, error) {
+	advisories, err := db.GetAdvisories(conn, pkgbuild.ResolvePaths(b.Package).Module)
+	if err != nil {
+		return nil, err
+	}
+	var critical []db.Advisory
+	for _, a := range advisories {
+		if a.Critical() && (version == "" || a.Affects(version)) {
+			critical = append(critical, a)
+		}
+	}
+	return critical, nil
+}
+
+// advisoryIDs lists the IDs of advisories, without duplicates.
+func advisoryIDs(advisories []db.Advisory) string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, a := range advisories {
+		if !seen[a.ID] {
+			seen[a.ID] = true
+			ids = append(ids, a.ID)
+		}
+	}
+	return strings.Join(ids, ", ")
+}
+
+// checkVulnerable refuses to install b at its version if a critical
+// advisory affects it, unless --accept-vulnerable is given. Databases
+// without advisories allow everything.
+func checkVulnerable(conn *sql.DB, b *db.Binary) error {
+	advisories, err := criticalAdvisories(conn, b, b.Version)
+	if err != nil || len(advisories) == 0 {
+		return err
+	}
+	ids := advisoryIDs(advisories)
+	if acceptVulnerable {
+		fmt.Printf("Warning: %s %s has critical vulnerabilities (%s); continuing because of --accept-vulnerable.\n", b.Name, b.Version, ids)
+		return nil
+	}
+	return withExitCode(ExitVulnerable, fmt.Errorf(
+		"%s %s has critical vulnerabilities (%s); use --accept-vulnerable to install anyway",
+		b.Name, b.Version, ids))
+}
+
+// safeLatest returns the version to install for b when following the
+// newest release: latest, or if latest has a critical advisory, the newest
+// release without one. If no release avoids them, latest is returned for
+// checkVulnerable to refuse. latest may be "" or "latest" to have it
+// resolved from the module proxy, which only happens if the module has
+// critical advisories.
+func safeLatest(conn *sql.DB, b *db.Binary, latest string) (string, error) {
+	advisories, err := criticalAdvisories(conn, b, "")
+	if err != nil || len(advisories) == 0 {
+		return latest, err
+	}
+	affected := func(v string) bool {
+		for _, a := range advisories {
+			if a.Affects(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	module := pkgbuild.ResolvePaths(b.Package).Module
+	proxy, err := goproxy.New()
+	if err != nil {
+		return latest, err
+	}
+	if latest == "" || latest == "latest" {
+		if latest, err = proxy.Latest(module); err != nil {
+			return "", withExitCode(ExitNetwork, err)
+		}
+	}
+	if !affected(latest) {
+		return latest, nil
+	}
+
+	versions, err := proxy.Versions(module)
+	if err != nil {
+		return "", withExitCode(ExitNetwork, err)
+	}
+	semver.Sort(versions)
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if semver.Prerelease(v) != "" || affected(v) {
+			continue
+		}
+		fmt.Printf("%s %s has critical vulnerabilities; using %s, the newest release without any.\n", b.Name, latest, v)
+		return v, nil
+	}
+	return latest, nil
+}
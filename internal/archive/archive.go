@@ -0,0 +1,209 @@
+// Package archive keeps copies of binaries replaced by upgrades so they can
+// be restored without rebuilding. Copies live under
+// <data dir>/gomanager/archive/<name>/<version>/<name>.
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jmelahman/gomanager/internal/dirs"
+)
+
+// Entry is an archived copy of a binary.
+type Entry struct {
+	Name       string
+	Version    string
+	Path       string
+	Size       int64
+	ArchivedAt time.Time
+}
+
+// Dir returns the archive root: $XDG_DATA_HOME/gomanager/archive, or
+// ~/.local/share/gomanager/archive if XDG_DATA_HOME is unset.
+func Dir() (string, error) {
+	dataDir, err := dirs.Data()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "archive"), nil
+}
+
+// Save copies the binary at src into the archive as name at version. An
+// existing copy of the same version is replaced.
+func Save(name, version, src string) (*Entry, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, name, version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create archive directory: %w", err)
+	}
+	dest := filepath.Join(dir, filepath.Base(src))
+	if err := copyFile(src, dest); err != nil {
+		return nil, fmt.Errorf("cannot archive %s: %w", name, err)
+	}
+	return entry(name, version, dest)
+}
+
+// List returns the archived copies of name, newest first. An empty name
+// lists every binary's copies, grouped by name.
+func List(name string) ([]Entry, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{name}
+	if name == "" {
+		names, err = subdirs(root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []Entry
+	for _, n := range names {
+		versions, err := subdirs(filepath.Join(root, n))
+		if err != nil {
+			return nil, err
+		}
+		var group []Entry
+		for _, v := range versions {
+			files, err := os.ReadDir(filepath.Join(root, n, v))
+			if err != nil || len(files) == 0 {
+				continue
+			}
+			e, err := entry(n, v, filepath.Join(root, n, v, files[0].Name()))
+			if err != nil {
+				continue
+			}
+			group = append(group, *e)
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].ArchivedAt.After(group[j].ArchivedAt) })
+		entries = append(entries, group...)
+	}
+	return entries, nil
+}
+
+// Find returns the archived copy of name at version, or the newest copy if
+// version is empty.
+func Find(name, version string) (*Entry, error) {
+	entries, err := List(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if version == "" || e.Version == version {
+			return &e, nil
+		}
+	}
+	if version == "" {
+		return nil, fmt.Errorf("no archived copies of %s", name)
+	}
+	return nil, fmt.Errorf("no archived copy of %s %s", name, version)
+}
+
+// Prune removes all but the keep newest copies of name (of every binary if
+// name is empty) and returns the removed entries.
+func Prune(name string, keep int) ([]Entry, error) {
+	entries, err := List(name)
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	kept := make(map[string]int)
+	for _, e := range entries {
+		if kept[e.Name] < keep {
+			kept[e.Name]++
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(e.Path)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	if root, err := Dir(); err == nil {
+		for n := range namesOf(entries) {
+			// Only removes the directory once it's empty.
+			os.Remove(filepath.Join(root, n))
+		}
+	}
+	return removed, nil
+}
+
+// Restore copies an archived binary to dest, replacing it atomically.
+func Restore(e *Entry, dest string) error {
+	tmp := dest + ".gomanager-restore"
+	if err := copyFile(e.Path, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot restore %s %s: %w", e.Name, e.Version, err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot restore %s %s: %w", e.Name, e.Version, err)
+	}
+	return nil
+}
+
+func entry(name, version, path string) (*Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Name: name, Version: version, Path: path, Size: info.Size(), ArchivedAt: info.ModTime()}, nil
+}
+
+// subdirs returns the names of the directories in dir, or nothing if dir
+// doesn't exist.
+func subdirs(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if f.IsDir() {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
+
+func namesOf(entries []Entry) map[string]bool {
+	names := make(map[string]bool)
+	for _, e := range entries {
+		names[e.Name] = true
+	}
+	return names
+}
+
+// copyFile copies src to dest with src's permissions. The copy's
+// modification time is the time of the copy, which orders archive entries.
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,228 @@
+// Package attest writes and verifies signed attestations of the tools a
+// manifest install provisioned: an in-toto statement listing each binary's
+// SHA-256 digest, package, and version, signed with Ed25519 in a DSSE
+// envelope.
+package attest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Identifiers of the attestation format.
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://github.com/jmelahman/gomanager/attestation/tools/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+)
+
+// ErrBadSignature is returned by Verify when no signature matches the key.
+var ErrBadSignature = errors.New("signature does not match the key")
+
+// Statement is an in-toto statement about installed binaries.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is an installed binary, identified by its file name and digest.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate describes how the subjects were provisioned.
+type Predicate struct {
+	// Manifest is the manifest file that was installed.
+	Manifest string `json:"manifest"`
+	// ManifestKey is the cache key of the manifest's tools and platform.
+	ManifestKey string `json:"manifestKey"`
+	// Platform is the GOOS/GOARCH the tools were installed for.
+	Platform string `json:"platform"`
+	// BinDir is the directory the tools were installed to.
+	BinDir string `json:"binDir"`
+	// Cached is set if the tools were already installed from an earlier
+	// run and nothing was built.
+	Cached    bool      `json:"cached"`
+	Timestamp time.Time `json:"timestamp"`
+	Tools     []Tool    `json:"tools"`
+}
+
+// Tool is a provisioned tool.
+type Tool struct {
+	Name    string `json:"name"`
+	Package string `json:"package,omitempty"`
+	Version string `json:"version,omitempty"`
+	// GoVersion is the Go toolchain the binary was built with, from its
+	// build info.
+	GoVersion string `json:"goVersion,omitempty"`
+	// Env lists the build environment variables it was built with.
+	Env []string `json:"env,omitempty"`
+	// SHA256 is the hex digest of the installed binary.
+	SHA256 string `json:"sha256"`
+}
+
+// NewStatement returns a statement about tools, with a subject per tool.
+func NewStatement(p Predicate) *Statement {
+	st := &Statement{Type: StatementType, PredicateType: PredicateType, Predicate: p}
+	for _, t := range p.Tools {
+		st.Subject = append(st.Subject, Subject{Name: t.Name, Digest: map[string]string{"sha256": t.SHA256}})
+	}
+	return st
+}
+
+// Envelope is a DSSE envelope holding a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature of an envelope's payload.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// Sign encodes st and signs it with key.
+func Sign(st *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := KeyID(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     payload,
+		Signatures:  []Signature{{KeyID: keyID, Sig: ed25519.Sign(key, pae(PayloadType, payload))}},
+	}, nil
+}
+
+// Verify checks that env is signed by pub and returns its statement.
+func Verify(env *Envelope, pub ed25519.PublicKey) (*Statement, error) {
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	signed := pae(env.PayloadType, env.Payload)
+	verified := false
+	for _, s := range env.Signatures {
+		if ed25519.Verify(pub, signed, s.Sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrBadSignature
+	}
+	var st Statement
+	if err := json.Unmarshal(env.Payload, &st); err != nil {
+		return nil, fmt.Errorf("invalid statement: %w", err)
+	}
+	if st.Type != StatementType || st.PredicateType != PredicateType {
+		return nil, fmt.Errorf("not a gomanager tools attestation")
+	}
+	return &st, nil
+}
+
+// pae returns the DSSE pre-authentication encoding of a payload, which is
+// what gets signed.
+func pae(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// KeyID identifies a public key by the SHA-256 digest of its DER encoding.
+func KeyID(pub ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadPrivateKey reads a PEM-encoded PKCS #8 Ed25519 private key, as
+// written by "openssl genpkey -algorithm ed25519".
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PEM-encoded PKIX Ed25519 public key, as written by
+// "openssl pkey -pubout".
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// readPEM returns the contents of the first PEM block of the given type in
+// the file at path.
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s has no %s block", path, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// FileDigest returns the hex SHA-256 digest of the file at path.
+func FileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,279 @@
+// Package bincache shares built binaries between machines. Binaries are
+// stored under a key derived from everything that affects the build (see
+// Key), next to a <key>.sha256 file holding the digest of their contents,
+// which is checked on every fetch.
+package bincache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrMiss is returned by Store.Get when the cache has no binary for a key.
+var ErrMiss = errors.New("not in cache")
+
+// Store is a cache backend holding objects by name.
+type Store interface {
+	// Get writes the object named name to dst, or returns ErrMiss.
+	Get(name, dst string) error
+	// Put uploads the file at src as the object named name.
+	Put(name, src string) error
+}
+
+// Key returns the cache key for a build of pkg at version for goos/goarch
+// with the given Go version and build environment (e.g. "CGO_ENABLED=0").
+func Key(pkg, version, goos, goarch, goVersion string, env []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s@%s\n%s/%s\n%s\n", pkg, version, goos, goarch, goVersion)
+	for _, e := range env {
+		fmt.Fprintf(h, "%s\n", e)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Open returns the store for a cache URL: a local directory (a path or
+// file:// URL), an http(s):// URL, s3://bucket/prefix, or gs://bucket/prefix.
+// HTTP requests send $GOMANAGER_CACHE_TOKEN as a bearer token if it is set;
+// S3 and GCS use the aws and gcloud CLIs and their configured credentials.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// A plain path (including a Windows drive letter)
+		return dirStore(rawURL), nil
+	}
+	switch u.Scheme {
+	case "file":
+		return dirStore(u.Path), nil
+	case "http", "https":
+		return &httpStore{base: strings.TrimSuffix(rawURL, "/"), token: os.Getenv("GOMANAGER_CACHE_TOKEN")}, nil
+	case "s3":
+		return &cliStore{base: strings.TrimSuffix(rawURL, "/"), command: []string{"aws", "s3", "cp", "--only-show-errors"}}, nil
+	case "gs":
+		return &cliStore{base: strings.TrimSuffix(rawURL, "/"), command: []string{"gcloud", "storage", "cp"}}, nil
+	}
+	return nil, fmt.Errorf("unsupported cache URL %q (want a directory, http(s)://, s3://, or gs://)", rawURL)
+}
+
+// Fetch downloads the binary for key to dst and checks it against its
+// recorded digest. It returns ErrMiss if the cache doesn't have it.
+func Fetch(s Store, key, dst string) error {
+	sumFile := dst + ".sha256"
+	defer os.Remove(sumFile)
+	if err := s.Get(key+".sha256", sumFile); err != nil {
+		return err
+	}
+	want, err := os.ReadFile(sumFile)
+	if err != nil {
+		return err
+	}
+	if err := s.Get(key, dst); err != nil {
+		return err
+	}
+	got, err := fileSHA256(dst)
+	if err != nil {
+		return err
+	}
+	if got != strings.TrimSpace(string(want)) {
+		os.Remove(dst)
+		return fmt.Errorf("cached binary %s does not match its recorded sha256", key)
+	}
+	return nil
+}
+
+// Upload stores the binary at src under key, along with its digest. The
+// digest is written last, so a partial upload is never fetched.
+func Upload(s Store, key, src string) error {
+	sum, err := fileSHA256(src)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(key, src); err != nil {
+		return err
+	}
+	sumFile, err := os.CreateTemp("", "gomanager-cache-*.sha256")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sumFile.Name())
+	if _, err := sumFile.WriteString(sum + "\n"); err != nil {
+		sumFile.Close()
+		return err
+	}
+	if err := sumFile.Close(); err != nil {
+		return err
+	}
+	return s.Put(key+".sha256", sumFile.Name())
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dirStore keeps objects as files in a directory, such as a shared volume.
+type dirStore string
+
+func (d dirStore) Get(name, dst string) error {
+	err := copyFile(filepath.Join(string(d), name), dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrMiss
+	}
+	return err
+}
+
+func (d dirStore) Put(name, src string) error {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return err
+	}
+	// Copy to a temporary file first so readers never see a partial object.
+	tmp := filepath.Join(string(d), "."+name+".tmp")
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(d), name))
+}
+
+// copyFile copies src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// httpStore fetches objects with GET and uploads them with PUT.
+type httpStore struct {
+	base  string
+	token string
+}
+
+// client is used for cache requests. Binaries can be large, so the timeout
+// is generous.
+var client = &http.Client{Timeout: 10 * time.Minute}
+
+func (h *httpStore) do(method, name string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, h.base+"/"+name, body)
+	if err != nil {
+		return nil, err
+	}
+	// Send a Content-Length rather than a chunked body, which many servers
+	// (and presigned object store URLs) reject.
+	req.ContentLength = size
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	return client.Do(req)
+}
+
+func (h *httpStore) Get(name, dst string) error {
+	resp, err := h.do(http.MethodGet, name, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrMiss
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s/%s: HTTP %d", h.base, name, resp.StatusCode)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (h *httpStore) Put(name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := h.do(http.MethodPut, name, f, fi.Size())
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT %s/%s: HTTP %d", h.base, name, resp.StatusCode)
+	}
+	return nil
+}
+
+// cliStore copies objects with a cloud provider's CLI (aws s3 cp, gcloud
+// storage cp), so its usual credential configuration applies.
+type cliStore struct {
+	base    string
+	command []string
+}
+
+// missMarkers are substrings of the CLIs' error output for a missing object.
+var missMarkers = []string{"404", "Not Found", "NoSuchKey", "No URLs matched", "does not exist"}
+
+func (c *cliStore) cp(src, dst string) error {
+	args := append(append([]string{}, c.command[1:]...), src, dst)
+	cmd := exec.Command(c.command[0], args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		for _, m := range missMarkers {
+			if strings.Contains(msg, m) {
+				return ErrMiss
+			}
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s %s: %s", strings.Join(c.command, " "), src, msg)
+	}
+	return nil
+}
+
+func (c *cliStore) Get(name, dst string) error {
+	return c.cp(c.base+"/"+name, dst)
+}
+
+func (c *cliStore) Put(name, src string) error {
+	return c.cp(src, c.base+"/"+name)
+}
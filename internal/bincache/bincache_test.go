@@ -0,0 +1,87 @@
+package bincache
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadFetch(t *testing.T) {
+	srv := httptest.NewServer(&Server{Dir: t.TempDir(), Token: "secret"})
+	defer srv.Close()
+	for name, s := range map[string]Store{
+		"dir":  dirStore(filepath.Join(t.TempDir(), "cache")),
+		"http": &httpStore{base: srv.URL, token: "secret"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			work := t.TempDir()
+			src := filepath.Join(work, "hello")
+			if err := os.WriteFile(src, []byte("binary"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			key := Key("github.com/acme/hello", "v1.0.0", "linux", "amd64", "go1.25.0", nil)
+			dst := filepath.Join(work, "fetched")
+			if err := Fetch(s, key, dst); !errors.Is(err, ErrMiss) {
+				t.Fatalf("Fetch before Upload = %v, want ErrMiss", err)
+			}
+			if err := Upload(s, key, src); err != nil {
+				t.Fatal(err)
+			}
+			if err := Fetch(s, key, dst); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(dst); string(data) != "binary" {
+				t.Errorf("fetched %q, want binary", data)
+			}
+
+			// A corrupted object fails its digest check and isn't kept.
+			if err := os.WriteFile(src, []byte("tampered"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put(key, src); err != nil {
+				t.Fatal(err)
+			}
+			if err := Fetch(s, key, dst); err == nil || errors.Is(err, ErrMiss) {
+				t.Errorf("Fetch of a corrupted object = %v, want a digest mismatch", err)
+			}
+			if _, err := os.Stat(dst); !os.IsNotExist(err) {
+				t.Errorf("Fetch kept the corrupted object: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPStoreUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(&Server{Dir: t.TempDir(), Token: "secret"})
+	defer srv.Close()
+	src := filepath.Join(t.TempDir(), "hello")
+	if err := os.WriteFile(src, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := &httpStore{base: srv.URL, token: "wrong"}
+	if err := Upload(s, testKey, src); err == nil {
+		t.Error("Upload with the wrong token succeeded")
+	}
+	if err := Fetch(s, testKey, filepath.Join(t.TempDir(), "fetched")); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("Fetch with the wrong token = %v, want an error other than a miss", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	key := Key("github.com/acme/hello", "v1.0.0", "linux", "amd64", "go1.25.0", []string{"CGO_ENABLED=0"})
+	if !objectName.MatchString(key) {
+		t.Errorf("Key = %q, not a valid object name", key)
+	}
+	for _, other := range []string{
+		Key("github.com/acme/hello", "v1.0.1", "linux", "amd64", "go1.25.0", []string{"CGO_ENABLED=0"}),
+		Key("github.com/acme/hello", "v1.0.0", "linux", "arm64", "go1.25.0", []string{"CGO_ENABLED=0"}),
+		Key("github.com/acme/hello", "v1.0.0", "linux", "amd64", "go1.24.0", []string{"CGO_ENABLED=0"}),
+		Key("github.com/acme/hello", "v1.0.0", "linux", "amd64", "go1.25.0", nil),
+	} {
+		if other == key {
+			t.Errorf("Key ignores part of the build: %s", key)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package bincache
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// objectName matches the names clients store: a hex key, optionally with
+// the .sha256 suffix of its digest file.
+var objectName = regexp.MustCompile(`^[0-9a-f]{64}(\.sha256)?$`)
+
+// Server serves a directory as a binary cache over HTTP: GET /<name>
+// downloads an object and PUT /<name> uploads one, the protocol of the
+// http(s):// store.
+type Server struct {
+	// Dir holds the cached objects.
+	Dir string
+	// Token is the bearer token required for uploads and, unless
+	// PublicRead is set, downloads. Empty disables authentication.
+	Token string
+	// PublicRead lets anyone download.
+	PublicRead bool
+	// MaxObjectSize is the largest upload accepted, in bytes. Zero means no
+	// limit.
+	MaxObjectSize int64
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if !objectName.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !s.PublicRead && !s.authorized(r) {
+			unauthorized(w)
+			return
+		}
+		s.get(w, r, name)
+	case http.MethodPut:
+		if !s.authorized(r) {
+			unauthorized(w)
+			return
+		}
+		s.put(w, r, name)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized reports whether r carries the server's token.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) == 1
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gomanager cache"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// get serves an object and marks it as used, so garbage collection keeps
+// recently fetched binaries.
+func (s *Server) get(w http.ResponseWriter, r *http.Request, name string) {
+	path := filepath.Join(s.Dir, name)
+	f, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+}
+
+// put stores an upload, replacing the object atomically once it has been
+// received in full.
+func (s *Server) put(w http.ResponseWriter, r *http.Request, name string) {
+	if s.MaxObjectSize > 0 && r.ContentLength > s.MaxObjectSize {
+		http.Error(w, "object too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := r.Body
+	if s.MaxObjectSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.MaxObjectSize)
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "object too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.Dir, name)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GCStats summarizes a garbage collection.
+type GCStats struct {
+	Removed int
+	Freed   int64
+	Kept    int
+	Size    int64
+}
+
+// GC removes cache entries (a binary and its digest) not used within
+// maxAge, then the least recently used entries until the cache is no
+// larger than maxSize. Zero disables either limit. Abandoned partial
+// uploads are always removed.
+func GC(dir string, maxAge time.Duration, maxSize int64) (GCStats, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return GCStats{}, nil
+	}
+	if err != nil {
+		return GCStats{}, err
+	}
+
+	type entry struct {
+		key   string
+		files []string
+		size  int64
+		used  time.Time
+	}
+	var stats GCStats
+	byKey := make(map[string]*entry)
+	for _, f := range files {
+		fi, err := f.Info()
+		if err != nil || f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if strings.HasPrefix(f.Name(), ".upload-") {
+			if time.Since(fi.ModTime()) > time.Hour && os.Remove(path) == nil {
+				stats.Freed += fi.Size()
+			}
+			continue
+		}
+		if !objectName.MatchString(f.Name()) {
+			continue
+		}
+		key := strings.TrimSuffix(f.Name(), ".sha256")
+		e := byKey[key]
+		if e == nil {
+			e = &entry{key: key}
+			byKey[key] = e
+		}
+		e.files = append(e.files, path)
+		e.size += fi.Size()
+		if fi.ModTime().After(e.used) {
+			e.used = fi.ModTime()
+		}
+	}
+
+	entries := make([]*entry, 0, len(byKey))
+	var total int64
+	for _, e := range byKey {
+		entries = append(entries, e)
+		total += e.size
+	}
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+
+	for _, e := range entries {
+		expired := maxAge > 0 && time.Since(e.used) > maxAge
+		oversize := maxSize > 0 && total > maxSize
+		if !expired && !oversize {
+			stats.Kept++
+			stats.Size += e.size
+			continue
+		}
+		for _, path := range e.files {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return stats, fmt.Errorf("cannot remove %s: %w", path, err)
+			}
+		}
+		total -= e.size
+		stats.Removed++
+		stats.Freed += e.size
+	}
+	return stats, nil
+}
@@ -0,0 +1,269 @@
+package bincache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey is a valid object name.
+var testKey = strings.Repeat("ab", 32)
+
+// serve sends a request for path with body (nil for none) and, if token
+// isn't empty, a bearer token to s, and returns the response.
+func serve(s *Server, method, path, token string, body io.Reader) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", body)
+	// Set the path as sent, without cleaning.
+	r.URL.Path = path
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+// readObject returns the contents of the object name in s, or "" if there
+// is none.
+func readObject(t *testing.T, s *Server, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// assertNoTemporaryFiles fails the test if an upload left a temporary
+// file in s's directory.
+func assertNoTemporaryFiles(t *testing.T, s *Server) {
+	t.Helper()
+	entries, _ := os.ReadDir(s.Dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".upload-") {
+			t.Errorf("upload left %s behind", e.Name())
+		}
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	s := &Server{Dir: t.TempDir(), Token: "secret"}
+	for _, token := range []string{"", "wrong"} {
+		w := serve(s, http.MethodPut, "/"+testKey, token, strings.NewReader("binary"))
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("PUT with token %q: HTTP %d, want 401 with a challenge", token, w.Code)
+		}
+		if got := readObject(t, s, testKey); got != "" {
+			t.Fatalf("unauthorized PUT stored %q", got)
+		}
+	}
+	// The token must be sent as a bearer token.
+	r := httptest.NewRequest(http.MethodPut, "/"+testKey, strings.NewReader("binary"))
+	r.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("PUT with a bare token: HTTP %d, want 401", w.Code)
+	}
+
+	if w := serve(s, http.MethodPut, "/"+testKey, "secret", strings.NewReader("binary")); w.Code != http.StatusCreated {
+		t.Fatalf("authorized PUT: HTTP %d, want 201", w.Code)
+	}
+	if got := readObject(t, s, testKey); got != "binary" {
+		t.Errorf("stored %q, want binary", got)
+	}
+	if w := serve(s, http.MethodGet, "/"+testKey, "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET without a token: HTTP %d, want 401", w.Code)
+	}
+	if w := serve(s, http.MethodGet, "/"+testKey, "secret", nil); w.Code != http.StatusOK || w.Body.String() != "binary" {
+		t.Errorf("authorized GET: HTTP %d %q, want 200 binary", w.Code, w.Body.String())
+	}
+	if w := serve(s, http.MethodDelete, "/"+testKey, "secret", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: HTTP %d, want 405", w.Code)
+	}
+}
+
+func TestServerPublicRead(t *testing.T) {
+	s := &Server{Dir: t.TempDir(), Token: "secret", PublicRead: true}
+	if err := os.WriteFile(filepath.Join(s.Dir, testKey), []byte("binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(s, http.MethodGet, "/"+testKey, "", nil); w.Code != http.StatusOK || w.Body.String() != "binary" {
+		t.Errorf("public GET: HTTP %d %q, want 200 binary", w.Code, w.Body.String())
+	}
+	if w := serve(s, http.MethodGet, "/"+testKey+".sha256", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("public GET of a missing object: HTTP %d, want 404", w.Code)
+	}
+	// Public reads don't open uploads.
+	if w := serve(s, http.MethodPut, "/"+testKey, "", strings.NewReader("evil")); w.Code != http.StatusUnauthorized {
+		t.Errorf("PUT without a token on a public cache: HTTP %d, want 401", w.Code)
+	}
+	if got := readObject(t, s, testKey); got != "binary" {
+		t.Errorf("object is %q after an unauthorized PUT, want binary", got)
+	}
+}
+
+func TestServerRejectsBadNames(t *testing.T) {
+	root := t.TempDir()
+	s := &Server{Dir: filepath.Join(root, "cache")}
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"/../x",
+		"/../secret",
+		"/" + testKey + "/../../x",
+		"/x",
+		"/" + strings.ToUpper(testKey),
+		"/" + testKey + ".tmp",
+		"/.upload-123",
+		"/" + testKey[:63],
+		"/",
+	} {
+		if w := serve(s, http.MethodPut, path, "", strings.NewReader("evil")); w.Code != http.StatusNotFound {
+			t.Errorf("PUT %s: HTTP %d, want 404", path, w.Code)
+		}
+		if w := serve(s, http.MethodGet, path, "", nil); w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("GET %s: HTTP %d %q, want 404", path, w.Code, w.Body.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "x")); err == nil {
+		t.Error("a PUT wrote outside the cache directory")
+	}
+	if _, err := os.Stat(s.Dir); err == nil {
+		t.Error("a rejected PUT created the cache directory")
+	}
+}
+
+func TestServerMaxObjectSize(t *testing.T) {
+	s := &Server{Dir: t.TempDir(), MaxObjectSize: 10}
+	if err := os.WriteFile(filepath.Join(s.Dir, testKey), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declared too large.
+	if w := serve(s, http.MethodPut, "/"+testKey, "", strings.NewReader(strings.Repeat("x", 11))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("PUT of 11 bytes: HTTP %d, want 413", w.Code)
+	}
+	// Too large without a declared length: the upload is cut off, and the
+	// object it would have replaced is kept.
+	r := httptest.NewRequest(http.MethodPut, "/"+testKey, strings.NewReader(strings.Repeat("x", 20)))
+	r.ContentLength = -1
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunked PUT of 20 bytes: HTTP %d, want 413", w.Code)
+	}
+	if got := readObject(t, s, testKey); got != "old" {
+		t.Errorf("object is %q after rejected uploads, want old", got)
+	}
+	assertNoTemporaryFiles(t, s)
+
+	if w := serve(s, http.MethodPut, "/"+testKey, "", strings.NewReader(strings.Repeat("x", 10))); w.Code != http.StatusCreated {
+		t.Errorf("PUT of 10 bytes: HTTP %d, want 201", w.Code)
+	}
+	if got := readObject(t, s, testKey); got != strings.Repeat("x", 10) {
+		t.Errorf("object is %q, want the new upload", got)
+	}
+	assertNoTemporaryFiles(t, s)
+}
+
+// failingReader returns data, then an error, like a client that drops the
+// connection mid-upload.
+type failingReader struct{ data io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.data.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func TestServerInterruptedUpload(t *testing.T) {
+	s := &Server{Dir: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(s.Dir, testKey), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(s, http.MethodPut, "/"+testKey, "", failingReader{strings.NewReader("partial")})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("interrupted PUT: HTTP %d, want 400", w.Code)
+	}
+	if got := readObject(t, s, testKey); got != "old" {
+		t.Errorf("object is %q after an interrupted upload, want old", got)
+	}
+	assertNoTemporaryFiles(t, s)
+}
+
+// writeAged writes name in dir with size bytes, last used age ago.
+func writeAged(t *testing.T, dir, name string, size int, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	used := time.Now().Add(-age)
+	if err := os.Chtimes(path, used, used); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exists reports whether name exists in dir.
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func TestGC(t *testing.T) {
+	dir := t.TempDir()
+	key := func(c string) string { return strings.Repeat(c, 64) }
+	// a is expired; b, c, and d are 100 bytes each with their digests,
+	// b least recently used.
+	writeAged(t, dir, key("a"), 100, 48*time.Hour)
+	writeAged(t, dir, key("a")+".sha256", 10, 48*time.Hour)
+	writeAged(t, dir, key("b"), 90, 3*time.Hour)
+	writeAged(t, dir, key("b")+".sha256", 10, 3*time.Hour)
+	writeAged(t, dir, key("c"), 90, 2*time.Hour)
+	writeAged(t, dir, key("c")+".sha256", 10, 2*time.Hour)
+	writeAged(t, dir, key("d"), 90, time.Hour)
+	writeAged(t, dir, key("d")+".sha256", 10, time.Hour)
+	writeAged(t, dir, ".upload-old", 50, 2*time.Hour)
+	writeAged(t, dir, ".upload-new", 50, time.Minute)
+	writeAged(t, dir, "README", 50, 48*time.Hour)
+
+	// c was fetched just now, so b is the least recently used.
+	s := &Server{Dir: dir}
+	if w := serve(s, http.MethodGet, "/"+key("c"), "", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET: HTTP %d", w.Code)
+	}
+
+	stats, err := GC(dir, 24*time.Hour, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		key("a"): false, key("a") + ".sha256": false,
+		key("b"): false, key("b") + ".sha256": false,
+		key("c"): true, key("c") + ".sha256": true,
+		key("d"): true, key("d") + ".sha256": true,
+		".upload-old": false, ".upload-new": true,
+		"README": true,
+	} {
+		if got := exists(dir, name); got != want {
+			t.Errorf("after GC, %s exists = %v, want %v", name, got, want)
+		}
+	}
+	want := GCStats{Removed: 2, Freed: 110 + 100 + 50, Kept: 2, Size: 200}
+	if stats != want {
+		t.Errorf("GC = %+v, want %+v", stats, want)
+	}
+
+	if stats, err := GC(filepath.Join(dir, "missing"), time.Hour, 0); err != nil || stats != (GCStats{}) {
+		t.Errorf("GC of a missing directory = %+v, %v, want nothing", stats, err)
+	}
+}
@@ -0,0 +1,266 @@
+// Package config loads the gomanager client configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jmelahman/gomanager/internal/dirs"
+)
+
+// Config is the client configuration, read from config.toml in the
+// gomanager config directory.
+type Config struct {
+	// Policy is the path to an organization policy file restricting which
+	// binaries may be installed. Relative paths are resolved against the
+	// config directory.
+	Policy string `toml:"policy"`
+	// Archive configures keeping copies of binaries replaced by upgrades.
+	Archive ArchiveConfig `toml:"archive"`
+	// Filter hides database entries by package path or owner.
+	Filter FilterConfig `toml:"filter"`
+	// System configures system-wide installs (--system).
+	System SystemConfig `toml:"system"`
+	// Cache configures the shared binary cache.
+	Cache CacheConfig `toml:"cache"`
+	// Database configures where the binary database is downloaded from.
+	Database DatabaseConfig `toml:"database"`
+	// Install holds defaults for install and upgrade.
+	Install InstallConfig `toml:"install"`
+	// GitHub configures access to the GitHub API.
+	GitHub GitHubConfig `toml:"github"`
+	// Telemetry configures reporting install outcomes to the curators.
+	Telemetry TelemetryConfig `toml:"telemetry"`
+}
+
+// DatabaseConfig is the [database] section of the configuration file.
+type DatabaseConfig struct {
+	// URL is where update-db downloads the database from. Empty means the
+	// published database.
+	URL string `toml:"url"`
+}
+
+// InstallConfig is the [install] section of the configuration file.
+type InstallConfig struct {
+	// BinDir is the directory binaries are installed to. Empty means where
+	// go install puts them (GOBIN, or the bin directory of GOPATH). A
+	// leading "~/" is expanded to the home directory.
+	BinDir string `toml:"bin_dir"`
+	// GOFLAGS are go command flags added to every build, before any the
+	// database records for a binary.
+	GOFLAGS string `toml:"goflags"`
+	// Jobs is the number of binaries built at once. Zero means one per
+	// CPU.
+	Jobs int `toml:"jobs"`
+	// AssumeYes skips confirming before installing or upgrading several
+	// binaries, like --yes.
+	AssumeYes bool `toml:"assume_yes"`
+	// AllowSumDBBypass lets GOSUMDB=off, GOINSECURE, -insecure in
+	// GOFLAGS, or a GONOSUMDB/GOPRIVATE exception in the go environment
+	// turn off checksum database verification of installs, which
+	// gomanager otherwise turns back on.
+	AllowSumDBBypass bool `toml:"allow_sumdb_bypass"`
+	// Verify smoke tests every installed or upgraded binary, like
+	// --verify.
+	Verify bool `toml:"verify"`
+}
+
+// GitHubConfig is the [github] section of the configuration file.
+type GitHubConfig struct {
+	// TokenCommand is run to get a GitHub token when GITHUB_TOKEN isn't
+	// set, e.g. "gh auth token". Its arguments are split on spaces; it
+	// isn't run by a shell.
+	TokenCommand string `toml:"token_command"`
+}
+
+// TelemetryConfig is the [telemetry] section of the configuration file.
+// Telemetry is opt-in: nothing is reported unless Endpoint is set.
+type TelemetryConfig struct {
+	// Endpoint is the URL anonymized install reports (package, version,
+	// platform, Go version, and failure class) are posted to.
+	Endpoint string `toml:"endpoint"`
+}
+
+// envOverrides maps environment variables to the settings they override.
+var envOverrides = []struct {
+	name string
+	set  func(c *Config, value string) error
+}{
+	{"GOMANAGER_DATABASE_URL", func(c *Config, v string) error { c.Database.URL = v; return nil }},
+	{"GOMANAGER_BIN_DIR", func(c *Config, v string) error { c.Install.BinDir = v; return nil }},
+	{"GOMANAGER_GOFLAGS", func(c *Config, v string) error { c.Install.GOFLAGS = v; return nil }},
+	{"GOMANAGER_JOBS", func(c *Config, v string) (err error) { c.Install.Jobs, err = strconv.Atoi(v); return err }},
+	{"GOMANAGER_ASSUME_YES", func(c *Config, v string) (err error) { c.Install.AssumeYes, err = strconv.ParseBool(v); return err }},
+	{"GOMANAGER_ALLOW_SUMDB_BYPASS", func(c *Config, v string) (err error) {
+		c.Install.AllowSumDBBypass, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"GOMANAGER_VERIFY", func(c *Config, v string) (err error) { c.Install.Verify, err = strconv.ParseBool(v); return err }},
+	{"GOMANAGER_GITHUB_TOKEN_COMMAND", func(c *Config, v string) error { c.GitHub.TokenCommand = v; return nil }},
+	{"GOMANAGER_CACHE_URL", func(c *Config, v string) error { c.Cache.URL = v; return nil }},
+	{"GOMANAGER_TELEMETRY_ENDPOINT", func(c *Config, v string) error { c.Telemetry.Endpoint = v; return nil }},
+}
+
+// EnvVars lists the environment variables that override configuration
+// settings.
+func EnvVars() []string {
+	names := make([]string, len(envOverrides))
+	for i, o := range envOverrides {
+		names[i] = o.name
+	}
+	return names
+}
+
+// CacheConfig is the [cache] section of the configuration file.
+type CacheConfig struct {
+	// URL locates the binary cache: a directory, an http(s):// URL, or an
+	// s3:// or gs:// bucket prefix. Empty disables the cache.
+	URL string `toml:"url"`
+	// ReadOnly stops uploading binaries built locally, for machines that
+	// should only consume the cache (e.g. developer laptops fed by CI).
+	ReadOnly bool `toml:"read_only"`
+}
+
+// Defaults for SystemConfig.
+const (
+	DefaultSystemPrefix = "/usr/local"
+	DefaultSystemState  = "/var/lib/gomanager/installed.json"
+)
+
+// SystemConfig is the [system] section of the configuration file.
+type SystemConfig struct {
+	// Prefix is the installation prefix for system-wide installs, which go
+	// in its bin directory. Empty means DefaultSystemPrefix.
+	Prefix string `toml:"prefix"`
+	// State is the path of the system-wide state file. Empty means
+	// DefaultSystemState.
+	State string `toml:"state"`
+}
+
+// ArchiveConfig is the [archive] section of the configuration file.
+type ArchiveConfig struct {
+	// Keep is the number of previous versions kept per binary. Zero (the
+	// default) disables archiving.
+	Keep int `toml:"keep"`
+}
+
+// FilterConfig is the [filter] section of the configuration file. It
+// narrows the published database to approved publishers: entries it
+// rejects are left out of search results and can't be installed or
+// upgraded.
+type FilterConfig struct {
+	// Include lists package path patterns to keep. When Include or Owners
+	// is set, only entries matching one of them are kept.
+	Include []string `toml:"include"`
+	// Owners lists repository owners (the second element of the package
+	// path, e.g. "mycorp" in github.com/mycorp/tool) to keep.
+	Owners []string `toml:"owners"`
+	// Exclude lists package path patterns to hide, even if included.
+	Exclude []string `toml:"exclude"`
+}
+
+// Active reports whether the filter hides anything.
+func (f FilterConfig) Active() bool {
+	return len(f.Include) > 0 || len(f.Owners) > 0 || len(f.Exclude) > 0
+}
+
+// Allows reports whether the filter keeps the package at pkg.
+func (f FilterConfig) Allows(pkg string) bool {
+	for _, pattern := range f.Exclude {
+		if MatchPackage(pattern, pkg) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 && len(f.Owners) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if MatchPackage(pattern, pkg) {
+			return true
+		}
+	}
+	if parts := strings.SplitN(pkg, "/", 3); len(parts) >= 2 {
+		for _, owner := range f.Owners {
+			if strings.EqualFold(owner, parts[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchPackage reports whether pkg matches pattern. A pattern ending in
+// "/*" or "/..." matches every package under that path; otherwise it is a
+// path.Match glob, so "github.com/*/lazygit" matches one element.
+func MatchPackage(pattern, pkg string) bool {
+	for _, suffix := range []string{"/...", "/*"} {
+		if prefix, ok := strings.CutSuffix(pattern, suffix); ok {
+			if ok, _ := path.Match(prefix, pkg); ok {
+				return true
+			}
+			for dir := path.Dir(pkg); dir != "." && dir != "/"; dir = path.Dir(dir) {
+				if ok, _ := path.Match(prefix, dir); ok {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	ok, _ := path.Match(pattern, pkg)
+	return ok
+}
+
+// Path returns the path to the configuration file.
+func Path() (string, error) {
+	configDir, err := dirs.Config()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "config.toml"), nil
+}
+
+// Load reads the configuration file. A missing file yields an empty
+// configuration.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(path)
+}
+
+// LoadPath reads the configuration file at path. A missing file yields an
+// empty configuration. Settings set by GOMANAGER_* environment variables
+// (see EnvVars) override the file's.
+func LoadPath(path string) (*Config, error) {
+	var c Config
+	_, err := toml.DecodeFile(path, &c)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if c.Policy != "" && !filepath.IsAbs(c.Policy) {
+		c.Policy = filepath.Join(filepath.Dir(path), c.Policy)
+	}
+	for _, o := range envOverrides {
+		if v, ok := os.LookupEnv(o.name); ok {
+			if err := o.set(&c, v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", o.name, err)
+			}
+		}
+	}
+	if rest, ok := strings.CutPrefix(c.Install.BinDir, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot expand bin_dir: %w", err)
+		}
+		c.Install.BinDir = filepath.Join(home, rest)
+	}
+	return &c, nil
+}
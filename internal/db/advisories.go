@@ -0,0 +1,123 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// SeverityCritical is the advisory severity that blocks installs.
+const SeverityCritical = "CRITICAL"
+
+// Advisory is a known vulnerability affecting a range of a module's
+// versions, from the advisories table.
+type Advisory struct {
+	// ID is the advisory identifier, e.g. "GO-2024-2687" or a GHSA ID.
+	ID     string
+	Module string
+	// Severity is "CRITICAL", "HIGH", "MODERATE", "LOW", or "" if the
+	// advisory doesn't rate it.
+	Severity string
+	// Introduced is the first affected version, or "" for all versions
+	// before Fixed.
+	Introduced string
+	// Fixed is the first version with the fix, or "" if there is none.
+	Fixed   string
+	Summary string
+}
+
+// createAdvisoriesTable creates the advisories table, which holds known
+// vulnerabilities in the modules of listed binaries, one row per affected
+// version range. Unlike the admin bookkeeping tables it is kept in the slim
+// database so clients can refuse vulnerable versions.
+func createAdvisoriesTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS advisories (
+			id TEXT NOT NULL,
+			module TEXT NOT NULL,
+			severity TEXT,
+			introduced TEXT,
+			fixed TEXT,
+			summary TEXT
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("CREATE INDEX IF NOT EXISTS idx_advisories_module ON advisories(module)")
+	return err
+}
+
+// HasAdvisories reports whether the database has an advisories table.
+// Databases published before advisories were collected don't.
+func HasAdvisories(conn *sql.DB) bool {
+	var n int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='advisories'").Scan(&n)
+	return n > 0
+}
+
+// ReplaceAdvisories replaces the advisories recorded for module.
+func ReplaceAdvisories(conn *sql.DB, module string, advisories []Advisory) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM advisories WHERE module = ?`, module); err != nil {
+		return err
+	}
+	for _, a := range advisories {
+		_, err := tx.Exec(
+			`INSERT INTO advisories (id, module, severity, introduced, fixed, summary) VALUES (?, ?, ?, ?, ?, ?)`,
+			a.ID, module, a.Severity, a.Introduced, a.Fixed, a.Summary,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAdvisories returns the advisories recorded for module. It returns nil
+// if the database has no advisories table.
+func GetAdvisories(conn *sql.DB, module string) ([]Advisory, error) {
+	if !HasAdvisories(conn) {
+		return nil, nil
+	}
+	rows, err := conn.Query(
+		`SELECT id, module, COALESCE(severity,''), COALESCE(introduced,''), COALESCE(fixed,''), COALESCE(summary,'')
+		 FROM advisories WHERE module = ? ORDER BY id`,
+		module,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var advisories []Advisory
+	for rows.Next() {
+		var a Advisory
+		if err := rows.Scan(&a.ID, &a.Module, &a.Severity, &a.Introduced, &a.Fixed, &a.Summary); err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, a)
+	}
+	return advisories, rows.Err()
+}
+
+// Affects reports whether version is in the advisory's affected range.
+// Versions that aren't valid semver (e.g. "latest") are never affected.
+func (a Advisory) Affects(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	if a.Introduced != "" && semver.Compare(version, a.Introduced) < 0 {
+		return false
+	}
+	return a.Fixed == "" || semver.Compare(version, a.Fixed) < 0
+}
+
+// Critical reports whether the advisory is rated critical.
+func (a Advisory) Critical() bool {
+	return strings.EqualFold(a.Severity, SeverityCritical)
+}
@@ -1,15 +1,26 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	_ "modernc.org/sqlite"
+	"github.com/jmelahman/gomanager/internal/dirs"
+	"modernc.org/sqlite"
 )
 
+// ErrNotFound is returned (wrapped) when a lookup matches no binary.
+var ErrNotFound = errors.New("not found in database")
+
 // Binary represents a row from the binaries table.
 type Binary struct {
 	ID          int
@@ -23,19 +34,99 @@ type Binary struct {
 	BuildStatus string
 	BuildFlags  string
 	BuildError  string
+	// BuildStrategy names the verify strategy that produced a confirmed
+	// build (e.g. "default", "cgo-disabled").
+	BuildStrategy string
+	// Archived reports whether the upstream repository is archived.
+	Archived bool
+	// LastVerified is when the build was last verified, or the zero time if
+	// it never has been.
+	LastVerified time.Time
+	// LDFlags holds the linker flags declared by the project's release
+	// configuration (e.g. goreleaser), which may contain template variables.
+	LDFlags string
+	// OwnerType is the GitHub account type of the repository owner
+	// ("Organization" or "User"), or empty if unknown.
+	OwnerType string
+	// TrustScore is a composite 0-100 score derived from the repository's
+	// owner, popularity, and security posture, or -1 if not yet scored.
+	TrustScore int
+	// License is the SPDX identifier of the repository's license, or empty
+	// if unknown.
+	License string
+	// VerifiedVersion is the version the last verification built, or empty
+	// if it isn't known.
+	VerifiedVersion string
+	// BuildDuration is how long the last confirmed build took, or zero if
+	// it wasn't recorded.
+	BuildDuration time.Duration
+	// DownloadSize is the total size in bytes of the module zips the last
+	// confirmed build needed, or zero if it wasn't recorded.
+	DownloadSize int64
+	// PlatformSupport records, by "goos/goarch", whether the package
+	// cross-built for that platform when last verified. It is nil if no
+	// platforms were checked.
+	PlatformSupport map[string]bool
+	// Confidence is a 0-100 score of how sure the database is that the
+	// entry is a working, intended binary, combining its discovery
+	// provenance, verification results, and curation; -1 if not yet scored.
+	Confidence int
+	// StatusReason is a short explanation of the build status for clients,
+	// e.g. why a package needs code generation. Unlike BuildError it is
+	// kept in the slim database.
+	StatusReason string
+	// RunOnly reports that go install failed but go run of the same
+	// package and version compiles, so the tool is usable with
+	// 'gomanager run' though not installable.
+	RunOnly bool
+	// Homepage is the project website from the repository's GitHub
+	// metadata, or empty if it has none.
+	Homepage string
+	// LongDescription is the first paragraph of the project's README, a
+	// fuller summary than Description, or empty if unknown.
+	LongDescription string
+	// SumDB records whether the module verified against the public
+	// checksum database (sum.golang.org) when last built: SumDBVerified,
+	// SumDBMissing, SumDBMismatch, or empty if unknown.
+	SumDB string
+	// Capabilities are the Linux file capabilities the binary needs to
+	// work, in setcap's text form (e.g. "cap_net_raw,cap_net_admin+ep"),
+	// or empty if it needs none.
+	Capabilities string
+	// PrivilegeNote explains what the binary needs elevated privileges or
+	// Capabilities for, or empty if it needs none.
+	PrivilegeNote string
+	// Tags are the repository's GitHub topics (e.g. "cli", "kubernetes"),
+	// lowercase and sorted.
+	Tags []string
+	// Local reports that the entry was read from the user's local overlay
+	// database rather than the published one. It is not stored.
+	Local bool
 }
 
-// DBPath returns the path to the local database file.
+// LowConfidence is the confidence score below which clients ask for
+// confirmation before installing.
+const LowConfidence = 50
+
+// TrustSignals are the inputs recorded alongside a binary's trust score.
+type TrustSignals struct {
+	// OwnerType is "Organization" or "User".
+	OwnerType string
+	// OwnerCreated is when the owner's account was created.
+	OwnerCreated time.Time
+	// WellKnown reports whether the owner is a well-known organization.
+	WellKnown bool
+	// Funded reports whether the repository has a .github/FUNDING.yml.
+	Funded bool
+	// Scorecard is the OpenSSF Scorecard score (0-10), or negative if the
+	// project hasn't been assessed.
+	Scorecard float64
+}
+
+// DBPath returns the path to the local database file, in the data
+// directory (see dirs.DataFile).
 func DBPath() (string, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", fmt.Errorf("cannot determine config directory: %w", err)
-	}
-	dir := filepath.Join(configDir, "gomanager")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return "", fmt.Errorf("cannot create config directory: %w", err)
-	}
-	return filepath.Join(dir, "database.db"), nil
+	return dirs.DataFile("database.db")
 }
 
 // Open opens the local database for reading.
@@ -47,8 +138,78 @@ func Open() (*sql.DB, error) {
 	return OpenPath(path)
 }
 
-// OpenPath opens a database at the given path.
+// OpenPath opens a database at the given path for reading; it never
+// modifies the file. It fails with an error wrapping ErrSchemaTooNew or
+// ErrSchemaTooOld if the database's schema version is outside the
+// supported range. A database lacking columns added since the binaries
+// table's first release is opened read-only, with the missing columns read
+// as NULL (see OpenForUpdate).
 func OpenPath(path string) (*sql.DB, error) {
+	conn, err := openSupported(path)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := tableColumns(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot read database columns: %w", err)
+	}
+	var missing []string
+	for _, col := range addedColumns {
+		if len(existing) > 0 && !existing[col.name] {
+			missing = append(missing, "NULL AS "+col.name)
+		}
+	}
+	if len(missing) == 0 {
+		return conn, nil
+	}
+	conn.Close()
+	dsn := "file:" + path + "?mode=ro"
+	legacyViews.Store(dsn, fmt.Sprintf(
+		"CREATE TEMP VIEW IF NOT EXISTS binaries AS SELECT *, %s FROM main.binaries",
+		strings.Join(missing, ", ")))
+	conn, err = sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	return conn, nil
+}
+
+// legacyViews maps the DSN of each database OpenPath opened without some of
+// addedColumns to the statement creating a temporary view that stands in
+// for its binaries table. Temporary views belong to a connection, so the
+// view is created on each connection database/sql opens.
+var legacyViews sync.Map
+
+func init() {
+	sqlite.RegisterConnectionHook(func(conn sqlite.ExecQuerierContext, dsn string) error {
+		stmt, ok := legacyViews.Load(dsn)
+		if !ok {
+			return nil
+		}
+		_, err := conn.ExecContext(context.Background(), stmt.(string), nil)
+		return err
+	})
+}
+
+// OpenForUpdate opens a database at the given path like OpenPath, but adds
+// any columns an older database lacks to it. It is for commands that write
+// to the database.
+func OpenForUpdate(path string) (*sql.DB, error) {
+	conn, err := openSupported(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateColumns(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot migrate database: %w", err)
+	}
+	return conn, nil
+}
+
+// openSupported opens the existing database at path, checking that its
+// schema version is supported.
+func openSupported(path string) (*sql.DB, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, fmt.Errorf("database not found at %s", path)
 	}
@@ -56,9 +217,99 @@ func OpenPath(path string) (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot open database: %w", err)
 	}
+	if err := CheckSchema(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
 	return conn, nil
 }
 
+// addedColumns lists columns added to the binaries table after its initial
+// release, with their definitions. Databases created before a column existed
+// are upgraded in place by migrateColumns.
+var addedColumns = []struct{ name, def string }{
+	{"ldflags", "TEXT"},
+	{"build_strategy", "TEXT"},
+	{"archived", "INTEGER DEFAULT 0"},
+	{"owner_type", "TEXT"},
+	{"owner_created", "TIMESTAMP"},
+	{"well_known", "INTEGER DEFAULT 0"},
+	{"funded", "INTEGER DEFAULT 0"},
+	{"scorecard", "REAL"},
+	{"trust_score", "INTEGER"},
+	{"license", "TEXT"},
+	{"verified_version", "TEXT"},
+	{"build_duration", "REAL"},
+	{"download_size", "INTEGER"},
+	{"discovery_query", "TEXT"},
+	{"platform_support", "TEXT"},
+	{"discovery_source", "TEXT"},
+	{"confidence", "INTEGER"},
+	{"status_reason", "TEXT"},
+	{"run_only", "INTEGER DEFAULT 0"},
+	{"homepage", "TEXT"},
+	{"long_description", "TEXT"},
+	{"sumdb_status", "TEXT"},
+	{"capabilities", "TEXT"},
+	{"privilege_note", "TEXT"},
+	{"tags", "TEXT"},
+}
+
+// columnBackfills holds statements run right after a column from
+// addedColumns is added, to populate it for existing rows.
+var columnBackfills = map[string]string{
+	// Confirmed builds verified after their last update were built at their
+	// current version.
+	"verified_version": `UPDATE binaries SET verified_version = version
+		WHERE build_status = 'confirmed'
+		  AND COALESCE(last_verified, '') >= COALESCE(updated_at, '')`,
+}
+
+// tableColumns returns the names of the binaries table's columns, or an
+// empty set if the table doesn't exist.
+func tableColumns(conn *sql.DB) (map[string]bool, error) {
+	rows, err := conn.Query("SELECT name FROM pragma_table_info('binaries')")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
+
+// migrateColumns adds any columns from addedColumns that are missing from an
+// existing binaries table. It is a no-op if the table doesn't exist yet.
+func migrateColumns(conn *sql.DB) error {
+	existing, err := tableColumns(conn)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	for _, col := range addedColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := conn.Exec(fmt.Sprintf("ALTER TABLE binaries ADD COLUMN %s %s", col.name, col.def)); err != nil {
+			return fmt.Errorf("add column %s: %w", col.name, err)
+		}
+		if backfill, ok := columnBackfills[col.name]; ok {
+			if _, err := conn.Exec(backfill); err != nil {
+				return fmt.Errorf("backfill column %s: %w", col.name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // CreatePath creates (if needed) and opens a database at the given path.
 // Unlike OpenPath, this does not error if the file does not exist yet.
 func CreatePath(path string) (*sql.DB, error) {
@@ -69,9 +320,10 @@ func CreatePath(path string) (*sql.DB, error) {
 	return conn, nil
 }
 
-// InitSchema creates the binaries table and indexes if they don't exist.
+// InitSchema creates the binaries table and indexes if they don't exist, and
+// adds any columns and statuses missing from an older existing table.
 func InitSchema(conn *sql.DB) error {
-	_, err := conn.Exec(`
+	_, err := conn.Exec(fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS binaries (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
@@ -82,23 +334,92 @@ func InitSchema(conn *sql.DB) error {
 			stars INTEGER DEFAULT 0,
 			is_primary INTEGER DEFAULT 1,
 			build_status TEXT DEFAULT 'unknown'
-				CHECK(build_status IN ('unknown','confirmed','failed','pending','regressed')),
+				CHECK(build_status IN (%s)),
 			build_flags TEXT DEFAULT '{}',
 			build_error TEXT,
+			ldflags TEXT,
+			build_strategy TEXT,
+			archived INTEGER DEFAULT 0,
+			owner_type TEXT,
+			owner_created TIMESTAMP,
+			well_known INTEGER DEFAULT 0,
+			funded INTEGER DEFAULT 0,
+			scorecard REAL,
+			trust_score INTEGER,
+			license TEXT,
+			verified_version TEXT,
+			build_duration REAL,
+			download_size INTEGER,
+			discovery_query TEXT,
+			platform_support TEXT,
+			discovery_source TEXT,
+			confidence INTEGER,
+			status_reason TEXT,
+			run_only INTEGER DEFAULT 0,
+			homepage TEXT,
+			long_description TEXT,
+			sumdb_status TEXT,
+			capabilities TEXT,
+			privilege_note TEXT,
+			tags TEXT,
 			last_verified TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
-	`)
+	`, quoteStatuses(Statuses)))
 	if err != nil {
 		return err
 	}
-	for _, idx := range []string{
-		"CREATE INDEX IF NOT EXISTS idx_package ON binaries(package)",
-		"CREATE INDEX IF NOT EXISTS idx_name ON binaries(name)",
-		"CREATE INDEX IF NOT EXISTS idx_build_status ON binaries(build_status)",
-		"CREATE INDEX IF NOT EXISTS idx_stars ON binaries(stars)",
-	} {
+	if err := migrateStatusCheck(conn); err != nil {
+		return err
+	}
+	if err := migrateColumns(conn); err != nil {
+		return err
+	}
+	if err := createHistoryTable(conn); err != nil {
+		return err
+	}
+	if err := createQueryStatsTable(conn); err != nil {
+		return err
+	}
+	if err := createEventsTable(conn); err != nil {
+		return err
+	}
+	if err := createAdvisoriesTable(conn); err != nil {
+		return err
+	}
+	if err := createModuleSumsTable(conn); err != nil {
+		return err
+	}
+	if err := createInstallReportsTable(conn); err != nil {
+		return err
+	}
+	if err := createFeedbackTable(conn); err != nil {
+		return err
+	}
+	if err := createJobsTable(conn); err != nil {
+		return err
+	}
+	if err := createLookupsTable(conn); err != nil {
+		return err
+	}
+	if err := stampSchemaVersion(conn); err != nil {
+		return err
+	}
+	return createIndexes(conn)
+}
+
+// indexes are created by InitSchema and MigrateSchema.
+var indexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_package ON binaries(package)",
+	"CREATE INDEX IF NOT EXISTS idx_name ON binaries(name)",
+	"CREATE INDEX IF NOT EXISTS idx_build_status ON binaries(build_status)",
+	"CREATE INDEX IF NOT EXISTS idx_stars ON binaries(stars)",
+	"CREATE INDEX IF NOT EXISTS idx_last_verified ON binaries(build_status, last_verified)",
+}
+
+func createIndexes(conn *sql.DB) error {
+	for _, idx := range indexes {
 		if _, err := conn.Exec(idx); err != nil {
 			return err
 		}
@@ -106,16 +427,18 @@ func InitSchema(conn *sql.DB) error {
 	return nil
 }
 
-// UpsertBinary inserts or updates a binary. On conflict (package), is_primary
-// is preserved so manual curation is not overwritten by the scanner.
+// UpsertBinary inserts or updates a binary. New packages are quarantined
+// until a maintainer approves them. On conflict (package), is_primary and
+// the build status are preserved so manual curation is not overwritten by
+// the scanner.
 func UpsertBinary(conn *sql.DB, name, pkg, version, description, repoURL string, stars int, isPrimary bool) error {
 	primary := 0
 	if isPrimary {
 		primary = 1
 	}
 	_, err := conn.Exec(`
-		INSERT INTO binaries (name, package, version, description, repo_url, stars, is_primary)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO binaries (name, package, version, description, repo_url, stars, is_primary, build_status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'quarantined')
 		ON CONFLICT(package) DO UPDATE SET
 			version = excluded.version,
 			description = excluded.description,
@@ -146,28 +469,37 @@ func GetExistingPackages(conn *sql.DB) (map[string]bool, error) {
 }
 
 // selectCols is the standard column list for binary queries.
+// Numeric columns are cast so values of an unexpected type (e.g. from a
+// newer schema) read as numbers instead of failing the scan.
 const selectCols = `id, name, package, COALESCE(version,'latest'),
         COALESCE(description,''), COALESCE(repo_url,''),
-        COALESCE(stars,0), COALESCE(is_primary,1),
+        COALESCE(CAST(stars AS INTEGER),0), COALESCE(CAST(is_primary AS INTEGER),1),
         COALESCE(build_status,'unknown'),
-        COALESCE(build_flags,'{}'), COALESCE(build_error,'')`
+        COALESCE(build_flags,'{}'), COALESCE(build_error,''),
+        COALESCE(ldflags,''), COALESCE(build_strategy,''),
+        COALESCE(last_verified,''), COALESCE(CAST(archived AS INTEGER),0),
+        COALESCE(owner_type,''), COALESCE(CAST(trust_score AS INTEGER),-1),
+        COALESCE(license,''), COALESCE(verified_version,''),
+        COALESCE(CAST(build_duration AS REAL),0), COALESCE(CAST(download_size AS INTEGER),0),
+        COALESCE(platform_support,''), COALESCE(CAST(confidence AS INTEGER),-1),
+        COALESCE(status_reason,''), COALESCE(CAST(run_only AS INTEGER),0),
+        COALESCE(homepage,''), COALESCE(long_description,''), COALESCE(sumdb_status,''),
+        COALESCE(capabilities,''), COALESCE(privilege_note,''), COALESCE(tags,'')`
 
-// GetUnverified returns binaries that need build verification.
+// GetUnverified returns binaries with the given build statuses for build
+// verification, at most limit (all if negative) of them in schedule order:
+// see Schedule.Priority.
 func GetUnverified(conn *sql.DB, statuses []string, limit int) ([]Binary, error) {
-	placeholders := make([]string, len(statuses))
 	args := make([]any, len(statuses))
 	for i, s := range statuses {
-		placeholders[i] = "?"
 		args[i] = s
 	}
-	args = append(args, limit)
 
 	query := fmt.Sprintf(
 		`SELECT %s FROM binaries
 		 WHERE build_status IN (%s)
-		 ORDER BY stars DESC
-		 LIMIT ?`,
-		selectCols, strings.Join(placeholders, ","),
+		 ORDER BY package`,
+		selectCols, placeholders(len(statuses)),
 	)
 
 	rows, err := conn.Query(query, args...)
@@ -175,16 +507,25 @@ func GetUnverified(conn *sql.DB, statuses []string, limit int) ([]Binary, error)
 		return nil, err
 	}
 	defer rows.Close()
-	return scanBinaries(rows)
+	binaries, err := scanBinaries(rows)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleBinaries(conn, binaries, limit)
 }
 
 // UpdateBuildResult updates the build status for a binary after verification.
+// The binary's current version is recorded as the verified version, and any
+// status reason and run-only capability are cleared.
 func UpdateBuildResult(conn *sql.DB, id int, status string, flags string, buildErr string) error {
 	_, err := conn.Exec(
 		`UPDATE binaries SET
 			build_status = ?,
 			build_flags = ?,
 			build_error = ?,
+			status_reason = NULL,
+			run_only = 0,
+			verified_version = version,
 			last_verified = datetime('now')
 		 WHERE id = ?`,
 		status, flags, buildErr, id,
@@ -192,15 +533,141 @@ func UpdateBuildResult(conn *sql.DB, id int, status string, flags string, buildE
 	return err
 }
 
-// Search finds binaries matching a query string.
-func Search(conn *sql.DB, query string) ([]Binary, error) {
-	q := "%" + strings.ToLower(query) + "%"
+// UpdateBuildCost records how long a confirmed build took and the size of
+// the module downloads it needed. A zero size leaves the recorded size
+// unchanged.
+func UpdateBuildCost(conn *sql.DB, id int, duration time.Duration, downloadSize int64) error {
+	_, err := conn.Exec(
+		`UPDATE binaries SET
+			build_duration = ?,
+			download_size = CASE WHEN ? > 0 THEN ? ELSE download_size END
+		 WHERE id = ?`,
+		duration.Seconds(), downloadSize, downloadSize, id,
+	)
+	return err
+}
+
+// UpdatePlatformSupport records which "goos/goarch" platforms a package
+// cross-built for.
+func UpdatePlatformSupport(conn *sql.DB, id int, platforms map[string]bool) error {
+	data, err := json.Marshal(platforms)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(`UPDATE binaries SET platform_support = ? WHERE id = ?`, string(data), id)
+	return err
+}
+
+// UpdateBuildStrategy records which verify strategy produced a confirmed
+// build.
+func UpdateBuildStrategy(conn *sql.DB, id int, strategy string) error {
+	_, err := conn.Exec(`UPDATE binaries SET build_strategy = ? WHERE id = ?`, strategy, id)
+	return err
+}
+
+// searchRank orders Search results by how well they match the query (?2,
+// lowercased; ?1 is its LIKE pattern): an exact name first, then
+// names starting with it, names containing it, package paths containing
+// it, and last descriptions containing it. Within each, confirmed builds
+// come before untested ones, and failed builds last.
+const searchRank = `CASE
+		WHEN LOWER(name) = ?2 THEN 0
+		WHEN instr(LOWER(name), ?2) = 1 THEN 1
+		WHEN instr(LOWER(name), ?2) > 0 THEN 2
+		WHEN LOWER(package) LIKE ?1 THEN 3
+		ELSE 4
+	END,
+	CASE COALESCE(build_status, 'unknown')
+		WHEN 'confirmed' THEN 0
+		WHEN 'failed' THEN 2
+		WHEN 'regressed' THEN 2
+		ELSE 1
+	END`
+
+// SearchFilter narrows Search results. The zero value matches everything.
+type SearchFilter struct {
+	// MinStars and MinTrust are the lowest star count and trust score to
+	// include; unscored binaries are excluded by a positive MinTrust.
+	MinStars int
+	MinTrust int
+	// Statuses, if not empty, are the build statuses to include.
+	Statuses []string
+	// Licenses, if not empty, are the SPDX license identifiers to include,
+	// compared case-insensitively. Binaries of unknown license are
+	// excluded.
+	Licenses []string
+	// Tags are tags each included binary must have all of.
+	Tags []string
+}
+
+// Empty reports whether f matches everything.
+func (f SearchFilter) Empty() bool {
+	return f.MinStars <= 0 && f.MinTrust <= 0 &&
+		len(f.Statuses) == 0 && len(f.Licenses) == 0 && len(f.Tags) == 0
+}
+
+// where returns the SQL conditions of f, joined with AND and each
+// preceded by it, and their arguments. The conditions' parameters are
+// numbered from first, so that they can follow numbered ones.
+func (f SearchFilter) where(first int) (string, []any) {
+	var conds []string
+	var args []any
+	param := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("?%d", first+len(args)-1)
+	}
+	if f.MinStars > 0 {
+		conds = append(conds, "COALESCE(CAST(stars AS INTEGER),0) >= "+param(f.MinStars))
+	}
+	if f.MinTrust > 0 {
+		conds = append(conds, "COALESCE(CAST(trust_score AS INTEGER),-1) >= "+param(f.MinTrust))
+	}
+	if len(f.Statuses) > 0 {
+		list := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			list[i] = param(status)
+		}
+		conds = append(conds, "COALESCE(build_status,'unknown') IN ("+strings.Join(list, ",")+")")
+	}
+	if len(f.Licenses) > 0 {
+		list := make([]string, len(f.Licenses))
+		for i, license := range f.Licenses {
+			list[i] = param(strings.ToLower(strings.TrimSpace(license)))
+		}
+		conds = append(conds, "LOWER(COALESCE(license,'')) IN ("+strings.Join(list, ",")+")")
+	}
+	for _, tag := range NormalizeTags(f.Tags) {
+		conds = append(conds, "instr(',' || COALESCE(tags,'') || ',', "+param(","+tag+",")+") > 0")
+	}
+	var sb strings.Builder
+	for _, c := range conds {
+		sb.WriteString(" AND " + c)
+	}
+	return sb.String(), args
+}
+
+// placeholders returns n comma-separated SQL placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// published restricts a query on binaries to packages clients may see,
+// leaving out quarantined packages awaiting approval.
+const published = `COALESCE(build_status,'') != '` + StatusQuarantined + `'`
+
+// Search finds binaries whose name, package path, or description contains
+// the query and that pass f, best matches first (see searchRank), then by
+// stars. Quarantined packages are never found.
+func Search(conn *sql.DB, query string, f SearchFilter) ([]Binary, error) {
+	q := strings.ToLower(query)
+	filter, filterArgs := f.where(3)
 	rows, err := conn.Query(
 		fmt.Sprintf(
 			`SELECT %s FROM binaries
-			 WHERE LOWER(name) LIKE ? OR LOWER(package) LIKE ? OR LOWER(description) LIKE ?
-			 ORDER BY stars DESC`, selectCols),
-		q, q, q,
+			 WHERE (LOWER(name) LIKE ?1 OR LOWER(package) LIKE ?1 OR LOWER(description) LIKE ?1)
+			   AND %s%s
+			 ORDER BY %s, stars DESC, package`, selectCols, published, filter, searchRank),
+		append([]any{"%" + q + "%", q}, filterArgs...)...,
 	)
 	if err != nil {
 		return nil, err
@@ -210,17 +677,27 @@ func Search(conn *sql.DB, query string) ([]Binary, error) {
 }
 
 // GetByName finds a binary by exact name. If multiple packages share the
-// same name, the one with the most stars is returned.
+// same name, the one with the most stars is returned. Quarantined packages
+// are never found; admin commands use GetAnyByName.
 func GetByName(conn *sql.DB, name string) (*Binary, error) {
+	return getByName(conn, name, published)
+}
+
+// GetAnyByName is GetByName including quarantined packages.
+func GetAnyByName(conn *sql.DB, name string) (*Binary, error) {
+	return getByName(conn, name, "1")
+}
+
+func getByName(conn *sql.DB, name, visible string) (*Binary, error) {
 	row := conn.QueryRow(
 		fmt.Sprintf(
-			`SELECT %s FROM binaries WHERE LOWER(name) = LOWER(?)
-			 ORDER BY stars DESC LIMIT 1`, selectCols),
+			`SELECT %s FROM binaries WHERE LOWER(name) = LOWER(?) AND %s
+			 ORDER BY stars DESC, package LIMIT 1`, selectCols, visible),
 		name,
 	)
 	b, err := scanBinary(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("binary %q not found in database", name)
+		return nil, fmt.Errorf("binary %q %w", name, ErrNotFound)
 	}
 	if err != nil {
 		return nil, err
@@ -228,12 +705,13 @@ func GetByName(conn *sql.DB, name string) (*Binary, error) {
 	return b, nil
 }
 
-// FindByName returns all binaries matching the given name (case-insensitive).
+// FindByName returns all binaries matching the given name (case-insensitive),
+// leaving out quarantined packages.
 func FindByName(conn *sql.DB, name string) ([]Binary, error) {
 	rows, err := conn.Query(
 		fmt.Sprintf(
-			`SELECT %s FROM binaries WHERE LOWER(name) = LOWER(?)
-			 ORDER BY stars DESC`, selectCols),
+			`SELECT %s FROM binaries WHERE LOWER(name) = LOWER(?) AND %s
+			 ORDER BY stars DESC, package`, selectCols, published),
 		name,
 	)
 	if err != nil {
@@ -243,17 +721,27 @@ func FindByName(conn *sql.DB, name string) ([]Binary, error) {
 	return scanBinaries(rows)
 }
 
-// GetByPackage finds a binary by exact package path.
+// GetByPackage finds a binary by exact package path. Quarantined packages
+// are never found; admin commands use GetAnyByPackage.
 func GetByPackage(conn *sql.DB, pkg string) (*Binary, error) {
+	return getByPackage(conn, pkg, published)
+}
+
+// GetAnyByPackage is GetByPackage including quarantined packages.
+func GetAnyByPackage(conn *sql.DB, pkg string) (*Binary, error) {
+	return getByPackage(conn, pkg, "1")
+}
+
+func getByPackage(conn *sql.DB, pkg, visible string) (*Binary, error) {
 	row := conn.QueryRow(
 		fmt.Sprintf(
-			`SELECT %s FROM binaries WHERE package = ?
-			 LIMIT 1`, selectCols),
+			`SELECT %s FROM binaries WHERE package = ? AND %s
+			 LIMIT 1`, selectCols, visible),
 		pkg,
 	)
 	b, err := scanBinary(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("package %q not found in database", pkg)
+		return nil, fmt.Errorf("package %q %w", pkg, ErrNotFound)
 	}
 	if err != nil {
 		return nil, err
@@ -264,7 +752,7 @@ func GetByPackage(conn *sql.DB, pkg string) (*Binary, error) {
 // ListAll returns all binaries ordered by stars descending.
 func ListAll(conn *sql.DB) ([]Binary, error) {
 	rows, err := conn.Query(
-		fmt.Sprintf(`SELECT %s FROM binaries ORDER BY stars DESC`, selectCols),
+		fmt.Sprintf(`SELECT %s FROM binaries ORDER BY stars DESC, package`, selectCols),
 	)
 	if err != nil {
 		return nil, err
@@ -273,58 +761,148 @@ func ListAll(conn *sql.DB) ([]Binary, error) {
 	return scanBinaries(rows)
 }
 
-func scanBinary(row *sql.Row) (*Binary, error) {
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRow reads one row selected with selectCols.
+func scanRow(row rowScanner) (Binary, error) {
 	var b Binary
 	var isPrimary int
+	var lastVerified string
+	var archived int
+	var buildSeconds float64
+	var platforms string
+	var runOnly int
+	var tags string
 	err := row.Scan(&b.ID, &b.Name, &b.Package, &b.Version,
 		&b.Description, &b.RepoURL, &b.Stars, &isPrimary,
-		&b.BuildStatus, &b.BuildFlags, &b.BuildError)
+		&b.BuildStatus, &b.BuildFlags, &b.BuildError,
+		&b.LDFlags, &b.BuildStrategy, &lastVerified, &archived,
+		&b.OwnerType, &b.TrustScore, &b.License, &b.VerifiedVersion,
+		&buildSeconds, &b.DownloadSize, &platforms, &b.Confidence,
+		&b.StatusReason, &runOnly, &b.Homepage, &b.LongDescription, &b.SumDB,
+		&b.Capabilities, &b.PrivilegeNote, &tags)
 	b.IsPrimary = isPrimary != 0
+	b.Archived = archived != 0
+	b.RunOnly = runOnly != 0
+	if !KnownStatus(b.BuildStatus) {
+		b.BuildStatus = "unknown"
+	}
+	b.LastVerified = parseTimestamp(lastVerified)
+	b.BuildDuration = time.Duration(buildSeconds * float64(time.Second))
+	if platforms != "" {
+		json.Unmarshal([]byte(platforms), &b.PlatformSupport)
+	}
+	if tags != "" {
+		b.Tags = strings.Split(tags, ",")
+	}
+	return b, err
+}
+
+// timestampLayouts are the formats SQLite timestamps are stored in:
+// datetime('now') and CURRENT_TIMESTAMP produce the first.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a SQLite timestamp (stored in UTC), returning the
+// zero time for empty or unparseable values.
+func parseTimestamp(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// VersionConfirmed reports whether the binary's current version has been
+// confirmed to build, as opposed to an earlier version whose confirmed
+// status carried over when the version was bumped.
+func (b *Binary) VersionConfirmed() bool {
+	return b.BuildStatus == "confirmed" && b.VerifiedVersion == b.Version
+}
+
+// VerifiedAge returns how long ago the build was last verified and whether
+// it has been verified at all.
+func (b *Binary) VerifiedAge() (time.Duration, bool) {
+	if b.LastVerified.IsZero() {
+		return 0, false
+	}
+	return time.Since(b.LastVerified), true
+}
+
+func scanBinary(row *sql.Row) (*Binary, error) {
+	b, err := scanRow(row)
 	return &b, err
 }
 
 func scanBinaries(rows *sql.Rows) ([]Binary, error) {
 	var result []Binary
 	for rows.Next() {
-		var b Binary
-		var isPrimary int
-		if err := rows.Scan(&b.ID, &b.Name, &b.Package, &b.Version,
-			&b.Description, &b.RepoURL, &b.Stars, &isPrimary,
-			&b.BuildStatus, &b.BuildFlags, &b.BuildError); err != nil {
+		b, err := scanRow(rows)
+		if err != nil {
 			return nil, err
 		}
-		b.IsPrimary = isPrimary != 0
 		result = append(result, b)
 	}
 	return result, rows.Err()
 }
 
-// MigrateSchema updates the database schema to support the 'regressed' build status.
+// MigrateSchema brings an existing database up to the current schema: it adds
+// any missing build statuses and columns, the build_history, query_stats, and events
+// tables, and any missing indexes.
 func MigrateSchema(conn *sql.DB) error {
-	var tableSql string
-	err := conn.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='binaries'").Scan(&tableSql)
-	if err != nil {
-		return nil // table doesn't exist, nothing to migrate
+	if err := migrateStatusCheck(conn); err != nil {
+		return err
 	}
-	if !strings.Contains(tableSql, "CHECK") || strings.Contains(tableSql, "'regressed'") {
-		return nil // already has regressed or no CHECK constraint
+	var exists int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='binaries'").Scan(&exists)
+	if exists == 0 {
+		return nil
 	}
-
-	newSql := strings.Replace(tableSql,
-		"'unknown','confirmed','failed','pending'",
-		"'unknown','confirmed','failed','pending','regressed'", 1)
-
-	if _, err := conn.Exec("PRAGMA writable_schema = ON"); err != nil {
-		return fmt.Errorf("enable writable_schema: %w", err)
+	if err := migrateColumns(conn); err != nil {
+		return err
 	}
-	if _, err := conn.Exec("UPDATE sqlite_master SET sql = ? WHERE type='table' AND name='binaries'", newSql); err != nil {
-		conn.Exec("PRAGMA writable_schema = OFF")
-		return fmt.Errorf("update schema: %w", err)
+	if err := createHistoryTable(conn); err != nil {
+		return err
 	}
-	if _, err := conn.Exec("PRAGMA writable_schema = OFF"); err != nil {
-		return fmt.Errorf("disable writable_schema: %w", err)
+	if err := createQueryStatsTable(conn); err != nil {
+		return err
 	}
-	return nil
+	if err := createEventsTable(conn); err != nil {
+		return err
+	}
+	if err := createAdvisoriesTable(conn); err != nil {
+		return err
+	}
+	if err := createModuleSumsTable(conn); err != nil {
+		return err
+	}
+	if err := createInstallReportsTable(conn); err != nil {
+		return err
+	}
+	if err := createFeedbackTable(conn); err != nil {
+		return err
+	}
+	if err := createJobsTable(conn); err != nil {
+		return err
+	}
+	if err := createLookupsTable(conn); err != nil {
+		return err
+	}
+	if err := stampSchemaVersion(conn); err != nil {
+		return err
+	}
+	return createIndexes(conn)
 }
 
 // UpdateVersion updates the version for a specific package.
@@ -338,13 +916,36 @@ func UpdateVersion(conn *sql.DB, id int, newVersion string) error {
 
 // GetStaleConfirmed returns confirmed packages that were updated since their last verification.
 // These are packages whose version was bumped by update-versions and need re-testing.
+// At most limit (all if negative) are returned, in schedule order.
 func GetStaleConfirmed(conn *sql.DB, limit int) ([]Binary, error) {
 	rows, err := conn.Query(
 		fmt.Sprintf(`SELECT %s FROM binaries
 		 WHERE build_status = 'confirmed'
 		   AND updated_at > COALESCE(last_verified, '1970-01-01')
-		 ORDER BY stars DESC LIMIT ?`, selectCols),
-		limit,
+		 ORDER BY package`, selectCols),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	binaries, err := scanBinaries(rows)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleBinaries(conn, binaries, limit)
+}
+
+// GetVerifiedBefore returns confirmed packages whose last verification is
+// older than cutoff, stalest first. Toolchain updates break builds over time,
+// so these are re-verified even when their version hasn't changed.
+func GetVerifiedBefore(conn *sql.DB, cutoff time.Time, limit int) ([]Binary, error) {
+	rows, err := conn.Query(
+		fmt.Sprintf(`SELECT %s FROM binaries
+		 WHERE build_status = 'confirmed'
+		   AND COALESCE(last_verified, '1970-01-01') < ?
+		 ORDER BY COALESCE(last_verified, '1970-01-01') ASC, stars DESC, package
+		 LIMIT ?`, selectCols),
+		cutoff.UTC().Format("2006-01-02 15:04:05"), limit,
 	)
 	if err != nil {
 		return nil, err
@@ -353,6 +954,127 @@ func GetStaleConfirmed(conn *sql.DB, limit int) ([]Binary, error) {
 	return scanBinaries(rows)
 }
 
+// SetArchived records whether a binary's upstream repository is archived.
+func SetArchived(conn *sql.DB, id int, archived bool) error {
+	value := 0
+	if archived {
+		value = 1
+	}
+	_, err := conn.Exec(`UPDATE binaries SET archived = ? WHERE id = ?`, value, id)
+	return err
+}
+
+// SetLicense records the SPDX license identifier for a package.
+func SetLicense(conn *sql.DB, pkg, license string) error {
+	_, err := conn.Exec(`UPDATE binaries SET license = ? WHERE package = ?`, license, pkg)
+	return err
+}
+
+// SetTags records a package's tags, normalized by NormalizeTags. An empty
+// list is stored as is, marking the package's tags as recorded.
+func SetTags(conn *sql.DB, pkg string, tags []string) error {
+	_, err := conn.Exec(`UPDATE binaries SET tags = ? WHERE package = ?`,
+		strings.Join(NormalizeTags(tags), ","), pkg)
+	return err
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty and duplicate ones
+// and any containing a comma, which separates them in the database, and
+// sorts the rest.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || strings.Contains(t, ",") || slices.Contains(normalized, t) {
+			continue
+		}
+		normalized = append(normalized, t)
+	}
+	slices.Sort(normalized)
+	return normalized
+}
+
+// SetProjectInfo records a package's homepage and README summary. Empty
+// strings are stored as is, marking the package as described.
+func SetProjectInfo(conn *sql.DB, pkg, homepage, longDescription string) error {
+	_, err := conn.Exec(`UPDATE binaries SET homepage = ?, long_description = ? WHERE package = ?`,
+		homepage, longDescription, pkg)
+	return err
+}
+
+// GetUndescribed returns the packages whose homepage and README summary, or
+// tags, haven't been recorded.
+func GetUndescribed(conn *sql.DB) ([]string, error) {
+	rows, err := conn.Query(`SELECT package FROM binaries WHERE long_description IS NULL OR tags IS NULL ORDER BY stars DESC, package`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var pkgs []string
+	for rows.Next() {
+		var pkg string
+		if err := rows.Scan(&pkg); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, rows.Err()
+}
+
+// UpdateTrust records the trust signals and composite score for a binary.
+func UpdateTrust(conn *sql.DB, id int, signals TrustSignals, score int) error {
+	var ownerCreated, scorecard any
+	if !signals.OwnerCreated.IsZero() {
+		ownerCreated = signals.OwnerCreated.UTC().Format("2006-01-02 15:04:05")
+	}
+	if signals.Scorecard >= 0 {
+		scorecard = signals.Scorecard
+	}
+	wellKnown, funded := 0, 0
+	if signals.WellKnown {
+		wellKnown = 1
+	}
+	if signals.Funded {
+		funded = 1
+	}
+	_, err := conn.Exec(
+		`UPDATE binaries SET
+			owner_type = ?,
+			owner_created = ?,
+			well_known = ?,
+			funded = ?,
+			scorecard = ?,
+			trust_score = ?
+		 WHERE id = ?`,
+		signals.OwnerType, ownerCreated, wellKnown, funded, scorecard, score, id,
+	)
+	return err
+}
+
+// SetStatusReason records the client-facing explanation of a binary's build
+// status. UpdateBuildResult clears it.
+func SetStatusReason(conn *sql.DB, id int, reason string) error {
+	_, err := conn.Exec(`UPDATE binaries SET status_reason = ? WHERE id = ?`, reason, id)
+	return err
+}
+
+// SetRunOnly records that a binary which failed go install compiles with
+// go run. UpdateBuildResult clears it.
+func SetRunOnly(conn *sql.DB, id int, runOnly bool) error {
+	v := 0
+	if runOnly {
+		v = 1
+	}
+	_, err := conn.Exec(`UPDATE binaries SET run_only = ? WHERE id = ?`, v, id)
+	return err
+}
+
+// UpdateConfidence stores a binary's confidence score.
+func UpdateConfidence(conn *sql.DB, id int, score int) error {
+	_, err := conn.Exec(`UPDATE binaries SET confidence = ? WHERE id = ?`, score, id)
+	return err
+}
+
 // PackageExists checks if a package path already exists in the database.
 func PackageExists(conn *sql.DB, pkg string) (bool, error) {
 	var count int
@@ -374,7 +1096,7 @@ func GetReposWithoutRoot(conn *sql.DB, limit int) ([]Binary, error) {
 		     WHERE b2.package = SUBSTR(b1.package, 1, INSTR(SUBSTR(b1.package, 12), '/') + 10)
 		   )
 		 GROUP BY SUBSTR(package, 1, INSTR(SUBSTR(package, 12), '/') + 10)
-		 ORDER BY stars DESC
+		 ORDER BY stars DESC, package
 		 LIMIT ?`, selectCols),
 		limit,
 	)
@@ -409,12 +1131,35 @@ func UpdatePackagePath(conn *sql.DB, id int, newPkg string) error {
 	return err
 }
 
+// RenameBinary changes the name of a binary.
+// Used to replace generic names seeded by the scanner.
+func RenameBinary(conn *sql.DB, id int, name string) error {
+	_, err := conn.Exec(
+		`UPDATE binaries SET name = ?, updated_at = datetime('now') WHERE id = ?`,
+		name, id,
+	)
+	return err
+}
+
 // DeleteBinary removes a binary entry by ID.
 func DeleteBinary(conn *sql.DB, id int) error {
 	_, err := conn.Exec(`DELETE FROM binaries WHERE id = ?`, id)
 	return err
 }
 
+// InstallName returns the file name go install gives the binary built from
+// pkg: its last path element, skipping a major version suffix.
+func InstallName(pkg string) string {
+	name := path.Base(pkg)
+	if majorVersionElem.MatchString(name) && path.Dir(pkg) != "." {
+		name = path.Base(path.Dir(pkg))
+	}
+	return name
+}
+
+// majorVersionElem matches a major version path element such as "v2".
+var majorVersionElem = regexp.MustCompile(`^v[0-9]+$`)
+
 // InstallCommand returns the full install command string for a binary,
 // including any required environment flags.
 func (b *Binary) InstallCommand() string {
@@ -430,6 +1175,30 @@ func (b *Binary) InstallCommand() string {
 	return cmd
 }
 
+// SeedBuildFlags records build flags and ldflags discovered from a project's
+// release configuration. Flags are only written for packages that have not
+// been verified yet and have no flags recorded, so verification results and
+// manual curation always take precedence.
+func SeedBuildFlags(conn *sql.DB, pkg, flags, ldflags string) error {
+	_, err := conn.Exec(
+		`UPDATE binaries SET
+			build_flags = CASE
+				WHEN COALESCE(build_flags,'{}') = '{}'
+				 AND COALESCE(build_status,'unknown') IN ('unknown','pending')
+				THEN ? ELSE build_flags END,
+			ldflags = CASE WHEN COALESCE(ldflags,'') = '' THEN ? ELSE ldflags END
+		 WHERE package = ?`,
+		flags, ldflags, pkg,
+	)
+	return err
+}
+
+// IsAllowedBuildEnv reports whether an environment variable may be recorded
+// in, and applied from, the build_flags field.
+func IsAllowedBuildEnv(key string) bool {
+	return allowedBuildEnv[key]
+}
+
 // allowedBuildEnv is the set of environment variable names that may be set
 // from the database build_flags field. Anything outside this list is ignored
 // to prevent a compromised database from injecting dangerous variables like
@@ -443,22 +1212,35 @@ var allowedBuildEnv = map[string]bool{
 	"CXX":         true,
 	"CGO_CFLAGS":  true,
 	"CGO_LDFLAGS": true,
+	"GOFLAGS":     true,
+	"GOTOOLCHAIN": true,
 }
 
-// EnvFlags returns the environment variable prefix (e.g. "CGO_ENABLED=0")
-// parsed from the BuildFlags JSON field. Only allowlisted variable names
-// are included; unknown keys are silently dropped.
-func (b *Binary) EnvFlags() string {
+// allowedGoFlags is the set of flags that may appear in a GOFLAGS value from
+// the database. GOFLAGS can otherwise run arbitrary programs (-toolexec,
+// -exec), so every other flag is dropped.
+var allowedGoFlags = map[string]bool{
+	"-mod=mod":        true,
+	"-buildvcs=false": true,
+	"-trimpath":       true,
+	"-modcacherw":     true,
+}
+
+// EnvVars returns the environment variables (e.g. "CGO_ENABLED=0") parsed
+// from the BuildFlags JSON field, in the order they appear. Only allowlisted
+// variable names are included; unknown keys are silently dropped, as are
+// GOFLAGS entries outside allowedGoFlags.
+func (b *Binary) EnvVars() []string {
 	if b.BuildFlags == "" || b.BuildFlags == "{}" {
-		return ""
+		return nil
 	}
 	// Simple JSON parsing without importing encoding/json to keep it light
 	// BuildFlags format: {"KEY":"VALUE",...}
 	s := strings.Trim(b.BuildFlags, "{}")
 	if s == "" {
-		return ""
+		return nil
 	}
-	var parts []string
+	var vars []string
 	for _, pair := range strings.Split(s, ",") {
 		pair = strings.TrimSpace(pair)
 		kv := strings.SplitN(pair, ":", 2)
@@ -470,7 +1252,38 @@ func (b *Binary) EnvFlags() string {
 		if !allowedBuildEnv[key] {
 			continue
 		}
-		parts = append(parts, key+"="+val)
+		if key == "GOFLAGS" {
+			var kept []string
+			for _, f := range strings.Fields(val) {
+				if allowedGoFlags[f] {
+					kept = append(kept, f)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			val = strings.Join(kept, " ")
+		}
+		vars = append(vars, key+"="+val)
+	}
+	return vars
+}
+
+// EnvFlags returns the environment variable prefix (e.g. "CGO_ENABLED=0")
+// for display in a shell command. Values containing spaces are quoted.
+func (b *Binary) EnvFlags() string {
+	return FormatEnv(b.EnvVars())
+}
+
+// FormatEnv formats KEY=VALUE environment variables as a shell command
+// prefix, quoting values that contain spaces.
+func FormatEnv(vars []string) string {
+	quoted := make([]string, len(vars))
+	for i, v := range vars {
+		quoted[i] = v
+		if key, val, _ := strings.Cut(v, "="); strings.ContainsAny(val, " \t") {
+			quoted[i] = key + `="` + val + `"`
+		}
 	}
-	return strings.Join(parts, " ")
+	return strings.Join(quoted, " ")
 }
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// newerDB creates a database at a temporary path as a newer release might
+// have written it, running each statement after the current schema is
+// created, and returns its path.
+func newerDB(t *testing.T, stmts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "database.db")
+	conn, err := CreatePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := InitSchema(conn); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	if err := MigrateSchema(conn); err != nil {
+		t.Fatalf("MigrateSchema: %v", err)
+	}
+	for _, stmt := range stmts {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return path
+}
+
+// readAll opens the database at path as a client would and reads every
+// binary through each lookup.
+func readAll(t *testing.T, path string) []Binary {
+	t.Helper()
+	conn, err := OpenPath(path)
+	if err != nil {
+		t.Fatalf("OpenPath: %v", err)
+	}
+	defer conn.Close()
+	all, err := ListAll(conn)
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	for _, b := range all {
+		if _, err := GetByPackage(conn, b.Package); err != nil {
+			t.Errorf("GetByPackage(%q): %v", b.Package, err)
+		}
+		if _, err := FindByName(conn, b.Name); err != nil {
+			t.Errorf("FindByName(%q): %v", b.Name, err)
+		}
+	}
+	if _, err := Search(conn, "", SearchFilter{}); err != nil {
+		t.Errorf("Search: %v", err)
+	}
+	return all
+}
+
+func TestForwardCompatUnknownStatus(t *testing.T) {
+	// A newer release's CHECK constraint allows a status this build
+	// doesn't know.
+	path := newerDB(t,
+		`PRAGMA ignore_check_constraints = ON`,
+		`INSERT INTO binaries (name, package, version, build_status) VALUES ('widget', 'github.com/acme/widget', 'v1.0.0', 'sandboxed')`,
+	)
+	all := readAll(t, path)
+	if len(all) != 1 || all[0].BuildStatus != "unknown" {
+		t.Errorf("binaries = %+v, want one with status unknown", all)
+	}
+
+	// Writers keep the status rather than failing the constraint.
+	conn, err := OpenForUpdate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := MigrateSchema(conn); err != nil {
+		t.Fatalf("MigrateSchema: %v", err)
+	}
+	var status string
+	if err := conn.QueryRow(`SELECT build_status FROM binaries`).Scan(&status); err != nil || status != "sandboxed" {
+		t.Errorf("stored status = %q (%v), want sandboxed", status, err)
+	}
+}
+
+func TestForwardCompatExtraColumn(t *testing.T) {
+	path := newerDB(t,
+		`ALTER TABLE binaries ADD COLUMN attestation TEXT NOT NULL DEFAULT ''`,
+		`INSERT INTO binaries (name, package, version, stars, attestation) VALUES ('widget', 'github.com/acme/widget', 'v1.0.0', 10, 'sigstore')`,
+	)
+	all := readAll(t, path)
+	if len(all) != 1 || all[0].Name != "widget" || all[0].Stars != 10 {
+		t.Errorf("binaries = %+v, want widget with 10 stars", all)
+	}
+}
+
+func TestForwardCompatUnknownBuildData(t *testing.T) {
+	// Build settings for a strategy or toolchain this build doesn't know,
+	// and values stored with types it doesn't expect.
+	path := newerDB(t,
+		`INSERT INTO binaries (name, package, version, stars, build_status, build_flags, build_strategy, platform_support, trust_score)
+		 VALUES ('widget', 'github.com/acme/widget', 'v1.0.0', 'many', 'confirmed',
+		         '{"CGO_ENABLED":"0","GOEXPERIMENT":"arenas","ZIG_TARGET":"x86_64-linux-musl"}',
+		         'zig-cc', '{"linux/amd64":{"ok":true}}', 'high')`,
+		`INSERT INTO binaries (name, package, version, build_flags, platform_support)
+		 VALUES ('gizmo', 'github.com/acme/gizmo', 'v1.0.0', 'not json', 'not json')`,
+	)
+	all := readAll(t, path)
+	if len(all) != 2 {
+		t.Fatalf("got %d binaries, want 2", len(all))
+	}
+	byName := make(map[string]Binary)
+	for _, b := range all {
+		byName[b.Name] = b
+	}
+	widget := byName["widget"]
+	if widget.BuildStrategy != "zig-cc" || widget.Stars != 0 || widget.TrustScore != 0 {
+		t.Errorf("widget = %+v", widget)
+	}
+	if got := widget.EnvVars(); !slices.Equal(got, []string{"CGO_ENABLED=0"}) {
+		t.Errorf("widget EnvVars = %q, want only CGO_ENABLED=0", got)
+	}
+	gizmo := byName["gizmo"]
+	if got := gizmo.EnvVars(); len(got) != 0 {
+		t.Errorf("gizmo EnvVars = %q, want none", got)
+	}
+}
+
+func TestForwardCompatNewerSchemaVersion(t *testing.T) {
+	for _, v := range []string{strconv.Itoa(SchemaVersion + 1), "3.0", ""} {
+		path := newerDB(t)
+		conn, err := CreatePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetMeta(conn, "schema_version", v); err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+
+		for name, open := range map[string]func(string) (*sql.DB, error){"OpenPath": OpenPath, "OpenForUpdate": OpenForUpdate} {
+			conn, err := open(path)
+			switch {
+			case v == "":
+				// Databases without a version predate the meta table.
+				if err != nil {
+					t.Errorf("%s with no schema version: %v", name, err)
+				}
+			case v == "3.0":
+				if err == nil {
+					t.Errorf("%s with schema version %q succeeded, want an error", name, v)
+				}
+			case !errors.Is(err, ErrSchemaTooNew):
+				t.Errorf("%s with schema version %s: got %v, want ErrSchemaTooNew", name, v, err)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+}
+
+func TestOpenPathDoesNotMigrate(t *testing.T) {
+	path := newerDB(t,
+		`ALTER TABLE binaries DROP COLUMN tags`,
+		`ALTER TABLE binaries DROP COLUMN sumdb_status`,
+		`INSERT INTO binaries (name, package, version) VALUES ('widget', 'github.com/acme/widget', 'v1.0.0')`,
+	)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := readAll(t, path)
+	if len(all) != 1 || all[0].Tags != nil || all[0].SumDB != "" {
+		t.Errorf("binaries = %+v, want widget without tags or sumdb status", all)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("OpenPath modified the database")
+	}
+
+	conn, err := OpenForUpdate(path)
+	if err != nil {
+		t.Fatalf("OpenForUpdate: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`UPDATE binaries SET tags = 'cli', sumdb_status = ?`, SumDBVerified); err != nil {
+		t.Fatalf("OpenForUpdate didn't add the missing columns: %v", err)
+	}
+}
+
+func TestQuarantinedHiddenFromClients(t *testing.T) {
+	path := newerDB(t,
+		`INSERT INTO binaries (name, package, version, stars, build_status) VALUES ('widget', 'github.com/acme/widget', 'v1.0.0', 100, 'quarantined')`,
+		`INSERT INTO binaries (name, package, version, stars, build_status) VALUES ('widget', 'github.com/other/widget', 'v1.0.0', 1, 'confirmed')`,
+		`INSERT INTO binaries (name, package, version) VALUES ('gizmo', 'github.com/acme/gizmo', 'v1.0.0')`,
+	)
+	conn, err := OpenPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := GetByPackage(conn, "github.com/acme/widget"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByPackage of a quarantined package: got %v, want ErrNotFound", err)
+	}
+	if b, err := GetByName(conn, "widget"); err != nil || b.Package != "github.com/other/widget" {
+		t.Errorf("GetByName(widget) = %+v, %v, want the confirmed package", b, err)
+	}
+	if found, err := FindByName(conn, "widget"); err != nil || len(found) != 1 {
+		t.Errorf("FindByName(widget) = %+v, %v, want only the confirmed package", found, err)
+	}
+	found, err := Search(conn, "widget", SearchFilter{})
+	if err != nil || len(found) != 1 || found[0].Package != "github.com/other/widget" {
+		t.Errorf("Search(widget) = %+v, %v, want only the confirmed package", found, err)
+	}
+	// Rows without a status are still found.
+	if _, err := GetByPackage(conn, "github.com/acme/gizmo"); err != nil {
+		t.Errorf("GetByPackage of a package without a status: %v", err)
+	}
+
+	if b, err := GetAnyByPackage(conn, "github.com/acme/widget"); err != nil || b.BuildStatus != StatusQuarantined {
+		t.Errorf("GetAnyByPackage = %+v, %v, want the quarantined package", b, err)
+	}
+	if b, err := GetAnyByName(conn, "widget"); err != nil || b.Package != "github.com/acme/widget" {
+		t.Errorf("GetAnyByName(widget) = %+v, %v, want the quarantined package with more stars", b, err)
+	}
+}
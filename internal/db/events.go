@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Curation events recorded with RecordEvent.
+const (
+	// EventApproved is recorded when a quarantined package is approved; the
+	// detail is the status it was given.
+	EventApproved = "approved"
+	// EventRejected is recorded when a quarantined package is rejected and
+	// deleted.
+	EventRejected = "rejected"
+	// EventRenamed is recorded under a package's new path when
+	// fix-module-paths moves it; the detail is the old path.
+	EventRenamed = "renamed"
+	// EventDuplicate is recorded when fix-module-paths deletes a package
+	// whose corrected path already exists; the detail is that path.
+	EventDuplicate = "duplicate"
+	// EventNameFixed is recorded when fix-names renames a package's
+	// binary; the detail is the old name.
+	EventNameFixed = "name-fixed"
+	// EventFeedback is recorded when import-feedback queues a package
+	// because users reported failing to install it; the detail summarizes
+	// the reports.
+	EventFeedback = "feedback"
+)
+
+// Event is one curation action from the events table.
+type Event struct {
+	Package string
+	Event   string
+	Detail  string
+	At      time.Time
+}
+
+// createEventsTable creates the events table, a log of curation actions
+// (approvals, rejections, path fixes) taken on packages. Rows are kept after
+// their package is deleted so removals can still be explained.
+func createEventsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			package TEXT NOT NULL,
+			event TEXT NOT NULL,
+			detail TEXT,
+			at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("CREATE INDEX IF NOT EXISTS idx_events_package ON events(package, at)")
+	return err
+}
+
+// RecordEvent appends a curation event for a package.
+func RecordEvent(conn *sql.DB, pkg, event, detail string) error {
+	_, err := conn.Exec(
+		`INSERT INTO events (package, event, detail, at) VALUES (?, ?, ?, datetime('now'))`,
+		pkg, event, detail,
+	)
+	return err
+}
+
+// GetEvents returns the curation events recorded for a package, oldest
+// first.
+func GetEvents(conn *sql.DB, pkg string) ([]Event, error) {
+	rows, err := conn.Query(
+		`SELECT package, event, COALESCE(detail,''), COALESCE(at,'')
+		 FROM events WHERE package = ?
+		 ORDER BY at, id`,
+		pkg,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Event
+	for rows.Next() {
+		var e Event
+		var at string
+		if err := rows.Scan(&e.Package, &e.Event, &e.Detail, &at); err != nil {
+			return nil, err
+		}
+		e.At = parseTimestamp(at)
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// Provenance describes how a package came to be in the database.
+type Provenance struct {
+	// Query is the scanner search query that found the repository, or empty
+	// if it wasn't recorded.
+	Query string
+	// Source names the heuristic that detected the entrypoint, or empty if
+	// it wasn't recorded.
+	Source string
+	// AddedAt is when the row was created.
+	AddedAt time.Time
+}
+
+// GetProvenance returns the discovery provenance recorded for a package.
+func GetProvenance(conn *sql.DB, pkg string) (Provenance, error) {
+	var p Provenance
+	var added string
+	err := conn.QueryRow(
+		`SELECT COALESCE(discovery_query,''), COALESCE(discovery_source,''), COALESCE(created_at,'')
+		 FROM binaries WHERE package = ?`, pkg,
+	).Scan(&p.Query, &p.Source, &added)
+	if err == sql.ErrNoRows {
+		return p, fmt.Errorf("package %q %w", pkg, ErrNotFound)
+	}
+	if err != nil {
+		return p, err
+	}
+	p.AddedAt = parseTimestamp(added)
+	return p, nil
+}
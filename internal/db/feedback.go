@@ -0,0 +1,77 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Sources of user feedback.
+const (
+	// FeedbackTelemetry is a failed install reported by client telemetry.
+	FeedbackTelemetry = "telemetry"
+	// FeedbackIssue is a GitHub issue filed with the install failure
+	// template.
+	FeedbackIssue = "issue"
+)
+
+// Feedback is one report of a failed install from a user.
+type Feedback struct {
+	// Key identifies the report for deduplication: the issue URL, or for
+	// telemetry the package, version, platform, Go version, class, and day.
+	Key      string
+	Source   string
+	Package  string
+	Version  string
+	Class    string
+	Platform string
+	// Detail is the issue URL, if any.
+	Detail string
+}
+
+// createFeedbackTable creates the feedback table, which records the user
+// reports of failed installs import-feedback has processed, so that the
+// same report imported again isn't counted twice. It is admin bookkeeping
+// and dropped from the slim database.
+func createFeedbackTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS feedback (
+			key TEXT PRIMARY KEY,
+			source TEXT NOT NULL,
+			package TEXT NOT NULL,
+			version TEXT NOT NULL DEFAULT '',
+			class TEXT NOT NULL DEFAULT '',
+			platform TEXT NOT NULL DEFAULT '',
+			detail TEXT,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("CREATE INDEX IF NOT EXISTS idx_feedback_package ON feedback(package, version)")
+	return err
+}
+
+// RecordFeedback records f and reports whether it is new, i.e. no report
+// with the same key was recorded before.
+func RecordFeedback(conn *sql.DB, f Feedback) (bool, error) {
+	res, err := conn.Exec(
+		`INSERT INTO feedback (key, source, package, version, class, platform, detail, received_at)
+		 VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), datetime('now'))
+		 ON CONFLICT(key) DO NOTHING`,
+		f.Key, f.Source, f.Package, f.Version, f.Class, f.Platform, f.Detail,
+	)
+	if err != nil {
+		return false, fmt.Errorf("record feedback for %s: %w", f.Package, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FeedbackCount returns how many reports have been recorded for pkg at
+// version.
+func FeedbackCount(conn *sql.DB, pkg, version string) (int, error) {
+	var n int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM feedback WHERE package = ? AND version = ?`, pkg, version).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BuildRecord is one verification attempt from the build_history table.
+type BuildRecord struct {
+	Package   string
+	Version   string
+	Status    string
+	Error     string
+	GoVersion string
+	// Strategy names the verify strategy that produced a confirmed build.
+	Strategy   string
+	VerifiedAt time.Time
+}
+
+// createHistoryTable creates the build_history table. Unlike build_error on
+// binaries, rows here are never overwritten, so flaky failures can be told
+// apart from persistent ones.
+func createHistoryTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS build_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			package TEXT NOT NULL,
+			version TEXT,
+			status TEXT NOT NULL,
+			error TEXT,
+			go_version TEXT,
+			strategy TEXT,
+			verified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("CREATE INDEX IF NOT EXISTS idx_history_package ON build_history(package, verified_at)")
+	return err
+}
+
+// AppendBuildHistory records a verification attempt.
+func AppendBuildHistory(conn *sql.DB, r BuildRecord) error {
+	_, err := conn.Exec(
+		`INSERT INTO build_history (package, version, status, error, go_version, strategy, verified_at)
+		 VALUES (?, ?, ?, ?, ?, ?, datetime('now'))`,
+		r.Package, r.Version, r.Status, r.Error, r.GoVersion, r.Strategy,
+	)
+	return err
+}
+
+// GetBuildHistory returns up to limit verification attempts for a package,
+// newest first.
+func GetBuildHistory(conn *sql.DB, pkg string, limit int) ([]BuildRecord, error) {
+	rows, err := conn.Query(
+		`SELECT package, COALESCE(version,''), status, COALESCE(error,''),
+		        COALESCE(go_version,''), COALESCE(strategy,''), COALESCE(verified_at,'')
+		 FROM build_history WHERE package = ?
+		 ORDER BY verified_at DESC, id DESC
+		 LIMIT ?`,
+		pkg, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query build history: %w", err)
+	}
+	defer rows.Close()
+
+	var result []BuildRecord
+	for rows.Next() {
+		var r BuildRecord
+		var verifiedAt string
+		if err := rows.Scan(&r.Package, &r.Version, &r.Status, &r.Error,
+			&r.GoVersion, &r.Strategy, &verifiedAt); err != nil {
+			return nil, err
+		}
+		r.VerifiedAt = parseTimestamp(verifiedAt)
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// ConfirmedVersions maps each package to the versions verification has
+// confirmed to build, from the build history.
+func ConfirmedVersions(conn *sql.DB) (map[string][]string, error) {
+	rows, err := conn.Query(
+		`SELECT DISTINCT package, version FROM build_history
+		 WHERE status = 'confirmed' AND COALESCE(version,'') NOT IN ('', 'latest')
+		 ORDER BY package, version`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query build history: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[string][]string)
+	for rows.Next() {
+		var pkg, version string
+		if err := rows.Scan(&pkg, &version); err != nil {
+			return nil, err
+		}
+		versions[pkg] = append(versions[pkg], version)
+	}
+	return versions, rows.Err()
+}
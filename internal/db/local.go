@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/jmelahman/gomanager/internal/dirs"
+)
+
+// LocalPath returns the path to the local overlay database, which holds
+// entries the user added themselves (internal tools, personal forks). It
+// lives next to the published database but is never replaced by update-db.
+func LocalPath() (string, error) {
+	return dirs.DataFile("local.db")
+}
+
+// HasLocal reports whether a local overlay database exists.
+func HasLocal() bool {
+	path, err := LocalPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// OpenLocal opens the local overlay database, creating it if needed.
+func OpenLocal() (*sql.DB, error) {
+	path, err := LocalPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := CreatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := InitSchema(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot initialize local database: %w", err)
+	}
+	return conn, nil
+}
+
+// PutLocal adds b to the local overlay, replacing any entry with the same
+// package path or name.
+func PutLocal(conn *sql.DB, b Binary) error {
+	if _, err := conn.Exec(`DELETE FROM binaries WHERE package = ? OR LOWER(name) = LOWER(?)`, b.Package, b.Name); err != nil {
+		return err
+	}
+	// Local entries are never verified, so last_verified stays NULL.
+	_, err := conn.Exec(
+		`INSERT INTO binaries (name, package, version, description, repo_url, build_status, build_flags)
+		 VALUES (?, ?, ?, ?, ?, 'unknown', ?)`,
+		b.Name, b.Package, b.Version, b.Description, b.RepoURL, b.BuildFlags,
+	)
+	return err
+}
+
+// DeleteLocal removes the overlay entry with the given package path or
+// name, reporting whether there was one.
+func DeleteLocal(conn *sql.DB, arg string) (bool, error) {
+	res, err := conn.Exec(`DELETE FROM binaries WHERE package = ? OR LOWER(name) = LOWER(?)`, arg, arg)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// markLocal sets Local on binaries read from the overlay.
+func markLocal(binaries []Binary) []Binary {
+	for i := range binaries {
+		binaries[i].Local = true
+	}
+	return binaries
+}
+
+// LocalByNameOrPackage looks up an overlay entry by package path or name.
+func LocalByNameOrPackage(conn *sql.DB, arg string) (*Binary, error) {
+	b, err := GetByPackage(conn, arg)
+	if err != nil {
+		b, err = GetByName(conn, arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.Local = true
+	return b, nil
+}
+
+// ListLocal returns every overlay entry, ordered by name.
+func ListLocal(conn *sql.DB) ([]Binary, error) {
+	rows, err := conn.Query(fmt.Sprintf(`SELECT %s FROM binaries ORDER BY name`, selectCols))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	binaries, err := scanBinaries(rows)
+	return markLocal(binaries), err
+}
+
+// SearchLocal is Search over the overlay.
+func SearchLocal(conn *sql.DB, query string, f SearchFilter) ([]Binary, error) {
+	binaries, err := Search(conn, query, f)
+	return markLocal(binaries), err
+}
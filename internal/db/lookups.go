@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Package repositories discover checks names against, recorded as the
+// source of a lookup. Homebrew, nixpkgs, and Debian are only checked with
+// discover --distros.
+const (
+	LookupAUR    = "aur"
+	LookupArch   = "arch"
+	LookupBrew   = "brew"
+	LookupNix    = "nix"
+	LookupDebian = "debian"
+)
+
+// createLookupsTable creates the distro_lookups table, which caches whether
+// discover found a package name in a distribution's repositories, so reruns
+// only check names that are new or whose result has expired.
+func createLookupsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS distro_lookups (
+			name TEXT NOT NULL,
+			source TEXT NOT NULL,
+			found INTEGER NOT NULL,
+			checked_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (name, source)
+		)
+	`)
+	return err
+}
+
+// GetLookups returns the cached lookups from source checked within maxAge,
+// mapping each name to whether it was found.
+func GetLookups(conn *sql.DB, source string, maxAge time.Duration) (map[string]bool, error) {
+	cutoff := time.Now().Add(-maxAge).UTC().Format("2006-01-02 15:04:05")
+	rows, err := conn.Query(
+		`SELECT name, found FROM distro_lookups WHERE source = ? AND checked_at >= ?`,
+		source, cutoff,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lookups := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var found bool
+		if err := rows.Scan(&name, &found); err != nil {
+			return nil, err
+		}
+		lookups[name] = found
+	}
+	return lookups, rows.Err()
+}
+
+// RecordLookups caches the results of looking up names in source, each
+// mapped to whether it was found.
+func RecordLookups(conn *sql.DB, source string, results map[string]bool) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for name, found := range results {
+		_, err := tx.Exec(
+			`INSERT INTO distro_lookups (name, source, found, checked_at) VALUES (?, ?, ?, datetime('now'))
+			 ON CONFLICT(name, source) DO UPDATE SET found = excluded.found, checked_at = excluded.checked_at`,
+			name, source, found,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PruneHistory deletes build_history rows whose package is no longer in the
+// binaries table (e.g. after a module path fix or removal). It returns the
+// number of rows deleted.
+func PruneHistory(conn *sql.DB) (int64, error) {
+	res, err := conn.Exec(`DELETE FROM build_history
+		WHERE package NOT IN (SELECT package FROM binaries)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SplitErrors moves bulky build output out of the database into the SQLite
+// database at path: build errors go to its build_errors table and build
+// history rows to its build_history table. The moved data is then removed
+// from conn, so the published artifact only carries build statuses. Running
+// it again merges into the same file. It returns the number of build errors
+// moved.
+func SplitErrors(conn *sql.DB, path string) (int64, error) {
+	// ATTACH only applies to one connection, so pin one from the pool
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	if _, err := c.ExecContext(ctx, "ATTACH DATABASE ? AS side", path); err != nil {
+		return 0, fmt.Errorf("attach %s: %w", path, err)
+	}
+	defer c.ExecContext(ctx, "DETACH DATABASE side")
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS side.build_errors (
+			package TEXT PRIMARY KEY,
+			error TEXT,
+			last_verified TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS side.build_history AS
+			SELECT * FROM main.build_history WHERE 0`,
+		`INSERT OR REPLACE INTO side.build_errors (package, error, last_verified)
+			SELECT package, build_error, last_verified FROM main.binaries
+			WHERE COALESCE(build_error,'') != ''`,
+		`INSERT INTO side.build_history (package, version, status, error, go_version, strategy, verified_at)
+			SELECT package, version, status, error, go_version, strategy, verified_at
+			FROM main.build_history`,
+		`DELETE FROM main.build_history`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec(`UPDATE main.binaries SET build_error = NULL
+		WHERE COALESCE(build_error,'') != ''`)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return moved, tx.Commit()
+}
+
+// Compact refreshes query planner statistics and rebuilds the database file
+// to reclaim the space left by deleted rows and cleared columns.
+func Compact(conn *sql.DB) error {
+	if _, err := conn.Exec("ANALYZE"); err != nil {
+		return fmt.Errorf("analyze: %w", err)
+	}
+	if _, err := conn.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	return nil
+}
+
+// slimColumns are binaries columns only used by admin commands. They are
+// cleared in the slim client database.
+var slimColumns = []string{
+	"build_error", "owner_created", "well_known", "funded", "scorecard",
+	"discovery_query", "discovery_source",
+}
+
+// WriteSlim writes a slim client copy of the database to path: quarantined
+// packages, build errors, build history, scanner statistics, curation
+// events, install telemetry and user feedback, and admin-only
+// bookkeeping columns are removed, and the variant is recorded as
+// VariantSlim. path must not exist yet.
+func WriteSlim(conn *sql.DB, path string) error {
+	if _, err := conn.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("copy database: %w", err)
+	}
+
+	slim, err := OpenPath(path)
+	if err != nil {
+		return err
+	}
+	defer slim.Close()
+
+	sets := make([]string, len(slimColumns))
+	for i, col := range slimColumns {
+		sets[i] = col + " = NULL"
+	}
+	stmts := []string{
+		"DELETE FROM binaries WHERE build_status = '" + StatusQuarantined + "'",
+		"UPDATE binaries SET " + strings.Join(sets, ", "),
+		"DROP TABLE IF EXISTS build_history",
+		"DROP TABLE IF EXISTS query_stats",
+		"DROP TABLE IF EXISTS events",
+		"DROP TABLE IF EXISTS jobs",
+		"DROP TABLE IF EXISTS distro_lookups",
+		"DROP TABLE IF EXISTS install_reports",
+		"DROP TABLE IF EXISTS feedback",
+	}
+	for _, stmt := range stmts {
+		if _, err := slim.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if err := SetMeta(slim, "variant", VariantSlim); err != nil {
+		return err
+	}
+	return Compact(slim)
+}
+
+// Check looks for problems that should stop the database from being
+// published: SQLite integrity errors, an empty binaries table, and build
+// statuses this build doesn't know. It returns a description of each problem
+// found.
+func Check(conn *sql.DB) ([]string, error) {
+	var problems []string
+
+	rows, err := conn.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("integrity check: %w", err)
+	}
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, "integrity: "+msg)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts, err := StatusCounts(conn)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, status := range slices.Sorted(maps.Keys(counts)) {
+		n := counts[status]
+		total += n
+		if !KnownStatus(status) {
+			problems = append(problems, fmt.Sprintf("%d binaries have unknown status %q", n, status))
+		}
+	}
+	if total == 0 {
+		problems = append(problems, "the binaries table is empty")
+	} else if counts["confirmed"] == 0 {
+		problems = append(problems, "no binaries are confirmed")
+	}
+	return problems, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Database variants, recorded under the "variant" meta key.
+const (
+	// VariantFull is the admin database with build errors, build history,
+	// and scan bookkeeping. Databases without a variant are full.
+	VariantFull = "full"
+	// VariantSlim is the client database published for update-db.
+	VariantSlim = "slim"
+)
+
+// ErrSlimDatabase is returned (wrapped) when an operation needs the full
+// database but was given a slim one.
+var ErrSlimDatabase = errors.New("slim client database")
+
+// createMetaTable creates the key/value meta table.
+func createMetaTable(conn *sql.DB) error {
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	)`)
+	return err
+}
+
+// GetMeta returns the value stored under key in the meta table, or an empty
+// string if the key (or the table) doesn't exist.
+func GetMeta(conn *sql.DB, key string) (string, error) {
+	var exists int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='meta'").Scan(&exists)
+	if exists == 0 {
+		return "", nil
+	}
+	var value string
+	err := conn.QueryRow("SELECT COALESCE(value,'') FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetMeta stores value under key in the meta table, creating the table if
+// needed.
+func SetMeta(conn *sql.DB, key, value string) error {
+	if err := createMetaTable(conn); err != nil {
+		return err
+	}
+	_, err := conn.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// Variant returns the database variant (VariantFull or VariantSlim).
+func Variant(conn *sql.DB) (string, error) {
+	v, err := GetMeta(conn, "variant")
+	if err != nil {
+		return "", err
+	}
+	if v == "" {
+		return VariantFull, nil
+	}
+	return v, nil
+}
+
+// RequireFull returns an error wrapping ErrSlimDatabase unless conn is a
+// full database.
+func RequireFull(conn *sql.DB) error {
+	v, err := Variant(conn)
+	if err != nil {
+		return fmt.Errorf("cannot read database variant: %w", err)
+	}
+	if v != VariantFull {
+		return fmt.Errorf("database is a %w; admin commands need the full database", ErrSlimDatabase)
+	}
+	return nil
+}
+
+// SchemaVersion is the schema version written by this build. It is bumped
+// only for changes older readers can't cope with (renamed or retyped
+// columns, new required tables); added columns are handled by
+// migrateColumns and don't need a bump.
+const SchemaVersion = 2
+
+// MinSchemaVersion is the oldest schema version this build can open.
+// Databases without a recorded version predate the meta table and are
+// version 1.
+const MinSchemaVersion = 1
+
+// Errors returned (wrapped) by CheckSchema.
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than supported")
+	ErrSchemaTooOld = errors.New("database schema is older than supported")
+)
+
+// GetSchemaVersion returns the schema version recorded in the meta table,
+// or 1 for databases that predate it.
+func GetSchemaVersion(conn *sql.DB) (int, error) {
+	v, err := GetMeta(conn, "schema_version")
+	if err != nil {
+		return 0, err
+	}
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema_version %q", v)
+	}
+	return n, nil
+}
+
+// CheckSchema returns an error wrapping ErrSchemaTooNew or ErrSchemaTooOld
+// if the database's schema version is outside the range this build
+// supports.
+func CheckSchema(conn *sql.DB) error {
+	v, err := GetSchemaVersion(conn)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+	switch {
+	case v > SchemaVersion:
+		return fmt.Errorf("%w: version %d, this gomanager supports %d-%d; upgrade gomanager",
+			ErrSchemaTooNew, v, MinSchemaVersion, SchemaVersion)
+	case v < MinSchemaVersion:
+		return fmt.Errorf("%w: version %d, this gomanager supports %d-%d; re-run gomanager update-db",
+			ErrSchemaTooOld, v, MinSchemaVersion, SchemaVersion)
+	}
+	return nil
+}
+
+// stampSchemaVersion records SchemaVersion in the meta table. It never
+// lowers a recorded version.
+func stampSchemaVersion(conn *sql.DB) error {
+	v, err := GetSchemaVersion(conn)
+	if err != nil {
+		return err
+	}
+	if v >= SchemaVersion {
+		return nil
+	}
+	return SetMeta(conn, "schema_version", strconv.Itoa(SchemaVersion))
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+)
+
+// capabilitiesRE matches file capabilities in setcap's text form: one or
+// more comma-separated capability names, an operator, and flags, e.g.
+// "cap_net_raw,cap_net_admin+ep".
+var capabilitiesRE = regexp.MustCompile(`^cap_[a-z_]+(,cap_[a-z_]+)*[=+-][eip]+$`)
+
+// ValidCapabilities reports whether caps is a capability set setcap
+// accepts and clients may offer to grant.
+func ValidCapabilities(caps string) bool {
+	return capabilitiesRE.MatchString(caps)
+}
+
+// SetPrivileges records the file capabilities a package needs and why it
+// needs them or other elevated privileges. Empty values clear them.
+func SetPrivileges(conn *sql.DB, id int, caps, note string) error {
+	_, err := conn.Exec(`UPDATE binaries SET capabilities = NULLIF(?, ''), privilege_note = NULLIF(?, '') WHERE id = ?`,
+		caps, note, id)
+	return err
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrReadOnly is returned (wrapped) when an ad-hoc query tries to modify
+// the database.
+var ErrReadOnly = errors.New("queries are read-only")
+
+// QueryResult holds the rows of an ad-hoc query.
+type QueryResult struct {
+	Columns []string
+	// Rows hold each row's values by column: nil for NULL, or an int64,
+	// float64, string, []byte, or time.Time.
+	Rows [][]any
+}
+
+// OpenQueryOnly opens the database at path for ad-hoc queries: the file is
+// opened read-only, the connection refuses writes, and it can't attach
+// other databases, so a mistyped statement can't modify or create anything.
+func OpenQueryOnly(path string) (*sql.DB, error) {
+	conn, err := OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	// PRAGMA query_only and limits apply to one connection, so use only one.
+	conn.SetMaxOpenConns(1)
+	if err := restrictQueries(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot make the connection read-only: %w", err)
+	}
+	return conn, nil
+}
+
+// restrictQueries makes conn's connection refuse writes and ATTACH. SQLite
+// creates the file an ATTACH names before query_only can refuse writes to
+// it, so attaching is disabled outright.
+func restrictQueries(conn *sql.DB) error {
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if _, err := c.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return err
+	}
+	_, err = sqlite.Limit(c, sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+	return err
+}
+
+// RunQuery runs query on a connection from OpenQueryOnly and returns every
+// row. A statement that would write fails with an error wrapping
+// ErrReadOnly.
+func RunQuery(conn *sql.DB, query string) (*QueryResult, error) {
+	// VACUUM INTO creates its file before the write is refused, and ATTACH
+	// is refused by OpenQueryOnly anyway; reject both before running
+	// anything.
+	for _, keyword := range statementKeywords(query) {
+		if keyword == "ATTACH" || keyword == "VACUUM" {
+			return nil, fmt.Errorf("%w: %s is not allowed", ErrReadOnly, keyword)
+		}
+	}
+	rows, err := conn.Query(query)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: cols}
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err)
+	}
+	return result, nil
+}
+
+// statementKeywords returns the first keyword of each statement in query,
+// in upper case, skipping comments and quoted strings and identifiers.
+func statementKeywords(query string) []string {
+	var keywords []string
+	start := true // at the start of a statement
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return keywords
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return keywords
+			}
+			i += end + 4
+		case c == ';':
+			start = true
+			i++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			quote := c
+			if quote == '[' {
+				quote = ']'
+			}
+			// A doubled quote inside a string reads as two adjacent
+			// strings, which is just as well here.
+			end := strings.IndexByte(query[i+1:], quote)
+			if end < 0 {
+				return keywords
+			}
+			i += end + 2
+			start = false
+		default:
+			j := i
+			for j < len(query) && isWordByte(query[j]) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			if start {
+				keywords = append(keywords, strings.ToUpper(query[i:j]))
+			}
+			start = false
+			i = j
+		}
+	}
+	return keywords
+}
+
+// isWordByte reports whether c can be part of an SQL keyword.
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// queryError wraps the errors SQLite gives for writes in ErrReadOnly.
+func queryError(err error) error {
+	msg := err.Error()
+	if strings.Contains(msg, "readonly") || strings.Contains(msg, "read-only") ||
+		strings.Contains(msg, "query_only") || strings.Contains(msg, "too many attached databases") {
+		return fmt.Errorf("%w: %v", ErrReadOnly, err)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestQueryCreatesNoFiles(t *testing.T) {
+	path := newerDB(t, `INSERT INTO binaries (name, package) VALUES ('hello', 'github.com/acme/hello')`)
+	conn, err := OpenQueryOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	result, err := RunQuery(conn, "SELECT name FROM binaries")
+	if err != nil || len(result.Rows) != 1 {
+		t.Fatalf("SELECT = %v, %v, want one row", result, err)
+	}
+
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.db")
+	for _, query := range []string{
+		"INSERT INTO binaries (name, package) VALUES ('x', 'x')",
+		"ATTACH '" + other + "' AS z",
+		"ATTACH '" + other + "' AS z; CREATE TABLE z.t(x)",
+		"SELECT 1; /* ; */ attach 'file:" + other + "?mode=rwc' AS z",
+		"VACUUM INTO '" + other + "'",
+		"-- comment\nvacuum main into '" + other + "'",
+	} {
+		if _, err := RunQuery(conn, query); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want ErrReadOnly", query, err)
+		}
+	}
+	// The connection refuses ATTACH even if a statement gets past RunQuery.
+	if _, err := conn.Exec("ATTACH '" + other + "' AS z"); err == nil || !errors.Is(queryError(err), ErrReadOnly) {
+		t.Errorf("ATTACH on the connection: got %v, want it refused", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("read-only queries created %s", entries[0].Name())
+	}
+}
+
+func TestStatementKeywords(t *testing.T) {
+	for query, want := range map[string][]string{
+		"SELECT 1":                        {"SELECT"},
+		"  select 1; attach 'x' as y;":    {"SELECT", "ATTACH"},
+		"SELECT ';attach'; VACUUM":        {"SELECT", "VACUUM"},
+		`SELECT "a;b", [c;d], ` + "`e;f`": {"SELECT"},
+		"-- attach\nSELECT 1":             {"SELECT"},
+		"/* ; attach */ SELECT 1":         {"SELECT"},
+		"SELECT 'it''s'; Vacuum":          {"SELECT", "VACUUM"},
+		"SELECT 'unterminated; ATTACH":    {"SELECT"},
+		"":                                nil,
+	} {
+		if got := statementKeywords(query); !slices.Equal(got, want) {
+			t.Errorf("statementKeywords(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Reasons a verification job was enqueued.
+const (
+	JobDiscovered = "discovered"
+	JobRootProbe  = "root-probe"
+	JobUpdated    = "version-update"
+	JobApproved   = "approved"
+	JobSeeded     = "seeded"
+	JobRequeued   = "requeued"
+	JobFeedback   = "feedback"
+)
+
+// MaxJobAttempts is how many failed builds a job is retried for before it
+// is left in the queue as dead.
+const MaxJobAttempts = 5
+
+// JobLease is how long a claimed job is reserved for its worker. Jobs whose
+// worker died are claimable again after it expires.
+const JobLease = time.Hour
+
+// Job is a pending verification in the jobs table.
+type Job struct {
+	Package    string
+	Reason     string
+	Attempts   int
+	LastError  string
+	EnqueuedAt time.Time
+	// NotBefore is when a failed job may be retried, or the zero time.
+	NotBefore time.Time
+	// ClaimedAt is when a worker claimed the job, or the zero time.
+	ClaimedAt time.Time
+	// Priority orders claimable jobs, highest first; see JobPriority.
+	Priority float64
+	// Quarantined reports that the package awaits approval, so the job
+	// can't be claimed yet.
+	Quarantined bool
+}
+
+// Dead reports whether the job has used up its retries.
+func (j Job) Dead() bool {
+	return j.Attempts >= MaxJobAttempts
+}
+
+// createJobsTable creates the jobs table, the queue of packages waiting for
+// build verification. Producers (scan, probe-roots, update-versions,
+// approve) enqueue packages and verify --queue consumes them, so a run
+// doesn't have to query the whole binaries table to find its work.
+func createJobsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			package TEXT NOT NULL UNIQUE,
+			reason TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			enqueued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			not_before TIMESTAMP,
+			claimed_at TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// EnqueueJob queues pkg for verification. A package already queued keeps
+// its place but has its reason updated and its retries reset, since
+// whatever prompted the new job (e.g. a version update) may fix the build.
+func EnqueueJob(conn *sql.DB, pkg, reason string) error {
+	_, err := conn.Exec(
+		`INSERT INTO jobs (package, reason, enqueued_at) VALUES (?, ?, datetime('now'))
+		 ON CONFLICT(package) DO UPDATE SET
+			reason = excluded.reason, attempts = 0, last_error = NULL, not_before = NULL`,
+		pkg, reason,
+	)
+	return err
+}
+
+// JobPriority scores a job from its package's schedule (see
+// Schedule.Priority), minus 2 per failed attempt so a broken package
+// doesn't starve the rest.
+func JobPriority(s Schedule, attempts int) float64 {
+	return s.Priority() - 2*float64(attempts)
+}
+
+// ListJobs returns the queued jobs, highest priority first.
+func ListJobs(conn *sql.DB) ([]Job, error) {
+	installs, err := InstallCounts(conn, time.Now().Add(-InstallWindow))
+	if err != nil {
+		return nil, fmt.Errorf("query install counts: %w", err)
+	}
+	rows, err := conn.Query(
+		`SELECT j.package, j.reason, j.attempts, COALESCE(j.last_error,''),
+			COALESCE(j.enqueued_at,''), COALESCE(j.not_before,''), COALESCE(j.claimed_at,''),
+			COALESCE(b.stars,0), COALESCE(b.last_verified,''), COALESCE(b.build_status,''),
+			COALESCE(b.version,''), COALESCE(b.verified_version,'')
+		 FROM jobs j LEFT JOIN binaries b ON b.package = j.package`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		var enqueued, notBefore, claimed, lastVerified, status string
+		var b Binary
+		if err := rows.Scan(&j.Package, &j.Reason, &j.Attempts, &j.LastError,
+			&enqueued, &notBefore, &claimed, &b.Stars, &lastVerified, &status,
+			&b.Version, &b.VerifiedVersion); err != nil {
+			return nil, err
+		}
+		j.EnqueuedAt = parseTimestamp(enqueued)
+		j.NotBefore = parseTimestamp(notBefore)
+		j.ClaimedAt = parseTimestamp(claimed)
+		j.Quarantined = status == StatusQuarantined
+		b.LastVerified = parseTimestamp(lastVerified)
+		j.Priority = JobPriority(ScheduleOf(b, installs[j.Package]), j.Attempts)
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(jobs, func(i, k int) bool { return jobs[i].Priority > jobs[k].Priority })
+	return jobs, nil
+}
+
+// claimable reports whether a worker may take j now.
+func (j Job) claimable(now time.Time) bool {
+	return !j.Dead() && !j.Quarantined &&
+		(j.NotBefore.IsZero() || !j.NotBefore.After(now)) &&
+		(j.ClaimedAt.IsZero() || now.Sub(j.ClaimedAt) > JobLease)
+}
+
+// ClaimJobs reserves up to n claimable jobs, highest priority first, and
+// returns them. Jobs stay reserved for JobLease unless completed or failed
+// first.
+func ClaimJobs(conn *sql.DB, n int) ([]Job, error) {
+	jobs, err := ListJobs(conn)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	var claimed []Job
+	for _, j := range jobs {
+		if len(claimed) >= n {
+			break
+		}
+		if !j.claimable(now) {
+			continue
+		}
+		if _, err := conn.Exec(`UPDATE jobs SET claimed_at = datetime('now') WHERE package = ?`, j.Package); err != nil {
+			return claimed, err
+		}
+		j.ClaimedAt = now
+		claimed = append(claimed, j)
+	}
+	return claimed, nil
+}
+
+// CompleteJob removes pkg's job from the queue.
+func CompleteJob(conn *sql.DB, pkg string) error {
+	_, err := conn.Exec(`DELETE FROM jobs WHERE package = ?`, pkg)
+	return err
+}
+
+// FailJob releases pkg's job after a failed build, counting the attempt
+// and backing off exponentially (1h, 2h, 4h, ...) before it is retried.
+func FailJob(conn *sql.DB, pkg, buildErr string) error {
+	_, err := conn.Exec(
+		`UPDATE jobs SET attempts = attempts + 1, last_error = ?, claimed_at = NULL,
+			not_before = datetime('now', '+' || (3600 << attempts) || ' seconds')
+		 WHERE package = ?`,
+		buildErr, pkg,
+	)
+	return err
+}
+
+// ReleaseJob gives up the claim on pkg's job without counting an attempt,
+// so the next run can claim it right away.
+func ReleaseJob(conn *sql.DB, pkg string) error {
+	_, err := conn.Exec(`UPDATE jobs SET claimed_at = NULL WHERE package = ?`, pkg)
+	return err
+}
+
+// RequeueDeadJobs resets the retries of every dead job, returning how many
+// there were.
+func RequeueDeadJobs(conn *sql.DB) (int64, error) {
+	res, err := conn.Exec(
+		`UPDATE jobs SET attempts = 0, reason = ?, last_error = NULL, not_before = NULL, claimed_at = NULL
+		 WHERE attempts >= ?`,
+		JobRequeued, MaxJobAttempts,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PruneJobs deletes jobs whose package is no longer in the binaries table.
+func PruneJobs(conn *sql.DB) (int64, error) {
+	res, err := conn.Exec(`DELETE FROM jobs WHERE package NOT IN (SELECT package FROM binaries)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmelahman/gomanager/internal/telemetry"
+)
+
+// createInstallReportsTable creates the install_reports table, which
+// aggregates the install telemetry clients send: how many installs of each
+// package version succeeded or failed, per platform, Go version, and
+// failure class, and on which days. It is admin bookkeeping and dropped
+// from the slim database.
+func createInstallReportsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS install_reports (
+			package TEXT NOT NULL,
+			version TEXT NOT NULL,
+			go_version TEXT NOT NULL,
+			goos TEXT NOT NULL,
+			goarch TEXT NOT NULL,
+			status TEXT NOT NULL,
+			class TEXT NOT NULL DEFAULT '',
+			count INTEGER NOT NULL DEFAULT 0,
+			first_seen TEXT NOT NULL,
+			last_seen TEXT NOT NULL,
+			PRIMARY KEY (package, version, go_version, goos, goarch, status, class)
+		)
+	`)
+	return err
+}
+
+// RecordInstallReports adds reports to the install_reports aggregates in
+// one transaction.
+func RecordInstallReports(conn *sql.DB, reports []telemetry.Report) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO install_reports
+			(package, version, go_version, goos, goarch, status, class, count, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT(package, version, go_version, goos, goarch, status, class) DO UPDATE SET
+			count = count + 1,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range reports {
+		if _, err := stmt.Exec(r.Package, r.Version, r.GoVersion, r.GOOS, r.GOARCH, r.Status, r.Class, r.Date, r.Date); err != nil {
+			return fmt.Errorf("record install report for %s: %w", r.Package, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// InstallFailures summarizes the reported installs of one package.
+type InstallFailures struct {
+	Package   string
+	Installed int
+	Failed    int
+	// Classes counts the failures by class.
+	Classes map[string]int
+	// LastFailed is the day of the most recent failure.
+	LastFailed string
+}
+
+// FailureRate is the fraction of reported installs that failed.
+func (f InstallFailures) FailureRate() float64 {
+	if f.Installed+f.Failed == 0 {
+		return 0
+	}
+	return float64(f.Failed) / float64(f.Installed+f.Failed)
+}
+
+// InstallFailuresByPackage returns the reported installs of every package
+// with at least one failure, most failures first.
+func InstallFailuresByPackage(conn *sql.DB) ([]InstallFailures, error) {
+	rows, err := conn.Query(`
+		SELECT package, status, class, SUM(count), MAX(last_seen)
+		FROM install_reports
+		WHERE package IN (SELECT package FROM install_reports WHERE status = ?)
+		GROUP BY package, status, class`, telemetry.StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byPkg := make(map[string]*InstallFailures)
+	var order []*InstallFailures
+	for rows.Next() {
+		var pkg, status, class, last string
+		var n int
+		if err := rows.Scan(&pkg, &status, &class, &n, &last); err != nil {
+			return nil, err
+		}
+		f := byPkg[pkg]
+		if f == nil {
+			f = &InstallFailures{Package: pkg, Classes: make(map[string]int)}
+			byPkg[pkg] = f
+			order = append(order, f)
+		}
+		if status == telemetry.StatusInstalled {
+			f.Installed += n
+			continue
+		}
+		f.Failed += n
+		f.Classes[class] += n
+		f.LastFailed = max(f.LastFailed, last)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	failures := make([]InstallFailures, len(order))
+	for i, f := range order {
+		failures[i] = *f
+	}
+	slices.SortStableFunc(failures, func(a, b InstallFailures) int {
+		if a.Failed != b.Failed {
+			return b.Failed - a.Failed
+		}
+		return strings.Compare(a.Package, b.Package)
+	})
+	return failures, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"math"
+	"slices"
+	"time"
+)
+
+// InstallWindow is how far back installs reported by client telemetry
+// count toward a package's popularity when scheduling verification.
+const InstallWindow = 90 * 24 * time.Hour
+
+// Schedule holds what verification scheduling weighs for a package, so that
+// limited build minutes go where users notice them first.
+type Schedule struct {
+	Stars int
+	// LastVerified is when the package was last verified, or the zero time
+	// if it never was.
+	LastVerified time.Time
+	// Bumped reports that the package's version changed since it was last
+	// verified, so clients are offered an unverified version.
+	Bumped bool
+	// Installs is the number of installs clients reported within
+	// InstallWindow.
+	Installs int
+}
+
+// ScheduleOf returns the schedule of b, which clients reported installing
+// installs times.
+func ScheduleOf(b Binary, installs int) Schedule {
+	return Schedule{
+		Stars:        b.Stars,
+		LastVerified: b.LastVerified,
+		Bumped:       b.VerifiedVersion != "" && b.Version != b.VerifiedVersion,
+		Installs:     installs,
+	}
+}
+
+// Priority scores the schedule, highest first: log2(stars+1), plus 8 if the
+// package was never verified or otherwise a point per 30 days since (at most
+// 12), plus 4 if its version was bumped since, plus 2*log2(installs+1) for
+// the installs users reported.
+func (s Schedule) Priority() float64 {
+	p := math.Log2(float64(max(s.Stars, 0)) + 1)
+	if s.LastVerified.IsZero() {
+		p += 8
+	} else {
+		p += min(time.Since(s.LastVerified).Hours()/24/30, 12)
+	}
+	if s.Bumped {
+		p += 4
+	}
+	return p + 2*math.Log2(float64(max(s.Installs, 0))+1)
+}
+
+// InstallCounts returns the number of installs (successful or not) clients
+// reported per package since since. Databases without install reports
+// yield an empty map.
+func InstallCounts(conn *sql.DB, since time.Time) (map[string]int, error) {
+	counts := make(map[string]int)
+	var exists int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='install_reports'").Scan(&exists)
+	if exists == 0 {
+		return counts, nil
+	}
+	rows, err := conn.Query(
+		`SELECT package, SUM(count) FROM install_reports WHERE last_seen >= ? GROUP BY package`,
+		since.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pkg string
+		var n int
+		if err := rows.Scan(&pkg, &n); err != nil {
+			return nil, err
+		}
+		counts[pkg] = n
+	}
+	return counts, rows.Err()
+}
+
+// scheduleBinaries sorts binaries by their schedule's priority, highest
+// first, and returns at most limit of them (all of them if limit is
+// negative).
+func scheduleBinaries(conn *sql.DB, binaries []Binary, limit int) ([]Binary, error) {
+	installs, err := InstallCounts(conn, time.Now().Add(-InstallWindow))
+	if err != nil {
+		return nil, err
+	}
+	priority := make(map[int]float64, len(binaries))
+	for _, b := range binaries {
+		priority[b.ID] = ScheduleOf(b, installs[b.Package]).Priority()
+	}
+	slices.SortStableFunc(binaries, func(a, b Binary) int {
+		return cmp.Compare(priority[b.ID], priority[a.ID])
+	})
+	if limit >= 0 && len(binaries) > limit {
+		binaries = binaries[:limit]
+	}
+	return binaries, nil
+}
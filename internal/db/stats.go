@@ -0,0 +1,197 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// OpenReadOnly opens the database at path without migrating it, for reading
+// snapshots that must not be modified. Queries against it should only use
+// columns every schema version has.
+func OpenReadOnly(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	if err := CheckSchema(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// StatusCounts returns the number of binaries with each build status.
+func StatusCounts(conn *sql.DB) (map[string]int, error) {
+	rows, err := conn.Query(
+		`SELECT COALESCE(build_status,'unknown'), COUNT(*) FROM binaries GROUP BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] += n
+	}
+	return counts, rows.Err()
+}
+
+// MonthCount is a count for one calendar month ("2006-01").
+type MonthCount struct {
+	Month string
+	Count int
+}
+
+// HistoryByMonth returns the number of build_history entries with the given
+// status per month, oldest first. Databases without build history (e.g.
+// slim ones) yield nothing.
+func HistoryByMonth(conn *sql.DB, status string) ([]MonthCount, error) {
+	var exists int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='build_history'").Scan(&exists)
+	if exists == 0 {
+		return nil, nil
+	}
+	rows, err := conn.Query(
+		`SELECT strftime('%Y-%m', verified_at) AS month, COUNT(*)
+		 FROM build_history WHERE status = ? AND verified_at IS NOT NULL
+		 GROUP BY month ORDER BY month`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var counts []MonthCount
+	for rows.Next() {
+		var c MonthCount
+		if err := rows.Scan(&c.Month, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// createQueryStatsTable creates the query_stats table, which counts how the
+// repositories each scanner search query found turned out.
+func createQueryStatsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS query_stats (
+			query TEXT PRIMARY KEY,
+			repos INTEGER DEFAULT 0,
+			no_binaries INTEGER DEFAULT 0,
+			mirrors INTEGER DEFAULT 0
+		)
+	`)
+	return err
+}
+
+// Outcomes of scanning a repository, for RecordQueryScan.
+const (
+	ScanAdded      = "added"
+	ScanNoBinaries = "no_binaries"
+	ScanMirror     = "mirror"
+)
+
+// RecordQueryScan counts a repository found by query and scanned with the
+// given outcome.
+func RecordQueryScan(conn *sql.DB, query, outcome string) error {
+	noBinaries, mirror := 0, 0
+	switch outcome {
+	case ScanNoBinaries:
+		noBinaries = 1
+	case ScanMirror:
+		mirror = 1
+	}
+	_, err := conn.Exec(`INSERT INTO query_stats (query, repos, no_binaries, mirrors) VALUES (?, 1, ?, ?)
+		ON CONFLICT(query) DO UPDATE SET
+			repos = repos + 1,
+			no_binaries = no_binaries + excluded.no_binaries,
+			mirrors = mirrors + excluded.mirrors`,
+		query, noBinaries, mirror)
+	return err
+}
+
+// SetDiscovery records how a package was discovered: the search query that
+// found its repository and the heuristic that detected its entrypoint (e.g.
+// "cmd/ directory" or "homebrew formula"). Either may be empty. Provenance
+// already recorded is kept.
+func SetDiscovery(conn *sql.DB, pkg, query, source string) error {
+	_, err := conn.Exec(
+		`UPDATE binaries SET
+			discovery_query = COALESCE(discovery_query, NULLIF(?, '')),
+			discovery_source = COALESCE(discovery_source, NULLIF(?, ''))
+		 WHERE package = ?`,
+		query, source, pkg)
+	return err
+}
+
+// QueryYield summarizes what one scanner search query found.
+type QueryYield struct {
+	Query string
+	// Repos is the number of repositories scanned from the query.
+	Repos int
+	// NoBinaries and Mirrors count repositories that were skipped.
+	NoBinaries int
+	Mirrors    int
+	// Binaries counts the packages the query added, and Confirmed and
+	// Failed (including regressed) their verification outcomes.
+	Binaries  int
+	Confirmed int
+	Failed    int
+	// UsefulRepos counts repositories with at least one confirmed binary.
+	UsefulRepos int
+}
+
+// Precision is the fraction of the query's repositories that produced a
+// confirmed binary.
+func (y QueryYield) Precision() float64 {
+	if y.Repos == 0 {
+		return 0
+	}
+	return float64(y.UsefulRepos) / float64(y.Repos)
+}
+
+// QueryYields returns the yield of every recorded search query. Repository
+// counts from before query_stats existed are estimated from the binaries
+// table.
+func QueryYields(conn *sql.DB) ([]QueryYield, error) {
+	rows, err := conn.Query(`
+		WITH found AS (
+			SELECT discovery_query AS query,
+			       COUNT(*) AS binaries,
+			       SUM(build_status = 'confirmed') AS confirmed,
+			       SUM(build_status IN ('failed','regressed')) AS failed,
+			       COUNT(DISTINCT repo_url) AS repos,
+			       COUNT(DISTINCT CASE WHEN build_status = 'confirmed' THEN repo_url END) AS useful
+			FROM binaries WHERE discovery_query IS NOT NULL
+			GROUP BY discovery_query
+		),
+		queries AS (
+			SELECT query FROM found UNION SELECT query FROM query_stats
+		)
+		SELECT q.query,
+		       MAX(COALESCE(s.repos, 0), COALESCE(f.repos, 0) + COALESCE(s.no_binaries, 0) + COALESCE(s.mirrors, 0)),
+		       COALESCE(s.no_binaries, 0), COALESCE(s.mirrors, 0),
+		       COALESCE(f.binaries, 0), COALESCE(f.confirmed, 0), COALESCE(f.failed, 0),
+		       COALESCE(f.useful, 0)
+		FROM queries q
+		LEFT JOIN found f ON f.query = q.query
+		LEFT JOIN query_stats s ON s.query = q.query`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var yields []QueryYield
+	for rows.Next() {
+		var y QueryYield
+		if err := rows.Scan(&y.Query, &y.Repos, &y.NoBinaries, &y.Mirrors,
+			&y.Binaries, &y.Confirmed, &y.Failed, &y.UsefulRepos); err != nil {
+			return nil, err
+		}
+		yields = append(yields, y)
+	}
+	return yields, rows.Err()
+}
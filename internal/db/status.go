@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Statuses lists the build statuses this build understands, in the order
+// they appear in the build_status CHECK constraint. Databases written by
+// newer releases may hold other statuses; those read as "unknown".
+var Statuses = []string{"unknown", "confirmed", "failed", "pending", "regressed", StatusQuarantined, StatusCodegen}
+
+// StatusQuarantined marks a package the scanner added that no maintainer
+// has reviewed yet. Quarantined packages are not verified and are left out
+// of the published slim database until approved.
+const StatusQuarantined = "quarantined"
+
+// StatusCodegen marks a package that can't be built with go install from
+// the module proxy because it needs code generation or assets built before
+// compiling (e.g. go:embed patterns matching files that aren't committed).
+// Clients install it from a release archive or a source checkout instead.
+// The binary's StatusReason says what is missing.
+const StatusCodegen = "codegen"
+
+// KnownStatus reports whether s is one of Statuses.
+func KnownStatus(s string) bool {
+	for _, known := range Statuses {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// statusCheck matches the build_status CHECK constraint in the binaries
+// table definition, capturing the quoted status list.
+var statusCheck = regexp.MustCompile(`CHECK\s*\(\s*build_status\s+IN\s*\(([^)]*)\)\s*\)`)
+
+// quoteStatuses renders statuses as an SQL list of string literals.
+func quoteStatuses(statuses []string) string {
+	quoted := make([]string, len(statuses))
+	for i, s := range statuses {
+		quoted[i] = "'" + s + "'"
+	}
+	return strings.Join(quoted, ",")
+}
+
+// migrateStatusCheck widens the build_status CHECK constraint of an
+// existing binaries table to allow every status in Statuses. Statuses the
+// constraint already allows are kept, so a database written by a newer
+// release never loses statuses this build doesn't know about.
+func migrateStatusCheck(conn *sql.DB) error {
+	var tableSql string
+	err := conn.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='binaries'").Scan(&tableSql)
+	if err != nil {
+		return nil // table doesn't exist, nothing to migrate
+	}
+	m := statusCheck.FindStringSubmatchIndex(tableSql)
+	if m == nil {
+		return nil // no CHECK constraint
+	}
+
+	var allowed []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(tableSql[m[2]:m[3]], ",") {
+		s = strings.Trim(strings.TrimSpace(s), `'"`)
+		if s != "" && !seen[s] {
+			allowed = append(allowed, s)
+			seen[s] = true
+		}
+	}
+	missing := false
+	for _, s := range Statuses {
+		if !seen[s] {
+			allowed = append(allowed, s)
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	newSql := tableSql[:m[2]] + quoteStatuses(allowed) + tableSql[m[3]:]
+
+	if _, err := conn.Exec("PRAGMA writable_schema = ON"); err != nil {
+		return fmt.Errorf("enable writable_schema: %w", err)
+	}
+	if _, err := conn.Exec("UPDATE sqlite_master SET sql = ? WHERE type='table' AND name='binaries'", newSql); err != nil {
+		conn.Exec("PRAGMA writable_schema = OFF")
+		return fmt.Errorf("update schema: %w", err)
+	}
+	if _, err := conn.Exec("PRAGMA writable_schema = OFF"); err != nil {
+		return fmt.Errorf("disable writable_schema: %w", err)
+	}
+	return nil
+}
+
+// QuarantineFilter selects quarantined packages. Zero fields match
+// everything.
+type QuarantineFilter struct {
+	// MinStars is the minimum star count.
+	MinStars int
+	// Match is a case-insensitive substring of the name, package, or
+	// description.
+	Match string
+	// Query is the scanner search query that discovered the package.
+	Query string
+}
+
+// ListQuarantined returns the quarantined packages matching f, most-starred
+// first.
+func ListQuarantined(conn *sql.DB, f QuarantineFilter) ([]Binary, error) {
+	where := []string{"build_status = ?", "COALESCE(stars,0) >= ?"}
+	args := []any{StatusQuarantined, f.MinStars}
+	if f.Match != "" {
+		where = append(where, "(LOWER(name) LIKE ? OR LOWER(package) LIKE ? OR LOWER(COALESCE(description,'')) LIKE ?)")
+		q := "%" + strings.ToLower(f.Match) + "%"
+		args = append(args, q, q, q)
+	}
+	if f.Query != "" {
+		where = append(where, "discovery_query = ?")
+		args = append(args, f.Query)
+	}
+	rows, err := conn.Query(
+		fmt.Sprintf(`SELECT %s FROM binaries WHERE %s ORDER BY stars DESC, package`,
+			selectCols, strings.Join(where, " AND ")),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanBinaries(rows)
+}
+
+// Approve moves a quarantined package to status (normally "unknown" or
+// "pending") so it is verified and published. It reports whether the
+// package was quarantined.
+func Approve(conn *sql.DB, id int, status string) (bool, error) {
+	res, err := conn.Exec(
+		`UPDATE binaries SET build_status = ?, updated_at = datetime('now')
+		 WHERE id = ? AND build_status = ?`,
+		status, id, StatusQuarantined)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
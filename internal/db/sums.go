@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Checksum database statuses recorded by SetSumDBStatus.
+const (
+	// SumDBVerified means the module's hashes matched sum.golang.org.
+	SumDBVerified = "verified"
+	// SumDBMissing means sum.golang.org has no record of the module
+	// version, so installing it needs a GONOSUMDB or GOPRIVATE exception.
+	SumDBMissing = "missing"
+	// SumDBMismatch means the module's contents didn't match the hashes
+	// sum.golang.org recorded.
+	SumDBMismatch = "mismatch"
+)
+
+// StripInsecureGOFLAGS returns the GOFLAGS value goflags without
+// -insecure, which lets the go command fetch modules over plain HTTP, and
+// the flags it removed. -mod=mod is kept: the go.sum entries it adds are
+// still verified against the checksum database, and it is only unsafe
+// together with GOINSECURE.
+func StripInsecureGOFLAGS(goflags string) (string, []string) {
+	var kept, removed []string
+	for _, f := range strings.Fields(goflags) {
+		name := "-" + strings.TrimLeft(f, "-")
+		if name == "-insecure" || (strings.HasPrefix(name, "-insecure=") && name != "-insecure=false") {
+			removed = append(removed, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return strings.Join(kept, " "), removed
+}
+
+// SetSumDBStatus records whether a package's module verified against the
+// checksum database.
+func SetSumDBStatus(conn *sql.DB, id int, status string) error {
+	_, err := conn.Exec(`UPDATE binaries SET sumdb_status = ? WHERE id = ?`, status, id)
+	return err
+}
+
+// EventSumChanged is recorded when verification builds a version whose
+// module hash differs from the one first recorded for it, which means the
+// tag was moved or the module contents rewritten upstream. The detail
+// names the version and both hashes.
+const EventSumChanged = "sum-changed"
+
+// createModuleSumsTable creates the module_sums table, which holds the
+// go.sum hash ("h1:...") of the module each package version verification
+// built came from. The first hash seen for a version is kept, so a later
+// build with different contents is detected rather than silently
+// accepted. Like advisories it is kept in the slim database so clients can
+// compare the binaries they install.
+func createModuleSumsTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS module_sums (
+			package TEXT NOT NULL,
+			version TEXT NOT NULL,
+			sum TEXT NOT NULL,
+			first_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (package, version)
+		)
+	`)
+	return err
+}
+
+// HasModuleSums reports whether the database has a module_sums table.
+// Databases published before module hashes were recorded don't.
+func HasModuleSums(conn *sql.DB) bool {
+	var n int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='module_sums'").Scan(&n)
+	return n > 0
+}
+
+// RecordModuleSum records sum as the module hash of pkg at version, unless
+// one is already recorded. It returns the recorded hash if it differs from
+// sum, and "" otherwise.
+func RecordModuleSum(conn *sql.DB, pkg, version, sum string) (string, error) {
+	var recorded string
+	err := conn.QueryRow(`SELECT sum FROM module_sums WHERE package = ? AND version = ?`, pkg, version).Scan(&recorded)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = conn.Exec(
+			`INSERT INTO module_sums (package, version, sum, first_seen) VALUES (?, ?, ?, datetime('now'))`,
+			pkg, version, sum,
+		)
+		if err != nil {
+			return "", fmt.Errorf("record module sum: %w", err)
+		}
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("query module sum: %w", err)
+	case recorded != sum:
+		return recorded, nil
+	}
+	return "", nil
+}
+
+// ModuleSum returns the module hash recorded for pkg at version, or "" if
+// none is (or the database predates module hashes).
+func ModuleSum(conn *sql.DB, pkg, version string) string {
+	if !HasModuleSums(conn) {
+		return ""
+	}
+	var sum string
+	conn.QueryRow(`SELECT sum FROM module_sums WHERE package = ? AND version = ?`, pkg, version).Scan(&sum)
+	return sum
+}
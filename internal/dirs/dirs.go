@@ -0,0 +1,158 @@
+// Package dirs locates gomanager's files following the XDG base directory
+// layout: settings the user edits go in the config directory, the
+// database and other data gomanager manages in the data directory, and
+// files that can be recreated at will in the cache directory.
+package dirs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Config returns the configuration directory: $XDG_CONFIG_HOME/gomanager,
+// or the platform's equivalent (see os.UserConfigDir).
+func Config() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine config directory: %w", err)
+	}
+	return filepath.Join(dir, "gomanager"), nil
+}
+
+// Data returns the data directory: $XDG_DATA_HOME/gomanager, or
+// ~/.local/share/gomanager if XDG_DATA_HOME is unset.
+func Data() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine data directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "gomanager"), nil
+}
+
+// Cache returns the cache directory: $XDG_CACHE_HOME/gomanager, or the
+// platform's equivalent (see os.UserCacheDir).
+func Cache() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %w", err)
+	}
+	return filepath.Join(dir, "gomanager"), nil
+}
+
+// DataFile returns the path of the file name in the data directory,
+// creating the directory if needed. A file of that name left in the
+// config directory by earlier versions, which kept everything there, is
+// moved over first.
+func DataFile(name string) (string, error) {
+	return fileIn(Data, name)
+}
+
+// CacheFile is DataFile for the cache directory.
+func CacheFile(name string) (string, error) {
+	return fileIn(Cache, name)
+}
+
+// fileIn returns the path of name in the directory returned by dir, as
+// described for DataFile.
+func fileIn(dir func() (string, error), name string) (string, error) {
+	d, err := dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(d, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create %s: %w", d, err)
+	}
+	path := filepath.Join(d, name)
+	if configDir, err := Config(); err == nil {
+		if err := migrate(filepath.Join(configDir, name), path); err != nil {
+			return "", fmt.Errorf("cannot move %s to %s: %w", name, d, err)
+		}
+	}
+	return path, nil
+}
+
+// LeftBehind returns the path of a file name left in the config directory
+// that DataFile or CacheFile didn't move because the data or cache
+// directory already has one, or "" if there is none.
+func LeftBehind(name string) string {
+	configDir, err := Config()
+	if err != nil {
+		return ""
+	}
+	old := filepath.Join(configDir, name)
+	if _, err := os.Stat(old); err != nil {
+		return ""
+	}
+	for _, dir := range []func() (string, error){Data, Cache} {
+		d, err := dir()
+		if err != nil || d == configDir {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+			return old
+		}
+	}
+	return ""
+}
+
+// migrate moves the file at old to path unless path already exists or old
+// doesn't.
+func migrate(old, path string) error {
+	if old == path {
+		return nil
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, err := os.Lstat(old); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Rename(old, path); err == nil {
+		return nil
+	}
+	// The directories may be on different filesystems: copy, then remove
+	// the original once the copy is complete.
+	if err := copyFile(old, path); err != nil {
+		return err
+	}
+	return os.Remove(old)
+}
+
+// copyFile copies src to dst by way of a temporary file, so that dst never
+// holds a partial copy.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
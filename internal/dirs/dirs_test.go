@@ -0,0 +1,86 @@
+package dirs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// xdgHome points the config, data, and cache directories into a temporary
+// directory and returns it.
+func xdgHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	return home
+}
+
+// writeFile creates the file at path, and its directory, holding data.
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileMigratesFromConfig(t *testing.T) {
+	for name, file := range map[string]func(string) (string, error){"DataFile": DataFile, "CacheFile": CacheFile} {
+		t.Run(name, func(t *testing.T) {
+			home := xdgHome(t)
+			old := filepath.Join(home, "config", "gomanager", "file")
+			writeFile(t, old, "old")
+
+			path, err := file("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != "old" {
+				t.Errorf("%s = %s holding %q (%v), want the config directory's file", name, path, data, err)
+			}
+			if _, err := os.Stat(old); err == nil {
+				t.Errorf("%s left %s behind", name, old)
+			}
+			if got := LeftBehind("file"); got != "" {
+				t.Errorf("LeftBehind = %q after the move, want none", got)
+			}
+		})
+	}
+}
+
+func TestCacheFileInCacheDir(t *testing.T) {
+	home := xdgHome(t)
+	path, err := CacheFile("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "cache", "gomanager", "src"); path != want {
+		t.Errorf("CacheFile(src) = %s, want %s", path, want)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		t.Errorf("CacheFile didn't create the cache directory: %v", err)
+	}
+}
+
+func TestLeftBehind(t *testing.T) {
+	for name, dir := range map[string]string{"data": "data", "cache": "cache"} {
+		t.Run(name, func(t *testing.T) {
+			home := xdgHome(t)
+			old := filepath.Join(home, "config", "gomanager", "file")
+			writeFile(t, old, "old")
+			writeFile(t, filepath.Join(home, dir, "gomanager", "file"), "new")
+			if got := LeftBehind("file"); got != old {
+				t.Errorf("LeftBehind = %q, want %s", got, old)
+			}
+		})
+	}
+	xdgHome(t)
+	if got := LeftBehind("file"); got != "" {
+		t.Errorf("LeftBehind with no files = %q, want none", got)
+	}
+}
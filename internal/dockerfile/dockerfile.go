@@ -0,0 +1,282 @@
+package dockerfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jmelahman/gomanager/internal/db"
+	"github.com/jmelahman/gomanager/internal/pkgbuild"
+)
+
+// Final stage bases.
+const (
+	// BaseDistroless runs the binary on a distroless image: static for
+	// CGO_ENABLED=0 builds, and base (with glibc) otherwise.
+	BaseDistroless = "distroless"
+	// BaseScratch runs the binary on an empty image. Only builds with
+	// CGO_ENABLED=0 can use it.
+	BaseScratch = "scratch"
+)
+
+// DefaultBuilder is the builder stage image when Options.Builder is empty.
+const DefaultBuilder = "golang:1"
+
+// safeName matches binary names that are safe to use as a file name in the
+// image.
+var safeName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// safePackage matches valid Go package paths.
+var safePackage = regexp.MustCompile(`^[a-zA-Z0-9./_-]+$`)
+
+// safeImage matches image references (e.g. "golang:1.22-bookworm").
+var safeImage = regexp.MustCompile(`^[a-zA-Z0-9./:@_-]+$`)
+
+// templateVar matches a goreleaser template variable such as {{.Version}}
+// or {{ .Tag }}.
+var templateVar = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+
+const dockerfileTemplate = `# Generated by gomanager-admin for {{.Package}}@{{.Version}}
+FROM {{.Builder}} AS builder
+{{- range .Env}}
+ENV {{.}}
+{{- end}}
+ENV GOBIN=/out
+RUN {{.Install}}
+
+FROM {{.Base}}
+LABEL org.opencontainers.image.title={{.QuotedName}} \
+      org.opencontainers.image.version={{.QuotedVersion}} \
+      org.opencontainers.image.source={{.Source}}
+{{- if .Description}} \
+      org.opencontainers.image.description={{.Description}}
+{{- end}}
+{{- if .License}} \
+      org.opencontainers.image.licenses={{.License}}
+{{- end}}
+{{- if .Scratch}}
+COPY --from=builder /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/
+{{- end}}
+COPY --from=builder /out/{{.Built}} /usr/local/bin/{{.Name}}
+{{- if .Scratch}}
+USER 65532:65532
+{{- end}}
+ENTRYPOINT {{.Entrypoint}}
+`
+
+// Options controls how a Dockerfile is generated.
+type Options struct {
+	// Builder is the image for the builder stage, or DefaultBuilder if
+	// empty.
+	Builder string
+	// Base is BaseDistroless or BaseScratch, or BaseDistroless if empty.
+	Base string
+}
+
+// templateData holds the values interpolated into dockerfileTemplate.
+type templateData struct {
+	Name          string
+	Package       string
+	Version       string
+	Builder       string
+	Base          string
+	Env           []string
+	Install       string
+	Built         string
+	Scratch       bool
+	QuotedName    string
+	QuotedVersion string
+	Source        string
+	Description   string
+	License       string
+	Entrypoint    string
+}
+
+// Generate writes a multi-stage Dockerfile for b to w. The builder stage
+// go installs the binary at its recorded version with its recorded build
+// environment and ldflags, and the final stage copies it onto a minimal
+// base image.
+func Generate(w io.Writer, b *db.Binary, opts Options) error {
+	version := b.Version
+	if version == "" || version == "latest" {
+		return fmt.Errorf("cannot generate Dockerfile for %q: no version tag available (version is %q)", b.Name, version)
+	}
+	if !safeName.MatchString(b.Name) {
+		return fmt.Errorf("unsafe binary name %q for Dockerfile generation", b.Name)
+	}
+	if !safePackage.MatchString(b.Package) {
+		return fmt.Errorf("unsafe package path %q for Dockerfile generation", b.Package)
+	}
+	builder := opts.Builder
+	if builder == "" {
+		builder = DefaultBuilder
+	}
+	if !safeImage.MatchString(builder) {
+		return fmt.Errorf("invalid builder image %q", builder)
+	}
+
+	noCGO := false
+	var env []string
+	for _, e := range b.EnvVars() {
+		if e == "CGO_ENABLED=0" {
+			noCGO = true
+		}
+		key, val, _ := strings.Cut(e, "=")
+		env = append(env, key+"="+strconv.Quote(val))
+	}
+
+	var base string
+	switch opts.Base {
+	case "", BaseDistroless:
+		base = "gcr.io/distroless/base-debian12:nonroot"
+		if noCGO {
+			base = "gcr.io/distroless/static-debian12:nonroot"
+		}
+	case BaseScratch:
+		if !noCGO {
+			return fmt.Errorf("cannot use a scratch base for %s: it isn't built with CGO_ENABLED=0", b.Name)
+		}
+		base = BaseScratch
+	default:
+		return fmt.Errorf("unknown base %q (want %s or %s)", opts.Base, BaseDistroless, BaseScratch)
+	}
+
+	install := []string{"go", "install", "-trimpath", "-ldflags=" + LDFlags(b), b.Package + "@" + version}
+	entrypoint := []string{"/usr/local/bin/" + b.Name}
+
+	source := b.RepoURL
+	if source == "" {
+		source = "https://" + b.Package
+	}
+	data := templateData{
+		Name:          b.Name,
+		Package:       b.Package,
+		Version:       version,
+		Builder:       builder,
+		Base:          base,
+		Env:           env,
+		Install:       jsonArray(install),
+		Built:         db.InstallName(b.Package),
+		Scratch:       base == BaseScratch,
+		QuotedName:    strconv.Quote(b.Name),
+		QuotedVersion: strconv.Quote(strings.TrimPrefix(version, "v")),
+		Source:        strconv.Quote(source),
+		Entrypoint:    jsonArray(entrypoint),
+	}
+	if b.Description != "" {
+		data.Description = strconv.Quote(b.Description)
+	}
+	if b.License != "" {
+		data.License = strconv.Quote(b.License)
+	}
+
+	tmpl, err := template.New("Dockerfile").Parse(dockerfileTemplate)
+	if err != nil {
+		return fmt.Errorf("template parse error: %w", err)
+	}
+	return tmpl.Execute(w, data)
+}
+
+// LDFlags returns the linker flags for building b: "-s -w" followed by the
+// flags recorded from its release configuration. Goreleaser variables that
+// can be derived from the version ({{.Version}}, {{.Tag}}, ...) are
+// expanded; -X flags using any other variable (e.g. {{.Commit}}) are
+// dropped, leaving the program's default for that value.
+func LDFlags(b *db.Binary) string {
+	vars := map[string]string{
+		"Version":     strings.TrimPrefix(b.Version, "v"),
+		"RawVersion":  strings.TrimPrefix(b.Version, "v"),
+		"Tag":         b.Version,
+		"ProjectName": path.Base(pkgbuild.ResolvePaths(b.Package).Module),
+	}
+	expand := func(s string) (string, bool) {
+		ok := true
+		s = templateVar.ReplaceAllStringFunc(s, func(m string) string {
+			v, known := vars[templateVar.FindStringSubmatch(m)[1]]
+			if !known {
+				ok = false
+			}
+			return v
+		})
+		return s, ok && !strings.Contains(s, "{{")
+	}
+
+	flags := []string{"-s", "-w"}
+	fields := splitFlags(b.LDFlags)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		switch {
+		case f == "-s" || f == "-w":
+			continue
+		case f == "-X" && i+1 < len(fields):
+			i++
+			if v, ok := expand(fields[i]); ok {
+				flags = append(flags, "-X", quoteFlag(v))
+			}
+		default:
+			if v, ok := expand(f); ok {
+				flags = append(flags, quoteFlag(v))
+			}
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// splitFlags splits s into arguments at unquoted whitespace, removing
+// single and double quotes as a shell would. Whitespace inside a template
+// action ({{ .Tag }}) doesn't split.
+func splitFlags(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	depth := 0
+	for i, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\'') && depth == 0:
+			quote = r
+			inArg = true
+		case quote == 0 && depth == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			if strings.HasPrefix(s[i:], "{{") {
+				depth++
+			} else if strings.HasPrefix(s[i:], "}}") && depth > 0 && (i == 0 || s[i-1] != '}') {
+				depth--
+			}
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// quoteFlag single-quotes a flag containing whitespace, which the go
+// command's -ldflags parsing would otherwise split.
+func quoteFlag(f string) string {
+	if strings.ContainsAny(f, " \t\n") && !strings.Contains(f, "'") {
+		return "'" + f + "'"
+	}
+	return f
+}
+
+// jsonArray formats args as the JSON array used by the exec forms of RUN
+// and ENTRYPOINT, so no shell interprets them.
+func jsonArray(args []string) string {
+	out, _ := json.Marshal(args)
+	return string(out)
+}
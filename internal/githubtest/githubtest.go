@@ -0,0 +1,399 @@
+// Package githubtest provides an in-memory fake of the GitHub REST API
+// endpoints used by gomanager-admin: repository search, repository and user
+// metadata, contents, readmes, licenses, releases and their assets, and the
+// rate limit. Point the admin commands at it with --github-api.
+package githubtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repo is a fake repository.
+type Repo struct {
+	Owner       string
+	Name        string
+	Description string
+	Stars       int
+	Archived    bool
+	Fork        bool
+	PushedAt    time.Time
+	// OwnerType is "User" (the default) or "Organization".
+	OwnerType string
+	// Release is the latest release tag; empty means no releases.
+	Release string
+	// Assets maps file names to the contents of files attached to Release.
+	Assets map[string][]byte
+	// License is the SPDX identifier reported by the license endpoint.
+	License string
+	// Files maps paths relative to the repository root to their contents.
+	// Directories are implied by the paths.
+	Files map[string]string
+	// Topics are matched by "topic:" search qualifiers and listed in
+	// repository metadata.
+	Topics []string
+	// Language is matched by "language:" search qualifiers.
+	Language string
+	// Homepage is the project website in the repository metadata.
+	Homepage string
+}
+
+// FullName returns "owner/name".
+func (r *Repo) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// Server is a fake GitHub API server. Its zero value is not usable; create
+// one with NewServer and close it when done.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	repos    map[string]*Repo
+	users    map[string]time.Time
+	requests []string
+	// Remaining is reported in X-RateLimit-Remaining. It defaults high
+	// enough that clients never sleep waiting for a reset.
+	Remaining int
+}
+
+// NewServer starts a fake GitHub API server with the given repositories.
+func NewServer(repos ...*Repo) *Server {
+	s := &Server{
+		repos:     make(map[string]*Repo),
+		users:     make(map[string]time.Time),
+		Remaining: 5000,
+	}
+	for _, r := range repos {
+		s.AddRepo(r)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddRepo adds or replaces a repository.
+func (s *Server) AddRepo(r *Repo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[strings.ToLower(r.FullName())] = r
+}
+
+// AddUser records an account's creation time for the users endpoint.
+// Repository owners without an explicit entry are reported as created on
+// 2015-01-01.
+func (s *Server) AddUser(login string, created time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[strings.ToLower(login)] = created
+}
+
+// Requests returns the request paths (with queries) served so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	remaining := s.Remaining
+	s.mu.Unlock()
+
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/rate_limit":
+		writeJSON(w, map[string]any{"resources": map[string]any{
+			"core": map[string]int{"limit": 5000, "remaining": remaining},
+		}})
+	case r.URL.Path == "/search/repositories":
+		s.search(w, r)
+	case len(parts) == 2 && parts[0] == "users":
+		s.user(w, parts[1])
+	case len(parts) == 5 && parts[0] == "download":
+		s.download(w, parts[1], parts[2], parts[3], parts[4])
+	case len(parts) >= 3 && parts[0] == "repos":
+		repo := s.repo(parts[1], parts[2])
+		if repo == nil {
+			notFound(w)
+			return
+		}
+		s.repoEndpoint(w, r, repo, parts[3:])
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) repo(owner, name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repos[strings.ToLower(owner+"/"+name)]
+}
+
+func (s *Server) repoEndpoint(w http.ResponseWriter, r *http.Request, repo *Repo, rest []string) {
+	switch {
+	case len(rest) == 0:
+		writeJSON(w, repoJSON(repo))
+	case rest[0] == "contents":
+		contents(w, r, repo, strings.Join(rest[1:], "/"))
+	case len(rest) == 2 && rest[0] == "releases" && rest[1] == "latest":
+		if repo.Release == "" {
+			notFound(w)
+			return
+		}
+		writeJSON(w, map[string]string{"tag_name": repo.Release})
+	case len(rest) == 3 && rest[0] == "releases" && rest[1] == "tags":
+		if repo.Release == "" || rest[2] != repo.Release {
+			notFound(w)
+			return
+		}
+		s.release(w, repo)
+	case len(rest) == 1 && rest[0] == "readme":
+		for _, name := range []string{"README.md", "README", "README.txt", "README.rst"} {
+			if _, ok := repo.Files[name]; ok {
+				contents(w, r, repo, name)
+				return
+			}
+		}
+		notFound(w)
+	case len(rest) == 1 && rest[0] == "license":
+		if repo.License == "" {
+			notFound(w)
+			return
+		}
+		writeJSON(w, map[string]any{"license": map[string]string{"spdx_id": repo.License}})
+	default:
+		notFound(w)
+	}
+}
+
+// release writes a release's metadata, with asset download URLs pointing
+// back at the server.
+func (s *Server) release(w http.ResponseWriter, repo *Repo) {
+	names := make([]string, 0, len(repo.Assets))
+	for name := range repo.Assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assets := make([]map[string]any, len(names))
+	for i, name := range names {
+		assets[i] = map[string]any{
+			"name":                 name,
+			"size":                 len(repo.Assets[name]),
+			"browser_download_url": s.URL + "/download/" + repo.FullName() + "/" + repo.Release + "/" + name,
+		}
+	}
+	writeJSON(w, map[string]any{"tag_name": repo.Release, "assets": assets})
+}
+
+// download serves a release asset's contents.
+func (s *Server) download(w http.ResponseWriter, owner, name, tag, asset string) {
+	repo := s.repo(owner, name)
+	if repo == nil || tag != repo.Release {
+		notFound(w)
+		return
+	}
+	data, ok := repo.Assets[asset]
+	if !ok {
+		notFound(w)
+		return
+	}
+	w.Write(data)
+}
+
+func (s *Server) user(w http.ResponseWriter, login string) {
+	s.mu.Lock()
+	created, ok := s.users[strings.ToLower(login)]
+	s.mu.Unlock()
+	if !ok {
+		created = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	writeJSON(w, map[string]any{"login": login, "created_at": created})
+}
+
+// search implements /search/repositories, supporting the topic:, language:,
+// stars:>N, fork:, and archived: qualifiers joined with "+" or spaces.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.ReplaceAll(r.URL.Query().Get("q"), "+", " ")
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	s.mu.Lock()
+	var matches []*Repo
+	for _, repo := range s.repos {
+		if matchesQuery(repo, query) {
+			matches = append(matches, repo)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Stars != matches[j].Stars {
+			return matches[i].Stars > matches[j].Stars
+		}
+		return matches[i].FullName() < matches[j].FullName()
+	})
+
+	items := []map[string]any{}
+	start := (page - 1) * perPage
+	for i := start; i < len(matches) && i < start+perPage; i++ {
+		items = append(items, repoJSON(matches[i]))
+	}
+	writeJSON(w, map[string]any{"total_count": len(matches), "items": items})
+}
+
+func matchesQuery(repo *Repo, query string) bool {
+	for _, term := range strings.Fields(query) {
+		key, val, _ := strings.Cut(term, ":")
+		switch key {
+		case "topic":
+			found := false
+			for _, t := range repo.Topics {
+				found = found || strings.EqualFold(t, val)
+			}
+			if !found {
+				return false
+			}
+		case "language":
+			if !strings.EqualFold(repo.Language, val) {
+				return false
+			}
+		case "stars":
+			n, _ := strconv.Atoi(strings.TrimPrefix(val, ">"))
+			if repo.Stars <= n {
+				return false
+			}
+		case "fork":
+			if strconv.FormatBool(repo.Fork) != val {
+				return false
+			}
+		case "archived":
+			if strconv.FormatBool(repo.Archived) != val {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// contents implements the contents endpoint: raw file contents when the
+// Accept header asks for them, a JSON listing for directories, and JSON
+// metadata for files otherwise.
+func contents(w http.ResponseWriter, r *http.Request, repo *Repo, path string) {
+	path = strings.Trim(path, "/")
+	if data, ok := repo.Files[path]; ok && path != "" {
+		if strings.Contains(r.Header.Get("Accept"), "raw") {
+			w.Write([]byte(data))
+			return
+		}
+		writeJSON(w, map[string]any{"name": baseName(path), "path": path, "type": "file", "size": len(data)})
+		return
+	}
+
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+	entries := make(map[string]string)
+	for p := range repo.Files {
+		rest, ok := strings.CutPrefix(p, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		name, _, isDir := strings.Cut(rest, "/")
+		if isDir {
+			entries[name] = "dir"
+		} else if _, seen := entries[name]; !seen {
+			entries[name] = "file"
+		}
+	}
+	if len(entries) == 0 {
+		notFound(w)
+		return
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	listing := make([]map[string]string, len(names))
+	for i, name := range names {
+		listing[i] = map[string]string{"name": name, "path": prefix + name, "type": entries[name]}
+	}
+	writeJSON(w, listing)
+}
+
+func repoJSON(r *Repo) map[string]any {
+	ownerType := r.OwnerType
+	if ownerType == "" {
+		ownerType = "User"
+	}
+	pushedAt := r.PushedAt
+	if pushedAt.IsZero() {
+		pushedAt = time.Now()
+	}
+	var license any
+	if r.License != "" {
+		license = map[string]string{"spdx_id": r.License}
+	}
+	topics := r.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	return map[string]any{
+		"id":               hashID(r.FullName()),
+		"name":             r.Name,
+		"full_name":        r.FullName(),
+		"description":      r.Description,
+		"homepage":         r.Homepage,
+		"stargazers_count": r.Stars,
+		"html_url":         "https://github.com/" + r.FullName(),
+		"archived":         r.Archived,
+		"fork":             r.Fork,
+		"pushed_at":        pushedAt,
+		"owner":            map[string]string{"login": r.Owner, "type": ownerType},
+		"license":          license,
+		"topics":           topics,
+	}
+}
+
+// hashID derives a stable numeric ID from a repository name.
+func hashID(s string) int {
+	h := 0
+	for _, c := range strings.ToLower(s) {
+		h = (h*31 + int(c)) & 0x7fffffff
+	}
+	return h
+}
+
+func baseName(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	writeJSON(w, map[string]string{"message": "Not Found"})
+}
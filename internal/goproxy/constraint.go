@@ -0,0 +1,78 @@
+package goproxy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Constraint is a range of versions to pin a tool to instead of an exact
+// version, resolved against the versions the proxy lists. "^1.2" allows
+// v1.2.0 and any later v1 version ("^0.9" only v0.9.x, as minor versions
+// of v0 modules may break compatibility), and "~1.2" (or "~1.2.3") allows
+// v1.2.0 (v1.2.3) and any later v1.2.x version. Pre-releases never match.
+type Constraint struct {
+	raw string
+	// min is the lowest allowed version and max the lowest version above
+	// the range.
+	min, max string
+}
+
+// IsConstraint reports whether s is written as a version constraint rather
+// than an exact version.
+func IsConstraint(s string) bool {
+	return strings.HasPrefix(s, "^") || strings.HasPrefix(s, "~")
+}
+
+// ParseConstraint parses a "^" or "~" version constraint.
+func ParseConstraint(s string) (Constraint, error) {
+	if !IsConstraint(s) {
+		return Constraint{}, fmt.Errorf("invalid version constraint %q: want ^ or ~ and a version", s)
+	}
+	op, version := s[0], "v"+strings.TrimPrefix(s[1:], "v")
+	if !semver.IsValid(version) || semver.Prerelease(version) != "" || semver.Build(version) != "" {
+		return Constraint{}, fmt.Errorf("invalid version constraint %q", s)
+	}
+	min := semver.Canonical(version)
+	var major, minor, patch int
+	fmt.Sscanf(min, "v%d.%d.%d", &major, &minor, &patch)
+	// parts is how many of major, minor, and patch are given.
+	parts := strings.Count(version, ".") + 1
+
+	var max string
+	switch {
+	case op == '~' && parts == 1, op == '^' && (major > 0 || parts == 1):
+		max = fmt.Sprintf("v%d.0.0", major+1)
+	case op == '~', minor > 0, parts == 2:
+		max = fmt.Sprintf("v%d.%d.0", major, minor+1)
+	default: // ^0.0.z
+		max = fmt.Sprintf("v0.0.%d", patch+1)
+	}
+	return Constraint{raw: s, min: min, max: max}, nil
+}
+
+// String returns the constraint as written.
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Allows reports whether version is in the range.
+func (c Constraint) Allows(version string) bool {
+	return semver.IsValid(version) && semver.Prerelease(version) == "" &&
+		semver.Compare(version, c.min) >= 0 && semver.Compare(version, c.max) < 0
+}
+
+// Matching returns the versions in the range, highest first.
+func (c Constraint) Matching(versions []string) []string {
+	var matching []string
+	for _, v := range versions {
+		if c.Allows(v) {
+			matching = append(matching, v)
+		}
+	}
+	semver.Sort(matching)
+	slices.Reverse(matching)
+	return matching
+}
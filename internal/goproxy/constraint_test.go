@@ -0,0 +1,77 @@
+package goproxy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allows     []string
+		rejects    []string
+	}{
+		{"^1.2", []string{"v1.2.0", "v1.2.9", "v1.9.0"}, []string{"v1.1.9", "v2.0.0", "v1.3.0-rc.1"}},
+		{"^1", []string{"v1.0.0", "v1.99.0"}, []string{"v0.9.0", "v2.0.0"}},
+		{"^v1.2.3", []string{"v1.2.3", "v1.4.0"}, []string{"v1.2.2", "v2.0.0"}},
+		// Minor versions of v0 may break compatibility.
+		{"^0.9", []string{"v0.9.0", "v0.9.5"}, []string{"v0.8.9", "v0.10.0", "v1.0.0"}},
+		{"^0.9.3", []string{"v0.9.3", "v0.9.9"}, []string{"v0.9.2", "v0.10.0"}},
+		{"^0", []string{"v0.0.1", "v0.9.0"}, []string{"v1.0.0"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.2", "v0.0.4"}},
+		{"~1.2", []string{"v1.2.0", "v1.2.9"}, []string{"v1.1.0", "v1.3.0"}},
+		{"~1.2.3", []string{"v1.2.3", "v1.2.4"}, []string{"v1.2.2", "v1.3.0"}},
+		{"~0.9", []string{"v0.9.0", "v0.9.1"}, []string{"v0.10.0"}},
+		{"~1", []string{"v1.0.0", "v1.5.0"}, []string{"v2.0.0"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		if c.String() != tt.constraint {
+			t.Errorf("ParseConstraint(%q).String() = %q", tt.constraint, c.String())
+		}
+		for _, v := range tt.allows {
+			if !c.Allows(v) {
+				t.Errorf("%s doesn't allow %s", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.rejects {
+			if c.Allows(v) {
+				t.Errorf("%s allows %s", tt.constraint, v)
+			}
+		}
+	}
+}
+
+func TestParseConstraintRejects(t *testing.T) {
+	for _, s := range []string{"", "1.2", "v1.2.0", "^", "~", "^x", "^1.2.3.4", "^1.2.0-rc.1", "^1.2.0+meta", ">=1.2", "^ 1.2"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestMatching(t *testing.T) {
+	c, err := ParseConstraint("^1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Matching([]string{"v1.2.0", "v1.10.0", "v2.0.0", "v1.3.0-rc.1", "junk", "v1.9.1", "v1.1.0"})
+	if want := []string{"v1.10.0", "v1.9.1", "v1.2.0"}; !slices.Equal(got, want) {
+		t.Errorf("Matching = %q, want %q", got, want)
+	}
+	if got := c.Matching(nil); len(got) != 0 {
+		t.Errorf("Matching(nil) = %q, want none", got)
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	for s, want := range map[string]bool{"^1.2": true, "~0.9": true, "v1.2.0": false, "latest": false, "": false} {
+		if got := IsConstraint(s); got != want {
+			t.Errorf("IsConstraint(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package goproxy queries a Go module proxy using the GOPROXY protocol.
+package goproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// DefaultURL is the proxy used when GOPROXY is unset.
+const DefaultURL = "https://proxy.golang.org"
+
+// ErrNoProxy is returned by New when GOPROXY names no proxy (e.g. "direct"
+// or "off").
+var ErrNoProxy = errors.New("GOPROXY does not name a module proxy")
+
+// Client is a module proxy client.
+type Client struct {
+	// URL is the proxy's base URL (http(s):// or file://), without a
+	// trailing slash.
+	URL  string
+	HTTP *http.Client
+}
+
+// New returns a client for the first proxy listed in GOPROXY, or DefaultURL
+// if GOPROXY is unset. Both http(s):// and file:// proxies are supported.
+func New() (*Client, error) {
+	url, err := proxyURL(os.Getenv("GOPROXY"))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{URL: url, HTTP: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// proxyURL picks the first http(s) or file:// entry from a GOPROXY list,
+// whose entries are separated by commas or pipes.
+func proxyURL(goproxy string) (string, error) {
+	if goproxy == "" {
+		return DefaultURL, nil
+	}
+	for _, entry := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		entry = strings.TrimSpace(entry)
+		for _, scheme := range []string{"https://", "http://", "file://"} {
+			if strings.HasPrefix(entry, scheme) {
+				return strings.TrimSuffix(entry, "/"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrNoProxy, goproxy)
+}
+
+// get returns the proxy's file at name (e.g. "example.com/mod/@v/list"),
+// read from the directory itself for a file:// proxy.
+func (c *Client) get(name string) ([]byte, error) {
+	if dir, ok := strings.CutPrefix(c.URL, "file://"); ok {
+		// file:///C:/proxy names a Windows drive.
+		if len(dir) > 2 && dir[0] == '/' && dir[2] == ':' {
+			dir = dir[1:]
+		}
+		return os.ReadFile(filepath.Join(filepath.FromSlash(dir), filepath.FromSlash(name)))
+	}
+	resp, err := c.HTTP.Get(c.URL + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Latest returns the version the proxy resolves modulePath@latest to. A
+// proxy that can't answer @latest (which is optional in the protocol) is
+// asked for its version list instead, as the go command does, and the
+// highest release, or failing that pre-release, is returned.
+func (c *Client) Latest(modulePath string) (string, error) {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+	version, err := c.latestInfo(escaped)
+	if err == nil {
+		return version, nil
+	}
+	err = fmt.Errorf("query %s@latest: %w", modulePath, err)
+	versions, listErr := c.Versions(modulePath)
+	if listErr != nil {
+		return "", err
+	}
+	if version := Highest(versions); version != "" {
+		return version, nil
+	}
+	return "", err
+}
+
+// latestInfo returns the version in the proxy's @latest response for the
+// escaped module path.
+func (c *Client) latestInfo(escaped string) (string, error) {
+	data, err := c.get(escaped + "/@latest")
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Version string
+		Time    time.Time
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", err
+	}
+	if info.Version == "" {
+		return "", errors.New("no version in response")
+	}
+	return info.Version, nil
+}
+
+// Highest returns the highest release in versions, or the highest
+// pre-release if there is no release, or "" if no version is valid.
+func Highest(versions []string) string {
+	var release, prerelease string
+	for _, v := range versions {
+		switch {
+		case !semver.IsValid(v):
+		case semver.Prerelease(v) == "":
+			if release == "" || semver.Compare(v, release) > 0 {
+				release = v
+			}
+		case prerelease == "" || semver.Compare(v, prerelease) > 0:
+			prerelease = v
+		}
+	}
+	if release != "" {
+		return release
+	}
+	return prerelease
+}
+
+// Versions returns the tagged versions the proxy lists for modulePath, in
+// no particular order.
+func (c *Client) Versions(modulePath string) ([]string, error) {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.get(escaped + "/@v/list")
+	if err != nil {
+		return nil, fmt.Errorf("list %s versions: %w", modulePath, err)
+	}
+	return strings.Fields(string(data)), nil
+}
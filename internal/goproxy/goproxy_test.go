@@ -0,0 +1,117 @@
+package goproxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProxyURL(t *testing.T) {
+	tests := []struct {
+		goproxy, want string
+	}{
+		{"", DefaultURL},
+		{"https://proxy.example.com/", "https://proxy.example.com"},
+		{"direct,http://localhost:3000|https://proxy.golang.org", "http://localhost:3000"},
+		{"off|file:///srv/proxy/,direct", "file:///srv/proxy"},
+	}
+	for _, tt := range tests {
+		if got, err := proxyURL(tt.goproxy); err != nil || got != tt.want {
+			t.Errorf("proxyURL(%q) = %q, %v, want %q", tt.goproxy, got, err, tt.want)
+		}
+	}
+	for _, goproxy := range []string{"direct", "off", "direct,off"} {
+		if _, err := proxyURL(goproxy); !errors.Is(err, ErrNoProxy) {
+			t.Errorf("proxyURL(%q): got %v, want ErrNoProxy", goproxy, err)
+		}
+	}
+}
+
+// writeProxy writes a file:// proxy directory holding the given files,
+// relative to the module path's escaped directory, and returns a client
+// for it.
+func writeProxy(t *testing.T, module string, files map[string]string) *Client {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(module), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Client{URL: "file://" + filepath.ToSlash(dir)}
+}
+
+func TestFileProxy(t *testing.T) {
+	// Module paths are escaped: uppercase letters become "!" and lowercase.
+	c := writeProxy(t, "github.com/!acme/tool", map[string]string{
+		"@latest": `{"Version":"v1.3.0","Time":"2026-01-01T00:00:00Z"}`,
+		"@v/list": "v1.2.0\nv1.3.0\n",
+	})
+	if got, err := c.Latest("github.com/Acme/tool"); err != nil || got != "v1.3.0" {
+		t.Errorf("Latest = %q, %v, want v1.3.0", got, err)
+	}
+	if got, err := c.Versions("github.com/Acme/tool"); err != nil || len(got) != 2 {
+		t.Errorf("Versions = %q, %v, want two versions", got, err)
+	}
+	if _, err := c.Versions("github.com/acme/missing"); err == nil {
+		t.Error("Versions of a module the proxy lacks succeeded")
+	}
+}
+
+func TestLatestFallsBackToList(t *testing.T) {
+	// A file:// proxy written by go mod download has no @latest.
+	c := writeProxy(t, "example.com/tool", map[string]string{
+		"@v/list": "v1.2.0\nv1.10.0\nv1.11.0-rc.1\nv1.9.0\n",
+	})
+	if got, err := c.Latest("example.com/tool"); err != nil || got != "v1.10.0" {
+		t.Errorf("Latest = %q, %v, want the highest listed release v1.10.0", got, err)
+	}
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/example.com/tool/@v/list":
+			w.Write([]byte("v0.1.0-alpha\nv0.2.0-beta\n"))
+		case "/example.com/empty/@v/list":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c = &Client{URL: srv.URL, HTTP: srv.Client()}
+	if got, err := c.Latest("example.com/tool"); err != nil || got != "v0.2.0-beta" {
+		t.Errorf("Latest = %q, %v, want the highest pre-release v0.2.0-beta", got, err)
+	}
+	if want := []string{"/example.com/tool/@latest", "/example.com/tool/@v/list"}; len(requests) != 2 || requests[0] != want[0] || requests[1] != want[1] {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+	// Without any versions, the @latest error is reported.
+	if _, err := c.Latest("example.com/empty"); err == nil || err.Error() != "query example.com/empty@latest: HTTP 404" {
+		t.Errorf("Latest of a module with no versions: got %v, want the @latest error", err)
+	}
+}
+
+func TestHighest(t *testing.T) {
+	tests := []struct {
+		versions []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"junk", "v1"}, "v1"},
+		{[]string{"v1.2.0", "v1.10.0", "v2.0.0-rc.1"}, "v1.10.0"},
+		{[]string{"v2.0.0-rc.1", "v2.0.0-rc.2"}, "v2.0.0-rc.2"},
+	}
+	for _, tt := range tests {
+		if got := Highest(tt.versions); got != tt.want {
+			t.Errorf("Highest(%q) = %q, want %q", tt.versions, got, tt.want)
+		}
+	}
+}
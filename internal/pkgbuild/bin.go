@@ -0,0 +1,192 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// safeURL matches release asset URLs that are safe to interpolate into a
+// double-quoted PKGBUILD source entry.
+var safeURL = regexp.MustCompile(`^https?://[A-Za-z0-9._~/%:+@=-]+$`)
+
+// safePath matches paths of files inside a release archive.
+var safePath = regexp.MustCompile(`^[A-Za-z0-9._/+-]+$`)
+
+// sha256Hex matches a hex-encoded SHA-256 digest.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+const binTemplate = `# Maintainer: gomanager <gomanager@generated>
+pkgname={{.PkgName}}-bin
+_pkgname={{.PkgName}}
+pkgver={{.PkgVer}}
+pkgrel=1
+pkgdesc="{{.PkgDesc}}"
+arch=({{range $i, $a := .Assets}}{{if $i}} {{end}}'{{$a.Arch}}'{{end}})
+url="{{.URL}}"
+license=('{{.LicenseID}}')
+provides=("$_pkgname")
+conflicts=("$_pkgname")
+options=('!strip')
+{{- range .Assets}}
+source_{{.Arch}}=("$_pkgname-$pkgver-{{.Arch}}{{.Ext}}::{{.URL}}")
+sha256sums_{{.Arch}}=('{{.SHA256}}')
+{{- end}}
+
+package() {
+{{- if .SameBinary}}
+  install -Dm 755 "{{(index .Assets 0).Binary}}" "$pkgdir/usr/bin/$_pkgname"
+{{- else}}
+  case "$CARCH" in
+{{- range .Assets}}
+    {{.Arch}}) install -Dm 755 "{{.Binary}}" "$pkgdir/usr/bin/$_pkgname" ;;
+{{- end}}
+  esac
+{{- end}}
+{{- if .LicenseFile}}
+  install -Dm 644 "{{.LicenseFile}}" -t "$pkgdir/usr/share/licenses/$_pkgname"
+{{- end}}
+{{- if .ReadmeFile}}
+  install -Dm 644 "{{.ReadmeFile}}" -t "$pkgdir/usr/share/doc/$_pkgname"
+{{- end}}
+}
+`
+
+// BinAsset is a prebuilt release archive for one architecture.
+type BinAsset struct {
+	// Arch is the Arch Linux architecture (e.g. "x86_64").
+	Arch string
+	// URL is the archive's download URL.
+	URL string
+	// Ext is the archive's extension including the dot (e.g. ".tar.gz"),
+	// kept so makepkg recognizes and extracts it.
+	Ext string
+	// SHA256 is the hex-encoded digest of the archive.
+	SHA256 string
+	// Binary is the path of the executable inside the extracted archive.
+	Binary string
+}
+
+// BinOptions describes the release archives for a -bin PKGBUILD.
+type BinOptions struct {
+	Assets []BinAsset
+	// LicenseID is the SPDX license identifier. If empty, the binary's
+	// recorded license is used, or "unknown" if it has none.
+	LicenseID string
+	// LicenseFile and ReadmeFile are paths inside the extracted archive
+	// (the same for every architecture), or empty to skip installing them.
+	LicenseFile string
+	ReadmeFile  string
+}
+
+// binTemplateData holds the values for -bin PKGBUILD generation.
+type binTemplateData struct {
+	PkgName     string
+	PkgVer      string
+	PkgDesc     string
+	URL         string
+	LicenseID   string
+	LicenseFile string
+	ReadmeFile  string
+	Assets      []BinAsset
+	SameBinary  bool
+}
+
+// GenerateBin writes a <name>-bin PKGBUILD to w that installs the binary
+// from prebuilt release archives instead of building from source. The
+// release version in asset URLs and archive paths is replaced with $pkgver
+// so the PKGBUILD can be bumped by changing pkgver and the checksums.
+func GenerateBin(w io.Writer, b *db.Binary, opts BinOptions) error {
+	version := b.Version
+	if version == "" || version == "latest" {
+		return fmt.Errorf("cannot generate PKGBUILD for %q: no version tag available (version is %q)", b.Name, version)
+	}
+	if !safeName.MatchString(b.Name) {
+		return fmt.Errorf("unsafe package name %q for PKGBUILD generation", b.Name)
+	}
+	if len(opts.Assets) == 0 {
+		return fmt.Errorf("cannot generate PKGBUILD for %q: no Linux release archives", b.Name)
+	}
+	pkgVer := strings.TrimPrefix(version, "v")
+
+	var assets []BinAsset
+	for _, a := range opts.Assets {
+		if !safeURL.MatchString(a.URL) {
+			return fmt.Errorf("unsafe asset URL %q for PKGBUILD generation", a.URL)
+		}
+		if !safePath.MatchString(a.Binary) || !safePath.MatchString(a.Ext) {
+			return fmt.Errorf("unsafe archive path %q for PKGBUILD generation", a.Binary)
+		}
+		if !sha256Hex.MatchString(a.SHA256) {
+			return fmt.Errorf("invalid sha256 %q for %s", a.SHA256, a.URL)
+		}
+		a.URL = withPkgver(a.URL, pkgVer)
+		a.Binary = withPkgver(a.Binary, pkgVer)
+		assets = append(assets, a)
+	}
+	for _, f := range []string{opts.LicenseFile, opts.ReadmeFile} {
+		if f != "" && !safePath.MatchString(f) {
+			return fmt.Errorf("unsafe archive path %q for PKGBUILD generation", f)
+		}
+	}
+
+	desc := strings.ReplaceAll(b.Description, `"`, `\"`)
+	if desc == "" {
+		desc = fmt.Sprintf("Go binary: %s", b.Name)
+	}
+	desc += " (prebuilt)"
+	url := b.RepoURL
+	if url == "" {
+		url = "https://" + b.Package
+	}
+	licenseID, err := license(b, opts.LicenseID)
+	if err != nil {
+		return err
+	}
+
+	same := true
+	for _, a := range assets {
+		same = same && a.Binary == assets[0].Binary
+	}
+
+	data := binTemplateData{
+		PkgName:     b.Name,
+		PkgVer:      pkgVer,
+		PkgDesc:     desc,
+		URL:         url,
+		LicenseID:   licenseID,
+		LicenseFile: withPkgver(opts.LicenseFile, pkgVer),
+		ReadmeFile:  withPkgver(opts.ReadmeFile, pkgVer),
+		Assets:      assets,
+		SameBinary:  same,
+	}
+	tmpl, err := template.New("PKGBUILD").Parse(binTemplate)
+	if err != nil {
+		return fmt.Errorf("template parse error: %w", err)
+	}
+	return tmpl.Execute(w, data)
+}
+
+// withPkgver replaces the release version in s with ${pkgver}. Versions
+// without a dot are left alone, since they could match unrelated digits.
+func withPkgver(s, pkgVer string) string {
+	if !strings.Contains(pkgVer, ".") {
+		return s
+	}
+	return strings.ReplaceAll(s, pkgVer, "${pkgver}")
+}
+
+// ArchFor returns the Arch Linux architecture for a Go GOARCH value, or ""
+// if it has none.
+func ArchFor(goarch string) string {
+	for _, ap := range archPlatforms {
+		if ap.platform == "linux/"+goarch {
+			return ap.arch
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fullOpts is what detection finds in a conventional repository.
+var fullOpts = &Options{LicenseID: "MIT", LicenseFile: "LICENSE", ReadmeFile: "README.md", HasGoMod: true}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		binary db.Binary
+		opts   *Options
+	}{
+		{
+			name: "root",
+			binary: db.Binary{
+				Name: "widget", Package: "github.com/acme/widget", Version: "v1.2.0",
+				Description: "Makes widgets", RepoURL: "https://github.com/acme/widget",
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "cmd-subpath",
+			binary: db.Binary{
+				Name: "foo", Package: "github.com/acme/tools/cmd/foo", Version: "v0.3.1",
+				Description: `The "foo" tool`, RepoURL: "https://github.com/acme/tools.git",
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "v2-module",
+			binary: db.Binary{
+				Name: "widget", Package: "github.com/acme/widget/v2/cmd/widget", Version: "v2.0.1",
+				RepoURL: "https://github.com/acme/widget",
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "v2-module-root",
+			binary: db.Binary{
+				Name: "gizmo", Package: "github.com/acme/gizmo/v3", Version: "v3.1.0",
+				RepoURL: "https://github.com/acme/gizmo",
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "nested-v2-module",
+			binary: db.Binary{
+				Name: "x", Package: "github.com/acme/mono/tools/v2/cmd/x", Version: "v2.4.0",
+				RepoURL: "https://github.com/acme/mono",
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "cgo-disabled",
+			binary: db.Binary{
+				Name: "static", Package: "github.com/acme/static", Version: "v1.0.0",
+				BuildFlags: `{"CGO_ENABLED":"0","GOFLAGS":"-mod=mod -buildvcs=false -toolexec=evil"}`,
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "cgo-flags",
+			binary: db.Binary{
+				Name: "sqlitetool", Package: "github.com/acme/sqlitetool", Version: "v1.0.0",
+				BuildFlags: `{"CGO_ENABLED":"1","CGO_LDFLAGS":"-lsqlite3 -lm","LD_PRELOAD":"/evil.so"}`,
+			},
+			opts: fullOpts,
+		},
+		{
+			name: "missing-license-readme",
+			binary: db.Binary{
+				Name: "bare", Package: "github.com/acme/bare", Version: "v0.1.0",
+			},
+			opts: &Options{HasGoMod: true},
+		},
+		{
+			name: "recorded-license",
+			binary: db.Binary{
+				Name: "widget", Package: "github.com/acme/widget", Version: "v1.2.0", License: "BSD-3-Clause",
+			},
+		},
+		{
+			name: "no-go-mod",
+			binary: db.Binary{
+				Name: "legacy", Package: "github.com/acme/legacy/cmd/legacy", Version: "1.0",
+				RepoURL: "https://github.com/acme/legacy",
+			},
+			opts: &Options{LicenseID: "GPL-2.0", LicenseFile: "COPYING", HasGoMod: false},
+		},
+		{
+			name: "platforms",
+			binary: db.Binary{
+				Name: "widget", Package: "github.com/acme/widget", Version: "v1.2.0",
+				PlatformSupport: map[string]bool{"linux/amd64": true, "linux/arm64": false, "linux/arm": true},
+			},
+			opts: fullOpts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Generate(&buf, &tt.binary, tt.opts); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("Generate output differs from %s:\n--- got\n%s\n--- want\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		binary db.Binary
+		opts   *Options
+	}{
+		{"no version", db.Binary{Name: "widget", Package: "github.com/acme/widget"}, nil},
+		{"latest", db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "latest"}, nil},
+		{"unsafe name", db.Binary{Name: "widget;rm", Package: "github.com/acme/widget", Version: "v1.0.0"}, nil},
+		{"unsafe package", db.Binary{Name: "widget", Package: "github.com/acme/$(id)", Version: "v1.0.0"}, nil},
+		{"unsafe license", db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0"}, &Options{LicenseID: "MIT'); rm -rf /; ('"}},
+		{"no linux platform", db.Binary{Name: "widget", Package: "github.com/acme/widget", Version: "v1.0.0", PlatformSupport: map[string]bool{"linux/amd64": false}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Generate(&bytes.Buffer{}, &tt.binary, tt.opts); err == nil {
+				t.Error("Generate succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	tests := []struct {
+		pkg  string
+		want Paths
+	}{
+		{"github.com/o/r", Paths{Module: "github.com/o/r", ModuleDir: ".", Build: "."}},
+		{"github.com/o/r/cmd/foo", Paths{Module: "github.com/o/r", ModuleDir: ".", Build: "./cmd/foo"}},
+		{"github.com/o/r/v4", Paths{Module: "github.com/o/r/v4", ModuleDir: ".", Build: "."}},
+		{"github.com/o/r/v4/cmd/foo", Paths{Module: "github.com/o/r/v4", ModuleDir: ".", Build: "./cmd/foo"}},
+		{"github.com/o/r/tools/v2/cmd/x", Paths{Module: "github.com/o/r/tools/v2", ModuleDir: "tools", Build: "./cmd/x"}},
+		{"github.com/o/r/a/b/v3", Paths{Module: "github.com/o/r/a/b/v3", ModuleDir: "a/b", Build: "."}},
+		// The last major version element ends the module path.
+		{"github.com/o/r/v2/tools/v3/cmd/x", Paths{Module: "github.com/o/r/v2/tools/v3", ModuleDir: "v2/tools", Build: "./cmd/x"}},
+		// v0 and v1 are never major version suffixes.
+		{"github.com/o/r/v1/cmd/x", Paths{Module: "github.com/o/r", ModuleDir: ".", Build: "./v1/cmd/x"}},
+		{"github.com/o/r/v0", Paths{Module: "github.com/o/r", ModuleDir: ".", Build: "./v0"}},
+		{"github.com/o/r/cmd/v10", Paths{Module: "github.com/o/r/cmd/v10", ModuleDir: "cmd", Build: "."}},
+		{"example.com/tool", Paths{Module: "example.com/tool", ModuleDir: ".", Build: "."}},
+	}
+	for _, tt := range tests {
+		if got := ResolvePaths(tt.pkg); got != tt.want {
+			t.Errorf("ResolvePaths(%q) = %+v, want %+v", tt.pkg, got, tt.want)
+		}
+	}
+}
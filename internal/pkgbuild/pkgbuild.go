@@ -3,6 +3,7 @@ package pkgbuild
 import (
 	"fmt"
 	"io"
+	"path"
 	"regexp"
 	"strings"
 	"text/template"
@@ -16,12 +17,20 @@ var safeName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 // safePackage matches valid Go module paths (alphanumerics, dots, slashes, hyphens, underscores).
 var safePackage = regexp.MustCompile(`^[a-zA-Z0-9./_-]+$`)
 
+// safeLicense matches SPDX license identifiers, which are interpolated into
+// a single-quoted PKGBUILD license entry.
+var safeLicense = regexp.MustCompile(`^[A-Za-z0-9.+-]+$`)
+
+// majorVersion matches a major version suffix element of a module path. Only
+// v2 and above are valid suffixes.
+var majorVersion = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+
 const pkgbuildTemplate = `# Maintainer: gomanager <gomanager@generated>
 pkgname={{.PkgName}}
 pkgver={{.PkgVer}}
 pkgrel=1
 pkgdesc="{{.PkgDesc}}"
-arch=('x86_64' 'aarch64')
+arch=({{range $i, $a := .Arch}}{{if $i}} {{end}}'{{$a}}'{{end}})
 url="{{.URL}}"
 license=('{{.LicenseID}}')
 {{- if .NoCGO}}
@@ -35,6 +44,9 @@ sha256sums=('SKIP')
 
 build() {
   cd "$pkgname" || exit
+{{- if ne .ModuleDir "."}}
+  cd {{.ModuleDir}} || exit
+{{- end}}
 {{- range .EnvVars}}
   export {{.}}
 {{- end}}
@@ -55,7 +67,7 @@ build() {
 
 package() {
   cd "$pkgname" || exit
-  install -Dm 755 {{.BuildPath}}/$pkgname -t "$pkgdir/usr/bin"
+  install -Dm 755 {{.BinaryPath}} -t "$pkgdir/usr/bin"
 {{- if .LicenseFile}}
   install -Dm 644 {{.LicenseFile}} -t "$pkgdir/usr/share/licenses/$pkgname"
 {{- end}}
@@ -65,11 +77,65 @@ package() {
 }
 `
 
+// archPlatforms maps Arch Linux architectures to the Go platform their
+// binaries are built for, in the order they are listed in arch=().
+var archPlatforms = []struct{ arch, platform string }{
+	{"x86_64", "linux/amd64"},
+	{"aarch64", "linux/arm64"},
+	{"armv7h", "linux/arm"},
+	{"i686", "linux/386"},
+	{"riscv64", "linux/riscv64"},
+}
+
+// defaultArch is used when a package has no recorded platform support.
+var defaultArch = []string{"x86_64", "aarch64"}
+
+// Arch returns the arch=() entries for a binary. Without platform support
+// data it is defaultArch; otherwise it lists the architectures whose Linux
+// platform was verified to build. Go binaries are native code, so 'any' is
+// never emitted. Architectures that weren't checked are only included when
+// every checked Linux platform built, so that a package verified on a subset
+// keeps the default set. It fails if no Linux platform built.
+func Arch(b *db.Binary) ([]string, error) {
+	checked, failed := false, false
+	for _, ap := range archPlatforms {
+		if ok, known := b.PlatformSupport[ap.platform]; known {
+			checked = true
+			failed = failed || !ok
+		}
+	}
+	if !checked {
+		return defaultArch, nil
+	}
+
+	var arches []string
+	for _, ap := range archPlatforms {
+		ok, known := b.PlatformSupport[ap.platform]
+		if ok || (!known && !failed && containsArch(defaultArch, ap.arch)) {
+			arches = append(arches, ap.arch)
+		}
+	}
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("%q does not build for any Linux architecture", b.Name)
+	}
+	return arches, nil
+}
+
+func containsArch(arches []string, arch string) bool {
+	for _, a := range arches {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
 // Options holds optional metadata that can be discovered from the repository
 // prior to PKGBUILD generation (e.g. via the GitHub API).
 type Options struct {
 	// LicenseID is the SPDX license identifier (e.g. "MIT", "Apache-2.0").
-	// If empty, "unknown" is used.
+	// If empty, the binary's recorded license is used, or "unknown" if it
+	// has none.
 	LicenseID string
 	// LicenseFile is the exact filename of the license (e.g. "LICENSE", "LICENSE.md").
 	// If empty, no license install line is emitted.
@@ -82,16 +148,34 @@ type Options struct {
 	HasGoMod bool
 }
 
+// license returns the license to declare for b: id if set, else the
+// license recorded in the database, else "unknown".
+func license(b *db.Binary, id string) (string, error) {
+	if id == "" {
+		id = b.License
+	}
+	if id == "" {
+		return "unknown", nil
+	}
+	if !safeLicense.MatchString(id) {
+		return "", fmt.Errorf("unsafe license %q for PKGBUILD generation", id)
+	}
+	return id, nil
+}
+
 // TemplateData holds the values for PKGBUILD generation.
 type TemplateData struct {
 	PkgName     string
 	PkgVer      string
 	PkgDesc     string
+	Arch        []string
 	URL         string
 	GitURL      string
 	TagPrefix   string
 	BuildPath   string
 	ModulePath  string
+	ModuleDir   string
+	BinaryPath  string
 	EnvVars     []string
 	NoCGO       bool
 	HasGoMod    bool
@@ -101,7 +185,8 @@ type TemplateData struct {
 }
 
 // Generate writes a PKGBUILD to the given writer for the specified binary.
-// If opts is nil, license and readme install lines are omitted.
+// If opts is nil, license and readme install lines are omitted and the
+// license is the binary's recorded one.
 func Generate(w io.Writer, b *db.Binary, opts *Options) error {
 	version := b.Version
 	if version == "" || version == "latest" {
@@ -138,69 +223,56 @@ func Generate(w io.Writer, b *db.Binary, opts *Options) error {
 		tagPrefix = "v"
 	}
 
-	// Determine the module path (e.g. "github.com/owner/repo" or
-	// "github.com/owner/repo/v4") and the build path relative to the repo root.
-	// For root packages (github.com/owner/repo), buildPath is "."
-	// For sub-packages (github.com/owner/repo/cmd/foo), buildPath is "./cmd/foo"
-	buildPath := "."
-	modulePath := strings.Join(strings.SplitN(b.Package, "/", 4)[:3], "/")
-	parts := strings.SplitN(b.Package, "/", 4) // github.com / owner / repo / rest
-	if len(parts) == 4 {
-		sub := parts[3]
-		// If the sub-path is just a major version (e.g. "v4"), include it in modulePath
-		if regexp.MustCompile(`^v\d+$`).MatchString(sub) {
-			modulePath = b.Package
-		} else {
-			// Strip leading version prefix if present (e.g. "v4/cmd/foo" -> "cmd/foo")
-			if idx := strings.Index(sub, "/"); idx >= 0 {
-				prefix := sub[:idx]
-				if regexp.MustCompile(`^v\d+$`).MatchString(prefix) {
-					modulePath = modulePath + "/" + prefix
-					sub = sub[idx+1:]
-				}
-			}
-			buildPath = "./" + sub
-		}
+	arch, err := Arch(b)
+	if err != nil {
+		return fmt.Errorf("cannot generate PKGBUILD: %w", err)
 	}
 
-	var envVars []string
-	flags := b.EnvFlags()
-	if flags != "" {
-		for _, f := range strings.Split(flags, " ") {
-			envVars = append(envVars, f)
-		}
+	paths := ResolvePaths(b.Package)
+	// A module in a subdirectory is tagged with the directory as a prefix,
+	// e.g. tools/v2.4.0 for github.com/o/r/tools/v2.
+	if paths.ModuleDir != "." {
+		tagPrefix = paths.ModuleDir + "/" + tagPrefix
 	}
 
-	// Detect if CGO is explicitly disabled
+	// Detect if CGO is explicitly disabled, and quote values for export
 	noCGO := false
-	for _, e := range envVars {
+	var envVars []string
+	for _, e := range b.EnvVars() {
 		if e == "CGO_ENABLED=0" {
 			noCGO = true
-			break
 		}
+		if key, val, _ := strings.Cut(e, "="); strings.ContainsAny(val, " \t") {
+			e = key + `="` + val + `"`
+		}
+		envVars = append(envVars, e)
 	}
 
-	var licenseID, licenseFile, readmeFile string
+	var optsLicense, licenseFile, readmeFile string
 	hasGoMod := true // assume modern project if opts not available
 	if opts != nil {
-		licenseID = opts.LicenseID
+		optsLicense = opts.LicenseID
 		licenseFile = opts.LicenseFile
 		readmeFile = opts.ReadmeFile
 		hasGoMod = opts.HasGoMod
 	}
-	if licenseID == "" {
-		licenseID = "unknown"
+	licenseID, err := license(b, optsLicense)
+	if err != nil {
+		return err
 	}
 
 	data := TemplateData{
 		PkgName:     b.Name,
 		PkgVer:      pkgVer,
 		PkgDesc:     desc,
+		Arch:        arch,
 		URL:         url,
 		GitURL:      gitURL,
 		TagPrefix:   tagPrefix,
-		BuildPath:   buildPath,
-		ModulePath:  modulePath,
+		BuildPath:   paths.Build,
+		ModulePath:  paths.Module,
+		ModuleDir:   paths.ModuleDir,
+		BinaryPath:  "./" + path.Join(paths.ModuleDir, paths.Build, "$pkgname"),
 		EnvVars:     envVars,
 		NoCGO:       noCGO,
 		HasGoMod:    hasGoMod,
@@ -215,3 +287,65 @@ func Generate(w io.Writer, b *db.Binary, opts *Options) error {
 	}
 	return tmpl.Execute(w, data)
 }
+
+// Paths describes where a package lives relative to its repository.
+type Paths struct {
+	// Module is the module path, including any major version suffix (e.g.
+	// "github.com/owner/repo/v4").
+	Module string
+	// ModuleDir is the module's directory relative to the repository root,
+	// "." for a module at the root.
+	ModuleDir string
+	// Build is the main package relative to ModuleDir, as passed to go build
+	// (e.g. "./cmd/foo", or "." for the module root).
+	Build string
+}
+
+// ResolvePaths derives the module path and build paths for a package path of
+// the form host/owner/repo[/...]. The last major version element (v2 and
+// above) after the repository ends the module path; elements before it name
+// a nested module's directory, and elements after it the main package within
+// that module. Following the major branch convention, the version element
+// itself is not a directory. Without a version element the module is
+// assumed to be at the repository root.
+//
+// For example:
+//
+//	github.com/o/r                 → module github.com/o/r, dir ., build .
+//	github.com/o/r/cmd/foo         → module github.com/o/r, dir ., build ./cmd/foo
+//	github.com/o/r/v4/cmd/foo      → module github.com/o/r/v4, dir ., build ./cmd/foo
+//	github.com/o/r/tools/v2/cmd/x  → module github.com/o/r/tools/v2, dir tools, build ./cmd/x
+func ResolvePaths(pkg string) Paths {
+	parts := strings.Split(pkg, "/")
+	if len(parts) <= 3 {
+		return Paths{Module: pkg, ModuleDir: ".", Build: "."}
+	}
+	root, rest := parts[:3], parts[3:]
+
+	major := -1
+	for i, p := range rest {
+		if majorVersion.MatchString(p) {
+			major = i
+		}
+	}
+	if major < 0 {
+		return Paths{
+			Module:    strings.Join(root, "/"),
+			ModuleDir: ".",
+			Build:     "./" + strings.Join(rest, "/"),
+		}
+	}
+
+	paths := Paths{
+		Module:    strings.Join(parts[:len(root)+major+1], "/"),
+		ModuleDir: ".",
+		Build:     ".",
+	}
+	if major > 0 {
+		paths.ModuleDir = strings.Join(rest[:major], "/")
+	}
+	if sub := rest[major+1:]; len(sub) > 0 {
+		paths.Build = "./" + strings.Join(sub, "/")
+	}
+	return paths
+}
@@ -0,0 +1,104 @@
+// Package policy evaluates binaries against an organization policy file.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jmelahman/gomanager/internal/db"
+)
+
+// Policy enforcement modes.
+const (
+	// ModeEnforce refuses installs that violate the policy.
+	ModeEnforce = "enforce"
+	// ModeWarn installs anyway after printing the violations.
+	ModeWarn = "warn"
+)
+
+// Policy restricts which binaries may be installed. The zero value allows
+// everything.
+type Policy struct {
+	// Mode is ModeEnforce (the default) or ModeWarn.
+	Mode string `toml:"mode"`
+	// AllowedLicenses lists the SPDX identifiers binaries may be licensed
+	// under. Empty allows any license.
+	AllowedLicenses []string `toml:"allowed_licenses"`
+	// AllowUnknownLicense permits binaries whose license isn't recorded when
+	// AllowedLicenses is set.
+	AllowUnknownLicense bool `toml:"allow_unknown_license"`
+	// MinStars is the minimum number of GitHub stars.
+	MinStars int `toml:"min_stars"`
+	// RequireConfirmed only allows binaries with a confirmed build.
+	RequireConfirmed bool `toml:"require_confirmed"`
+	// Denylist names binaries or package paths that may never be installed.
+	// An entry ending in "/..." matches every package under that path.
+	Denylist []string `toml:"denylist"`
+}
+
+// Violation is a reason a binary doesn't satisfy a policy.
+type Violation struct {
+	// Denylisted is set when the violation is a denylist match.
+	Denylisted bool
+	Reason     string
+}
+
+// Load reads a policy file.
+func Load(path string) (*Policy, error) {
+	var p Policy
+	if _, err := toml.DecodeFile(path, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = ModeEnforce
+	case ModeEnforce, ModeWarn:
+	default:
+		return nil, fmt.Errorf("invalid policy %s: unknown mode %q (want %s or %s)", path, p.Mode, ModeEnforce, ModeWarn)
+	}
+	return &p, nil
+}
+
+// Check returns the ways b violates the policy, or nil if it complies.
+func (p *Policy) Check(b *db.Binary) []Violation {
+	var violations []Violation
+	for _, entry := range p.Denylist {
+		if denylistMatch(entry, b) {
+			violations = append(violations, Violation{Denylisted: true, Reason: fmt.Sprintf("denylisted by %q", entry)})
+		}
+	}
+	if len(p.AllowedLicenses) > 0 {
+		switch {
+		case b.License == "":
+			if !p.AllowUnknownLicense {
+				violations = append(violations, Violation{Reason: "license is unknown"})
+			}
+		case !containsFold(p.AllowedLicenses, b.License):
+			violations = append(violations, Violation{Reason: fmt.Sprintf("license %s is not allowed", b.License)})
+		}
+	}
+	if b.Stars < p.MinStars {
+		violations = append(violations, Violation{Reason: fmt.Sprintf("%d stars is below the minimum of %d", b.Stars, p.MinStars)})
+	}
+	if p.RequireConfirmed && b.BuildStatus != "confirmed" {
+		violations = append(violations, Violation{Reason: fmt.Sprintf("build status is %s, not confirmed", b.BuildStatus)})
+	}
+	return violations
+}
+
+func denylistMatch(entry string, b *db.Binary) bool {
+	if prefix, ok := strings.CutSuffix(entry, "/..."); ok {
+		return b.Package == prefix || strings.HasPrefix(b.Package, prefix+"/")
+	}
+	return entry == b.Package || strings.EqualFold(entry, b.Name)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Package progress emits machine-readable progress events as newline-delimited
+// JSON so that GUIs and CI wrappers can follow install, upgrade, and verify
+// runs without scraping human-oriented output.
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types, in the order they are normally emitted for one package.
+const (
+	Resolve    = "resolve"
+	Download   = "download"
+	BuildStart = "build-start"
+	BuildEnd   = "build-end"
+	Result     = "result"
+)
+
+// Formats accepted by the --progress flag.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Event is a single NDJSON progress record.
+type Event struct {
+	Event    string    `json:"event"`
+	Time     time.Time `json:"time"`
+	Name     string    `json:"name,omitempty"`
+	Package  string    `json:"package,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Module   string    `json:"module,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Duration float64   `json:"duration_seconds,omitempty"`
+}
+
+// Reporter writes events to an underlying writer. A nil *Reporter is valid
+// and discards all events, so callers don't need to check whether progress
+// output was requested.
+type Reporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// New returns a Reporter for the given --progress format. It returns nil for
+// the text format and an error for unknown formats.
+func New(format string, w io.Writer) (*Reporter, error) {
+	switch format {
+	case "", FormatText:
+		return nil, nil
+	case FormatJSON:
+		return &Reporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown progress format %q (want %s or %s)", format, FormatText, FormatJSON)
+	}
+}
+
+// Enabled reports whether events are being written.
+func (r *Reporter) Enabled() bool {
+	return r != nil
+}
+
+// Emit writes a single event. The event time is filled in if unset.
+func (r *Reporter) Emit(ev Event) {
+	if r == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(ev)
+}
+
+// GoOutput returns a writer to use as the stderr of a go command. Lines of
+// the form "go: downloading <module> <version>" are turned into Download
+// events; everything else is retained and available from the returned
+// buffer once the command has finished.
+func (r *Reporter) GoOutput(name, pkg string) (io.WriteCloser, *bytes.Buffer) {
+	var rest bytes.Buffer
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sc := bufio.NewScanner(pr)
+		for sc.Scan() {
+			line := sc.Text()
+			if mod, ok := strings.CutPrefix(line, "go: downloading "); ok {
+				fields := strings.Fields(mod)
+				ev := Event{Event: Download, Name: name, Package: pkg}
+				if len(fields) > 0 {
+					ev.Module = fields[0]
+				}
+				if len(fields) > 1 {
+					ev.Version = fields[1]
+				}
+				r.Emit(ev)
+				continue
+			}
+			rest.WriteString(line)
+			rest.WriteByte('\n')
+		}
+		io.Copy(io.Discard, pr)
+	}()
+	return &goOutput{pw: pw, done: done}, &rest
+}
+
+// goOutput closes the pipe and waits for the scanner goroutine on Close so
+// the retained output is complete once Close returns.
+type goOutput struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+}
+
+func (g *goOutput) Write(p []byte) (int, error) { return g.pw.Write(p) }
+
+func (g *goOutput) Close() error {
+	err := g.pw.Close()
+	<-g.done
+	return err
+}
@@ -0,0 +1,308 @@
+// Package release finds, downloads, and unpacks the prebuilt archives
+// attached to GitHub releases. The admin uses it to generate -bin
+// PKGBUILDs; the client installs from it when a package can't be built
+// with go install.
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxAssetSize bounds release archive downloads.
+const MaxAssetSize = 512 << 20
+
+// Asset is a file attached to a GitHub release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// archiveExts are the supported archive formats, in order of preference.
+var archiveExts = []string{".tar.gz", ".tgz", ".zip"}
+
+// assetArches maps GOARCH values to the patterns release archives use for
+// them in their file names.
+var assetArches = []struct {
+	goarch  string
+	pattern *regexp.Regexp
+}{
+	{"amd64", regexp.MustCompile(`(^|[^a-z0-9])(amd64|x86_64|x64|64-?bit)([^a-z0-9]|$)`)},
+	{"arm64", regexp.MustCompile(`(^|[^a-z0-9])(arm64|aarch64)([^a-z0-9]|$)`)},
+	{"arm", regexp.MustCompile(`(^|[^a-z0-9])(armv7l?|armhf|arm)([^a-z0-9]|$)`)},
+	{"386", regexp.MustCompile(`(^|[^a-z0-9])(386|i386|i686|x86|32-?bit)([^a-z0-9]|$)`)},
+}
+
+// assetOSes maps GOOS values to the patterns release archives use for them.
+var assetOSes = map[string]*regexp.Regexp{
+	"linux":   regexp.MustCompile(`linux`),
+	"darwin":  regexp.MustCompile(`darwin|macos|(^|[^a-z])mac([^a-z]|$)|apple`),
+	"windows": regexp.MustCompile(`windows|(^|[^a-z])win(32|64)?([^a-z]|$)`),
+	"freebsd": regexp.MustCompile(`freebsd`),
+}
+
+// checksumAsset matches the names of checksum files published by goreleaser
+// and similar tools.
+var checksumAsset = regexp.MustCompile(`(?i)(checksums?\.txt|sha256sums?(\.txt)?)$`)
+
+// FetchAssets returns the assets of the release tagged tag, using the
+// GitHub REST API at apiBase. token may be empty.
+func FetchAssets(client *http.Client, apiBase, owner, repo, token, tag string) ([]Asset, error) {
+	url := fmt.Sprintf(apiBase+"/repos/%s/%s/releases/tags/%s", owner, repo, tag)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("no GitHub release for %s/%s %s", owner, repo, tag)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("fetch release %s: status %d", tag, resp.StatusCode)
+	}
+	var release struct {
+		Assets []Asset `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	return release.Assets, nil
+}
+
+// Archives picks one archive per architecture for goos from a release's
+// assets, keyed by GOARCH. glibc builds are preferred over musl ones, and
+// tarballs over zips.
+func Archives(assets []Asset, goos string) map[string]Asset {
+	osPattern, ok := assetOSes[goos]
+	if !ok {
+		return nil
+	}
+	rank := func(a Asset) int {
+		r := 0
+		for i, ext := range archiveExts {
+			if strings.HasSuffix(strings.ToLower(a.Name), ext) {
+				r = i
+			}
+		}
+		if strings.Contains(strings.ToLower(a.Name), "musl") {
+			r += len(archiveExts)
+		}
+		return r
+	}
+
+	picked := make(map[string]Asset)
+	for _, a := range assets {
+		name := strings.ToLower(a.Name)
+		if !osPattern.MatchString(name) || ArchiveExt(name) == "" {
+			continue
+		}
+		for _, aa := range assetArches {
+			if !aa.pattern.MatchString(name) {
+				continue
+			}
+			if cur, ok := picked[aa.goarch]; !ok || rank(a) < rank(cur) {
+				picked[aa.goarch] = a
+			}
+			break
+		}
+	}
+	return picked
+}
+
+// ArchiveExt returns the archive extension of name, or "" if it isn't a
+// supported archive format.
+func ArchiveExt(name string) string {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[len(name)-len(ext):]
+		}
+	}
+	return ""
+}
+
+// Checksums downloads the release's checksum file, if it has one, and
+// returns its digests by file name. It returns nil if there is none.
+func Checksums(client *http.Client, assets []Asset) (map[string]string, error) {
+	for _, a := range assets {
+		if checksumAsset.MatchString(a.Name) {
+			data, err := Download(client, a.URL)
+			if err != nil {
+				return nil, err
+			}
+			return ParseChecksums(data), nil
+		}
+	}
+	return nil, nil
+}
+
+// ParseChecksums parses a sha256sum-style checksum file into a map from
+// file name to hex digest.
+func ParseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// Verify returns the hex sha256 of data, failing if sums lists a different
+// digest for the asset name.
+func Verify(data []byte, name string, sums map[string]string) (string, error) {
+	digest := sha256.Sum256(data)
+	sum := hex.EncodeToString(digest[:])
+	if want, ok := sums[name]; ok && want != sum {
+		return "", fmt.Errorf("checksum mismatch for %s: release lists %s, downloaded %s", name, want, sum)
+	}
+	return sum, nil
+}
+
+// Download fetches url, failing if the body exceeds MaxAssetSize.
+func Download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("download %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAssetSize {
+		return nil, fmt.Errorf("download %s: larger than %d MB", url, MaxAssetSize>>20)
+	}
+	return data, nil
+}
+
+// Files lists the regular files in a .tar.gz/.tgz or .zip archive, with
+// whether each is executable.
+func Files(data []byte, ext string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := walk(data, ext, func(name string, mode int64, _ io.Reader) error {
+		files[name] = mode&0o111 != 0
+		return nil
+	})
+	return files, err
+}
+
+// Extract returns the contents of the file at name in a .tar.gz/.tgz or
+// .zip archive.
+func Extract(data []byte, ext, name string) ([]byte, error) {
+	var contents []byte
+	found := false
+	err := walk(data, ext, func(f string, _ int64, r io.Reader) error {
+		if found || f != name {
+			return nil
+		}
+		found = true
+		var err error
+		contents, err = io.ReadAll(io.LimitReader(r, MaxAssetSize))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s not found in archive", name)
+	}
+	return contents, nil
+}
+
+// walk calls fn with the cleaned path, mode, and contents of each regular
+// file in an archive.
+func walk(data []byte, ext string, fn func(name string, mode int64, r io.Reader) error) error {
+	if strings.EqualFold(ext, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(path.Clean(f.Name), int64(f.Mode().Perm()), rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := fn(path.Clean(hdr.Name), hdr.Mode, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Find returns the shortest path in files whose base name matches one of
+// names (case-insensitively), or "".
+func Find(files map[string]bool, names []string) string {
+	var matches []string
+	for f := range files {
+		for _, n := range names {
+			if strings.EqualFold(path.Base(f), n) {
+				matches = append(matches, f)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if len(matches[i]) != len(matches[j]) {
+			return len(matches[i]) < len(matches[j])
+		}
+		return matches[i] < matches[j]
+	})
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
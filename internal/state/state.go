@@ -6,33 +6,118 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/jmelahman/gomanager/internal/dirs"
+)
+
+// Release channels an installed binary can follow.
+const (
+	// ChannelStable follows the version confirmed in the database.
+	ChannelStable = "stable"
+	// ChannelLatest follows the module proxy's @latest version.
+	ChannelLatest = "latest"
 )
 
 // InstalledBinary tracks a locally installed binary.
 type InstalledBinary struct {
-	Name       string    `json:"name"`
-	Package    string    `json:"package"`
-	Version    string    `json:"version"`
+	Name        string    `json:"name"`
+	Package     string    `json:"package"`
+	Version     string    `json:"version"`
 	InstalledAt time.Time `json:"installed_at"`
+	// Channel is the release channel upgrades follow. Empty means
+	// ChannelStable.
+	Channel string `json:"channel,omitempty"`
+	// Pinned excludes the binary from upgrade --all.
+	Pinned bool `json:"pinned,omitempty"`
+	// BinDir is the directory the binary was installed to. It is empty
+	// for binaries installed before it was recorded.
+	BinDir string `json:"bin_dir,omitempty"`
+	// ReportedVersion is the version the binary printed when run with
+	// --version by install --verify, if it printed one.
+	ReportedVersion string `json:"reported_version,omitempty"`
+	// Smoke is the result of the last smoke test of the binary by
+	// install --verify, or nil if it wasn't tested since it was installed.
+	Smoke *SmokeTest `json:"smoke,omitempty"`
+	// Sum is the go.sum hash ("h1:...") of the module the binary was
+	// built from, from its build info, or empty if it has none.
+	Sum string `json:"sum,omitempty"`
+	// SHA256 is the hex SHA-256 digest of the binary file as installed,
+	// or empty for binaries installed before it was recorded.
+	SHA256 string `json:"sha256,omitempty"`
+	// Aliases are the names of symlinks to the binary created next to it
+	// by install --alias.
+	Aliases []string `json:"aliases,omitempty"`
+	// DesktopEntry is the path of the .desktop launcher entry created for
+	// the binary by install --desktop, or empty if there is none.
+	DesktopEntry string `json:"desktop_entry,omitempty"`
+}
+
+// SmokeTest is the outcome of running an installed binary with --version
+// (or --help) to check that it executes.
+type SmokeTest struct {
+	OK bool `json:"ok"`
+	// Error describes how the binary failed to run, if it did.
+	Error    string    `json:"error,omitempty"`
+	TestedAt time.Time `json:"tested_at"`
+}
+
+// Broken reports whether the binary's last smoke test failed.
+func (b InstalledBinary) Broken() bool {
+	return b.Smoke != nil && !b.Smoke.OK
+}
+
+// ReleaseChannel returns the binary's release channel.
+func (b InstalledBinary) ReleaseChannel() string {
+	if b.Channel == "" {
+		return ChannelStable
+	}
+	return b.Channel
+}
+
+// Snapshot is a named copy of the installed binaries, saved to switch
+// between tool sets or to recover after experiments.
+type Snapshot struct {
+	CreatedAt time.Time                  `json:"created_at"`
+	Binaries  map[string]InstalledBinary `json:"binaries"`
 }
 
 // State holds local gomanager state.
 type State struct {
 	Installed map[string]InstalledBinary `json:"installed"`
+	Snapshots map[string]Snapshot        `json:"snapshots,omitempty"`
+}
+
+// file overrides the per-user state file when set with UseFile.
+var file string
+
+// UseFile makes Load and Save use the state file at path instead of the
+// per-user one, e.g. for system-wide installs.
+func UseFile(path string) {
+	file = path
 }
 
 func statePath() (string, error) {
-	configDir, err := os.UserConfigDir()
+	if file != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return "", fmt.Errorf("cannot create state directory: %w", err)
+		}
+		return file, nil
+	}
+	dir, err := dirs.Config()
 	if err != nil {
-		return "", fmt.Errorf("cannot determine config directory: %w", err)
+		return "", err
 	}
-	dir := filepath.Join(configDir, "gomanager")
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf("cannot create config directory: %w", err)
 	}
 	return filepath.Join(dir, "installed.json"), nil
 }
 
+// Path returns the path of the state file.
+func Path() (string, error) {
+	return statePath()
+}
+
 // Load reads the state from disk.
 func Load() (*State, error) {
 	path, err := statePath()
@@ -69,14 +154,73 @@ func (s *State) Save() error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-// MarkInstalled records a binary as installed.
-func (s *State) MarkInstalled(name, pkg, version string) {
+// MarkInstalled records a binary as installed in binDir. A reinstall of
+// the same package keeps its release channel, pin, aliases, and desktop
+// entry.
+func (s *State) MarkInstalled(name, pkg, version, binDir string) {
+	var channel string
+	var pinned bool
+	var aliases []string
+	var desktopEntry string
+	if prev, ok := s.Installed[name]; ok && prev.Package == pkg {
+		channel = prev.Channel
+		pinned = prev.Pinned
+		aliases = prev.Aliases
+		desktopEntry = prev.DesktopEntry
+	}
 	s.Installed[name] = InstalledBinary{
-		Name:        name,
-		Package:     pkg,
-		Version:     version,
-		InstalledAt: time.Now(),
+		Name:         name,
+		Package:      pkg,
+		Version:      version,
+		InstalledAt:  time.Now(),
+		Channel:      channel,
+		Pinned:       pinned,
+		BinDir:       binDir,
+		Aliases:      aliases,
+		DesktopEntry: desktopEntry,
+	}
+}
+
+// SetChannel sets the release channel of an installed binary.
+func (s *State) SetChannel(name, channel string) error {
+	b, ok := s.Installed[name]
+	if !ok {
+		return fmt.Errorf("%s is not installed", name)
+	}
+	if channel != ChannelStable && channel != ChannelLatest {
+		return fmt.Errorf("unknown channel %q (want %s or %s)", channel, ChannelStable, ChannelLatest)
+	}
+	b.Channel = channel
+	if channel == ChannelStable {
+		b.Channel = ""
+	}
+	s.Installed[name] = b
+	return nil
+}
+
+// SetPinned pins or unpins an installed binary.
+func (s *State) SetPinned(name string, pinned bool) error {
+	b, ok := s.Installed[name]
+	if !ok {
+		return fmt.Errorf("%s is not installed", name)
+	}
+	b.Pinned = pinned
+	s.Installed[name] = b
+	return nil
+}
+
+// SaveSnapshot records the installed binaries as the snapshot name,
+// replacing any snapshot with that name.
+func (s *State) SaveSnapshot(name string) Snapshot {
+	snap := Snapshot{CreatedAt: time.Now(), Binaries: make(map[string]InstalledBinary, len(s.Installed))}
+	for n, b := range s.Installed {
+		snap.Binaries[n] = b
+	}
+	if s.Snapshots == nil {
+		s.Snapshots = make(map[string]Snapshot)
 	}
+	s.Snapshots[name] = snap
+	return snap
 }
 
 // Remove removes a binary from the installed list.
@@ -0,0 +1,130 @@
+// Package telemetry defines the anonymized install reports clients send
+// when telemetry is turned on, and reads them back for aggregation by the
+// build pipeline. A report names only a published package, its version, the
+// platform, the Go toolchain version, and the outcome; paths, user names,
+// and error messages are never included, only the class of failure.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Install outcomes.
+const (
+	StatusInstalled = "installed"
+	StatusFailed    = "failed"
+)
+
+// Failure classes, from Classify.
+const (
+	ClassNetwork   = "network"   // a download or proxy request failed
+	ClassChecksum  = "checksum"  // checksum database verification failed
+	ClassNotFound  = "not-found" // the module, version, or package doesn't exist
+	ClassToolchain = "toolchain" // the module needs a newer Go toolchain
+	ClassCgo       = "cgo"       // the C toolchain or a C library is missing
+	ClassCompile   = "compile"   // the Go code doesn't compile
+	ClassOther     = "other"
+)
+
+// Report is one install attempt.
+type Report struct {
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	Status    string `json:"status"`
+	// Class is the failure class of a failed install.
+	Class string `json:"class,omitempty"`
+	// Date is the day of the attempt (YYYY-MM-DD), in UTC.
+	Date string `json:"date"`
+}
+
+// Valid reports whether r has everything an aggregated report needs.
+func (r Report) Valid() bool {
+	if r.Package == "" || r.Version == "" || r.Date == "" {
+		return false
+	}
+	switch r.Status {
+	case StatusInstalled:
+		return r.Class == ""
+	case StatusFailed:
+		return r.Class != ""
+	}
+	return false
+}
+
+// classPatterns match go command output to failure classes, checked in
+// order: network errors are often reported while verifying checksums or
+// looking up versions, so they are checked first.
+var classPatterns = []struct {
+	class string
+	re    *regexp.Regexp
+}{
+	{ClassNetwork, regexp.MustCompile(`(?i)dial tcp|i/o timeout|connection (refused|reset)|no such host|tls: |context deadline exceeded|unexpected EOF|\b50[234] `)},
+	{ClassChecksum, regexp.MustCompile(`(?i)SECURITY ERROR|checksum mismatch|verifying module|verifying .*go\.mod`)},
+	{ClassNotFound, regexp.MustCompile(`(?i)unknown revision|no matching versions|cannot find module|invalid version|does not contain package|module declares its path as|404 Not Found`)},
+	{ClassToolchain, regexp.MustCompile(`(?i)requires go ?>=|module requires Go|toolchain not available|go\.mod requires go`)},
+	{ClassCgo, regexp.MustCompile(`(?i)cgo|gcc|clang|C compiler|pkg-config|\.h: No such file`)},
+	{ClassCompile, regexp.MustCompile(`\.go:\d+(:\d+)?: |undefined: |build constraints exclude all Go files`)},
+}
+
+// Classify returns the failure class of an install that failed with the
+// given output (the go command's, or an error message).
+func Classify(output string) string {
+	for _, p := range classPatterns {
+		if p.re.MatchString(output) {
+			return p.class
+		}
+	}
+	return ClassOther
+}
+
+// Send posts reports to endpoint as newline-delimited JSON.
+func Send(client *http.Client, endpoint string, reports []Report) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range reports {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	resp, err := client.Post(endpoint, "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Read reads newline-delimited reports, as Send posts them, from r. Blank
+// lines are ignored; lines that aren't valid reports are skipped and
+// counted, since collected reports come from untrusted clients.
+func Read(r io.Reader) (reports []Report, skipped int, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var rep Report
+		if err := json.Unmarshal([]byte(line), &rep); err != nil || !rep.Valid() {
+			skipped++
+			continue
+		}
+		reports = append(reports, rep)
+	}
+	return reports, skipped, sc.Err()
+}